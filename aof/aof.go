@@ -0,0 +1,256 @@
+package aof
+
+import (
+	"errors"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/lib/logger"
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/parser"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CmdLine is alias for [][]byte, represents a command line
+type CmdLine = [][]byte
+
+const (
+	// FsyncAlways flushes aof file to disk after every write command, it is slow but the safest
+	FsyncAlways = "always"
+	// FsyncEverySec flushes aof file to disk every second, at most one second of data may be lost
+	FsyncEverySec = "everysec"
+	// FsyncNo lets operating system decide when to flush aof file to disk
+	FsyncNo = "no"
+)
+
+const aofQueueSize = 1 << 16
+
+// payload is a command to append, dbIndex is the database it executed on
+type payload struct {
+	// data is the command encoded in RESP, it is encoded before queued since arguments may be stored as values
+	data    []byte
+	dbIndex int
+}
+
+// Options configures Persister
+type Options struct {
+	Filename string
+	// Fsync is one of FsyncAlways, FsyncEverySec and FsyncNo, default is FsyncEverySec
+	Fsync string
+	// LoadTruncated allows loading aof file with incomplete command at the end,
+	// the incomplete command is removed from the file
+	LoadTruncated bool
+}
+
+// Persister appends write commands to aof file in RESP format, and replays the file to restore data
+type Persister struct {
+	db          database.DB
+	aofChan     chan *payload
+	aofFile     *os.File
+	aofFilename string
+	aofFsync    string
+	// loadTruncated is the aof-load-truncated option
+	loadTruncated bool
+	// aofFinished is closed once all commands in aofChan are written
+	aofFinished chan struct{}
+	// closing stops the everysec fsync goroutine
+	closing chan struct{}
+	// mu protects aofFile and currentDB
+	mu sync.Mutex
+	// currentDB is the database selected by the last command in aof file
+	currentDB int
+	// closeMu is held by SaveCmdLine for reading and by Close for writing,
+	// so no command is sent to aofChan or written to aofFile once closed
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+// NewPersister creates Persister, db is used to replay commands when loading aof file.
+// It doesn't start appending until Start is invoked, so that loading won't append commands again.
+func NewPersister(db database.DB, options Options) (*Persister, error) {
+	fsync := options.Fsync
+	switch fsync {
+	case FsyncAlways, FsyncEverySec, FsyncNo:
+	case "":
+		fsync = FsyncEverySec
+	default:
+		return nil, errors.New("invalid appendfsync: " + fsync)
+	}
+	return &Persister{
+		db:            db,
+		aofFilename:   options.Filename,
+		aofFsync:      fsync,
+		loadTruncated: options.LoadTruncated,
+		aofFinished:   make(chan struct{}),
+		closing:       make(chan struct{}),
+	}, nil
+}
+
+// Start opens aof file for appending and starts background goroutines
+func (persister *Persister) Start() error {
+	aofFile, err := os.OpenFile(persister.aofFilename, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	persister.aofFile = aofFile
+	// the file may end in any database, select it again before the first command
+	persister.currentDB = -1
+	persister.aofChan = make(chan *payload, aofQueueSize)
+	go persister.listenCmd()
+	if persister.aofFsync == FsyncEverySec {
+		go persister.fsyncEverySecond()
+	}
+	return nil
+}
+
+// SaveCmdLine appends command executed on the given database to aof file.
+// It returns after the command is written and flushed if appendfsync is always, otherwise it is queued.
+// Commands saved after Close are dropped.
+func (persister *Persister) SaveCmdLine(dbIndex int, cmdLine CmdLine) {
+	persister.closeMu.RLock()
+	defer persister.closeMu.RUnlock()
+	if persister.closed {
+		return
+	}
+	p := &payload{
+		data:    protocol.NewMultiBulkReply(cmdLine).ToBytes(),
+		dbIndex: dbIndex,
+	}
+	if persister.aofFsync == FsyncAlways {
+		persister.writeAof(p)
+		return
+	}
+	persister.aofChan <- p
+}
+
+// listenCmd writes queued commands into aof file
+func (persister *Persister) listenCmd() {
+	for p := range persister.aofChan {
+		persister.writeAof(p)
+	}
+	close(persister.aofFinished)
+}
+
+func (persister *Persister) writeAof(p *payload) {
+	persister.mu.Lock()
+	defer persister.mu.Unlock()
+	if p.dbIndex != persister.currentDB {
+		selectCmd := utils.ToCmdLine("select", strconv.Itoa(p.dbIndex))
+		_, err := persister.aofFile.Write(protocol.NewMultiBulkReply(selectCmd).ToBytes())
+		if err != nil {
+			logger.Warn(err)
+			return // skip this command
+		}
+		persister.currentDB = p.dbIndex
+	}
+	_, err := persister.aofFile.Write(p.data)
+	if err != nil {
+		logger.Warn(err)
+	}
+	if persister.aofFsync == FsyncAlways {
+		persister.fsync()
+	}
+}
+
+// fsync flushes aof file to disk, the caller should hold mu
+func (persister *Persister) fsync() {
+	if err := persister.aofFile.Sync(); err != nil {
+		logger.Error("fsync aof failed: " + err.Error())
+	}
+}
+
+func (persister *Persister) fsyncEverySecond() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			persister.mu.Lock()
+			persister.fsync()
+			persister.mu.Unlock()
+		case <-persister.closing:
+			return
+		}
+	}
+}
+
+// LoadAof replays commands in aof file, a missing file is treated as empty.
+// If the file ends with an incomplete command, it is removed from the file when aof-load-truncated is enabled,
+// otherwise an error is returned.
+func (persister *Persister) LoadAof() error {
+	file, err := os.Open(persister.aofFilename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// validSize is the size of complete commands, commands are written in canonical RESP,
+	// so the size of a command equals the size of its encoding
+	var validSize int64
+	loaded := 0
+	conn := connection.NewFakeConn()
+	ch := parser.ParseStream(file)
+	for p := range ch {
+		if p.Err != nil {
+			if p.Err == io.EOF || p.Err == io.ErrUnexpectedEOF {
+				break
+			}
+			return errors.New("bad file format in aof at offset " + strconv.FormatInt(validSize, 10) + ": " + p.Err.Error())
+		}
+		cmd, ok := p.Data.(*protocol.MultiBulkReply)
+		if !ok {
+			return errors.New("bad file format in aof at offset " + strconv.FormatInt(validSize, 10) + ": require multi bulk protocol")
+		}
+		reply := persister.db.Exec(conn, cmd.Args)
+		if protocol.IsErrorReply(reply) {
+			logger.Error("exec err ", string(reply.ToBytes()))
+		}
+		validSize += int64(len(cmd.ToBytes()))
+		loaded++
+	}
+
+	if validSize < info.Size() {
+		if !persister.loadTruncated {
+			return errors.New("unexpected end of aof file, set aof-load-truncated yes to load it")
+		}
+		logger.Warn("aof file is truncated, " + strconv.FormatInt(info.Size()-validSize, 10) +
+			" bytes of incomplete command at the end are removed")
+		if err := os.Truncate(persister.aofFilename, validSize); err != nil {
+			return err
+		}
+	}
+	logger.Info("aof loaded, " + strconv.Itoa(loaded) + " commands replayed")
+	return nil
+}
+
+// Close waits for queued commands to be written, then flushes and closes aof file
+func (persister *Persister) Close() {
+	persister.closeMu.Lock()
+	if persister.aofFile == nil || persister.closed {
+		persister.closeMu.Unlock()
+		return
+	}
+	// commands being saved have finished since closeMu is locked
+	persister.closed = true
+	close(persister.aofChan)
+	persister.closeMu.Unlock()
+	<-persister.aofFinished
+	close(persister.closing)
+	persister.mu.Lock()
+	defer persister.mu.Unlock()
+	persister.fsync()
+	if err := persister.aofFile.Close(); err != nil {
+		logger.Warn(err)
+	}
+}
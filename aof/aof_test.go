@@ -0,0 +1,172 @@
+package aof
+
+import (
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordDB records commands replayed by Persister
+type recordDB struct {
+	mu       sync.Mutex
+	cmdLines []string
+}
+
+func (db *recordDB) Exec(client redis.Connection, cmdLine [][]byte) redis.Reply {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.cmdLines = append(db.cmdLines, string(protocol.NewMultiBulkReply(cmdLine).ToBytes()))
+	return protocol.NewOkReply()
+}
+
+func (db *recordDB) AfterClientClose(conn redis.Connection) {}
+
+func (db *recordDB) Close() {}
+
+func encode(cmdLines ...CmdLine) []byte {
+	var buf []byte
+	for _, cmdLine := range cmdLines {
+		buf = append(buf, protocol.NewMultiBulkReply(cmdLine).ToBytes()...)
+	}
+	return buf
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	for _, fsync := range []string{FsyncAlways, FsyncEverySec, FsyncNo} {
+		filename := filepath.Join(t.TempDir(), "appendonly.aof")
+		persister, err := NewPersister(&recordDB{}, Options{Filename: filename, Fsync: fsync})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := persister.Start(); err != nil {
+			t.Fatal(err)
+		}
+		persister.SaveCmdLine(0, utils.ToCmdLine("set", "a", "1"))
+		persister.SaveCmdLine(1, utils.ToCmdLine("set", "b", "2"))
+		persister.SaveCmdLine(1, utils.ToCmdLine("del", "b"))
+		persister.Close()
+		// commands after close are dropped
+		persister.SaveCmdLine(0, utils.ToCmdLine("set", "c", "3"))
+		persister.Close()
+
+		db := &recordDB{}
+		loader, _ := NewPersister(db, Options{Filename: filename})
+		if err := loader.LoadAof(); err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{
+			string(encode(utils.ToCmdLine("select", "0"))),
+			string(encode(utils.ToCmdLine("set", "a", "1"))),
+			string(encode(utils.ToCmdLine("select", "1"))),
+			string(encode(utils.ToCmdLine("set", "b", "2"))),
+			string(encode(utils.ToCmdLine("del", "b"))),
+		}
+		if strings.Join(db.cmdLines, "") != strings.Join(expected, "") {
+			t.Errorf("appendfsync %s: expected %q, actually %q", fsync, expected, db.cmdLines)
+		}
+	}
+}
+
+func TestLoadTruncated(t *testing.T) {
+	complete := encode(utils.ToCmdLine("select", "0"), utils.ToCmdLine("set", "a", "1"))
+	last := encode(utils.ToCmdLine("set", "b", "2"))
+	for size := 1; size < len(last); size++ {
+		filename := filepath.Join(t.TempDir(), "appendonly.aof")
+		if err := os.WriteFile(filename, append(append([]byte{}, complete...), last[:size]...), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		persister, _ := NewPersister(&recordDB{}, Options{Filename: filename})
+		if err := persister.LoadAof(); err == nil {
+			t.Errorf("expected error when loading truncated tail %q", last[:size])
+		}
+
+		db := &recordDB{}
+		persister, _ = NewPersister(db, Options{Filename: filename, LoadTruncated: true})
+		if err := persister.LoadAof(); err != nil {
+			t.Errorf("unexpected error %v when loading truncated tail %q", err, last[:size])
+			continue
+		}
+		if len(db.cmdLines) != 2 {
+			t.Errorf("expected 2 commands replayed, actually %d", len(db.cmdLines))
+		}
+		content, _ := os.ReadFile(filename)
+		if string(content) != string(complete) {
+			t.Errorf("expected tail %q removed, actually %q", last[:size], content)
+		}
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	complete := encode(utils.ToCmdLine("set", "a", "1"))
+	for _, tail := range []string{"\n", "x\n", "*1\r\n\n", "*x\r\n", "+OK\r\n"} {
+		filename := filepath.Join(t.TempDir(), "appendonly.aof")
+		if err := os.WriteFile(filename, append(append([]byte{}, complete...), tail...), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// a corrupt file is not loaded even if aof-load-truncated is enabled
+		persister, _ := NewPersister(&recordDB{}, Options{Filename: filename, LoadTruncated: true})
+		if err := persister.LoadAof(); err == nil {
+			t.Errorf("expected error when loading corrupt tail %q", tail)
+		}
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	persister, _ := NewPersister(&recordDB{}, Options{Filename: filepath.Join(t.TempDir(), "missing.aof")})
+	if err := persister.LoadAof(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCloseWhileSaving(t *testing.T) {
+	for _, fsync := range []string{FsyncAlways, FsyncEverySec} {
+		filename := filepath.Join(t.TempDir(), "appendonly.aof")
+		persister, _ := NewPersister(&recordDB{}, Options{Filename: filename, Fsync: fsync})
+		if err := persister.Start(); err != nil {
+			t.Fatal(err)
+		}
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					persister.SaveCmdLine(0, utils.ToCmdLine("incr", "a"))
+				}
+			}()
+		}
+		persister.Close()
+		wg.Wait()
+
+		// the file ends with complete commands
+		persister, _ = NewPersister(&recordDB{}, Options{Filename: filename})
+		if err := persister.LoadAof(); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestSaveModifiedArgs(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "appendonly.aof")
+	persister, _ := NewPersister(&recordDB{}, Options{Filename: filename, Fsync: FsyncNo})
+	if err := persister.Start(); err != nil {
+		t.Fatal(err)
+	}
+	// arguments may be stored as values and modified in place later
+	cmdLine := utils.ToCmdLine("set", "a", "1")
+	persister.SaveCmdLine(0, cmdLine)
+	cmdLine[2][0] = '2'
+	persister.Close()
+
+	content, _ := os.ReadFile(filename)
+	expected := encode(utils.ToCmdLine("select", "0"), utils.ToCmdLine("set", "a", "1"))
+	if string(content) != string(expected) {
+		t.Errorf("expected %q, actually %q", expected, content)
+	}
+}
@@ -16,11 +16,13 @@ type ServerProperties struct {
 	Port              int    `cfg:"port"`
 	AppendOnly        bool   `cfg:"appendonly"`
 	AppendFilename    string `cfg:"appendfilename"`
+	AppendFsync       string `cfg:"appendfsync"`
+	AofLoadTruncated  bool   `cfg:"aof-load-truncated"`
 	MaxClients        int    `cfg:"maxclients"`
 	RequirePass       string `cfg:"requirepass"`
 	Databases         int    `cfg:"databases"`
 	RDBFilename       string `cfg:"dbfilename"`
-	MasterAuth        string `cfg:"masterauth""`
+	MasterAuth        string `cfg:"masterauth"`
 	SlaveAnnouncePort int    `cfg:"slave-announce-port"`
 	SlaveAnnounceIP   string `cfg:"slave-announce-ip"`
 	ReplTimeout       int    `cfg:"repl-timeout"`
@@ -35,14 +37,18 @@ var Properties *ServerProperties
 func init() {
 	// default config
 	Properties = &ServerProperties{
-		Bind:       "127.0.0.1",
-		Port:       6379,
-		AppendOnly: false,
+		Bind:             "127.0.0.1",
+		Port:             6379,
+		AppendOnly:       false,
+		AofLoadTruncated: true,
 	}
 }
 
 func parse(src io.Reader) *ServerProperties {
-	config := &ServerProperties{}
+	config := &ServerProperties{
+		// the same as redis, an aof file truncated by crash is loaded by default
+		AofLoadTruncated: true,
+	}
 
 	// read config file
 	rawMap := make(map[string]string)
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := "bind 0.0.0.0\nport 6399\nappendonly yes\n# comment\nappendfsync always\n"
+	p := parse(strings.NewReader(src))
+	if p.Bind != "0.0.0.0" || p.Port != 6399 || !p.AppendOnly || p.AppendFsync != "always" {
+		t.Errorf("unexpected properties %+v", p)
+	}
+	if !p.AofLoadTruncated {
+		t.Error("expected aof-load-truncated enabled by default")
+	}
+	p = parse(strings.NewReader("aof-load-truncated no\n"))
+	if p.AofLoadTruncated {
+		t.Error("expected aof-load-truncated disabled")
+	}
+}
@@ -0,0 +1,624 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/datastruct/bitmap"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// maxBitOffset is the max offset of SETBIT, bitmaps are limited to 512MB the same as strings
+const maxBitOffset = maxStringSize*8 - 1
+
+// bitmaps share storage with strings, so GET returns the bytes written by SETBIT
+func (db *DB) getAsBitMap(key string) (*bitmap.BitMap, protocol.ErrorReply) {
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return nil, errReply
+	}
+	if bytes == nil {
+		return nil, nil
+	}
+	return bitmap.FromBytes(bytes), nil
+}
+
+func parseBitOffset(arg []byte) (int64, protocol.ErrorReply) {
+	offset, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil || offset < 0 || offset > maxBitOffset {
+		return 0, protocol.NewErrReply("ERR bit offset is not an integer or out of range")
+	}
+	return offset, nil
+}
+
+// execSetBit sets the bit at offset and returns the original bit, the string grows as needed
+func execSetBit(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	offset, errReply := parseBitOffset(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	var val byte
+	switch string(args[2]) {
+	case "1":
+		val = 1
+	case "0":
+		val = 0
+	default:
+		return protocol.NewErrReply("ERR bit is not an integer or out of range")
+	}
+
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	bm := bitmap.FromBytes(bytes)
+	original := bm.GetBit(offset)
+	bm.SetBit(offset, val)
+	db.PutEntity(key, &database.DataEntity{
+		Data: bm.ToBytes(),
+	})
+	db.addAof(utils.ToCmdLine3("setbit", args...))
+	return protocol.NewIntReply(int64(original))
+}
+
+// execGetBit returns the bit at offset, bits beyond the string are 0
+func execGetBit(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	offset, errReply := parseBitOffset(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	bm, errReply := db.getAsBitMap(key)
+	if errReply != nil {
+		return errReply
+	}
+	if bm == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(int64(bm.GetBit(offset)))
+}
+
+// parseBitRange parses `start end [BYTE|BIT]` and converts it to bit range [begin, end)
+// ok is false if the range is empty
+func parseBitRange(startArg, endArg []byte, unitArgs [][]byte, byteSize int64) (begin int64, end int64, ok bool, errReply protocol.ErrorReply) {
+	start, err := strconv.ParseInt(string(startArg), 10, 64)
+	if err != nil {
+		return 0, 0, false, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	stop, err := strconv.ParseInt(string(endArg), 10, 64)
+	if err != nil {
+		return 0, 0, false, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	isBit := false
+	if len(unitArgs) == 1 {
+		switch strings.ToUpper(string(unitArgs[0])) {
+		case "BYTE":
+		case "BIT":
+			isBit = true
+		default:
+			return 0, 0, false, protocol.NewSyntaxErrReply()
+		}
+	} else if len(unitArgs) > 1 {
+		return 0, 0, false, protocol.NewSyntaxErrReply()
+	}
+	if isBit {
+		b, e, ok := normalizeRange(start, stop, byteSize*8)
+		return int64(b), int64(e), ok, nil
+	}
+	b, e, ok := normalizeRange(start, stop, byteSize)
+	return int64(b) * 8, int64(e) * 8, ok, nil
+}
+
+// execBitCount counts the set bits, BYTE range by default
+// BITCOUNT key [start end [BYTE | BIT]]
+func execBitCount(db *DB, args [][]byte) redis.Reply {
+	if len(args) == 2 {
+		return protocol.NewSyntaxErrReply()
+	}
+	key := string(args[0])
+	bm, errReply := db.getAsBitMap(key)
+	if errReply != nil {
+		return errReply
+	}
+	var byteSize int64
+	if bm != nil {
+		byteSize = int64(len(bm.ToBytes()))
+	}
+	begin, end := int64(0), byteSize*8
+	if len(args) > 2 {
+		var ok bool
+		begin, end, ok, errReply = parseBitRange(args[1], args[2], args[3:], byteSize)
+		if errReply != nil {
+			return errReply
+		}
+		if !ok {
+			return protocol.NewIntReply(0)
+		}
+	}
+	if bm == nil || begin >= end {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(countBits(bm, begin, end))
+}
+
+// execBitPos returns the position of the first bit set to 1 or 0
+// BITPOS key bit [start [end [BYTE | BIT]]]
+func execBitPos(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	var target byte
+	switch string(args[1]) {
+	case "1":
+		target = 1
+	case "0":
+		target = 0
+	default:
+		return protocol.NewErrReply("ERR The bit argument must be 1 or 0.")
+	}
+	if len(args) > 5 {
+		return protocol.NewSyntaxErrReply()
+	}
+
+	bm, errReply := db.getAsBitMap(key)
+	if errReply != nil {
+		return errReply
+	}
+	var byteSize int64
+	if bm != nil {
+		byteSize = int64(len(bm.ToBytes()))
+	}
+	endGiven := len(args) >= 4
+	begin, end, ok := int64(0), byteSize*8, true
+	if len(args) >= 3 {
+		endArg := []byte("-1")
+		var unitArgs [][]byte
+		if endGiven {
+			endArg = args[3]
+			unitArgs = args[4:]
+		}
+		begin, end, ok, errReply = parseBitRange(args[2], endArg, unitArgs, byteSize)
+		if errReply != nil {
+			return errReply
+		}
+	}
+	if bm == nil {
+		// a missing key is an empty string, which is all zeros
+		if target == 0 {
+			return protocol.NewIntReply(0)
+		}
+		return protocol.NewIntReply(-1)
+	}
+	if !ok {
+		return protocol.NewIntReply(-1)
+	}
+
+	pos := findBit(bm, target, begin, end)
+	if pos == -1 && target == 0 && !endGiven {
+		// bits beyond the string are considered as 0
+		return protocol.NewIntReply(byteSize * 8)
+	}
+	return protocol.NewIntReply(pos)
+}
+
+// countBits counts set bits within [begin, end), whole bytes are counted in batch
+func countBits(bm *bitmap.BitMap, begin int64, end int64) int64 {
+	var count int64
+	countBit := func(offset int64, val byte) bool {
+		count += int64(val)
+		return true
+	}
+	firstByte := (begin + 7) / 8
+	lastByte := end / 8
+	if firstByte >= lastByte {
+		bm.ForEachBit(begin, end, countBit)
+		return count
+	}
+	if begin < firstByte*8 {
+		bm.ForEachBit(begin, firstByte*8, countBit)
+	}
+	bm.ForEachByte(int(firstByte), int(lastByte), func(offset int64, val byte) bool {
+		count += int64(bits.OnesCount8(val))
+		return true
+	})
+	if lastByte*8 < end {
+		bm.ForEachBit(lastByte*8, end, countBit)
+	}
+	return count
+}
+
+// findBit returns the offset of the first bit equals to target within [begin, end), or -1 if not found
+// bytes without the target bit are skipped in batch
+func findBit(bm *bitmap.BitMap, target byte, begin int64, end int64) int64 {
+	pos := int64(-1)
+	checkBit := func(offset int64, val byte) bool {
+		if val == target {
+			pos = offset
+			return false
+		}
+		return true
+	}
+	firstByte := (begin + 7) / 8
+	lastByte := end / 8
+	if firstByte >= lastByte {
+		bm.ForEachBit(begin, end, checkBit)
+		return pos
+	}
+	if begin < firstByte*8 {
+		bm.ForEachBit(begin, firstByte*8, checkBit)
+	}
+	if pos == -1 {
+		skip := byte(0)
+		if target == 0 {
+			skip = 0xff
+		}
+		bm.ForEachByte(int(firstByte), int(lastByte), func(offset int64, val byte) bool {
+			if val == skip {
+				return true
+			}
+			bm.ForEachBit(offset*8, offset*8+8, checkBit)
+			return false
+		})
+	}
+	if pos == -1 && lastByte*8 < end {
+		bm.ForEachBit(lastByte*8, end, checkBit)
+	}
+	return pos
+}
+
+// execBitOp performs bitwise operation between strings and stores the result into the destination key
+// BITOP AND | OR | XOR | NOT destkey key [key ...]
+func execBitOp(db *DB, args [][]byte) redis.Reply {
+	op := strings.ToUpper(string(args[0]))
+	dest := string(args[1])
+	keys := args[2:]
+	switch op {
+	case "AND", "OR", "XOR":
+	case "NOT":
+		if len(keys) != 1 {
+			return protocol.NewErrReply("ERR BITOP NOT must be called with a single source key.")
+		}
+	default:
+		return protocol.NewSyntaxErrReply()
+	}
+
+	values := make([][]byte, len(keys))
+	maxLen := 0
+	for i, key := range keys {
+		bytes, errReply := db.getAsString(string(key))
+		if errReply != nil {
+			return errReply
+		}
+		values[i] = bytes
+		if len(bytes) > maxLen {
+			maxLen = len(bytes)
+		}
+	}
+
+	// shorter strings are padded with zero bytes
+	result := make([]byte, maxLen)
+	for i := 0; i < maxLen; i++ {
+		var b byte
+		for j, value := range values {
+			var v byte
+			if i < len(value) {
+				v = value[i]
+			}
+			if j == 0 {
+				b = v
+				continue
+			}
+			switch op {
+			case "AND":
+				b &= v
+			case "OR":
+				b |= v
+			case "XOR":
+				b ^= v
+			}
+		}
+		if op == "NOT" {
+			b = ^b
+		}
+		result[i] = b
+	}
+
+	db.Remove(dest)
+	if maxLen > 0 {
+		db.PutEntity(dest, &database.DataEntity{
+			Data: result,
+		})
+	}
+	db.addAof(utils.ToCmdLine3("bitop", args...))
+	return protocol.NewIntReply(int64(maxLen))
+}
+
+const (
+	bitFieldGet = iota
+	bitFieldSet
+	bitFieldIncrBy
+)
+
+const (
+	overflowWrap = iota
+	overflowSat
+	overflowFail
+)
+
+// bitFieldOp is a subcommand of BITFIELD
+type bitFieldOp struct {
+	op       int
+	signed   bool
+	width    int
+	offset   int64
+	value    int64 // value of SET or increment of INCRBY
+	overflow int
+}
+
+func parseBitFieldType(arg []byte) (signed bool, width int, errReply protocol.ErrorReply) {
+	errReply = protocol.NewErrReply("ERR Invalid bitfield type. Use something like i16 u8. " +
+		"Note that u64 is not supported but i64 is.")
+	s := string(arg)
+	if len(s) < 2 {
+		return false, 0, errReply
+	}
+	switch s[0] {
+	case 'i', 'I':
+		signed = true
+	case 'u', 'U':
+		signed = false
+	default:
+		return false, 0, errReply
+	}
+	w, err := strconv.Atoi(s[1:])
+	if err != nil || w < 1 || (signed && w > 64) || (!signed && w > 63) {
+		return false, 0, errReply
+	}
+	return signed, w, nil
+}
+
+// parseBitFieldOffset parses offset of bit field, `#N` means N times the width
+func parseBitFieldOffset(arg []byte, width int) (int64, protocol.ErrorReply) {
+	s := string(arg)
+	multiply := false
+	if len(s) > 0 && s[0] == '#' {
+		multiply = true
+		s = s[1:]
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, protocol.NewErrReply("ERR bit offset is not an integer or out of range")
+	}
+	if multiply {
+		if offset > maxBitOffset/int64(width) {
+			return 0, protocol.NewErrReply("ERR bit offset is not an integer or out of range")
+		}
+		offset *= int64(width)
+	}
+	if offset+int64(width)-1 > maxBitOffset {
+		return 0, protocol.NewErrReply("ERR bit offset is not an integer or out of range")
+	}
+	return offset, nil
+}
+
+// parseBitFieldOps parses subcommands of BITFIELD, readonly is true for BITFIELD_RO
+func parseBitFieldOps(args [][]byte, readonly bool) ([]*bitFieldOp, protocol.ErrorReply) {
+	ops := make([]*bitFieldOp, 0)
+	overflow := overflowWrap
+	for i := 0; i < len(args); {
+		subCmd := strings.ToUpper(string(args[i]))
+		if readonly && subCmd != "GET" {
+			return nil, protocol.NewErrReply("ERR BITFIELD_RO only supports the GET subcommand")
+		}
+		if subCmd == "OVERFLOW" {
+			if i+1 >= len(args) {
+				return nil, protocol.NewSyntaxErrReply()
+			}
+			switch strings.ToUpper(string(args[i+1])) {
+			case "WRAP":
+				overflow = overflowWrap
+			case "SAT":
+				overflow = overflowSat
+			case "FAIL":
+				overflow = overflowFail
+			default:
+				return nil, protocol.NewErrReply("ERR Invalid OVERFLOW type specified")
+			}
+			i += 2
+			continue
+		}
+
+		op := &bitFieldOp{overflow: overflow}
+		argNum := 3
+		switch subCmd {
+		case "GET":
+			op.op = bitFieldGet
+		case "SET":
+			op.op = bitFieldSet
+			argNum = 4
+		case "INCRBY":
+			op.op = bitFieldIncrBy
+			argNum = 4
+		default:
+			return nil, protocol.NewSyntaxErrReply()
+		}
+		if i+argNum > len(args) {
+			return nil, protocol.NewSyntaxErrReply()
+		}
+		var errReply protocol.ErrorReply
+		op.signed, op.width, errReply = parseBitFieldType(args[i+1])
+		if errReply != nil {
+			return nil, errReply
+		}
+		op.offset, errReply = parseBitFieldOffset(args[i+2], op.width)
+		if errReply != nil {
+			return nil, errReply
+		}
+		if argNum == 4 {
+			value, err := strconv.ParseInt(string(args[i+3]), 10, 64)
+			if err != nil {
+				return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			op.value = value
+		}
+		ops = append(ops, op)
+		i += argNum
+	}
+	return ops, nil
+}
+
+// toSigned sign-extends the lowest `width` bits of val
+func toSigned(val uint64, width int) int64 {
+	if width < 64 && val&(1<<(width-1)) > 0 {
+		val |= ^uint64(0) << width
+	}
+	return int64(val)
+}
+
+// wrapBits keeps the lowest `width` bits of val, sign-extended if signed
+func wrapBits(val uint64, width int, signed bool) int64 {
+	if width < 64 {
+		val &= ^(^uint64(0) << width)
+	}
+	if signed {
+		return toSigned(val, width)
+	}
+	return int64(val)
+}
+
+// addSigned returns value + incr within the given width according to the overflow policy
+// ok is false if overflow is FAIL and it overflows
+func addSigned(value int64, incr int64, width int, overflow int) (result int64, ok bool) {
+	max := int64(math.MaxInt64)
+	if width < 64 {
+		max = 1<<(width-1) - 1
+	}
+	min := -max - 1
+	sum := uint64(value) + uint64(incr)
+	var limit int64
+	if (incr > 0 && value > max-incr) || value > max {
+		limit = max
+	} else if (incr < 0 && value < min-incr) || value < min {
+		limit = min
+	} else {
+		return int64(sum), true
+	}
+	switch overflow {
+	case overflowSat:
+		return limit, true
+	case overflowFail:
+		return 0, false
+	}
+	return wrapBits(sum, width, true), true
+}
+
+// addUnsigned returns value + incr within the given width according to the overflow policy
+// ok is false if overflow is FAIL and it overflows
+func addUnsigned(value uint64, incr int64, width int, overflow int) (result int64, ok bool) {
+	max := uint64(1)<<width - 1
+	sum := value + uint64(incr)
+	var limit uint64
+	if value > max || (incr > 0 && uint64(incr) > max-value) {
+		limit = max
+	} else if incr < 0 && uint64(-incr) > value {
+		limit = 0
+	} else {
+		return int64(sum), true
+	}
+	switch overflow {
+	case overflowSat:
+		return int64(limit), true
+	case overflowFail:
+		return 0, false
+	}
+	return wrapBits(sum, width, false), true
+}
+
+func bitFieldGeneric(db *DB, args [][]byte, readonly bool) redis.Reply {
+	key := string(args[0])
+	ops, errReply := parseBitFieldOps(args[1:], readonly)
+	if errReply != nil {
+		return errReply
+	}
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	bm := bitmap.FromBytes(bytes)
+	written := false
+	result := make([]redis.Reply, len(ops))
+	for i, op := range ops {
+		current := bm.GetBits(op.offset, op.width)
+		var currentVal int64
+		if op.signed {
+			currentVal = toSigned(current, op.width)
+		} else {
+			currentVal = int64(current)
+		}
+		if op.op == bitFieldGet {
+			result[i] = protocol.NewIntReply(currentVal)
+			continue
+		}
+
+		// SET is checked for overflow as adding value to 0
+		var newVal int64
+		var ok bool
+		switch {
+		case op.op == bitFieldSet && op.signed:
+			newVal, ok = addSigned(op.value, 0, op.width, op.overflow)
+		case op.op == bitFieldSet:
+			newVal, ok = addUnsigned(uint64(op.value), 0, op.width, op.overflow)
+		case op.signed:
+			newVal, ok = addSigned(currentVal, op.value, op.width, op.overflow)
+		default:
+			newVal, ok = addUnsigned(current, op.value, op.width, op.overflow)
+		}
+		if !ok {
+			result[i] = protocol.NewNullBulkReply()
+			continue
+		}
+		bm.SetBits(op.offset, op.width, uint64(newVal))
+		written = true
+		if op.op == bitFieldSet {
+			result[i] = protocol.NewIntReply(currentVal)
+		} else {
+			result[i] = protocol.NewIntReply(newVal)
+		}
+	}
+	if written {
+		db.PutEntity(key, &database.DataEntity{
+			Data: bm.ToBytes(),
+		})
+		db.addAof(utils.ToCmdLine3("bitfield", args...))
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+// execBitField treats the string as an array of integers with arbitrary width
+// BITFIELD key [GET encoding offset | [OVERFLOW WRAP | SAT | FAIL] SET encoding offset value | INCRBY encoding offset increment ...]
+func execBitField(db *DB, args [][]byte) redis.Reply {
+	return bitFieldGeneric(db, args, false)
+}
+
+// execBitFieldRO is the read-only variant of BITFIELD
+func execBitFieldRO(db *DB, args [][]byte) redis.Reply {
+	return bitFieldGeneric(db, args, true)
+}
+
+// prepareBitOp returns the destination key as write key and the source keys as read keys
+func prepareBitOp(args [][]byte) ([]string, []string) {
+	return prepareSetCalculateStore(args[1:])
+}
+
+func init() {
+	RegisterCommand("SetBit", execSetBit, writeFirstKey, 4)
+	RegisterCommand("GetBit", execGetBit, readFirstKey, 3)
+	RegisterCommand("BitCount", execBitCount, readFirstKey, -2)
+	RegisterCommand("BitPos", execBitPos, readFirstKey, -3)
+	RegisterCommand("BitOp", execBitOp, prepareBitOp, -4)
+	RegisterCommand("BitField", execBitField, writeFirstKey, -2)
+	RegisterCommand("BitField_RO", execBitFieldRO, readFirstKey, -2)
+}
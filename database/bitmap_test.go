@@ -0,0 +1,179 @@
+package database
+
+import "testing"
+
+func TestSetBit(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "setbit", "k", "7", "1"), 0)
+	assertBulkReply(t, execCmd(db, "get", "k"), "\x01")
+	reply := execCmd(db, "get", "k")
+	assertIntReply(t, execCmd(db, "setbit", "k", "7", "0"), 1)
+	assertIntReply(t, execCmd(db, "setbit", "k", "1", "1"), 0)
+	// reply made before modification is not affected
+	assertBulkReply(t, reply, "\x01")
+	assertBulkReply(t, execCmd(db, "get", "k"), "\x40")
+	// grow
+	assertIntReply(t, execCmd(db, "setbit", "k", "23", "1"), 0)
+	assertBulkReply(t, execCmd(db, "get", "k"), "\x40\x00\x01")
+	assertIntReply(t, execCmd(db, "getbit", "k", "23"), 1)
+	assertIntReply(t, execCmd(db, "getbit", "k", "100"), 0)
+
+	assertErrReply(t, execCmd(db, "setbit", "k", "0", "2"), "ERR bit is not an integer or out of range")
+	execCmd(db, "rpush", "list", "a")
+	assertErrReply(t, execCmd(db, "setbit", "list", "0", "1"),
+		"WRONGTYPE Operation against a key holding the wrong kind of value")
+}
+
+func TestBitField(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "k", "\x00")
+	reply := execCmd(db, "get", "k")
+	mget := execCmd(db, "mget", "k")
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "u8", "0", "255", "get", "u8", "0"), "*2\r\n:0\r\n:255\r\n")
+	assertBulkReply(t, reply, "\x00")
+	assertMultiBulkReply(t, mget, "\x00")
+	assertReply(t, execCmd(db, "bitfield", "k", "incrby", "u8", "8", "2"), "*1\r\n:2\r\n")
+	assertBulkReply(t, execCmd(db, "get", "k"), "\xff\x02")
+	assertReply(t, execCmd(db, "bitfield", "k", "overflow", "fail", "incrby", "u8", "0", "1"), "*1\r\n$-1\r\n")
+	assertReply(t, execCmd(db, "bitfield_ro", "k", "get", "i8", "0"), "*1\r\n:-1\r\n")
+}
+
+func TestSetRange(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "k", "hello")
+	reply := execCmd(db, "getrange", "k", "0", "-1")
+	assertIntReply(t, execCmd(db, "setrange", "k", "0", "J"), 5)
+	assertBulkReply(t, reply, "hello")
+	assertIntReply(t, execCmd(db, "setrange", "k", "7", "!"), 8)
+	assertBulkReply(t, execCmd(db, "get", "k"), "Jello\x00\x00!")
+	assertIntReply(t, execCmd(db, "setrange", "missing", "0", ""), 0)
+	assertNotExists(t, db, "missing")
+}
+
+func TestBitCount(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "k", "foobar")
+	assertIntReply(t, execCmd(db, "bitcount", "k"), 26)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "0", "0"), 4)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "1", "1"), 6)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "-2", "-1"), 7)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "1", "1", "byte"), 6)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "5", "30", "bit"), 17)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "-1", "-1", "bit"), 0)
+	assertIntReply(t, execCmd(db, "bitcount", "k", "3", "1"), 0)
+	assertIntReply(t, execCmd(db, "bitcount", "missing"), 0)
+	assertIntReply(t, execCmd(db, "bitcount", "missing", "0", "-1"), 0)
+
+	assertErrReply(t, execCmd(db, "bitcount", "k", "0"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "bitcount", "k", "0", "1", "word"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "bitcount", "k", "a", "1"), "ERR value is not an integer or out of range")
+	execCmd(db, "rpush", "list", "a")
+	assertErrReply(t, execCmd(db, "bitcount", "list"), wrongTypeErr)
+}
+
+func TestBitPos(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "k", "\xff\xf0\x00")
+	assertIntReply(t, execCmd(db, "bitpos", "k", "0"), 12)
+	execCmd(db, "set", "k", "\x00\xff\xf0")
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1", "0"), 8)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1", "2"), 16)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1", "2", "-1", "byte"), 16)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1", "7", "15", "bit"), 8)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "0", "12", "-1", "bit"), 20)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1", "7", "-3", "bit"), 8)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1", "0", "7", "bit"), -1)
+	execCmd(db, "set", "k", "\x00\x00\x00")
+	assertIntReply(t, execCmd(db, "bitpos", "k", "1"), -1)
+
+	// bits beyond the string are considered as 0 unless end is given
+	execCmd(db, "set", "k", "\xff\xff\xff")
+	assertIntReply(t, execCmd(db, "bitpos", "k", "0"), 24)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "0", "1"), 24)
+	assertIntReply(t, execCmd(db, "bitpos", "k", "0", "0", "-1"), -1)
+	assertIntReply(t, execCmd(db, "bitpos", "missing", "0"), 0)
+	assertIntReply(t, execCmd(db, "bitpos", "missing", "1"), -1)
+
+	assertErrReply(t, execCmd(db, "bitpos", "k", "2"), "ERR The bit argument must be 1 or 0.")
+	assertErrReply(t, execCmd(db, "bitpos", "k", "1", "0", "-1", "bit", "x"), "Err syntax error")
+}
+
+func TestBitOp(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "\xf0\x0f")
+	execCmd(db, "set", "b", "\x3c")
+	assertIntReply(t, execCmd(db, "bitop", "and", "dest", "a", "b"), 2)
+	assertBulkReply(t, execCmd(db, "get", "dest"), "\x30\x00")
+	assertIntReply(t, execCmd(db, "bitop", "or", "dest", "a", "b"), 2)
+	assertBulkReply(t, execCmd(db, "get", "dest"), "\xfc\x0f")
+	assertIntReply(t, execCmd(db, "bitop", "xor", "dest", "a", "b", "missing"), 2)
+	assertBulkReply(t, execCmd(db, "get", "dest"), "\xcc\x0f")
+	assertIntReply(t, execCmd(db, "bitop", "not", "dest", "a"), 2)
+	assertBulkReply(t, execCmd(db, "get", "dest"), "\x0f\xf0")
+	// destination may be one of sources
+	assertIntReply(t, execCmd(db, "bitop", "not", "a", "a"), 2)
+	assertBulkReply(t, execCmd(db, "get", "a"), "\x0f\xf0")
+	// destination is removed if all sources are empty
+	assertIntReply(t, execCmd(db, "bitop", "or", "dest", "missing"), 0)
+	assertNotExists(t, db, "dest")
+
+	assertErrReply(t, execCmd(db, "bitop", "not", "dest", "a", "b"), "ERR BITOP NOT must be called with a single source key.")
+	assertErrReply(t, execCmd(db, "bitop", "nand", "dest", "a", "b"), "Err syntax error")
+	execCmd(db, "rpush", "list", "a")
+	assertErrReply(t, execCmd(db, "bitop", "and", "dest", "a", "list"), wrongTypeErr)
+}
+
+func TestGetBitOffset(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "getbit", "missing", "0"), 0)
+	assertErrReply(t, execCmd(db, "getbit", "k", "-1"), "ERR bit offset is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "setbit", "k", "4294967296", "1"), "ERR bit offset is not an integer or out of range")
+	assertNotExists(t, db, "k")
+}
+
+func TestBitFieldOverflow(t *testing.T) {
+	db := makeTestDB()
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "i8", "0", "100", "incrby", "i8", "0", "100"), "*2\r\n:0\r\n:-56\r\n")
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "i8", "0", "100", "overflow", "sat",
+		"incrby", "i8", "0", "100", "incrby", "i8", "0", "-300"), "*3\r\n:-56\r\n:127\r\n:-128\r\n")
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "u8", "0", "250", "incrby", "u8", "0", "10"), "*2\r\n:128\r\n:4\r\n")
+	assertReply(t, execCmd(db, "bitfield", "k", "overflow", "sat", "set", "u8", "0", "250",
+		"incrby", "u8", "0", "10", "incrby", "u8", "0", "-300"), "*3\r\n:4\r\n:255\r\n:0\r\n")
+	assertReply(t, execCmd(db, "bitfield", "k", "overflow", "fail", "set", "u8", "0", "256",
+		"set", "i8", "0", "128", "incrby", "u8", "0", "-1", "set", "u8", "0", "1"), "*4\r\n$-1\r\n$-1\r\n$-1\r\n:0\r\n")
+	// overflow policy only affects the following subcommands
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "u8", "0", "256", "overflow", "fail", "set", "u8", "0", "256"),
+		"*2\r\n:1\r\n$-1\r\n")
+	assertBulkReply(t, execCmd(db, "get", "k"), "\x00")
+
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "i64", "0", "-1", "incrby", "i64", "0", "1"), "*2\r\n:0\r\n:0\r\n")
+	assertReply(t, execCmd(db, "bitfield", "k", "overflow", "sat", "set", "i64", "0", "9223372036854775807",
+		"incrby", "i64", "0", "1", "get", "u63", "0"), "*3\r\n:0\r\n:9223372036854775807\r\n:4611686018427387903\r\n")
+	assertReply(t, execCmd(db, "bitfield", "k", "set", "u4", "#1", "0", "get", "u8", "0"), "*2\r\n:15\r\n:112\r\n")
+}
+
+func TestBitFieldArgs(t *testing.T) {
+	db := makeTestDB()
+	assertReply(t, execCmd(db, "bitfield", "missing", "get", "u8", "0"), "*1\r\n:0\r\n")
+	assertNotExists(t, db, "missing")
+	assertReply(t, execCmd(db, "bitfield_ro", "missing", "get", "i4", "100"), "*1\r\n:0\r\n")
+
+	typeErr := "ERR Invalid bitfield type. Use something like i16 u8. Note that u64 is not supported but i64 is."
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "u64", "0"), typeErr)
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "i65", "0"), typeErr)
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "i0", "0"), typeErr)
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "x8", "0"), typeErr)
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "u8", "-1"), "ERR bit offset is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "u8", "4294967290"), "ERR bit offset is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "bitfield", "k", "get", "u8", "#536870912"), "ERR bit offset is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "bitfield", "k", "overflow", "none", "get", "u8", "0"), "ERR Invalid OVERFLOW type specified")
+	assertErrReply(t, execCmd(db, "bitfield", "k", "set", "u8", "0"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "bitfield", "k", "incrby", "u8", "0", "x"), "ERR value is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "bitfield", "k", "del", "u8", "0"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "bitfield_ro", "k", "set", "u8", "0", "1"), "ERR BITFIELD_RO only supports the GET subcommand")
+	// nothing is written if any subcommand is invalid
+	assertErrReply(t, execCmd(db, "bitfield", "k", "set", "u8", "0", "1", "get", "u64", "0"), typeErr)
+	assertNotExists(t, db, "k")
+	execCmd(db, "rpush", "list", "a")
+	assertErrReply(t, execCmd(db, "bitfield", "list", "get", "u8", "0"), wrongTypeErr)
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"container/list"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// blockedReply is returned by the executor of a blocking command when none of its keys is ready.
+// DB.Exec parks the client and runs the command again once one of the keys may be ready.
+type blockedReply struct {
+	keys    []string
+	timeout time.Duration // 0 means block forever
+	// cmdLine is the command to run again once ready, nil means the original one.
+	// Such as XREAD, `$` should be resolved when the command is blocked.
+	cmdLine CmdLine
+}
+
+// ToBytes never be invoked, blockedReply won't be sent to client
+func (r *blockedReply) ToBytes() []byte {
+	return nil
+}
+
+// waiter is a client blocked by some keys
+type waiter struct {
+	conn redis.Connection
+	keys []string
+	// ready is signaled when one of keys may be ready to serve
+	ready chan struct{}
+	// cancelled is closed when the client disconnected
+	cancelled chan struct{}
+}
+
+// blockingKeys tracks clients blocked by keys, waiters of each key are served in FIFO order
+type blockingKeys struct {
+	mu      sync.Mutex
+	keys    map[string]*list.List // key -> list of *waiter
+	waiters map[redis.Connection]*waiter
+}
+
+func makeBlockingKeys() *blockingKeys {
+	return &blockingKeys{
+		keys:    make(map[string]*list.List),
+		waiters: make(map[redis.Connection]*waiter),
+	}
+}
+
+// block puts client into waiting queues of the given keys
+// it should be invoked while holding the locks of keys, so that no push is missed
+func (b *blockingKeys) block(conn redis.Connection, keys []string) *waiter {
+	b.mu.Lock()
+	w, ok := b.waiters[conn]
+	if !ok {
+		w = &waiter{
+			conn:      conn,
+			keys:      keys,
+			ready:     make(chan struct{}, 1),
+			cancelled: make(chan struct{}),
+		}
+		b.waiters[conn] = w
+		for _, key := range keys {
+			queue, ok := b.keys[key]
+			if !ok {
+				queue = list.New()
+				b.keys[key] = queue
+			}
+			queue.PushBack(w)
+		}
+	}
+	b.mu.Unlock()
+	// client may disconnect before it is blocked
+	if conn.IsClosed() {
+		b.cancel(conn)
+	}
+	return w
+}
+
+// unblock removes client from waiting queues, and wakes up the next waiter of its keys,
+// so that elements not consumed by this client won't be left behind
+func (b *blockingKeys) unblock(conn redis.Connection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, ok := b.waiters[conn]
+	if !ok {
+		return
+	}
+	delete(b.waiters, conn)
+	for _, key := range w.keys {
+		queue, ok := b.keys[key]
+		if !ok {
+			continue
+		}
+		for e := queue.Front(); e != nil; e = e.Next() {
+			if e.Value.(*waiter) == w {
+				queue.Remove(e)
+				break
+			}
+		}
+		if queue.Len() == 0 {
+			delete(b.keys, key)
+			continue
+		}
+		notify(queue.Front().Value.(*waiter))
+	}
+}
+
+// signal wakes up the first client blocked by the given key
+func (b *blockingKeys) signal(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue, ok := b.keys[key]
+	if !ok {
+		return
+	}
+	notify(queue.Front().Value.(*waiter))
+}
+
+// cancel wakes up the blocked client which has disconnected
+func (b *blockingKeys) cancel(conn redis.Connection) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	w, ok := b.waiters[conn]
+	if !ok {
+		return
+	}
+	select {
+	case <-w.cancelled:
+	default:
+		close(w.cancelled)
+	}
+}
+
+func notify(w *waiter) {
+	select {
+	case w.ready <- struct{}{}:
+	default:
+		// already notified
+	}
+}
+
+// signalKeyReady wakes up clients blocked by the given key, it should be invoked after pushing elements into key
+func (db *DB) signalKeyReady(key string) {
+	db.blocking.signal(key)
+}
+
+// blockUntilReady parks the client until the blocking command is served, timeout or the client disconnected
+func (db *DB) blockUntilReady(c redis.Connection, cmdLine CmdLine, blocked *blockedReply) redis.Reply {
+	w := db.blocking.block(c, blocked.keys)
+	defer db.blocking.unblock(c)
+	if blocked.cmdLine != nil {
+		cmdLine = blocked.cmdLine
+	}
+
+	var timeout <-chan time.Time
+	if blocked.timeout > 0 {
+		timer := time.NewTimer(blocked.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	for {
+		select {
+		case <-w.ready:
+			result := db.execNormalCommand(c, cmdLine)
+			if _, ok := result.(*blockedReply); !ok {
+				return result
+			}
+			// elements have been taken by other clients, keep waiting
+		case <-timeout:
+			return protocol.NewNullMultiBulkReply()
+		case <-w.cancelled:
+			return &protocol.NoReply{}
+		}
+	}
+}
+
+// parseBlockingTimeout parses the timeout in seconds of blocking commands, 0 means block forever
+func parseBlockingTimeout(arg []byte) (time.Duration, protocol.ErrorReply) {
+	seconds, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) ||
+		seconds > float64(math.MaxInt64/int64(time.Second)) {
+		return 0, protocol.NewErrReply("ERR timeout is not a float or out of range")
+	}
+	if seconds < 0 {
+		return 0, protocol.NewErrReply("ERR timeout is negative")
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout == 0 && seconds > 0 {
+		// a timeout shorter than 1ns is rounded up, it shouldn't mean blocking forever
+		timeout = time.Nanosecond
+	}
+	return timeout, nil
+}
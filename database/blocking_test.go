@@ -0,0 +1,163 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"testing"
+	"time"
+)
+
+// execAsync executes command in background, the reply is sent through the returned channel
+func execAsync(db *DB, conn redis.Connection, cmdLine ...string) <-chan redis.Reply {
+	ch := make(chan redis.Reply, 1)
+	go func() {
+		ch <- db.Exec(conn, utils.ToCmdLine(cmdLine...))
+	}()
+	return ch
+}
+
+// waitBlocked waits until the given number of clients are blocked
+func waitBlocked(t *testing.T, db *DB, count int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		db.blocking.mu.Lock()
+		blocked := len(db.blocking.waiters)
+		db.blocking.mu.Unlock()
+		if blocked == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d blocked clients", count)
+}
+
+func receive(t *testing.T, ch <-chan redis.Reply) redis.Reply {
+	t.Helper()
+	select {
+	case reply := <-ch:
+		return reply
+	case <-time.After(time.Second):
+		t.Fatal("blocking command is not served")
+		return nil
+	}
+}
+
+func TestBLPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list2", "a", "b")
+	// served immediately by the first non-empty list
+	assertMultiBulkReply(t, execCmd(db, "blpop", "list1", "list2", "0"), "list2", "a")
+	assertMultiBulkReply(t, execCmd(db, "brpop", "list1", "list2", "0"), "list2", "b")
+
+	ch := execAsync(db, connection.NewFakeConn(), "blpop", "list1", "list2", "0")
+	waitBlocked(t, db, 1)
+	execCmd(db, "rpush", "list2", "c")
+	assertMultiBulkReply(t, receive(t, ch), "list2", "c")
+	assertNotExists(t, db, "list2")
+	waitBlocked(t, db, 0)
+}
+
+func TestBlockingTimeout(t *testing.T) {
+	db := makeTestDB()
+	start := time.Now()
+	assertReply(t, execCmd(db, "blpop", "list", "0.05"), "*-1\r\n")
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected blocking for 50ms, actually %s", elapsed)
+	}
+	waitBlocked(t, db, 0)
+
+	// the shortest timeout doesn't block forever
+	for _, timeout := range []string{"0.0000000001", "1e-300"} {
+		ch := execAsync(db, connection.NewFakeConn(), "blpop", "list", timeout)
+		assertReply(t, receive(t, ch), "*-1\r\n")
+	}
+	waitBlocked(t, db, 0)
+
+	assertErrReply(t, execCmd(db, "blpop", "list", "-1"), "ERR timeout is negative")
+	assertErrReply(t, execCmd(db, "blpop", "list", "abc"), "ERR timeout is not a float or out of range")
+	assertErrReply(t, execCmd(db, "blpop", "list", "inf"), "ERR timeout is not a float or out of range")
+}
+
+func TestBlockingFIFO(t *testing.T) {
+	db := makeTestDB()
+	first := execAsync(db, connection.NewFakeConn(), "blpop", "list", "0")
+	waitBlocked(t, db, 1)
+	second := execAsync(db, connection.NewFakeConn(), "brpop", "list", "0")
+	waitBlocked(t, db, 2)
+
+	execCmd(db, "rpush", "list", "a")
+	assertMultiBulkReply(t, receive(t, first), "list", "a")
+	waitBlocked(t, db, 1)
+	execCmd(db, "rpush", "list", "b")
+	assertMultiBulkReply(t, receive(t, second), "list", "b")
+}
+
+func TestBlockingClientClosed(t *testing.T) {
+	db := makeTestDB()
+	conn := connection.NewFakeConn()
+	closed := execAsync(db, conn, "blpop", "list", "0")
+	waitBlocked(t, db, 1)
+	other := execAsync(db, connection.NewFakeConn(), "blpop", "list", "0")
+	waitBlocked(t, db, 2)
+
+	conn.MarkClosed()
+	db.blocking.cancel(conn)
+	if _, ok := receive(t, closed).(*protocol.NoReply); !ok {
+		t.Error("expected no reply for closed client")
+	}
+	waitBlocked(t, db, 1)
+	// the element is served to the remaining client
+	execCmd(db, "rpush", "list", "a")
+	assertMultiBulkReply(t, receive(t, other), "list", "a")
+
+	// client closed before it is blocked
+	conn = connection.NewFakeConn()
+	conn.MarkClosed()
+	if _, ok := db.Exec(conn, utils.ToCmdLine("blpop", "list", "0")).(*protocol.NoReply); !ok {
+		t.Error("expected no reply for closed client")
+	}
+	waitBlocked(t, db, 0)
+}
+
+func TestBLMove(t *testing.T) {
+	db := makeTestDB()
+	ch := execAsync(db, connection.NewFakeConn(), "blmove", "src", "dest", "right", "left", "0")
+	waitBlocked(t, db, 1)
+	execCmd(db, "rpush", "src", "a", "b")
+	assertBulkReply(t, receive(t, ch), "b")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "b")
+
+	assertBulkReply(t, execCmd(db, "brpoplpush", "src", "dest", "0"), "a")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "a", "b")
+	assertReply(t, execCmd(db, "brpoplpush", "src", "dest", "0.01"), "*-1\r\n")
+
+	// a blocked BLMOVE wakes up the client blocked by its destination
+	dest := execAsync(db, connection.NewFakeConn(), "blpop", "other", "0")
+	waitBlocked(t, db, 1)
+	execCmd(db, "rpush", "src", "c")
+	assertBulkReply(t, execCmd(db, "blmove", "src", "other", "left", "left", "0"), "c")
+	assertMultiBulkReply(t, receive(t, dest), "other", "c")
+}
+
+func TestLMPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list2", "a", "b", "c")
+	assertReply(t, execCmd(db, "lmpop", "2", "list1", "list2", "left"), "*2\r\n$5\r\nlist2\r\n*1\r\n$1\r\na\r\n")
+	assertReply(t, execCmd(db, "lmpop", "2", "list1", "list2", "right", "count", "5"),
+		"*2\r\n$5\r\nlist2\r\n*2\r\n$1\r\nc\r\n$1\r\nb\r\n")
+	assertReply(t, execCmd(db, "lmpop", "2", "list1", "list2", "left"), "*-1\r\n")
+
+	assertErrReply(t, execCmd(db, "lmpop", "0", "list1", "left"), "ERR numkeys should be greater than 0")
+	assertErrReply(t, execCmd(db, "lmpop", "3", "list1", "left"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "lmpop", "1", "list1", "up"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "lmpop", "1", "list1", "left", "count", "0"), "ERR count should be greater than 0")
+
+	ch := execAsync(db, connection.NewFakeConn(), "blmpop", "0", "2", "list1", "list2", "left", "count", "2")
+	waitBlocked(t, db, 1)
+	execCmd(db, "rpush", "list1", "x", "y", "z")
+	assertReply(t, receive(t, ch), "*2\r\n$5\r\nlist1\r\n*2\r\n$1\r\nx\r\n$1\r\ny\r\n")
+	assertReply(t, execCmd(db, "blmpop", "0.01", "1", "list2", "left"), "*-1\r\n")
+}
@@ -1,24 +1,143 @@
 package database
 
 import (
+	"github.com/Ravior/goredis/aof"
+	"github.com/Ravior/goredis/config"
+	"github.com/Ravior/goredis/interface/database"
 	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/logger"
 	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 )
 
+const (
+	defaultDatabases      = 16
+	defaultAppendFilename = "appendonly.aof"
+)
+
 // MultiDB is a set of multiple database set
 type MultiDB struct {
 	dbSet []*atomic.Value // *DB
+
+	// closing stops background jobs such as active expire cycle
+	closing chan struct{}
+
+	// aofHandler is nil if appendonly is disabled
+	aofHandler *aof.Persister
+	// swapMu protects index of databases, SWAPDB holds it for writing while changing them
+	// and appending commands holds it for reading, so commands are appended with the index they executed on
+	swapMu sync.RWMutex
 }
 
 // NewStandaloneServer creates a standalone redis server, with multi database and all other funtions
 func NewStandaloneServer() *MultiDB {
-	return &MultiDB{}
+	mdb := &MultiDB{
+		closing: make(chan struct{}),
+	}
+	if config.Properties.Databases <= 0 {
+		config.Properties.Databases = defaultDatabases
+	}
+	mdb.dbSet = make([]*atomic.Value, config.Properties.Databases)
+	for i := range mdb.dbSet {
+		singleDB := makeDB()
+		singleDB.index = i
+		singleDB.lockOrder = i
+		holder := &atomic.Value{}
+		holder.Store(singleDB)
+		mdb.dbSet[i] = holder
+	}
+	if config.Properties.AppendOnly {
+		mdb.setupAof()
+	}
+	go mdb.activeExpire()
+	return mdb
+}
+
+// setupAof loads aof file and appends write commands to it since then
+func (mdb *MultiDB) setupAof() {
+	filename := config.Properties.AppendFilename
+	if filename == "" {
+		filename = defaultAppendFilename
+	}
+	aofHandler, err := aof.NewPersister(mdb, aof.Options{
+		Filename:      filename,
+		Fsync:         config.Properties.AppendFsync,
+		LoadTruncated: config.Properties.AofLoadTruncated,
+	})
+	if err != nil {
+		logger.Fatal(err)
+	}
+	// commands replayed by loading are not appended again since addAof of databases is not bound yet
+	if err := aofHandler.LoadAof(); err != nil {
+		logger.Fatal("load aof failed: " + err.Error())
+	}
+	if err := aofHandler.Start(); err != nil {
+		logger.Fatal(err)
+	}
+	mdb.aofHandler = aofHandler
+	for _, holder := range mdb.dbSet {
+		singleDB := holder.Load().(*DB)
+		singleDB.addAof = func(line CmdLine) {
+			// index of database changes after SWAPDB, so read it when appending
+			mdb.swapMu.RLock()
+			defer mdb.swapMu.RUnlock()
+			aofHandler.SaveCmdLine(singleDB.index, line)
+		}
+	}
+}
+
+// addAofBetween appends command executed on srcDB which refers to destDB by index,
+// both indexes are read at the same time so that the command is replayed on the same databases after SWAPDB
+func (mdb *MultiDB) addAofBetween(srcDB *DB, destDB *DB, makeCmdLine func(destIndex int) CmdLine) {
+	if mdb.aofHandler == nil {
+		return
+	}
+	mdb.swapMu.RLock()
+	defer mdb.swapMu.RUnlock()
+	mdb.aofHandler.SaveCmdLine(srcDB.index, makeCmdLine(destDB.index))
+}
+
+// addAof appends commands not bound to a database, such as FLUSHALL and SWAPDB
+func (mdb *MultiDB) addAof(cmdLine CmdLine) {
+	if mdb.aofHandler != nil {
+		mdb.aofHandler.SaveCmdLine(0, cmdLine)
+	}
 }
 
 // Exec executes command
 // parameter `cmdLine` contains command and its arguments, for example: "set key value"
 func (mdb *MultiDB) Exec(conn redis.Connection, cmdLine [][]byte) (result redis.Reply) {
+	cmdName := strings.ToLower(string(cmdLine[0]))
+	// special commands which cannot execute within one database
+	switch cmdName {
+	case "select":
+		if len(cmdLine) != 2 {
+			return protocol.NewArgNumErrReply(cmdName)
+		}
+		return execSelect(conn, mdb, cmdLine[1:])
+	case "swapdb":
+		if len(cmdLine) != 3 {
+			return protocol.NewArgNumErrReply(cmdName)
+		}
+		return mdb.execSwapDB(cmdLine[1:])
+	case "move":
+		if len(cmdLine) != 3 {
+			return protocol.NewArgNumErrReply(cmdName)
+		}
+		return mdb.execMove(conn, cmdLine[1:])
+	case "copy":
+		if len(cmdLine) < 3 {
+			return protocol.NewArgNumErrReply(cmdName)
+		}
+		return mdb.execCopy(conn, cmdLine[1:])
+	case "flushall":
+		return mdb.flushAll(cmdLine[1:])
+	}
+
 	// normal commands
 	dbIndex := conn.GetDBIndex()
 	selectedDB, errReply := mdb.selectDB(dbIndex)
@@ -30,11 +149,17 @@ func (mdb *MultiDB) Exec(conn redis.Connection, cmdLine [][]byte) (result redis.
 
 // AfterClientClose does some clean after client close connection
 func (mdb *MultiDB) AfterClientClose(conn redis.Connection) {
-
+	// wake up the command blocking the client
+	for _, holder := range mdb.dbSet {
+		holder.Load().(*DB).blocking.cancel(conn)
+	}
 }
 
 func (mdb *MultiDB) Close() {
-
+	close(mdb.closing)
+	if mdb.aofHandler != nil {
+		mdb.aofHandler.Close()
+	}
 }
 
 func (mdb *MultiDB) selectDB(dbIndex int) (*DB, *protocol.StandardErrReply) {
@@ -43,3 +168,196 @@ func (mdb *MultiDB) selectDB(dbIndex int) (*DB, *protocol.StandardErrReply) {
 	}
 	return mdb.dbSet[dbIndex].Load().(*DB), nil
 }
+
+func parseDBIndex(arg []byte) (int, *protocol.StandardErrReply) {
+	dbIndex, err := strconv.Atoi(string(arg))
+	if err != nil {
+		return 0, protocol.NewErrReply("ERR invalid DB index")
+	}
+	return dbIndex, nil
+}
+
+func execSelect(c redis.Connection, mdb *MultiDB, args [][]byte) redis.Reply {
+	dbIndex, errReply := parseDBIndex(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	if dbIndex >= len(mdb.dbSet) || dbIndex < 0 {
+		return protocol.NewErrReply("ERR DB index is out of range")
+	}
+	c.SelectDB(dbIndex)
+	return protocol.NewOkReply()
+}
+
+// execSwapDB swaps two databases atomically, connections selecting one of them will see the other one
+func (mdb *MultiDB) execSwapDB(args [][]byte) redis.Reply {
+	dbIndex1, errReply := parseDBIndex(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	dbIndex2, errReply := parseDBIndex(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	db1, errReply := mdb.selectDB(dbIndex1)
+	if errReply != nil {
+		return errReply
+	}
+	db2, errReply := mdb.selectDB(dbIndex2)
+	if errReply != nil {
+		return errReply
+	}
+	if dbIndex1 == dbIndex2 {
+		return protocol.NewOkReply()
+	}
+	mdb.swapMu.Lock()
+	defer mdb.swapMu.Unlock()
+	db1.index, db2.index = dbIndex2, dbIndex1
+	mdb.dbSet[dbIndex1].Store(db2)
+	mdb.dbSet[dbIndex2].Store(db1)
+	mdb.addAof(utils.ToCmdLine3("swapdb", args...))
+	return protocol.NewOkReply()
+}
+
+// flushAll removes all keys of all databases
+func (mdb *MultiDB) flushAll(args [][]byte) redis.Reply {
+	async, errReply := parseFlushOption(args)
+	if errReply != nil {
+		return errReply
+	}
+	for _, holder := range mdb.dbSet {
+		holder.Load().(*DB).Flush(async)
+	}
+	if !async {
+		reclaimMemory()
+	}
+	mdb.addAof(utils.ToCmdLine3("flushall", args...))
+	return protocol.NewOkReply()
+}
+
+// execMove moves a key from the selected database to the given database
+func (mdb *MultiDB) execMove(c redis.Connection, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dbIndex, errReply := parseDBIndex(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	srcDB, errReply := mdb.selectDB(c.GetDBIndex())
+	if errReply != nil {
+		return errReply
+	}
+	destDB, errReply := mdb.selectDB(dbIndex)
+	if errReply != nil {
+		return errReply
+	}
+	if srcDB == destDB {
+		return protocol.NewErrReply("ERR source and destination objects are the same")
+	}
+	// always lock the database with smaller lock order first to avoid dead lock
+	keys := []string{key}
+	if srcDB.lockOrder < destDB.lockOrder {
+		srcDB.RWLocks(keys, nil)
+		destDB.RWLocks(keys, nil)
+	} else {
+		destDB.RWLocks(keys, nil)
+		srcDB.RWLocks(keys, nil)
+	}
+	defer srcDB.RWUnLocks(keys, nil)
+	defer destDB.RWUnLocks(keys, nil)
+
+	entity, exists := srcDB.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(0)
+	}
+	if destDB.PutIfAbsent(key, entity) == 0 {
+		return protocol.NewIntReply(0)
+	}
+	expireTime, hasTTL := srcDB.TTL(key)
+	srcDB.Remove(key)
+	if hasTTL {
+		destDB.Expire(key, expireTime)
+	}
+	destDB.signalKeyReady(key)
+	mdb.addAofBetween(srcDB, destDB, func(destIndex int) CmdLine {
+		return utils.ToCmdLine("move", key, strconv.Itoa(destIndex))
+	})
+	return protocol.NewIntReply(1)
+}
+
+// execCopy copies the value and ttl of source key to destination key, which may be in another database
+// COPY source destination [DB destination-db] [REPLACE]
+func (mdb *MultiDB) execCopy(c redis.Connection, args [][]byte) redis.Reply {
+	src, dest := string(args[0]), string(args[1])
+	srcDB, errReply := mdb.selectDB(c.GetDBIndex())
+	if errReply != nil {
+		return errReply
+	}
+	destDB := srcDB
+	replace := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "REPLACE":
+			replace = true
+		case "DB":
+			if i+1 >= len(args) {
+				return protocol.NewSyntaxErrReply()
+			}
+			dbIndex, errReply := parseDBIndex(args[i+1])
+			if errReply != nil {
+				return errReply
+			}
+			destDB, errReply = mdb.selectDB(dbIndex)
+			if errReply != nil {
+				return errReply
+			}
+			i++
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+	if srcDB == destDB && src == dest {
+		return protocol.NewErrReply("ERR source and destination objects are the same")
+	}
+
+	// always lock the database with smaller lock order first to avoid dead lock
+	if srcDB == destDB {
+		srcDB.RWLocks([]string{dest}, []string{src})
+		defer srcDB.RWUnLocks([]string{dest}, []string{src})
+	} else {
+		if srcDB.lockOrder < destDB.lockOrder {
+			srcDB.RWLocks(nil, []string{src})
+			destDB.RWLocks([]string{dest}, nil)
+		} else {
+			destDB.RWLocks([]string{dest}, nil)
+			srcDB.RWLocks(nil, []string{src})
+		}
+		defer srcDB.RWUnLocks(nil, []string{src})
+		defer destDB.RWUnLocks([]string{dest}, nil)
+	}
+
+	entity, exists := srcDB.GetEntity(src)
+	if !exists {
+		return protocol.NewIntReply(0)
+	}
+	if _, exists := destDB.GetEntity(dest); exists {
+		if !replace {
+			return protocol.NewIntReply(0)
+		}
+		destDB.Remove(dest)
+	}
+	destDB.PutEntity(dest, &database.DataEntity{
+		Data: copyValue(entity.Data),
+	})
+	if expireTime, hasTTL := srcDB.TTL(src); hasTTL {
+		destDB.Expire(dest, expireTime)
+	}
+	destDB.signalKeyReady(dest)
+	mdb.addAofBetween(srcDB, destDB, func(destIndex int) CmdLine {
+		cmdLine := utils.ToCmdLine("copy", src, dest, "DB", strconv.Itoa(destIndex))
+		if replace {
+			cmdLine = append(cmdLine, []byte("REPLACE"))
+		}
+		return cmdLine
+	})
+	return protocol.NewIntReply(1)
+}
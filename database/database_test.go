@@ -0,0 +1,268 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/config"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/utils"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func makeTestMultiDB(t *testing.T, appendOnly bool, filename string) *MultiDB {
+	properties := config.Properties
+	t.Cleanup(func() {
+		config.Properties = properties
+	})
+	config.Properties = &config.ServerProperties{
+		Databases:      4,
+		AppendOnly:     appendOnly,
+		AppendFilename: filename,
+		AppendFsync:    "always",
+	}
+	return NewStandaloneServer()
+}
+
+// putString puts a string value into the database of the given index
+func putString(mdb *MultiDB, dbIndex int, key string, value string) {
+	db, _ := mdb.selectDB(dbIndex)
+	db.PutEntity(key, &database.DataEntity{
+		Data: []byte(value),
+	})
+}
+
+// getString returns the string value in the database of the given index, returns false if the key doesn't exist
+func getString(mdb *MultiDB, dbIndex int, key string) (string, bool) {
+	db, _ := mdb.selectDB(dbIndex)
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return "", false
+	}
+	return string(entity.Data.([]byte)), true
+}
+
+func TestSelect(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	putString(mdb, 0, "a", "0")
+	putString(mdb, 3, "a", "3")
+	assertOkReply(t, mdb.Exec(conn, utils.ToCmdLine("select", "3")))
+	if conn.GetDBIndex() != 3 {
+		t.Errorf("expected db 3 selected, actually %d", conn.GetDBIndex())
+	}
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("del", "a")), 1)
+	if _, exists := getString(mdb, 3, "a"); exists {
+		t.Error("expected key deleted from db 3")
+	}
+	if val, _ := getString(mdb, 0, "a"); val != "0" {
+		t.Errorf("expected key kept in db 0, actually %q", val)
+	}
+
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("select", "4")), "ERR DB index is out of range")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("select", "-1")), "ERR DB index is out of range")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("select", "x")), "ERR invalid DB index")
+}
+
+func TestSwapDB(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	putString(mdb, 0, "a", "0")
+	assertOkReply(t, mdb.Exec(conn, utils.ToCmdLine("swapdb", "0", "1")))
+	if _, exists := getString(mdb, 0, "a"); exists {
+		t.Error("expected key swapped out of db 0")
+	}
+	if val, _ := getString(mdb, 1, "a"); val != "0" {
+		t.Errorf("expected key swapped into db 1, actually %q", val)
+	}
+	for i := 0; i < 2; i++ {
+		if db, _ := mdb.selectDB(i); db.index != i {
+			t.Errorf("expected index %d, actually %d", i, db.index)
+		}
+	}
+	assertOkReply(t, mdb.Exec(conn, utils.ToCmdLine("swapdb", "1", "1")))
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("swapdb", "0", "4")), "ERR DB index is out of range")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("swapdb", "0", "x")), "ERR invalid DB index")
+}
+
+// TestSwapDBAof checks commands running along with SWAPDB are replayed on the same databases
+func TestSwapDBAof(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "appendonly.aof")
+	mdb := makeTestMultiDB(t, true, filename)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(dbIndex int) {
+			defer wg.Done()
+			conn := connection.NewFakeConn()
+			conn.SelectDB(dbIndex)
+			for j := 0; j < 200; j++ {
+				key := strconv.Itoa(dbIndex) + ":" + strconv.Itoa(j)
+				mdb.Exec(conn, utils.ToCmdLine("set", key, key))
+				switch j % 3 {
+				case 0:
+					mdb.Exec(conn, utils.ToCmdLine("move", key, strconv.Itoa(j%4)))
+				case 1:
+					mdb.Exec(conn, utils.ToCmdLine("copy", key, key+":copy", "db", strconv.Itoa(j%4)))
+				}
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		conn := connection.NewFakeConn()
+		for j := 0; j < 200; j++ {
+			mdb.Exec(conn, utils.ToCmdLine("swapdb", strconv.Itoa(j%4), strconv.Itoa((j+1)%4)))
+		}
+	}()
+	wg.Wait()
+	mdb.Close()
+
+	loaded := makeTestMultiDB(t, true, filename)
+	defer loaded.Close()
+	for i := 0; i < 4; i++ {
+		db, _ := mdb.selectDB(i)
+		keys := db.keyspace().data.Keys()
+		sort.Strings(keys)
+		loadedDB, _ := loaded.selectDB(i)
+		loadedKeys := loadedDB.keyspace().data.Keys()
+		sort.Strings(loadedKeys)
+		if strings.Join(keys, ",") != strings.Join(loadedKeys, ",") {
+			t.Errorf("db %d: expected keys %v, actually %v", i, keys, loadedKeys)
+		}
+	}
+}
+
+func TestMove(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	mdb.Exec(conn, utils.ToCmdLine("set", "a", "0", "ex", "100"))
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("move", "a", "1")), 1)
+	if _, exists := getString(mdb, 0, "a"); exists {
+		t.Error("expected key moved out of db 0")
+	}
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("move", "a", "1")), 0)
+
+	// an existing key in destination is not overwritten
+	putString(mdb, 0, "a", "1")
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("move", "a", "1")), 0)
+	if val, _ := getString(mdb, 0, "a"); val != "1" {
+		t.Errorf("expected key kept in db 0, actually %q", val)
+	}
+	if val, _ := getString(mdb, 1, "a"); val != "0" {
+		t.Errorf("expected key moved into db 1, actually %q", val)
+	}
+	// ttl goes with the value
+	destDB, _ := mdb.selectDB(1)
+	assertTTL(t, destDB, "a", 100)
+
+	conn.SelectDB(1)
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("move", "a", "1")), "ERR source and destination objects are the same")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("move", "a", "4")), "ERR DB index is out of range")
+}
+
+func TestCopyString(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	mdb.Exec(conn, utils.ToCmdLine("set", "src", "hello"))
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "dest")), 1)
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("setrange", "dest", "0", "J")), 5)
+	mdb.Exec(conn, utils.ToCmdLine("setbit", "src", "0", "1"))
+	assertBulkReply(t, mdb.Exec(conn, utils.ToCmdLine("get", "dest")), "Jello")
+	assertBulkReply(t, mdb.Exec(conn, utils.ToCmdLine("get", "src")), "\xe8ello")
+}
+
+func TestFlushAll(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	for i := 0; i < 4; i++ {
+		conn.SelectDB(i)
+		mdb.Exec(conn, utils.ToCmdLine("set", "a", strconv.Itoa(i)))
+	}
+	freed := atomic.LoadInt64(&lazyfreedObjects)
+	assertOkReply(t, mdb.Exec(conn, utils.ToCmdLine("flushall", "async")))
+	for i := 0; i < 4; i++ {
+		if _, exists := getString(mdb, i, "a"); exists {
+			t.Errorf("expected db %d flushed", i)
+		}
+	}
+	// keyspaces of all databases are released in background
+	waitLazyfreed(t, freed+4)
+	mdb.Exec(conn, utils.ToCmdLine("set", "a", "1"))
+	assertOkReply(t, mdb.Exec(conn, utils.ToCmdLine("flushall", "sync")))
+	if _, exists := getString(mdb, 0, "a"); exists {
+		t.Error("expected db 0 flushed")
+	}
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("flushall", "lazy")), "Err syntax error")
+}
+
+func TestCopy(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	mdb.Exec(conn, utils.ToCmdLine("rpush", "src", "a", "b"))
+	mdb.Exec(conn, utils.ToCmdLine("expire", "src", "100"))
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "dest")), 1)
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("ttl", "dest")), 100)
+	// modifying the copy won't affect the source
+	mdb.Exec(conn, utils.ToCmdLine("rpush", "dest", "c"))
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("llen", "src")), 2)
+
+	// an existing destination is overwritten only with REPLACE
+	mdb.Exec(conn, utils.ToCmdLine("set", "str", "v"))
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "str")), 0)
+	assertBulkReply(t, mdb.Exec(conn, utils.ToCmdLine("get", "str")), "v")
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "str", "replace")), 1)
+	assertMultiBulkReply(t, mdb.Exec(conn, utils.ToCmdLine("lrange", "str", "0", "-1")), "a", "b")
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "missing", "dest", "replace")), 0)
+
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "src", "db", "2")), 1)
+	conn.SelectDB(2)
+	assertMultiBulkReply(t, mdb.Exec(conn, utils.ToCmdLine("lrange", "src", "0", "-1")), "a", "b")
+	assertIntReply(t, mdb.Exec(conn, utils.ToCmdLine("ttl", "src")), 100)
+
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "src")), "ERR source and destination objects are the same")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "dest", "db", "4")), "ERR DB index is out of range")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "dest", "db")), "Err syntax error")
+	assertErrReply(t, mdb.Exec(conn, utils.ToCmdLine("copy", "src", "dest", "foo")), "Err syntax error")
+}
+
+func TestCopyTypes(t *testing.T) {
+	mdb := makeTestMultiDB(t, false, "")
+	defer mdb.Close()
+	conn := connection.NewFakeConn()
+	exec := func(args ...string) redis.Reply {
+		return mdb.Exec(conn, utils.ToCmdLine(args...))
+	}
+	exec("hset", "hash", "f", "v")
+	exec("sadd", "set", "a")
+	exec("zadd", "zset", "1", "a")
+	exec("pfadd", "hll", "a")
+	exec("xadd", "stream", "1", "f", "v")
+	for _, key := range []string{"hash", "set", "zset", "hll", "stream"} {
+		assertIntReply(t, exec("copy", key, key+":copy"), 1)
+	}
+	exec("hset", "hash:copy", "f2", "v")
+	exec("sadd", "set:copy", "b")
+	exec("zadd", "zset:copy", "2", "a")
+	exec("pfadd", "hll:copy", "b")
+	exec("xadd", "stream:copy", "2", "f", "v")
+	assertIntReply(t, exec("hlen", "hash"), 1)
+	assertIntReply(t, exec("scard", "set"), 1)
+	assertBulkReply(t, exec("zscore", "zset", "a"), "1")
+	assertIntReply(t, exec("pfcount", "hll"), 1)
+	assertIntReply(t, exec("xlen", "stream"), 1)
+	assertIntReply(t, exec("pfcount", "hll:copy"), 2)
+	assertIntReply(t, exec("xlen", "stream:copy"), 2)
+}
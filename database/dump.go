@@ -0,0 +1,135 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/serialize"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// execDump returns the serialized value of key, which can be restored by RESTORE
+func execDump(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewNullBulkReply()
+	}
+	payload, err := serialize.Dump(entity.Data)
+	if err != nil {
+		return protocol.NewErrReply("ERR " + err.Error())
+	}
+	return protocol.NewBulkReply(payload)
+}
+
+// restoreOption is the options of RESTORE
+type restoreOption struct {
+	replace bool
+	absTTL  bool
+}
+
+func parseRestoreOption(args [][]byte) (*restoreOption, protocol.ErrorReply) {
+	option := &restoreOption{}
+	hasIdleTime, hasFreq := false, false
+	for i := 0; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case arg == "REPLACE":
+			option.replace = true
+		case arg == "ABSTTL":
+			option.absTTL = true
+		case arg == "IDLETIME" && i+1 < len(args) && !hasFreq:
+			// LRU isn't supported, so the idle time is validated and ignored
+			idleTime, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			if idleTime < 0 {
+				return nil, protocol.NewErrReply("ERR Invalid IDLETIME value, must be >= 0")
+			}
+			hasIdleTime = true
+			i++
+		case arg == "FREQ" && i+1 < len(args) && !hasIdleTime:
+			// LFU isn't supported, so the frequency is validated and ignored
+			freq, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			if freq < 0 || freq > 255 {
+				return nil, protocol.NewErrReply("ERR Invalid FREQ value, must be >= 0 and <= 255")
+			}
+			hasFreq = true
+			i++
+		default:
+			return nil, protocol.NewSyntaxErrReply()
+		}
+	}
+	return option, nil
+}
+
+// execRestore creates key from the payload generated by DUMP, ttl is in milliseconds and 0 means no expiration
+func execRestore(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	ttl, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	if ttl < 0 {
+		return protocol.NewErrReply("ERR Invalid TTL value, must be >= 0")
+	}
+	payload := args[2]
+	option, errReply := parseRestoreOption(args[3:])
+	if errReply != nil {
+		return errReply
+	}
+	if !option.absTTL && ttl > math.MaxInt64/int64(time.Millisecond) {
+		return protocol.NewErrReply("ERR invalid expire time in 'restore' command")
+	}
+
+	_, exists := db.GetEntity(key)
+	if exists && !option.replace {
+		return protocol.NewErrReply("BUSYKEY Target key name already exists.")
+	}
+	val, err := serialize.Restore(payload)
+	if err != nil {
+		return protocol.NewErrReply("ERR " + err.Error())
+	}
+
+	var expireAt time.Time
+	if ttl > 0 {
+		if option.absTTL {
+			expireAt = time.UnixMilli(ttl)
+		} else {
+			expireAt = time.Now().Add(time.Duration(ttl) * time.Millisecond)
+		}
+		if expireAt.Before(time.Now()) {
+			// the key expires at once, so it only removes the existing one
+			if exists {
+				db.Remove(key)
+				db.addAof(utils.ToCmdLine("del", key))
+			}
+			return protocol.NewOkReply()
+		}
+	}
+
+	db.Remove(key)
+	db.PutEntity(key, &database.DataEntity{
+		Data: val,
+	})
+	db.addAof(utils.ToCmdLine3("restore", args[0], []byte("0"), payload, []byte("REPLACE")))
+	if ttl > 0 {
+		db.Expire(key, expireAt)
+		db.addAof(makeExpireCmd(key, expireAt))
+	}
+	db.signalKeyReady(key)
+	return protocol.NewOkReply()
+}
+
+func init() {
+	RegisterCommand("Dump", execDump, readFirstKey, 2)
+	RegisterCommand("Restore", execRestore, writeFirstKey, -4)
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/protocol"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func dumpKey(t *testing.T, db *DB, key string) string {
+	t.Helper()
+	reply, ok := execCmd(db, "dump", key).(*protocol.BulkReply)
+	if !ok {
+		t.Fatalf("dump %s failed", key)
+	}
+	return string(reply.Arg)
+}
+
+func TestDumpRestore(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "c")
+	execCmd(db, "hset", "hash", "f", "v")
+	execCmd(db, "sadd", "set", "a", "b")
+	execCmd(db, "zadd", "zset", "1", "a", "2", "b")
+	execCmd(db, "set", "str", "v")
+	for _, key := range []string{"list", "hash", "set", "zset", "str"} {
+		payload := dumpKey(t, db, key)
+		assertOkReply(t, execCmd(db, "restore", key+"2", "0", payload))
+		// members of hash and set are dumped in random order
+		if key != "hash" && key != "set" && dumpKey(t, db, key+"2") != payload {
+			t.Errorf("restored %s differs from the origin", key)
+		}
+		assertErrReply(t, execCmd(db, "restore", key+"2", "0", payload), "BUSYKEY Target key name already exists.")
+		assertOkReply(t, execCmd(db, "restore", key+"2", "0", payload, "replace"))
+	}
+	assertReply(t, execCmd(db, "lrange", "list2", "0", "-1"), "*3\r\n$1\r\na\r\n$1\r\nb\r\n$1\r\nc\r\n")
+	assertBulkReply(t, execCmd(db, "hget", "hash2", "f"), "v")
+	assertIntReply(t, execCmd(db, "sismember", "set2", "b"), 1)
+	assertReply(t, execCmd(db, "dump", "missing"), "$-1\r\n")
+	assertErrReply(t, execCmd(db, "restore", "bad", "0", "payload"), "ERR DUMP payload version or checksum are wrong")
+}
+
+func TestRestoreTTL(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "str", "v")
+	payload := dumpKey(t, db, "str")
+
+	assertOkReply(t, execCmd(db, "restore", "k1", "100000", payload))
+	assertIntReply(t, execCmd(db, "ttl", "k1"), 100)
+	expireAt := time.Now().Add(time.Hour).UnixMilli()
+	assertOkReply(t, execCmd(db, "restore", "k2", strconv.FormatInt(expireAt, 10), payload, "absttl"))
+	assertIntReply(t, execCmd(db, "pexpiretime", "k2"), expireAt)
+	// expired at once
+	assertOkReply(t, execCmd(db, "restore", "k3", "1", payload, "absttl"))
+	assertIntReply(t, execCmd(db, "exists", "k3"), 0)
+
+	assertErrReply(t, execCmd(db, "restore", "k4", "9223372036854775807", payload),
+		"ERR invalid expire time in 'restore' command")
+	assertErrReply(t, execCmd(db, "restore", "k4", "-1", payload), "ERR Invalid TTL value, must be >= 0")
+	assertOkReply(t, execCmd(db, "restore", "k4", "9223372036854775807", payload, "absttl"))
+	assertIntReply(t, execCmd(db, "pexpiretime", "k4"), 9223372036854775807)
+	assertIntReply(t, execCmd(db, "exists", "k4"), 1)
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"time"
+)
+
+const (
+	// activeExpireInterval is how often the active expire cycle runs
+	activeExpireInterval = 100 * time.Millisecond
+	// activeExpireKeysPerLoop is how many volatile keys are sampled in one loop
+	activeExpireKeysPerLoop = 20
+	// activeExpireAcceptableStale means the cycle stops once less than 25% of sampled keys are expired
+	activeExpireAcceptableStale = activeExpireKeysPerLoop / 4
+	// activeExpireTimeLimit is the time budget of one cycle for each db, 25% of the interval
+	activeExpireTimeLimit = activeExpireInterval / 4
+)
+
+// activeExpireCycle samples keys with ttl and removes the expired ones, just like the active expire cycle of redis.
+// Lazy expiration only removes keys which are read, this cycle releases memory of dead keys which are never read.
+func (db *DB) activeExpireCycle() {
+	start := time.Now()
+	ttlMap := db.keyspace().ttlMap
+	for ttlMap.Len() > 0 {
+		sampled := ttlMap.RandomDistinctKeys(activeExpireKeysPerLoop)
+		expired := 0
+		for _, key := range sampled {
+			if db.expireIfNeeded(key) {
+				expired++
+			}
+		}
+		// most sampled keys are alive, it's not worth looking for expired keys any more
+		if expired <= activeExpireAcceptableStale {
+			return
+		}
+		if time.Since(start) > activeExpireTimeLimit {
+			return
+		}
+	}
+}
+
+// expireIfNeeded removes the key if it has expired, returns whether it has been removed
+func (db *DB) expireIfNeeded(key string) bool {
+	keys := []string{key}
+	db.RWLocks(keys, nil)
+	defer db.RWUnLocks(keys, nil)
+	return db.IsExpired(key)
+}
+
+// activeExpire runs active expire cycle on every database until mdb is closed
+func (mdb *MultiDB) activeExpire() {
+	ticker := time.NewTicker(activeExpireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			for _, holder := range mdb.dbSet {
+				holder.Load().(*DB).activeExpireCycle()
+			}
+		case <-mdb.closing:
+			return
+		}
+	}
+}
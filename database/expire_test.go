@@ -0,0 +1,132 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "expire", "a", "100"), 0)
+	assertIntReply(t, execCmd(db, "ttl", "a"), -2)
+	assertIntReply(t, execCmd(db, "pttl", "a"), -2)
+
+	execCmd(db, "set", "a", "1")
+	assertIntReply(t, execCmd(db, "ttl", "a"), -1)
+	assertIntReply(t, execCmd(db, "expire", "a", "100"), 1)
+	assertIntReply(t, execCmd(db, "ttl", "a"), 100)
+	assertIntReply(t, execCmd(db, "pexpire", "a", "200000"), 1)
+	assertIntReply(t, execCmd(db, "ttl", "a"), 200)
+
+	at := time.Now().Add(300 * time.Second)
+	assertIntReply(t, execCmd(db, "pexpireat", "a", strconv.FormatInt(at.UnixMilli(), 10)), 1)
+	assertIntReply(t, execCmd(db, "pexpiretime", "a"), at.UnixMilli())
+	assertIntReply(t, execCmd(db, "expiretime", "a"), at.Unix())
+	assertIntReply(t, execCmd(db, "expireat", "a", strconv.FormatInt(at.Unix(), 10)), 1)
+	assertIntReply(t, execCmd(db, "expiretime", "a"), at.Unix())
+
+	assertIntReply(t, execCmd(db, "persist", "a"), 1)
+	assertIntReply(t, execCmd(db, "persist", "a"), 0)
+	assertIntReply(t, execCmd(db, "ttl", "a"), -1)
+	assertIntReply(t, execCmd(db, "expiretime", "a"), -1)
+
+	// expire time in the past deletes the key
+	assertIntReply(t, execCmd(db, "expire", "a", "-1"), 1)
+	assertNotExists(t, db, "a")
+	execCmd(db, "set", "a", "1")
+	assertIntReply(t, execCmd(db, "expireat", "a", "1"), 1)
+	assertNotExists(t, db, "a")
+
+	assertErrReply(t, execCmd(db, "expire", "a", "abc"), "ERR value is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "expire", "a", "9223372036854775807"), "ERR invalid expire time in 'expire' command")
+}
+
+func TestExpireFlags(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "1")
+	assertIntReply(t, execCmd(db, "expire", "a", "100", "xx"), 0)
+	assertIntReply(t, execCmd(db, "expire", "a", "100", "gt"), 0)
+	assertIntReply(t, execCmd(db, "expire", "a", "100", "nx"), 1)
+	assertIntReply(t, execCmd(db, "expire", "a", "200", "nx"), 0)
+	assertIntReply(t, execCmd(db, "expire", "a", "200", "xx"), 1)
+	assertIntReply(t, execCmd(db, "expire", "a", "100", "gt"), 0)
+	assertIntReply(t, execCmd(db, "expire", "a", "300", "gt"), 1)
+	assertIntReply(t, execCmd(db, "expire", "a", "400", "lt"), 0)
+	assertIntReply(t, execCmd(db, "expire", "a", "100", "lt"), 1)
+	assertIntReply(t, execCmd(db, "ttl", "a"), 100)
+
+	// a persistent key is regarded as infinite ttl
+	execCmd(db, "persist", "a")
+	assertIntReply(t, execCmd(db, "expire", "a", "100", "lt"), 1)
+
+	assertErrReply(t, execCmd(db, "expire", "a", "100", "nx", "xx"),
+		"ERR NX and XX, GT or LT options at the same time are not compatible")
+	assertErrReply(t, execCmd(db, "expire", "a", "100", "gt", "lt"),
+		"ERR GT and LT options at the same time are not compatible")
+	assertErrReply(t, execCmd(db, "expire", "a", "100", "foo"), "ERR Unsupported option foo")
+}
+
+func TestLazyExpire(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "1", "px", "10")
+	time.Sleep(20 * time.Millisecond)
+	assertReply(t, execCmd(db, "get", "a"), "$-1\r\n")
+	assertIntReply(t, execCmd(db, "ttl", "a"), -2)
+	if _, ok := db.keyspace().data.Get("a"); ok {
+		t.Error("expected expired key removed on read")
+	}
+}
+
+func TestActiveExpireCycle(t *testing.T) {
+	db := makeTestDB()
+	for i := 0; i < 1000; i++ {
+		execCmd(db, "set", "volatile"+strconv.Itoa(i), "v", "px", "10")
+	}
+	for i := 0; i < 10; i++ {
+		execCmd(db, "set", "alive"+strconv.Itoa(i), "v", "ex", "100")
+		execCmd(db, "set", "persistent"+strconv.Itoa(i), "v")
+	}
+	time.Sleep(20 * time.Millisecond)
+	db.activeExpireCycle()
+	// the cycle stops once few sampled keys are expired, most expired keys should be removed
+	if n := db.keyspace().data.Len(); n > 20+activeExpireKeysPerLoop*4 {
+		t.Errorf("expected expired keys removed, actually %d keys left", n)
+	}
+	for i := 0; i < 10; i++ {
+		for _, key := range []string{"alive" + strconv.Itoa(i), "persistent" + strconv.Itoa(i)} {
+			if _, exists := db.GetEntity(key); !exists {
+				t.Errorf("expected %s exists", key)
+			}
+		}
+	}
+}
+
+// TestActiveExpireCycleDeleting checks the cycle finishes while clients remove volatile keys during sampling
+func TestActiveExpireCycleDeleting(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		db := makeTestDB()
+		keys := []string{"del"}
+		for i := 0; i < 30; i++ {
+			key := "volatile" + strconv.Itoa(i)
+			execCmd(db, "set", key, "v", "ex", "100")
+			keys = append(keys, key)
+		}
+		sampling := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			close(sampling)
+			for i := 0; i < 100; i++ {
+				db.activeExpireCycle()
+			}
+		}()
+		<-sampling
+		execCmd(db, keys...)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("active expire cycle doesn't finish")
+		}
+	}
+}
@@ -0,0 +1,468 @@
+package database
+
+import (
+	"fmt"
+	SortedSet "github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/geohash"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// positions are stored in sorted set, the score is the 52 bits geohash of position
+
+func parseCoordinate(lngArg, latArg []byte) (float64, float64, protocol.ErrorReply) {
+	lng, err := strconv.ParseFloat(string(lngArg), 64)
+	if err != nil {
+		return 0, 0, protocol.NewErrReply("ERR value is not a valid float")
+	}
+	lat, err := strconv.ParseFloat(string(latArg), 64)
+	if err != nil {
+		return 0, 0, protocol.NewErrReply("ERR value is not a valid float")
+	}
+	if !geohash.Valid(lng, lat) {
+		return 0, 0, protocol.NewErrReply(fmt.Sprintf("ERR invalid longitude,latitude pair %f,%f", lng, lat))
+	}
+	return lng, lat, nil
+}
+
+// decodeScore returns the center of the geohash cell represented by score
+func decodeScore(score float64) (float64, float64) {
+	return geohash.Decode(uint64(score), geohash.MaxStep).Center()
+}
+
+// parseDistanceUnit returns how many meters an unit is
+func parseDistanceUnit(arg []byte) (float64, protocol.ErrorReply) {
+	switch strings.ToLower(string(arg)) {
+	case "m":
+		return 1, nil
+	case "km":
+		return 1000, nil
+	case "ft":
+		return 0.3048, nil
+	case "mi":
+		return 1609.34, nil
+	}
+	return 0, protocol.NewErrReply("ERR unsupported unit provided. please use M, KM, FT, MI")
+}
+
+// formatCoordinate formats coordinate in fixed-point notation without trailing zeros
+func formatCoordinate(val float64) []byte {
+	s := strconv.FormatFloat(val, 'f', 17, 64)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return []byte(s)
+}
+
+func formatDistance(dist float64) []byte {
+	return []byte(strconv.FormatFloat(dist, 'f', 4, 64))
+}
+
+func coordinateReply(lng, lat float64) redis.Reply {
+	return protocol.NewMultiBulkReply([][]byte{formatCoordinate(lng), formatCoordinate(lat)})
+}
+
+// execGeoAdd adds positions into sorted set
+// GEOADD key [NX | XX] [CH] longitude latitude member [longitude latitude member ...]
+func execGeoAdd(db *DB, args [][]byte) redis.Reply {
+	// GEOADD is translated into ZADD which shares the flags
+	zaddArgs := [][]byte{args[0]}
+	i := 1
+parseFlags:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "NX", "XX", "CH":
+			zaddArgs = append(zaddArgs, args[i])
+		default:
+			break parseFlags
+		}
+	}
+	triples := args[i:]
+	if len(triples) == 0 || len(triples)%3 != 0 {
+		return protocol.NewErrReply("ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... ")
+	}
+	for j := 0; j < len(triples); j += 3 {
+		lng, lat, errReply := parseCoordinate(triples[j], triples[j+1])
+		if errReply != nil {
+			return errReply
+		}
+		hash := geohash.Encode(lng, lat, geohash.MaxStep)
+		zaddArgs = append(zaddArgs, []byte(strconv.FormatUint(hash, 10)), triples[j+2])
+	}
+	return execZAdd(db, zaddArgs)
+}
+
+// execGeoPos returns positions of members, nil for missing members
+func execGeoPos(db *DB, args [][]byte) redis.Reply {
+	sortedSet, errReply := db.getAsSortedSet(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	result := make([]redis.Reply, len(args)-1)
+	for i, member := range args[1:] {
+		if sortedSet == nil {
+			result[i] = protocol.NewNullMultiBulkReply()
+			continue
+		}
+		element, exists := sortedSet.Get(string(member))
+		if !exists {
+			result[i] = protocol.NewNullMultiBulkReply()
+			continue
+		}
+		result[i] = coordinateReply(decodeScore(element.Score))
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+// execGeoDist returns the distance between two members
+// GEODIST key member1 member2 [M | KM | FT | MI]
+func execGeoDist(db *DB, args [][]byte) redis.Reply {
+	if len(args) > 4 {
+		return protocol.NewSyntaxErrReply()
+	}
+	unit := 1.0
+	if len(args) == 4 {
+		var errReply protocol.ErrorReply
+		unit, errReply = parseDistanceUnit(args[3])
+		if errReply != nil {
+			return errReply
+		}
+	}
+	sortedSet, errReply := db.getAsSortedSet(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewNullBulkReply()
+	}
+	element1, exists := sortedSet.Get(string(args[1]))
+	if !exists {
+		return protocol.NewNullBulkReply()
+	}
+	element2, exists := sortedSet.Get(string(args[2]))
+	if !exists {
+		return protocol.NewNullBulkReply()
+	}
+	lng1, lat1 := decodeScore(element1.Score)
+	lng2, lat2 := decodeScore(element2.Score)
+	return protocol.NewBulkReply(formatDistance(geohash.Distance(lng1, lat1, lng2, lat2) / unit))
+}
+
+// execGeoHash returns the standard geohash strings of members
+func execGeoHash(db *DB, args [][]byte) redis.Reply {
+	sortedSet, errReply := db.getAsSortedSet(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	result := make([][]byte, len(args)-1)
+	for i, member := range args[1:] {
+		if sortedSet == nil {
+			continue
+		}
+		element, exists := sortedSet.Get(string(member))
+		if !exists {
+			continue
+		}
+		result[i] = []byte(geohash.ToString(decodeScore(element.Score)))
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+const (
+	geoSortNone = iota
+	geoSortAsc
+	geoSortDesc
+)
+
+type geoSearchOption struct {
+	fromMember []byte
+	fromLonLat bool
+	lng        float64
+	lat        float64
+
+	byRadius bool
+	radius   float64
+	byBox    bool
+	width    float64
+	height   float64
+	// unit is how many meters the unit of radius, box and distance is
+	unit float64
+
+	sort      int
+	count     int64
+	any       bool
+	withCoord bool
+	withDist  bool
+	withHash  bool
+	storeDist bool
+}
+
+// parseDistanceArg parses radius, width or height of search area, NaN and infinity are not allowed
+func parseDistanceArg(arg []byte, name string) (float64, protocol.ErrorReply) {
+	val, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(val) || math.IsInf(val, 0) {
+		return 0, protocol.NewErrReply("ERR need numeric " + name)
+	}
+	return val, nil
+}
+
+// parseGeoSearchOption parses options of GEOSEARCH, STOREDIST is allowed for GEOSEARCHSTORE instead of WITH* options
+func parseGeoSearchOption(args [][]byte, opt *geoSearchOption, store bool) protocol.ErrorReply {
+	for i := 0; i < len(args); i++ {
+		switch arg := strings.ToUpper(string(args[i])); {
+		case arg == "FROMMEMBER" && i+1 < len(args):
+			opt.fromMember = args[i+1]
+			i++
+		case arg == "FROMLONLAT" && i+2 < len(args):
+			lng, lat, errReply := parseCoordinate(args[i+1], args[i+2])
+			if errReply != nil {
+				return errReply
+			}
+			opt.fromLonLat, opt.lng, opt.lat = true, lng, lat
+			i += 2
+		case arg == "BYRADIUS" && i+2 < len(args):
+			radius, errReply := parseDistanceArg(args[i+1], "radius")
+			if errReply != nil {
+				return errReply
+			}
+			if radius < 0 {
+				return protocol.NewErrReply("ERR radius cannot be negative")
+			}
+			unit, errReply := parseDistanceUnit(args[i+2])
+			if errReply != nil {
+				return errReply
+			}
+			opt.byRadius, opt.radius, opt.unit = true, radius*unit, unit
+			i += 2
+		case arg == "BYBOX" && i+3 < len(args):
+			width, errReply := parseDistanceArg(args[i+1], "width")
+			if errReply != nil {
+				return errReply
+			}
+			height, errReply := parseDistanceArg(args[i+2], "height")
+			if errReply != nil {
+				return errReply
+			}
+			if width < 0 || height < 0 {
+				return protocol.NewErrReply("ERR height or width cannot be negative")
+			}
+			unit, errReply := parseDistanceUnit(args[i+3])
+			if errReply != nil {
+				return errReply
+			}
+			opt.byBox, opt.width, opt.height, opt.unit = true, width*unit, height*unit, unit
+			i += 3
+		case arg == "ASC":
+			opt.sort = geoSortAsc
+		case arg == "DESC":
+			opt.sort = geoSortDesc
+		case arg == "COUNT" && i+1 < len(args):
+			count, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			if count <= 0 {
+				return protocol.NewErrReply("ERR COUNT must be > 0")
+			}
+			opt.count = count
+			i++
+			if i+1 < len(args) && strings.ToUpper(string(args[i+1])) == "ANY" {
+				opt.any = true
+				i++
+			}
+		case arg == "ANY":
+			return protocol.NewErrReply("ERR the ANY argument requires COUNT argument")
+		case arg == "WITHCOORD" && !store:
+			opt.withCoord = true
+		case arg == "WITHDIST" && !store:
+			opt.withDist = true
+		case arg == "WITHHASH" && !store:
+			opt.withHash = true
+		case arg == "STOREDIST" && store:
+			opt.storeDist = true
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+	if (opt.fromMember == nil) == !opt.fromLonLat {
+		return protocol.NewErrReply("ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+	}
+	if opt.byRadius == opt.byBox {
+		return protocol.NewErrReply("ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")
+	}
+	return nil
+}
+
+type geoPoint struct {
+	member string
+	score  float64
+	lng    float64
+	lat    float64
+	// dist is the distance in meters to the center of search area
+	dist float64
+}
+
+// geoSearch returns members within the search area, nil if the key not exists
+func (db *DB) geoSearch(key string, opt *geoSearchOption) ([]*geoPoint, protocol.ErrorReply) {
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return nil, errReply
+	}
+	if sortedSet == nil {
+		return nil, nil
+	}
+	lng, lat := opt.lng, opt.lat
+	if opt.fromMember != nil {
+		element, exists := sortedSet.Get(string(opt.fromMember))
+		if !exists {
+			return nil, protocol.NewErrReply("ERR could not decode requested zset member")
+		}
+		lng, lat = decodeScore(element.Score)
+	}
+	var areas [][2]uint64
+	if opt.byRadius {
+		areas = geohash.SearchAreasByRadius(lng, lat, opt.radius)
+	} else {
+		areas = geohash.SearchAreasByBox(lng, lat, opt.width, opt.height)
+	}
+
+	points := make([]*geoPoint, 0)
+	for _, area := range areas {
+		min := &SortedSet.ScoreBorder{Value: float64(area[0])}
+		max := &SortedSet.ScoreBorder{Value: float64(area[1]), Exclude: true}
+		sortedSet.ForEachByScore(min, max, 0, -1, false, func(element *SortedSet.Element) bool {
+			pLng, pLat := decodeScore(element.Score)
+			var dist float64
+			if opt.byRadius {
+				dist = geohash.Distance(lng, lat, pLng, pLat)
+				if dist > opt.radius {
+					return true
+				}
+			} else {
+				var ok bool
+				dist, ok = geohash.DistanceInBox(opt.width, opt.height, lng, lat, pLng, pLat)
+				if !ok {
+					return true
+				}
+			}
+			points = append(points, &geoPoint{
+				member: element.Member,
+				score:  element.Score,
+				lng:    pLng,
+				lat:    pLat,
+				dist:   dist,
+			})
+			// with ANY, returns as soon as enough matches are found
+			return !opt.any || int64(len(points)) < opt.count
+		})
+		if opt.any && int64(len(points)) >= opt.count {
+			break
+		}
+	}
+
+	sortMode := opt.sort
+	if sortMode == geoSortNone && opt.count > 0 && !opt.any {
+		// the nearest ones are returned if COUNT is given
+		sortMode = geoSortAsc
+	}
+	switch sortMode {
+	case geoSortAsc:
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].dist < points[j].dist
+		})
+	case geoSortDesc:
+		sort.SliceStable(points, func(i, j int) bool {
+			return points[i].dist > points[j].dist
+		})
+	}
+	if opt.count > 0 && int64(len(points)) > opt.count {
+		points = points[:opt.count]
+	}
+	return points, nil
+}
+
+// execGeoSearch returns members within the area of circle or box
+// GEOSEARCH key <FROMMEMBER member | FROMLONLAT longitude latitude> <BYRADIUS radius <M | KM | FT | MI> | BYBOX width height <M | KM | FT | MI>>
+// [ASC | DESC] [COUNT count [ANY]] [WITHCOORD] [WITHDIST] [WITHHASH]
+func execGeoSearch(db *DB, args [][]byte) redis.Reply {
+	opt := &geoSearchOption{}
+	if errReply := parseGeoSearchOption(args[1:], opt, false); errReply != nil {
+		return errReply
+	}
+	points, errReply := db.geoSearch(string(args[0]), opt)
+	if errReply != nil {
+		return errReply
+	}
+	if len(points) == 0 {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	if !opt.withCoord && !opt.withDist && !opt.withHash {
+		result := make([][]byte, len(points))
+		for i, point := range points {
+			result[i] = []byte(point.member)
+		}
+		return protocol.NewMultiBulkReply(result)
+	}
+	result := make([]redis.Reply, len(points))
+	for i, point := range points {
+		item := []redis.Reply{protocol.NewBulkReply([]byte(point.member))}
+		if opt.withDist {
+			item = append(item, protocol.NewBulkReply(formatDistance(point.dist/opt.unit)))
+		}
+		if opt.withHash {
+			item = append(item, protocol.NewIntReply(int64(point.score)))
+		}
+		if opt.withCoord {
+			item = append(item, coordinateReply(point.lng, point.lat))
+		}
+		result[i] = protocol.NewMultiRawReply(item)
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+// execGeoSearchStore stores the result of GEOSEARCH into the destination key
+// scores are geohash of positions, or distances in the given unit with STOREDIST
+// GEOSEARCHSTORE destination source <FROMMEMBER member | FROMLONLAT longitude latitude> <BYRADIUS radius <M | KM | FT | MI> | BYBOX width height <M | KM | FT | MI>>
+// [ASC | DESC] [COUNT count [ANY]] [STOREDIST]
+func execGeoSearchStore(db *DB, args [][]byte) redis.Reply {
+	dest := string(args[0])
+	opt := &geoSearchOption{}
+	if errReply := parseGeoSearchOption(args[2:], opt, true); errReply != nil {
+		return errReply
+	}
+	points, errReply := db.geoSearch(string(args[1]), opt)
+	if errReply != nil {
+		return errReply
+	}
+	db.Remove(dest)
+	if len(points) > 0 {
+		sortedSet := SortedSet.NwSortedSet()
+		for _, point := range points {
+			score := point.score
+			if opt.storeDist {
+				score = point.dist / opt.unit
+			}
+			sortedSet.Add(point.member, score)
+		}
+		db.PutEntity(dest, &database.DataEntity{
+			Data: sortedSet,
+		})
+		db.signalKeyReady(dest)
+	}
+	db.addAof(utils.ToCmdLine3("geosearchstore", args...))
+	return protocol.NewIntReply(int64(len(points)))
+}
+
+func init() {
+	RegisterCommand("GeoAdd", execGeoAdd, writeFirstKey, -5)
+	RegisterCommand("GeoPos", execGeoPos, readFirstKey, -2)
+	RegisterCommand("GeoDist", execGeoDist, readFirstKey, -4)
+	RegisterCommand("GeoHash", execGeoHash, readFirstKey, -2)
+	RegisterCommand("GeoSearch", execGeoSearch, readFirstKey, -7)
+	RegisterCommand("GeoSearchStore", execGeoSearchStore, prepareZRangeStore, -8)
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/protocol"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestGeoSearch(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "geoadd", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania"), 2)
+	assertBulkReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "Catania"), "166274.1516")
+	assertMultiBulkReply(t, execCmd(db, "geohash", "Sicily", "Palermo"), "sqc8b49rny0")
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "Sicily", "fromlonlat", "15", "37", "byradius", "200", "km", "asc"),
+		"Catania", "Palermo")
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "bybox", "100", "100", "km"),
+		"Palermo")
+	assertIntReply(t, execCmd(db, "geosearchstore", "dest", "Sicily", "fromlonlat", "15", "37", "byradius", "100", "km"), 1)
+}
+
+func TestGeoInvalidArgs(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "geoadd", "Sicily", "13.361389", "38.115556", "Palermo")
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "nan", "38", "x"), "ERR invalid longitude,latitude pair NaN,38.000000")
+	for _, radius := range []string{"nan", "inf", "-inf", "abc"} {
+		assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "byradius", radius, "km"),
+			"ERR need numeric radius")
+	}
+	for _, width := range []string{"nan", "+inf"} {
+		assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "bybox", width, "1", "km"),
+			"ERR need numeric width")
+		assertErrReply(t, execCmd(db, "geosearchstore", "dest", "Sicily", "frommember", "Palermo", "bybox", "1", width, "km"),
+			"ERR need numeric height")
+	}
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "byradius", "-1", "km"),
+		"ERR radius cannot be negative")
+}
+
+func TestGeoAdd(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "geoadd", "Sicily", "13.361389", "38.115556", "Palermo"), 1)
+	assertIntReply(t, execCmd(db, "geoadd", "Sicily", "nx", "15", "37", "Palermo", "15.087269", "37.502669", "Catania"), 1)
+	assertIntReply(t, execCmd(db, "geoadd", "Sicily", "xx", "ch", "15", "37", "Palermo", "15", "37", "Agrigento"), 1)
+	assertIntReply(t, execCmd(db, "zcard", "Sicily"), 2)
+	assertIntReply(t, execCmd(db, "geoadd", "Sicily", "-180", "85.05112878", "edge"), 1)
+
+	usage := "ERR syntax error. Try GEOADD key [x1] [y1] [name1] [x2] [y2] [name2] ... "
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "13", "38", "x", "14"), usage)
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "nx", "13", "38"), usage)
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "181", "38", "x"), "ERR invalid longitude,latitude pair 181.000000,38.000000")
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "13", "86", "x"), "ERR invalid longitude,latitude pair 13.000000,86.000000")
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "a", "38", "x"), "ERR value is not a valid float")
+	assertErrReply(t, execCmd(db, "geoadd", "Sicily", "nx", "xx", "13", "38", "x"), "ERR XX and NX options at the same time are not compatible")
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "geoadd", "str", "13", "38", "x"), wrongTypeErr)
+}
+
+func TestGeoPos(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "geoadd", "Sicily", "13.361389", "38.115556", "Palermo")
+	raw := execCmd(db, "geopos", "Sicily", "Palermo", "missing")
+	reply, ok := raw.(*protocol.MultiRawReply)
+	if !ok || len(reply.Replies) != 2 {
+		t.Fatalf("unexpected reply %q", raw.ToBytes())
+	}
+	position := replyStrings(t, reply.Replies[0])
+	lng, _ := strconv.ParseFloat(position[0], 64)
+	lat, _ := strconv.ParseFloat(position[1], 64)
+	if math.Abs(lng-13.361389) > 1e-5 || math.Abs(lat-38.115556) > 1e-5 {
+		t.Errorf("unexpected position %v", position)
+	}
+	assertReply(t, reply.Replies[1], "*-1\r\n")
+	assertReply(t, execCmd(db, "geopos", "missing", "Palermo"), "*1\r\n*-1\r\n")
+}
+
+func TestGeoDist(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "geoadd", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania")
+	assertBulkReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "Catania", "km"), "166.2742")
+	assertBulkReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "Catania", "MI"), "103.3182")
+	assertBulkReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "Palermo"), "0.0000")
+	assertReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "missing"), "$-1\r\n")
+	assertReply(t, execCmd(db, "geodist", "missing", "Palermo", "Catania"), "$-1\r\n")
+	assertErrReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "Catania", "yd"),
+		"ERR unsupported unit provided. please use M, KM, FT, MI")
+	assertErrReply(t, execCmd(db, "geodist", "Sicily", "Palermo", "Catania", "km", "x"), "Err syntax error")
+
+	assertReply(t, execCmd(db, "geohash", "Sicily", "Palermo", "missing", "Catania"),
+		"*3\r\n$11\r\nsqc8b49rny0\r\n$-1\r\n$11\r\nsqdtr74hyu0\r\n")
+	assertReply(t, execCmd(db, "geohash", "missing", "Palermo"), "*1\r\n$-1\r\n")
+}
+
+func TestGeoSearchOptions(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "geoadd", "Sicily", "13.361389", "38.115556", "Palermo", "15.087269", "37.502669", "Catania",
+		"12.758489", "38.788135", "edge1", "17.241510", "38.788135", "edge2")
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "Sicily", "fromlonlat", "15", "37", "byradius", "200", "km", "desc"),
+		"Palermo", "Catania")
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "Sicily", "fromlonlat", "15", "37", "bybox", "400", "400", "km", "asc"),
+		"Catania", "Palermo", "edge2", "edge1")
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "Sicily", "fromlonlat", "15", "37", "byradius", "1000", "km", "count", "1"),
+		"Catania")
+	if members := replyStrings(t, execCmd(db, "geosearch", "Sicily", "fromlonlat", "15", "37",
+		"byradius", "1000", "km", "count", "2", "any")); len(members) != 2 {
+		t.Errorf("expected 2 members, actually %v", members)
+	}
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "Sicily", "fromlonlat", "0", "0", "byradius", "1", "km"))
+	assertMultiBulkReply(t, execCmd(db, "geosearch", "missing", "fromlonlat", "15", "37", "byradius", "1", "km"))
+
+	reply := execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "byradius", "200", "km", "asc",
+		"withdist", "withhash", "withcoord")
+	multiRaw, ok := reply.(*protocol.MultiRawReply)
+	if !ok || len(multiRaw.Replies) != 3 {
+		t.Fatalf("unexpected reply %q", reply.ToBytes())
+	}
+	item, ok := multiRaw.Replies[2].(*protocol.MultiRawReply)
+	if !ok || len(item.Replies) != 4 {
+		t.Fatalf("unexpected item %q", multiRaw.Replies[2].ToBytes())
+	}
+	assertBulkReply(t, item.Replies[0], "Catania")
+	assertBulkReply(t, item.Replies[1], "166.2742")
+	assertIntReply(t, item.Replies[2], 3479447370796909)
+	if position := replyStrings(t, item.Replies[3]); len(position) != 2 {
+		t.Errorf("unexpected position %v", position)
+	}
+
+	assertIntReply(t, execCmd(db, "geosearchstore", "dest", "Sicily", "frommember", "Palermo",
+		"byradius", "200", "km", "storedist"), 3)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "dest", "0", "-1"), "Palermo", "edge1", "Catania")
+	assertBulkReply(t, execCmd(db, "zscore", "dest", "Palermo"), "0")
+	assertIntReply(t, execCmd(db, "geosearchstore", "dest", "Sicily", "fromlonlat", "0", "0", "byradius", "1", "km"), 0)
+	assertNotExists(t, db, "dest")
+
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "byradius", "1", "km", "asc", "withdist"),
+		"ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "fromlonlat", "15", "37", "byradius", "1", "km"),
+		"ERR exactly one of FROMMEMBER or FROMLONLAT can be specified for GEOSEARCH")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "asc", "withdist", "withhash"),
+		"ERR exactly one of BYRADIUS and BYBOX can be specified for GEOSEARCH")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "missing", "byradius", "1", "km"),
+		"ERR could not decode requested zset member")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "byradius", "1", "km", "count", "0"),
+		"ERR COUNT must be > 0")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "byradius", "1", "km", "any"),
+		"ERR the ANY argument requires COUNT argument")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "bybox", "-1", "1", "km"),
+		"ERR height or width cannot be negative")
+	assertErrReply(t, execCmd(db, "geosearch", "Sicily", "frommember", "Palermo", "byradius", "1", "km", "storedist"),
+		"Err syntax error")
+	assertErrReply(t, execCmd(db, "geosearchstore", "dest", "Sicily", "frommember", "Palermo", "byradius", "1", "km", "withdist"),
+		"Err syntax error")
+}
@@ -0,0 +1,418 @@
+package database
+
+import (
+	Dict "github.com/Ravior/goredis/datastruct/dict"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/wildcard"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+func (db *DB) getAsDict(key string) (Dict.Dict, protocol.ErrorReply) {
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil, nil
+	}
+	dict, ok := entity.Data.(Dict.Dict)
+	if !ok {
+		return nil, &protocol.WrongTypeErrReply{}
+	}
+	return dict, nil
+}
+
+func (db *DB) getOrInitDict(key string) (dict Dict.Dict, inited bool, errReply protocol.ErrorReply) {
+	dict, errReply = db.getAsDict(key)
+	if errReply != nil {
+		return nil, false, errReply
+	}
+	inited = false
+	if dict == nil {
+		dict = Dict.CreateSimpleDict()
+		db.PutEntity(key, &database.DataEntity{
+			Data: dict,
+		})
+		inited = true
+	}
+	return dict, inited, nil
+}
+
+// execHSet sets fields of hash, returns the number of new fields
+func execHSet(db *DB, args [][]byte) redis.Reply {
+	if len(args)%2 != 1 {
+		return protocol.NewArgNumErrReply("hset")
+	}
+	key := string(args[0])
+	dict, _, errReply := db.getOrInitDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := 0
+	for i := 1; i < len(args); i += 2 {
+		result += dict.Put(string(args[i]), args[i+1])
+	}
+	db.addAof(utils.ToCmdLine3("hset", args...))
+	return protocol.NewIntReply(int64(result))
+}
+
+// execHSetNX sets field of hash only if the field not exists
+func execHSetNX(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, _, errReply := db.getOrInitDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := dict.PutIfAbsent(string(args[1]), args[2])
+	if result > 0 {
+		db.addAof(utils.ToCmdLine3("hsetnx", args...))
+	}
+	return protocol.NewIntReply(int64(result))
+}
+
+// execHGet gets value of field
+func execHGet(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewNullBulkReply()
+	}
+	raw, exists := dict.Get(string(args[1]))
+	if !exists {
+		return protocol.NewNullBulkReply()
+	}
+	return protocol.NewBulkReply(raw.([]byte))
+}
+
+// execHMGet gets values of fields, nil for absent field
+func execHMGet(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := make([][]byte, len(args)-1)
+	if dict == nil {
+		return protocol.NewMultiBulkReply(result)
+	}
+	for i, field := range args[1:] {
+		raw, exists := dict.Get(string(field))
+		if exists {
+			result[i] = raw.([]byte)
+		}
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+// execHDel removes fields of hash, the key will be removed if the hash becomes empty
+func execHDel(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewIntReply(0)
+	}
+	deleted := 0
+	for _, field := range args[1:] {
+		deleted += dict.Remove(string(field))
+	}
+	if dict.Len() == 0 {
+		db.Remove(key)
+	}
+	if deleted > 0 {
+		db.addAof(utils.ToCmdLine3("hdel", args...))
+	}
+	return protocol.NewIntReply(int64(deleted))
+}
+
+// execHExists checks whether the field exists in hash
+func execHExists(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewIntReply(0)
+	}
+	if _, exists := dict.Get(string(args[1])); exists {
+		return protocol.NewIntReply(1)
+	}
+	return protocol.NewIntReply(0)
+}
+
+// execHLen returns the number of fields in hash
+func execHLen(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(int64(dict.Len()))
+}
+
+// execHStrLen returns the length of value bound to field
+func execHStrLen(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewIntReply(0)
+	}
+	raw, exists := dict.Get(string(args[1]))
+	if !exists {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(int64(len(raw.([]byte))))
+}
+
+// execHKeys returns all fields of hash
+func execHKeys(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	fields := make([][]byte, 0, dict.Len())
+	dict.ForEach(func(field string, val interface{}) bool {
+		fields = append(fields, []byte(field))
+		return true
+	})
+	return protocol.NewMultiBulkReply(fields)
+}
+
+// execHVals returns all values of hash
+func execHVals(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	values := make([][]byte, 0, dict.Len())
+	dict.ForEach(func(field string, val interface{}) bool {
+		values = append(values, val.([]byte))
+		return true
+	})
+	return protocol.NewMultiBulkReply(values)
+}
+
+// execHGetAll returns all fields and values of hash
+func execHGetAll(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	result := make([][]byte, 0, dict.Len()*2)
+	dict.ForEach(func(field string, val interface{}) bool {
+		result = append(result, []byte(field), val.([]byte))
+		return true
+	})
+	return protocol.NewMultiBulkReply(result)
+}
+
+// execHIncrBy increments the integer value of field by the given amount
+func execHIncrBy(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	field := string(args[1])
+	delta, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	var val int64
+	if dict != nil {
+		if raw, exists := dict.Get(field); exists {
+			val, err = strconv.ParseInt(string(raw.([]byte)), 10, 64)
+			if err != nil {
+				return protocol.NewErrReply("ERR hash value is not an integer")
+			}
+		}
+	}
+	if (delta > 0 && val > math.MaxInt64-delta) || (delta < 0 && val < math.MinInt64-delta) {
+		return protocol.NewErrReply("ERR increment or decrement would overflow")
+	}
+	val += delta
+	if dict == nil {
+		dict, _, _ = db.getOrInitDict(key)
+	}
+	dict.Put(field, []byte(strconv.FormatInt(val, 10)))
+	db.addAof(utils.ToCmdLine3("hincrby", args...))
+	return protocol.NewIntReply(val)
+}
+
+// execHIncrByFloat increments the float value of field by the given amount
+func execHIncrByFloat(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	field := string(args[1])
+	delta, err := strconv.ParseFloat(string(args[2]), 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return protocol.NewErrReply("ERR value is not a valid float")
+	}
+
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	var val float64
+	if dict != nil {
+		if raw, exists := dict.Get(field); exists {
+			val, err = strconv.ParseFloat(string(raw.([]byte)), 64)
+			if err != nil || math.IsNaN(val) {
+				return protocol.NewErrReply("ERR hash value is not a float")
+			}
+		}
+	}
+	val += delta
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return protocol.NewErrReply("ERR increment would produce NaN or Infinity")
+	}
+	if dict == nil {
+		dict, _, _ = db.getOrInitDict(key)
+	}
+	result := []byte(strconv.FormatFloat(val, 'f', -1, 64))
+	dict.Put(field, result)
+	// propagate the result instead of the increment, the same as INCRBYFLOAT
+	db.addAof(utils.ToCmdLine3("hset", args[0], args[1], result))
+	return protocol.NewBulkReply(result)
+}
+
+// execHRandField returns random fields of hash
+// a positive count returns distinct fields, a negative count allows the same field to be returned multiple times
+func execHRandField(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	withCount := len(args) >= 2
+	var count int64
+	withValues := false
+	if withCount {
+		var err error
+		count, err = strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		if len(args) == 3 {
+			if strings.ToUpper(string(args[2])) != "WITHVALUES" {
+				return protocol.NewSyntaxErrReply()
+			}
+			withValues = true
+		} else if len(args) > 3 {
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	if dict == nil {
+		if withCount {
+			return protocol.NewEmptyMultiBulkReply()
+		}
+		return protocol.NewNullBulkReply()
+	}
+	fields := dict.Keys()
+	if !withCount {
+		return protocol.NewBulkReply([]byte(fields[rand.Intn(len(fields))]))
+	}
+
+	var picked []string
+	if count >= 0 {
+		if count > int64(len(fields)) {
+			count = int64(len(fields))
+		}
+		picked = make([]string, count)
+		for i, j := range rand.Perm(len(fields))[:count] {
+			picked[i] = fields[j]
+		}
+	} else {
+		if count == math.MinInt64 || -count > math.MaxInt32 {
+			return protocol.NewErrReply("ERR value is out of range")
+		}
+		picked = make([]string, -count)
+		for i := range picked {
+			picked[i] = fields[rand.Intn(len(fields))]
+		}
+	}
+
+	result := make([][]byte, 0, len(picked)*2)
+	for _, field := range picked {
+		result = append(result, []byte(field))
+		if withValues {
+			raw, _ := dict.Get(field)
+			result = append(result, raw.([]byte))
+		}
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+// execHScan iterates fields and values of hash by cursor, the cursor is the bucket to continue with
+func execHScan(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	option, errReply := parseScanOption(args[2:], false)
+	if errReply != nil {
+		return errReply
+	}
+	dict, errReply := db.getAsDict(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := make([][]byte, 0)
+	if dict == nil {
+		return makeScanReply(0, result)
+	}
+	cursor = dict.Scan(cursor, option.count, func(field string, val interface{}) bool {
+		if wildcard.Match(option.pattern, field) {
+			result = append(result, []byte(field), val.([]byte))
+		}
+		return true
+	})
+	return makeScanReply(cursor, result)
+}
+
+func init() {
+	RegisterCommand("HSet", execHSet, writeFirstKey, -4)
+	RegisterCommand("HSetNX", execHSetNX, writeFirstKey, 4)
+	RegisterCommand("HGet", execHGet, readFirstKey, 3)
+	RegisterCommand("HMGet", execHMGet, readFirstKey, -3)
+	RegisterCommand("HDel", execHDel, writeFirstKey, -3)
+	RegisterCommand("HExists", execHExists, readFirstKey, 3)
+	RegisterCommand("HLen", execHLen, readFirstKey, 2)
+	RegisterCommand("HStrLen", execHStrLen, readFirstKey, 3)
+	RegisterCommand("HKeys", execHKeys, readFirstKey, 2)
+	RegisterCommand("HVals", execHVals, readFirstKey, 2)
+	RegisterCommand("HGetAll", execHGetAll, readFirstKey, 2)
+	RegisterCommand("HIncrBy", execHIncrBy, writeFirstKey, 4)
+	RegisterCommand("HIncrByFloat", execHIncrByFloat, writeFirstKey, 4)
+	RegisterCommand("HRandField", execHRandField, readFirstKey, -2)
+	RegisterCommand("HScan", execHScan, readFirstKey, -3)
+}
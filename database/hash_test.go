@@ -0,0 +1,143 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/protocol"
+	"strconv"
+	"testing"
+)
+
+func TestHScan(t *testing.T) {
+	db := makeTestDB()
+	args := []string{"hset", "h"}
+	for i := 0; i < 1000; i++ {
+		args = append(args, "f"+strconv.Itoa(i), strconv.Itoa(i))
+	}
+	execCmd(db, args...)
+
+	seen := make(map[string]string)
+	batches := scanAll(t, db, "hscan", "h", "count", "20")
+	if len(batches) < 2 {
+		t.Errorf("expected more than one call, actually %d", len(batches))
+	}
+	for _, batch := range batches {
+		for i := 0; i+1 < len(batch); i += 2 {
+			seen[batch[i]] = batch[i+1]
+		}
+	}
+	if len(seen) != 1000 {
+		t.Errorf("expected 1000 fields, actually %d", len(seen))
+	}
+	if seen["f10"] != "10" {
+		t.Errorf("expected value 10, actually %s", seen["f10"])
+	}
+
+	batches = scanAll(t, db, "hscan", "h", "match", "f1?", "count", "1000")
+	if len(batches) != 1 || len(batches[0]) != 20 {
+		t.Errorf("expected 10 matched fields in one call, actually %v", batches)
+	}
+	assertReply(t, execCmd(db, "hscan", "missing", "0"), "*2\r\n$1\r\n0\r\n*0\r\n")
+	assertErrReply(t, execCmd(db, "hscan", "h", "x"), "ERR invalid cursor")
+}
+
+func TestHSet(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "hset", "h", "a", "1", "b", "2"), 2)
+	assertIntReply(t, execCmd(db, "hset", "h", "a", "3", "c", "4"), 1)
+	assertBulkReply(t, execCmd(db, "hget", "h", "a"), "3")
+	assertReply(t, execCmd(db, "hget", "h", "x"), "$-1\r\n")
+	assertReply(t, execCmd(db, "hget", "missing", "a"), "$-1\r\n")
+	assertReply(t, execCmd(db, "hmget", "h", "a", "x", "b"), "*3\r\n$1\r\n3\r\n$-1\r\n$1\r\n2\r\n")
+	assertReply(t, execCmd(db, "hmget", "missing", "a"), "*1\r\n$-1\r\n")
+	assertErrReply(t, execCmd(db, "hset", "h", "a", "1", "b"), "ERR wrong number of arguments for 'hset' command")
+
+	assertIntReply(t, execCmd(db, "hsetnx", "h", "a", "5"), 0)
+	assertIntReply(t, execCmd(db, "hsetnx", "h", "d", "5"), 1)
+	assertBulkReply(t, execCmd(db, "hget", "h", "d"), "5")
+
+	assertIntReply(t, execCmd(db, "hlen", "h"), 4)
+	assertIntReply(t, execCmd(db, "hexists", "h", "a"), 1)
+	assertIntReply(t, execCmd(db, "hexists", "h", "x"), 0)
+	assertIntReply(t, execCmd(db, "hstrlen", "h", "a"), 1)
+	assertIntReply(t, execCmd(db, "hstrlen", "h", "x"), 0)
+
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "hset", "str", "a", "1"), wrongTypeErr)
+	assertErrReply(t, execCmd(db, "hget", "str", "a"), wrongTypeErr)
+}
+
+func TestHDel(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "hset", "h", "a", "1", "b", "2")
+	assertIntReply(t, execCmd(db, "hdel", "h", "a", "x"), 1)
+	assertIntReply(t, execCmd(db, "hlen", "h"), 1)
+	// the key is removed with its last field
+	assertIntReply(t, execCmd(db, "hdel", "h", "b"), 1)
+	assertIntReply(t, execCmd(db, "exists", "h"), 0)
+	assertIntReply(t, execCmd(db, "hdel", "h", "b"), 0)
+}
+
+func TestHGetAll(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "hset", "h", "a", "1", "b", "2", "c", "3")
+	assertUnorderedReply(t, execCmd(db, "hkeys", "h"), "a", "b", "c")
+	assertUnorderedReply(t, execCmd(db, "hvals", "h"), "1", "2", "3")
+	all := replyStrings(t, execCmd(db, "hgetall", "h"))
+	if len(all) != 6 {
+		t.Fatalf("expected 6 elements, actually %v", all)
+	}
+	for i := 0; i < len(all); i += 2 {
+		if all[i+1] != strconv.Itoa(int(all[i][0]-'a')+1) {
+			t.Errorf("unexpected value %s of field %s", all[i+1], all[i])
+		}
+	}
+	assertMultiBulkReply(t, execCmd(db, "hkeys", "missing"))
+	assertMultiBulkReply(t, execCmd(db, "hvals", "missing"))
+	assertMultiBulkReply(t, execCmd(db, "hgetall", "missing"))
+}
+
+func TestHIncrBy(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "hincrby", "h", "a", "5"), 5)
+	assertIntReply(t, execCmd(db, "hincrby", "h", "a", "-10"), -5)
+	assertBulkReply(t, execCmd(db, "hget", "h", "a"), "-5")
+	execCmd(db, "hset", "h", "max", "9223372036854775807", "str", "abc")
+	assertErrReply(t, execCmd(db, "hincrby", "h", "max", "1"), "ERR increment or decrement would overflow")
+	assertErrReply(t, execCmd(db, "hincrby", "h", "str", "1"), "ERR hash value is not an integer")
+	assertErrReply(t, execCmd(db, "hincrby", "h", "a", "x"), "ERR value is not an integer or out of range")
+
+	assertBulkReply(t, execCmd(db, "hincrbyfloat", "h", "f", "1.5"), "1.5")
+	assertBulkReply(t, execCmd(db, "hincrbyfloat", "h", "f", "-0.5"), "1")
+	assertErrReply(t, execCmd(db, "hincrbyfloat", "h", "str", "1"), "ERR hash value is not a float")
+	assertErrReply(t, execCmd(db, "hincrbyfloat", "h", "f", "x"), "ERR value is not a valid float")
+	assertErrReply(t, execCmd(db, "hincrbyfloat", "h", "f", "inf"), "ERR value is not a valid float")
+	execCmd(db, "hset", "h", "big", "1.7e308")
+	assertErrReply(t, execCmd(db, "hincrbyfloat", "h", "big", "1.7e308"), "ERR increment would produce NaN or Infinity")
+}
+
+func TestHRandField(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "hset", "h", "a", "1", "b", "2", "c", "3")
+	field := execCmd(db, "hrandfield", "h").(*protocol.BulkReply)
+	if s := string(field.Arg); s != "a" && s != "b" && s != "c" {
+		t.Errorf("unexpected field %s", s)
+	}
+	assertUnorderedReply(t, execCmd(db, "hrandfield", "h", "10"), "a", "b", "c")
+	if fields := replyStrings(t, execCmd(db, "hrandfield", "h", "2")); len(fields) != 2 || fields[0] == fields[1] {
+		t.Errorf("expected 2 distinct fields, actually %v", fields)
+	}
+	if fields := replyStrings(t, execCmd(db, "hrandfield", "h", "-5")); len(fields) != 5 {
+		t.Errorf("expected 5 fields, actually %v", fields)
+	}
+	pairs := replyStrings(t, execCmd(db, "hrandfield", "h", "-4", "withvalues"))
+	if len(pairs) != 8 {
+		t.Fatalf("expected 8 elements, actually %v", pairs)
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		assertBulkReply(t, execCmd(db, "hget", "h", pairs[i]), pairs[i+1])
+	}
+	assertMultiBulkReply(t, execCmd(db, "hrandfield", "h", "0"))
+	assertReply(t, execCmd(db, "hrandfield", "missing"), "$-1\r\n")
+	assertMultiBulkReply(t, execCmd(db, "hrandfield", "missing", "1"))
+	assertErrReply(t, execCmd(db, "hrandfield", "h", "1", "values"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "hrandfield", "h", "-9223372036854775808"), "ERR value is out of range")
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/datastruct/hyperloglog"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+)
+
+func invalidHyperLogLogErr() protocol.ErrorReply {
+	return protocol.NewErrReply("WRONGTYPE Key is not a valid HyperLogLog string value.")
+}
+
+// getAsHyperLogLog decodes the HyperLogLog stored in key.
+// HyperLogLog is stored as a string encoded by MarshalBinary, the same as redis,
+// so string commands can read it and PF* commands reject strings which are not HyperLogLog.
+func (db *DB) getAsHyperLogLog(key string) (*hyperloglog.HyperLogLog, protocol.ErrorReply) {
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return nil, errReply
+	}
+	if bytes == nil {
+		return nil, nil
+	}
+	hll := &hyperloglog.HyperLogLog{}
+	if err := hll.UnmarshalBinary(bytes); err != nil {
+		return nil, invalidHyperLogLogErr()
+	}
+	return hll, nil
+}
+
+// putHyperLogLog stores the encoded HyperLogLog, ttl of key is kept
+func (db *DB) putHyperLogLog(key string, hll *hyperloglog.HyperLogLog) {
+	data, _ := hll.MarshalBinary()
+	db.PutEntity(key, &database.DataEntity{
+		Data: data,
+	})
+}
+
+// execPFAdd adds elements into HyperLogLog, returns 1 if the estimated cardinality may be changed.
+// Registers are updated in place like SETBIT, instead of decoding and encoding the whole HyperLogLog.
+func execPFAdd(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	created := bytes == nil
+	if created {
+		bytes, _ = hyperloglog.New().MarshalBinary()
+	}
+	bytes, updated, err := hyperloglog.AddEncoded(bytes, args[1:]...)
+	if err != nil {
+		return invalidHyperLogLogErr()
+	}
+	if !updated && !created {
+		return protocol.NewIntReply(0)
+	}
+	// data may be moved by growing or promotion
+	db.PutEntity(key, &database.DataEntity{
+		Data: bytes,
+	})
+	db.addAof(utils.ToCmdLine3("pfadd", args...))
+	return protocol.NewIntReply(1)
+}
+
+// execPFCount returns the estimated cardinality of the union of HyperLogLogs
+func execPFCount(db *DB, args [][]byte) redis.Reply {
+	if len(args) == 1 {
+		hll, errReply := db.getAsHyperLogLog(string(args[0]))
+		if errReply != nil {
+			return errReply
+		}
+		if hll == nil {
+			return protocol.NewIntReply(0)
+		}
+		return protocol.NewIntReply(int64(hll.Count()))
+	}
+	merged := hyperloglog.New()
+	for _, arg := range args {
+		hll, errReply := db.getAsHyperLogLog(string(arg))
+		if errReply != nil {
+			return errReply
+		}
+		if hll != nil {
+			merged.Merge(hll)
+		}
+	}
+	return protocol.NewIntReply(int64(merged.Count()))
+}
+
+// execPFMerge merges HyperLogLogs into the destination key, the destination key is a source too if exists
+func execPFMerge(db *DB, args [][]byte) redis.Reply {
+	dest := string(args[0])
+	destHll, errReply := db.getAsHyperLogLog(dest)
+	if errReply != nil {
+		return errReply
+	}
+	if destHll == nil {
+		destHll = hyperloglog.New()
+	}
+	for _, arg := range args[1:] {
+		hll, errReply := db.getAsHyperLogLog(string(arg))
+		if errReply != nil {
+			return errReply
+		}
+		if hll != nil {
+			destHll.Merge(hll)
+		}
+	}
+	db.putHyperLogLog(dest, destHll)
+	db.addAof(utils.ToCmdLine3("pfmerge", args...))
+	return protocol.NewOkReply()
+}
+
+func init() {
+	RegisterCommand("PFAdd", execPFAdd, writeFirstKey, -2)
+	RegisterCommand("PFCount", execPFCount, readAllKeys, -2)
+	RegisterCommand("PFMerge", execPFMerge, prepareSetCalculateStore, -2)
+}
@@ -0,0 +1,75 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/protocol"
+	"strconv"
+	"testing"
+)
+
+const invalidHllErr = "WRONGTYPE Key is not a valid HyperLogLog string value."
+
+func TestPFAdd(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "pfadd", "hll", "a", "b", "c"), 1)
+	assertIntReply(t, execCmd(db, "pfadd", "hll", "a"), 0)
+	assertIntReply(t, execCmd(db, "pfcount", "hll"), 3)
+	assertIntReply(t, execCmd(db, "pfcount", "missing"), 0)
+
+	// PFADD without element creates an empty HyperLogLog
+	assertIntReply(t, execCmd(db, "pfadd", "empty"), 1)
+	assertIntReply(t, execCmd(db, "pfadd", "empty"), 0)
+	assertIntReply(t, execCmd(db, "pfcount", "empty"), 0)
+}
+
+func TestPFCountMulti(t *testing.T) {
+	db := makeTestDB()
+	for i := 0; i < 10; i++ {
+		execCmd(db, "pfadd", "hll1", strconv.Itoa(i))
+		execCmd(db, "pfadd", "hll2", strconv.Itoa(i+5))
+	}
+	assertIntReply(t, execCmd(db, "pfcount", "hll1", "hll2", "missing"), 15)
+	assertOkReply(t, execCmd(db, "pfmerge", "dest", "hll1", "hll2", "missing"))
+	assertIntReply(t, execCmd(db, "pfcount", "dest"), 15)
+	// sources are kept
+	assertIntReply(t, execCmd(db, "pfcount", "hll1"), 10)
+
+	// destination is merged too
+	assertOkReply(t, execCmd(db, "pfmerge", "hll1", "hll2"))
+	assertIntReply(t, execCmd(db, "pfcount", "hll1"), 15)
+}
+
+func TestHyperLogLogAsString(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "pfadd", "hll", "a", "b", "c")
+	assertReply(t, execCmd(db, "type", "hll"), "+string\r\n")
+
+	// the encoded value can be copied by GET and SET
+	raw := execCmd(db, "get", "hll")
+	reply, ok := raw.(*protocol.BulkReply)
+	if !ok {
+		t.Fatalf("expected bulk reply, actually %q", raw.ToBytes())
+	}
+	assertOkReply(t, execCmd(db, "set", "copy", string(reply.Arg)))
+	assertIntReply(t, execCmd(db, "pfcount", "copy"), 3)
+	assertIntReply(t, execCmd(db, "pfadd", "copy", "d"), 1)
+	assertIntReply(t, execCmd(db, "pfcount", "copy"), 4)
+	assertIntReply(t, execCmd(db, "pfcount", "hll"), 3)
+}
+
+func TestHyperLogLogWrongType(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "str", "hello")
+	assertErrReply(t, execCmd(db, "pfadd", "str", "a"), invalidHllErr)
+	assertErrReply(t, execCmd(db, "pfcount", "str"), invalidHllErr)
+	assertErrReply(t, execCmd(db, "pfmerge", "dest", "str"), invalidHllErr)
+	assertBulkReply(t, execCmd(db, "get", "str"), "hello")
+
+	// HyperLogLog corrupted by string commands
+	execCmd(db, "pfadd", "hll", "a")
+	execCmd(db, "append", "hll", "x")
+	assertErrReply(t, execCmd(db, "pfcount", "hll"), invalidHllErr)
+
+	execCmd(db, "rpush", "list", "a")
+	assertErrReply(t, execCmd(db, "pfadd", "list", "a"),
+		"WRONGTYPE Operation against a key holding the wrong kind of value")
+}
@@ -1,8 +1,20 @@
 package database
 
 import (
+	Dict "github.com/Ravior/goredis/datastruct/dict"
+	List "github.com/Ravior/goredis/datastruct/list"
+	HashSet "github.com/Ravior/goredis/datastruct/set"
+	SortedSet "github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/datastruct/stream"
+	"github.com/Ravior/goredis/interface/database"
 	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/wildcard"
 	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // execDel removes a key from db
@@ -14,11 +26,507 @@ func execDel(db *DB, args [][]byte) redis.Reply {
 
 	deleted := db.Removes(keys...)
 	if deleted > 0 {
-		//db.addAof(utils.ToCmdLine3("del", args...))
+		db.addAof(utils.ToCmdLine3("del", args...))
 	}
 	return protocol.NewIntReply(int64(deleted))
 }
 
+// execUnlink removes keys from db, big values will be released in background
+func execUnlink(db *DB, args [][]byte) redis.Reply {
+	keys := make([]string, len(args))
+	for i, v := range args {
+		keys[i] = string(v)
+	}
+
+	deleted := db.Unlinks(keys...)
+	if deleted > 0 {
+		db.addAof(utils.ToCmdLine3("unlink", args...))
+	}
+	return protocol.NewIntReply(int64(deleted))
+}
+
+// parseFlushOption parses ASYNC/SYNC option of FLUSHDB and FLUSHALL
+func parseFlushOption(args [][]byte) (async bool, errReply protocol.ErrorReply) {
+	if len(args) == 0 {
+		return false, nil
+	}
+	if len(args) > 1 {
+		return false, protocol.NewSyntaxErrReply()
+	}
+	switch strings.ToUpper(string(args[0])) {
+	case "ASYNC":
+		return true, nil
+	case "SYNC":
+		return false, nil
+	}
+	return false, protocol.NewSyntaxErrReply()
+}
+
+// execFlushDB removes all keys from selected database
+func execFlushDB(db *DB, args [][]byte) redis.Reply {
+	async, errReply := parseFlushOption(args)
+	if errReply != nil {
+		return errReply
+	}
+	db.Flush(async)
+	if !async {
+		reclaimMemory()
+	}
+	db.addAof(utils.ToCmdLine3("flushdb", args...))
+	return protocol.NewOkReply()
+}
+
+// flags of expire commands
+const (
+	expireNX = 1 << iota // set expiry only when the key has no expiry
+	expireXX             // set expiry only when the key has an existing expiry
+	expireGT             // set expiry only when the new expiry is greater than current one
+	expireLT             // set expiry only when the new expiry is less than current one
+)
+
+// parseExpireFlags parses NX/XX/GT/LT options of expire commands
+func parseExpireFlags(args [][]byte) (int, protocol.ErrorReply) {
+	flags := 0
+	for _, arg := range args {
+		switch strings.ToUpper(string(arg)) {
+		case "NX":
+			flags |= expireNX
+		case "XX":
+			flags |= expireXX
+		case "GT":
+			flags |= expireGT
+		case "LT":
+			flags |= expireLT
+		default:
+			return 0, protocol.NewErrReply("ERR Unsupported option " + string(arg))
+		}
+	}
+	if flags&expireNX != 0 && flags&(expireXX|expireGT|expireLT) != 0 {
+		return 0, protocol.NewErrReply("ERR NX and XX, GT or LT options at the same time are not compatible")
+	}
+	if flags&expireGT != 0 && flags&expireLT != 0 {
+		return 0, protocol.NewErrReply("ERR GT and LT options at the same time are not compatible")
+	}
+	return flags, nil
+}
+
+// expireGeneric sets expire time of the given key, it returns 1 if the timeout was set
+func expireGeneric(db *DB, key string, expireAt time.Time, flags int) redis.Reply {
+	_, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(0)
+	}
+	// a persistent key is regarded as infinite ttl by GT and LT
+	current, hasTTL := db.TTL(key)
+	if flags&expireNX != 0 && hasTTL {
+		return protocol.NewIntReply(0)
+	}
+	if flags&expireXX != 0 && !hasTTL {
+		return protocol.NewIntReply(0)
+	}
+	if flags&expireGT != 0 && (!hasTTL || !expireAt.After(current)) {
+		return protocol.NewIntReply(0)
+	}
+	if flags&expireLT != 0 && hasTTL && !expireAt.Before(current) {
+		return protocol.NewIntReply(0)
+	}
+
+	if !expireAt.After(time.Now()) {
+		// expire time in the past, delete the key right now
+		db.Remove(key)
+		db.addAof(utils.ToCmdLine("del", key))
+		return protocol.NewIntReply(1)
+	}
+	db.Expire(key, expireAt)
+	db.addAof(makeExpireCmd(key, expireAt))
+	return protocol.NewIntReply(1)
+}
+
+// parseExpireTime converts argument of expire commands to time.Time
+// unit is the duration of one unit of raw value, absolute means the raw value is a unix timestamp
+func parseExpireTime(arg []byte, unit time.Duration, absolute bool, cmdName string) (time.Time, protocol.ErrorReply) {
+	raw, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil {
+		return time.Time{}, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	if absolute {
+		if unit == time.Second {
+			return time.Unix(raw, 0), nil
+		}
+		return time.UnixMilli(raw), nil
+	}
+	if raw > math.MaxInt64/int64(unit) || raw < math.MinInt64/int64(unit) {
+		return time.Time{}, protocol.NewErrReply("ERR invalid expire time in '" + cmdName + "' command")
+	}
+	return time.Now().Add(time.Duration(raw) * unit), nil
+}
+
+func makeExpireExecutor(cmdName string, unit time.Duration, absolute bool) ExecFunc {
+	return func(db *DB, args [][]byte) redis.Reply {
+		key := string(args[0])
+		expireAt, errReply := parseExpireTime(args[1], unit, absolute, cmdName)
+		if errReply != nil {
+			return errReply
+		}
+		flags, errReply := parseExpireFlags(args[2:])
+		if errReply != nil {
+			return errReply
+		}
+		return expireGeneric(db, key, expireAt, flags)
+	}
+}
+
+// execTTL returns a key's time to live in seconds
+func execTTL(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	_, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(-2)
+	}
+	expireTime, hasTTL := db.TTL(key)
+	if !hasTTL {
+		return protocol.NewIntReply(-1)
+	}
+	ttl := time.Until(expireTime)
+	return protocol.NewIntReply(int64((ttl + 500*time.Millisecond) / time.Second))
+}
+
+// execPTTL returns a key's time to live in milliseconds
+func execPTTL(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	_, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(-2)
+	}
+	expireTime, hasTTL := db.TTL(key)
+	if !hasTTL {
+		return protocol.NewIntReply(-1)
+	}
+	return protocol.NewIntReply(time.Until(expireTime).Milliseconds())
+}
+
+// execExpireTime returns the absolute unix timestamp in seconds at which the given key will expire
+func execExpireTime(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	_, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(-2)
+	}
+	expireTime, hasTTL := db.TTL(key)
+	if !hasTTL {
+		return protocol.NewIntReply(-1)
+	}
+	return protocol.NewIntReply(expireTime.Unix())
+}
+
+// execPExpireTime returns the absolute unix timestamp in milliseconds at which the given key will expire
+func execPExpireTime(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	_, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(-2)
+	}
+	expireTime, hasTTL := db.TTL(key)
+	if !hasTTL {
+		return protocol.NewIntReply(-1)
+	}
+	return protocol.NewIntReply(expireTime.UnixMilli())
+}
+
+// execPersist removes expiration from a key
+func execPersist(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	_, exists := db.GetEntity(key)
+	if !exists {
+		return protocol.NewIntReply(0)
+	}
+	_, hasTTL := db.TTL(key)
+	if !hasTTL {
+		return protocol.NewIntReply(0)
+	}
+	db.Persist(key)
+	db.addAof(utils.ToCmdLine3("persist", args...))
+	return protocol.NewIntReply(1)
+}
+
+// execExists returns the number of existing keys, a key given multiple times is counted multiple times
+func execExists(db *DB, args [][]byte) redis.Reply {
+	result := int64(0)
+	for _, arg := range args {
+		if _, exists := db.GetEntity(string(arg)); exists {
+			result++
+		}
+	}
+	return protocol.NewIntReply(result)
+}
+
+// typeName returns the type of value reported by TYPE
+func typeName(val interface{}) string {
+	switch val.(type) {
+	case []byte:
+		return "string"
+	case List.List:
+		return "list"
+	case Dict.Dict:
+		return "hash"
+	case *HashSet.Set:
+		return "set"
+	case *SortedSet.SortedSet:
+		return "zset"
+	case *stream.Stream:
+		return "stream"
+	}
+	return "none"
+}
+
+// execType returns the type of value stored at key
+func execType(db *DB, args [][]byte) redis.Reply {
+	entity, exists := db.GetEntity(string(args[0]))
+	if !exists {
+		return protocol.NewStatusReply("none")
+	}
+	return protocol.NewStatusReply(typeName(entity.Data))
+}
+
+// renameGeneric moves value and ttl of src to dest, dest will be overwritten
+func (db *DB) renameGeneric(src string, dest string, entity *database.DataEntity) {
+	expireTime, hasTTL := db.TTL(src)
+	db.Remove(src)
+	db.Remove(dest)
+	db.PutEntity(dest, entity)
+	if hasTTL {
+		db.Expire(dest, expireTime)
+	}
+	db.signalKeyReady(dest)
+}
+
+// execRename renames a key, the ttl goes with the value
+func execRename(db *DB, args [][]byte) redis.Reply {
+	src, dest := string(args[0]), string(args[1])
+	entity, exists := db.GetEntity(src)
+	if !exists {
+		return protocol.NewErrReply("ERR no such key")
+	}
+	if src != dest {
+		db.renameGeneric(src, dest, entity)
+	}
+	db.addAof(utils.ToCmdLine3("rename", args...))
+	return protocol.NewOkReply()
+}
+
+// execRenameNx renames a key only if the new key does not exist
+func execRenameNx(db *DB, args [][]byte) redis.Reply {
+	src, dest := string(args[0]), string(args[1])
+	entity, exists := db.GetEntity(src)
+	if !exists {
+		return protocol.NewErrReply("ERR no such key")
+	}
+	if _, exists := db.GetEntity(dest); exists {
+		return protocol.NewIntReply(0)
+	}
+	db.renameGeneric(src, dest, entity)
+	db.addAof(utils.ToCmdLine3("renamenx", args...))
+	return protocol.NewIntReply(1)
+}
+
+// copyValue returns a deep copy of value, so that modifying the copy won't affect the origin.
+// Elements of collections are shared, since they are never modified in place.
+func copyValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case []byte:
+		return cloneBytes(v)
+	case List.List:
+		result := List.NewQuickList()
+		v.ForEach(func(i int, e interface{}) bool {
+			result.Add(e)
+			return true
+		})
+		return result
+	case Dict.Dict:
+		result := Dict.CreateSimpleDict()
+		v.ForEach(func(field string, value interface{}) bool {
+			result.Put(field, value)
+			return true
+		})
+		return result
+	case *HashSet.Set:
+		return HashSet.Make(v.ToSlice()...)
+	case *SortedSet.SortedSet:
+		result := SortedSet.NwSortedSet()
+		v.ForEach(0, v.Len(), false, func(element *SortedSet.Element) bool {
+			result.Add(element.Member, element.Score)
+			return true
+		})
+		return result
+	case *stream.Stream:
+		return v.Clone()
+	}
+	return val
+}
+
+// execTouch returns the number of existing keys, it refreshes nothing since LRU is not supported
+func execTouch(db *DB, args [][]byte) redis.Reply {
+	return execExists(db, args)
+}
+
+// randomKeyMaxTries limits attempts to find a key not expired
+const randomKeyMaxTries = 100
+
+// execRandomKey returns a random key
+func execRandomKey(db *DB, args [][]byte) redis.Reply {
+	for i := 0; i < randomKeyMaxTries; i++ {
+		key, ok := db.keyspace().data.RandomKey()
+		if !ok {
+			return protocol.NewNullBulkReply()
+		}
+		if db.keyExists(key) {
+			return protocol.NewBulkReply([]byte(key))
+		}
+	}
+	return protocol.NewNullBulkReply()
+}
+
+// execKeys returns all keys matching the glob-style pattern
+func execKeys(db *DB, args [][]byte) redis.Reply {
+	pattern := string(args[0])
+	keys := make([]string, 0)
+	db.keyspace().data.ForEach(func(key string, val interface{}) bool {
+		if wildcard.Match(pattern, key) {
+			keys = append(keys, key)
+		}
+		return true
+	})
+	// expired keys are removed after ForEach which locks shards of data
+	result := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		if db.keyExists(key) {
+			result = append(result, []byte(key))
+		}
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+const defaultScanCount = 10
+
+// scanOption is the MATCH, COUNT and TYPE options of SCAN family commands
+type scanOption struct {
+	pattern  string
+	count    int
+	typeName string
+}
+
+// parseScanCursor parses cursor of SCAN family commands
+func parseScanCursor(arg []byte) (int, protocol.ErrorReply) {
+	cursor, err := strconv.ParseUint(string(arg), 10, 63)
+	if err != nil {
+		return 0, protocol.NewErrReply("ERR invalid cursor")
+	}
+	return int(cursor), nil
+}
+
+// parseScanOption parses options after cursor, TYPE is only allowed if withType
+func parseScanOption(args [][]byte, withType bool) (*scanOption, protocol.ErrorReply) {
+	option := &scanOption{
+		pattern: "*",
+		count:   defaultScanCount,
+	}
+	for i := 0; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		if i+1 >= len(args) {
+			return nil, protocol.NewSyntaxErrReply()
+		}
+		switch {
+		case arg == "MATCH":
+			option.pattern = string(args[i+1])
+		case arg == "COUNT":
+			count, err := strconv.Atoi(string(args[i+1]))
+			if err != nil {
+				return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			if count < 1 {
+				return nil, protocol.NewSyntaxErrReply()
+			}
+			option.count = count
+		case arg == "TYPE" && withType:
+			option.typeName = strings.ToLower(string(args[i+1]))
+		default:
+			return nil, protocol.NewSyntaxErrReply()
+		}
+		i++
+	}
+	return option, nil
+}
+
+// makeScanReply returns the next cursor and elements
+func makeScanReply(cursor int, elements [][]byte) redis.Reply {
+	return protocol.NewMultiRawReply([]redis.Reply{
+		protocol.NewBulkReply([]byte(strconv.Itoa(cursor))),
+		protocol.NewMultiBulkReply(elements),
+	})
+}
+
+// execScan iterates keys by cursor, the cursor is the index of the next shard to visit.
+// Since shards of data never change, a key existing during the whole iteration is returned at least once.
+func execScan(db *DB, args [][]byte) redis.Reply {
+	cursor, errReply := parseScanCursor(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	option, errReply := parseScanOption(args[1:], true)
+	if errReply != nil {
+		return errReply
+	}
+	keys := make([]string, 0)
+	cursor = db.keyspace().data.Scan(cursor, option.count, func(key string, val interface{}) bool {
+		if !wildcard.Match(option.pattern, key) {
+			return true
+		}
+		if option.typeName != "" {
+			entity, _ := val.(*database.DataEntity)
+			if entity == nil || typeName(entity.Data) != option.typeName {
+				return true
+			}
+		}
+		keys = append(keys, key)
+		return true
+	})
+	result := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		if db.keyExists(key) {
+			result = append(result, []byte(key))
+		}
+	}
+	return makeScanReply(cursor, result)
+}
+
+// keyExists returns whether key exists, it locks the key since an expired key will be removed
+func (db *DB) keyExists(key string) bool {
+	keys := []string{key}
+	db.RWLocks(keys, nil)
+	defer db.RWUnLocks(keys, nil)
+	_, exists := db.GetEntity(key)
+	return exists
+}
+
 func init() {
-	RegisterCommand("Del", execDel)
+	RegisterCommand("Del", execDel, writeAllKeys, -2)
+	RegisterCommand("Unlink", execUnlink, writeAllKeys, -2)
+	RegisterCommand("FlushDB", execFlushDB, noPrepare, -1)
+	RegisterCommand("Expire", makeExpireExecutor("expire", time.Second, false), writeFirstKey, -3)
+	RegisterCommand("PExpire", makeExpireExecutor("pexpire", time.Millisecond, false), writeFirstKey, -3)
+	RegisterCommand("ExpireAt", makeExpireExecutor("expireat", time.Second, true), writeFirstKey, -3)
+	RegisterCommand("PExpireAt", makeExpireExecutor("pexpireat", time.Millisecond, true), writeFirstKey, -3)
+	RegisterCommand("TTL", execTTL, readFirstKey, 2)
+	RegisterCommand("PTTL", execPTTL, readFirstKey, 2)
+	RegisterCommand("ExpireTime", execExpireTime, readFirstKey, 2)
+	RegisterCommand("PExpireTime", execPExpireTime, readFirstKey, 2)
+	RegisterCommand("Persist", execPersist, writeFirstKey, 2)
+	RegisterCommand("Exists", execExists, readAllKeys, -2)
+	RegisterCommand("Type", execType, readFirstKey, 2)
+	RegisterCommand("Rename", execRename, writeAllKeys, 3)
+	RegisterCommand("RenameNx", execRenameNx, writeAllKeys, 3)
+	RegisterCommand("Touch", execTouch, readAllKeys, -2)
+	RegisterCommand("RandomKey", execRandomKey, noPrepare, 1)
+	RegisterCommand("Keys", execKeys, noPrepare, 2)
+	RegisterCommand("Scan", execScan, noPrepare, -2)
 }
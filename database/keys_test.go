@@ -0,0 +1,222 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/protocol"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitLazyfreed waits until lazyfreeWorker has released the given number of jobs
+func waitLazyfreed(t *testing.T, expected int64) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt64(&lazyfreedObjects) < expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d lazyfreed objects, actually %d", expected, atomic.LoadInt64(&lazyfreedObjects))
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestUnlink(t *testing.T) {
+	db := makeTestDB()
+	args := []string{"rpush", "list"}
+	for i := 0; i < 100; i++ {
+		args = append(args, strconv.Itoa(i))
+	}
+	execCmd(db, args...)
+	execCmd(db, "set", "str", "v")
+	freed := atomic.LoadInt64(&lazyfreedObjects)
+	assertIntReply(t, execCmd(db, "unlink", "list", "str", "missing"), 2)
+	assertIntReply(t, execCmd(db, "exists", "list", "str"), 0)
+	// only the big list is released in background
+	waitLazyfreed(t, freed+1)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt64(&lazyfreedObjects); n != freed+1 {
+		t.Errorf("expected 1 lazyfreed object, actually %d", n-freed)
+	}
+}
+
+func TestFlushDB(t *testing.T) {
+	db := makeTestDB()
+	for _, option := range []string{"", "async", "SYNC"} {
+		execCmd(db, "set", "a", "1")
+		execCmd(db, "set", "b", "2", "ex", "100")
+		freed := atomic.LoadInt64(&lazyfreedObjects)
+		if option == "" {
+			assertOkReply(t, execCmd(db, "flushdb"))
+		} else {
+			assertOkReply(t, execCmd(db, "flushdb", option))
+		}
+		if option == "async" {
+			waitLazyfreed(t, freed+1)
+		}
+		assertIntReply(t, execCmd(db, "exists", "a", "b"), 0)
+		assertIntReply(t, execCmd(db, "ttl", "b"), -2)
+	}
+	assertErrReply(t, execCmd(db, "flushdb", "lazy"), "Err syntax error")
+}
+
+func TestScan(t *testing.T) {
+	db := makeTestDB()
+	for i := 0; i < 1000; i++ {
+		execCmd(db, "set", "str"+strconv.Itoa(i), "v")
+	}
+	execCmd(db, "rpush", "list", "a")
+	seen := make(map[string]bool)
+	for _, batch := range scanAll(t, db, "scan", "", "count", "100") {
+		for _, key := range batch {
+			seen[key] = true
+		}
+	}
+	if len(seen) != 1001 {
+		t.Errorf("expected 1001 keys, actually %d", len(seen))
+	}
+	var keys []string
+	for _, batch := range scanAll(t, db, "scan", "", "type", "list", "count", "100000") {
+		keys = append(keys, batch...)
+	}
+	if len(keys) != 1 || keys[0] != "list" {
+		t.Errorf("expected list, actually %v", keys)
+	}
+	assertErrReply(t, execCmd(db, "scan", "-1"), "ERR invalid cursor")
+	assertErrReply(t, execCmd(db, "scan", "0", "count", "0"), "Err syntax error")
+}
+
+func TestRandomKey(t *testing.T) {
+	db := makeTestDB()
+	assertReply(t, execCmd(db, "randomkey"), "$-1\r\n")
+
+	execCmd(db, "set", "a", "1")
+	assertBulkReply(t, execCmd(db, "randomkey"), "a")
+
+	for i := 0; i < 10; i++ {
+		execCmd(db, "set", "key"+strconv.Itoa(i), "v")
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		raw := execCmd(db, "randomkey")
+		reply, ok := raw.(*protocol.BulkReply)
+		if !ok {
+			t.Fatalf("expected bulk reply, actually %q", raw.ToBytes())
+		}
+		seen[string(reply.Arg)] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("expected random keys, actually %v", seen)
+	}
+	for key := range seen {
+		assertIntReply(t, execCmd(db, "exists", key), 1)
+	}
+}
+
+func TestExists(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "1")
+	execCmd(db, "rpush", "list", "a")
+	assertIntReply(t, execCmd(db, "exists", "a", "list", "missing"), 2)
+	// duplicated keys are counted multiple times
+	assertIntReply(t, execCmd(db, "exists", "a", "a", "a"), 3)
+	assertIntReply(t, execCmd(db, "touch", "a", "a", "missing"), 2)
+	execCmd(db, "pexpire", "a", "1")
+	time.Sleep(10 * time.Millisecond)
+	assertIntReply(t, execCmd(db, "exists", "a"), 0)
+}
+
+func TestType(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "str", "v")
+	execCmd(db, "pfadd", "hll", "a")
+	execCmd(db, "rpush", "list", "a")
+	execCmd(db, "hset", "hash", "f", "v")
+	execCmd(db, "sadd", "set", "a")
+	execCmd(db, "zadd", "zset", "1", "a")
+	execCmd(db, "xadd", "stream", "*", "f", "v")
+	cases := map[string]string{
+		"str":     "string",
+		"hll":     "string",
+		"list":    "list",
+		"hash":    "hash",
+		"set":     "set",
+		"zset":    "zset",
+		"stream":  "stream",
+		"missing": "none",
+	}
+	for key, expected := range cases {
+		assertReply(t, execCmd(db, "type", key), "+"+expected+"\r\n")
+	}
+}
+
+func TestRename(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "1", "ex", "100")
+	execCmd(db, "set", "b", "2")
+	assertOkReply(t, execCmd(db, "rename", "a", "b"))
+	assertIntReply(t, execCmd(db, "exists", "a"), 0)
+	assertBulkReply(t, execCmd(db, "get", "b"), "1")
+	// ttl goes with the value
+	assertIntReply(t, execCmd(db, "ttl", "b"), 100)
+	assertOkReply(t, execCmd(db, "rename", "b", "b"))
+	assertBulkReply(t, execCmd(db, "get", "b"), "1")
+
+	// ttl of destination is removed
+	execCmd(db, "set", "c", "3")
+	execCmd(db, "expire", "b", "100")
+	assertOkReply(t, execCmd(db, "rename", "c", "b"))
+	assertIntReply(t, execCmd(db, "ttl", "b"), -1)
+	assertErrReply(t, execCmd(db, "rename", "missing", "b"), "ERR no such key")
+}
+
+func TestRenameNx(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "1", "ex", "100")
+	execCmd(db, "set", "b", "2")
+	assertIntReply(t, execCmd(db, "renamenx", "a", "b"), 0)
+	assertBulkReply(t, execCmd(db, "get", "b"), "2")
+	assertIntReply(t, execCmd(db, "renamenx", "a", "a"), 0)
+	assertIntReply(t, execCmd(db, "renamenx", "a", "c"), 1)
+	assertIntReply(t, execCmd(db, "exists", "a"), 0)
+	assertBulkReply(t, execCmd(db, "get", "c"), "1")
+	assertIntReply(t, execCmd(db, "ttl", "c"), 100)
+	assertErrReply(t, execCmd(db, "renamenx", "missing", "b"), "ERR no such key")
+}
+
+// TestRenameWakeUp checks clients blocked on the destination are woken up
+func TestRenameWakeUp(t *testing.T) {
+	db := makeTestDB()
+	ch := execAsync(db, connection.NewFakeConn(), "blpop", "dest", "0")
+	waitBlocked(t, db, 1)
+	execCmd(db, "rpush", "src", "a")
+	assertOkReply(t, execCmd(db, "rename", "src", "dest"))
+	assertMultiBulkReply(t, receive(t, ch), "dest", "a")
+}
+
+func TestKeys(t *testing.T) {
+	db := makeTestDB()
+	for _, key := range []string{"hello", "hallo", "hxllo", "heeello", "world", "h*llo"} {
+		execCmd(db, "set", key, "v")
+	}
+	execCmd(db, "set", "hillo", "v", "px", "1")
+	time.Sleep(10 * time.Millisecond)
+	assertUnorderedReply(t, execCmd(db, "keys", "h?llo"), "hello", "hallo", "hxllo", "h*llo")
+	assertUnorderedReply(t, execCmd(db, "keys", "h*llo"), "hello", "hallo", "hxllo", "heeello", "h*llo")
+	assertUnorderedReply(t, execCmd(db, "keys", "h[ae]llo"), "hello", "hallo")
+	assertUnorderedReply(t, execCmd(db, "keys", "h[^e]llo"), "hallo", "hxllo", "h*llo")
+	assertUnorderedReply(t, execCmd(db, "keys", "h\\*llo"), "h*llo")
+	assertUnorderedReply(t, execCmd(db, "keys", "*"), "hello", "hallo", "hxllo", "heeello", "world", "h*llo")
+	assertMultiBulkReply(t, execCmd(db, "keys", "missing*"))
+
+	var keys []string
+	for _, batch := range scanAll(t, db, "scan", "", "match", "h[a-e]llo") {
+		keys = append(keys, batch...)
+	}
+	sort.Strings(keys)
+	if strings.Join(keys, ",") != "hallo,hello" {
+		t.Errorf("expected hallo and hello, actually %v", keys)
+	}
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/datastruct/dict"
+	"github.com/Ravior/goredis/datastruct/list"
+	"github.com/Ravior/goredis/datastruct/set"
+	"github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/datastruct/stream"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+const (
+	// values with more elements than lazyfreeThreshold are released in background, the same as redis
+	lazyfreeThreshold = 64
+	// lazyfreeReclaimEffort is how many elements released in background make memory returned to OS
+	lazyfreeReclaimEffort = 1 << 16
+)
+
+// lazyfreeJob is a value or keyspace detached from db, effort is the number of elements in it
+type lazyfreeJob struct {
+	obj    interface{}
+	effort int64
+}
+
+var (
+	// lazyfreeQueue holds values and keyspaces waiting to be released by lazyfreeWorker
+	lazyfreeQueue = make(chan *lazyfreeJob, 1024)
+	// lazyfreedObjects counts jobs released by lazyfreeWorker, like lazyfreed_objects of redis
+	lazyfreedObjects int64
+)
+
+func init() {
+	go lazyfreeWorker()
+}
+
+// lazyfreeWorker releases values detached by UNLINK and keyspaces detached by FLUSHDB ASYNC.
+// There is no explicit free in Go, memory of unreachable values is reclaimed by GC, and taking them apart
+// only burns CPU. So the worker drops them and, once enough elements are released, forces a collection
+// returning memory to OS, which is what FLUSHDB SYNC does before replying.
+func lazyfreeWorker() {
+	var pending int64
+	for job := range lazyfreeQueue {
+		pending += job.effort
+		atomic.AddInt64(&lazyfreedObjects, 1)
+		// reclaim once for a batch of jobs
+		if pending >= lazyfreeReclaimEffort && len(lazyfreeQueue) == 0 {
+			reclaimMemory()
+			pending = 0
+		}
+	}
+}
+
+// reclaimMemory collects unreachable values and returns memory to OS, it costs time in proportion to the heap
+func reclaimMemory() {
+	debug.FreeOSMemory()
+}
+
+// freeEffort returns the number of elements in the given value
+func freeEffort(val interface{}) int64 {
+	switch v := val.(type) {
+	case dict.Dict:
+		return int64(v.Len())
+	case list.List:
+		return int64(v.Len())
+	case *set.Set:
+		return int64(v.Len())
+	case *sortedset.SortedSet:
+		return v.Len()
+	case *stream.Stream:
+		return v.Len()
+	}
+	return 1
+}
+
+// lazyfree hands a job to lazyfreeWorker without blocking
+func lazyfree(job *lazyfreeJob) {
+	select {
+	case lazyfreeQueue <- job:
+	default:
+		// lazyfree worker is busy, the job is dropped and reclaimed by GC in time
+	}
+}
+
+// freeValueAsync releases a value removed from keyspace, small values are just dropped
+func freeValueAsync(val interface{}) {
+	effort := freeEffort(val)
+	if effort <= lazyfreeThreshold {
+		return
+	}
+	lazyfree(&lazyfreeJob{obj: val, effort: effort})
+}
+
+// freeKeyspaceAsync releases a flushed keyspace in background
+func freeKeyspaceAsync(space *keyspace) {
+	lazyfree(&lazyfreeJob{
+		obj:    space,
+		effort: int64(space.data.Len() + space.ttlMap.Len()),
+	})
+}
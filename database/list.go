@@ -0,0 +1,832 @@
+package database
+
+import (
+	List "github.com/Ravior/goredis/datastruct/list"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (db *DB) getAsList(key string) (List.List, protocol.ErrorReply) {
+	entity, ok := db.GetEntity(key)
+	if !ok {
+		return nil, nil
+	}
+	list, ok := entity.Data.(List.List)
+	if !ok {
+		return nil, &protocol.WrongTypeErrReply{}
+	}
+	return list, nil
+}
+
+func (db *DB) getOrInitList(key string) (list List.List, isNew bool, errReply protocol.ErrorReply) {
+	list, errReply = db.getAsList(key)
+	if errReply != nil {
+		return nil, false, errReply
+	}
+	isNew = false
+	if list == nil {
+		list = List.NewQuickList()
+		db.PutEntity(key, &database.DataEntity{
+			Data: list,
+		})
+		isNew = true
+	}
+	return list, isNew, nil
+}
+
+func equalsTo(value []byte) List.Expected {
+	return func(a interface{}) bool {
+		return utils.BytesEquals(a.([]byte), value)
+	}
+}
+
+// normalizeRange converts redis style [start, stop] (negative index counts from tail) into [begin, end)
+// ok is false if the range is empty
+func normalizeRange(start, stop int64, size int64) (begin int, end int, ok bool) {
+	if start < 0 {
+		start = size + start
+	}
+	if stop < 0 {
+		stop = size + stop
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start > stop || start >= size {
+		return 0, 0, false
+	}
+	if stop >= size {
+		stop = size - 1
+	}
+	return int(start), int(stop) + 1, true
+}
+
+func pushGeneric(db *DB, args [][]byte, left bool, onlyExists bool) redis.Reply {
+	key := string(args[0])
+	values := args[1:]
+
+	var list List.List
+	var errReply protocol.ErrorReply
+	if onlyExists {
+		list, errReply = db.getAsList(key)
+		if errReply != nil {
+			return errReply
+		}
+		if list == nil {
+			return protocol.NewIntReply(0)
+		}
+	} else {
+		list, _, errReply = db.getOrInitList(key)
+		if errReply != nil {
+			return errReply
+		}
+	}
+
+	for _, value := range values {
+		if left {
+			list.Insert(0, value)
+		} else {
+			list.Add(value)
+		}
+	}
+	db.signalKeyReady(key)
+	return protocol.NewIntReply(int64(list.Len()))
+}
+
+// execLPush inserts element at head of list
+func execLPush(db *DB, args [][]byte) redis.Reply {
+	result := pushGeneric(db, args, true, false)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("lpush", args...))
+	}
+	return result
+}
+
+// execLPushX inserts element at head of list, only if list exists
+func execLPushX(db *DB, args [][]byte) redis.Reply {
+	result := pushGeneric(db, args, true, true)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("lpushx", args...))
+	}
+	return result
+}
+
+// execRPush inserts element at last of list
+func execRPush(db *DB, args [][]byte) redis.Reply {
+	result := pushGeneric(db, args, false, false)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("rpush", args...))
+	}
+	return result
+}
+
+// execRPushX inserts element at last of list, only if list exists
+func execRPushX(db *DB, args [][]byte) redis.Reply {
+	result := pushGeneric(db, args, false, true)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("rpushx", args...))
+	}
+	return result
+}
+
+// popFromList removes and returns at most count elements from head or tail of list,
+// the key will be removed if the list becomes empty
+func (db *DB) popFromList(key string, list List.List, left bool, count int) [][]byte {
+	if count > list.Len() {
+		count = list.Len()
+	}
+	values := make([][]byte, count)
+	for i := 0; i < count; i++ {
+		if left {
+			values[i], _ = list.Remove(0).([]byte)
+		} else {
+			values[i], _ = list.RemoveLast().([]byte)
+		}
+	}
+	if list.Len() == 0 {
+		db.Remove(key)
+	}
+	return values
+}
+
+func popGeneric(db *DB, args [][]byte, left bool, cmdName string) redis.Reply {
+	if len(args) > 2 {
+		return protocol.NewArgNumErrReply(cmdName)
+	}
+	key := string(args[0])
+	withCount := len(args) == 2
+	count := 1
+	if withCount {
+		c, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil || c < 0 {
+			return protocol.NewErrReply("ERR value is out of range, must be positive")
+		}
+		if c > math.MaxInt32 {
+			c = math.MaxInt32
+		}
+		count = int(c)
+	}
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		if withCount {
+			return protocol.NewNullMultiBulkReply()
+		}
+		return protocol.NewNullBulkReply()
+	}
+	values := db.popFromList(key, list, left, count)
+	if len(values) > 0 {
+		db.addAof(utils.ToCmdLine3(cmdName, args...))
+	}
+	if withCount {
+		return protocol.NewMultiBulkReply(values)
+	}
+	return protocol.NewBulkReply(values[0])
+}
+
+// execLPop removes the first element of list, and return it
+func execLPop(db *DB, args [][]byte) redis.Reply {
+	return popGeneric(db, args, true, "lpop")
+}
+
+// execRPop removes last element of list then return it
+func execRPop(db *DB, args [][]byte) redis.Reply {
+	return popGeneric(db, args, false, "rpop")
+}
+
+// execLLen gets length of list
+func execLLen(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(int64(list.Len()))
+}
+
+// execLIndex gets element of list at given list
+func execLIndex(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	index64, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewNullBulkReply()
+	}
+
+	size := int64(list.Len())
+	if index64 < -size || index64 >= size {
+		return protocol.NewNullBulkReply()
+	}
+	if index64 < 0 {
+		index64 = size + index64
+	}
+	val, _ := list.Get(int(index64)).([]byte)
+	return protocol.NewBulkReply(val)
+}
+
+// execLSet puts element at index of list
+func execLSet(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	index64, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	value := args[2]
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewErrReply("ERR no such key")
+	}
+
+	size := int64(list.Len())
+	if index64 < -size || index64 >= size {
+		return protocol.NewErrReply("ERR index out of range")
+	}
+	if index64 < 0 {
+		index64 = size + index64
+	}
+	list.Set(int(index64), value)
+	db.addAof(utils.ToCmdLine3("lset", args...))
+	return protocol.NewOkReply()
+}
+
+// execLRange gets elements of list in given range
+func execLRange(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	start, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	stop, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+
+	begin, end, ok := normalizeRange(start, stop, int64(list.Len()))
+	if !ok {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	slice := list.Range(begin, end)
+	result := make([][]byte, len(slice))
+	for i, raw := range slice {
+		result[i], _ = raw.([]byte)
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+// execLRem removes element of list
+func execLRem(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	count64, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	value := args[2]
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewIntReply(0)
+	}
+
+	var removed int
+	if count64 == 0 {
+		removed = list.RemoveAllByVal(equalsTo(value))
+	} else if count64 > 0 {
+		if count64 > math.MaxInt32 {
+			count64 = math.MaxInt32
+		}
+		removed = list.RemoveByVal(equalsTo(value), int(count64))
+	} else {
+		if count64 < -math.MaxInt32 {
+			count64 = -math.MaxInt32
+		}
+		removed = list.ReverseRemoveByVal(equalsTo(value), int(-count64))
+	}
+
+	if list.Len() == 0 {
+		db.Remove(key)
+	}
+	if removed > 0 {
+		db.addAof(utils.ToCmdLine3("lrem", args...))
+	}
+	return protocol.NewIntReply(int64(removed))
+}
+
+// execLTrim trims list so that it contains only the elements in given range
+func execLTrim(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	start, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	stop, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewOkReply()
+	}
+
+	begin, end, ok := normalizeRange(start, stop, int64(list.Len()))
+	if !ok {
+		db.Remove(key)
+		db.addAof(utils.ToCmdLine3("ltrim", args...))
+		return protocol.NewOkReply()
+	}
+	for i := list.Len(); i > end; i-- {
+		list.RemoveLast()
+	}
+	for i := 0; i < begin; i++ {
+		list.Remove(0)
+	}
+	db.addAof(utils.ToCmdLine3("ltrim", args...))
+	return protocol.NewOkReply()
+}
+
+// execLInsert inserts element before or after the pivot element
+func execLInsert(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	var before bool
+	switch strings.ToUpper(string(args[1])) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+		before = false
+	default:
+		return protocol.NewSyntaxErrReply()
+	}
+	pivot := args[2]
+	value := args[3]
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		return protocol.NewIntReply(0)
+	}
+
+	index := -1
+	list.ForEach(func(i int, v interface{}) bool {
+		if utils.BytesEquals(v.([]byte), pivot) {
+			index = i
+			return false
+		}
+		return true
+	})
+	if index < 0 {
+		return protocol.NewIntReply(-1)
+	}
+	if !before {
+		index++
+	}
+	list.Insert(index, value)
+	db.addAof(utils.ToCmdLine3("linsert", args...))
+	return protocol.NewIntReply(int64(list.Len()))
+}
+
+// execLPos returns the index of matching elements inside a list
+func execLPos(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	value := args[1]
+	var rank int64 = 1
+	var count int64 = 0
+	var maxLen int64 = 0
+	withCount := false
+	for i := 2; i < len(args); i += 2 {
+		if i+1 >= len(args) {
+			return protocol.NewSyntaxErrReply()
+		}
+		num, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		switch strings.ToUpper(string(args[i])) {
+		case "RANK":
+			if num == 0 || num == math.MinInt64 {
+				return protocol.NewErrReply("ERR RANK can't be zero: use 1 to start from the first match, " +
+					"2 from the second ... or use negative to start from the end of the list")
+			}
+			rank = num
+		case "COUNT":
+			if num < 0 {
+				return protocol.NewErrReply("ERR COUNT can't be negative")
+			}
+			count = num
+			withCount = true
+		case "MAXLEN":
+			if num < 0 {
+				return protocol.NewErrReply("ERR MAXLEN can't be negative")
+			}
+			maxLen = num
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+
+	list, errReply := db.getAsList(key)
+	if errReply != nil {
+		return errReply
+	}
+	if list == nil {
+		if withCount {
+			return protocol.NewEmptyMultiBulkReply()
+		}
+		return protocol.NewNullBulkReply()
+	}
+
+	size := list.Len()
+	scanned := size
+	if maxLen > 0 && maxLen < int64(size) {
+		scanned = int(maxLen)
+	}
+	// skip the first |rank|-1 matches
+	skip := rank - 1
+	if rank < 0 {
+		skip = -rank - 1
+	}
+	matches := make([]redis.Reply, 0)
+	visit := func(i int, v interface{}) bool {
+		if !utils.BytesEquals(v.([]byte), value) {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		matches = append(matches, protocol.NewIntReply(int64(i)))
+		// without COUNT only the first match is needed, COUNT 0 means all matches
+		return withCount && (count == 0 || int64(len(matches)) < count)
+	}
+	if rank > 0 {
+		list.ForEach(func(i int, v interface{}) bool {
+			if i >= scanned {
+				return false
+			}
+			return visit(i, v)
+		})
+	} else {
+		slice := list.Range(size-scanned, size)
+		for i := len(slice) - 1; i >= 0; i-- {
+			if !visit(size-scanned+i, slice[i]) {
+				break
+			}
+		}
+	}
+
+	if withCount {
+		return protocol.NewMultiRawReply(matches)
+	}
+	if len(matches) == 0 {
+		return protocol.NewNullBulkReply()
+	}
+	return matches[0]
+}
+
+// parseListSide parses LEFT or RIGHT argument, returns whether it's LEFT
+func parseListSide(arg []byte) (bool, protocol.ErrorReply) {
+	switch strings.ToUpper(string(arg)) {
+	case "LEFT":
+		return true, nil
+	case "RIGHT":
+		return false, nil
+	}
+	return false, protocol.NewSyntaxErrReply()
+}
+
+// moveElement pops an element from source list and pushes it to destination list.
+// returns nil if the source list doesn't exist
+func (db *DB) moveElement(srcKey, destKey string, fromLeft, toLeft bool) ([]byte, protocol.ErrorReply) {
+	srcList, errReply := db.getAsList(srcKey)
+	if errReply != nil {
+		return nil, errReply
+	}
+	if srcList == nil {
+		return nil, nil
+	}
+	// check type of destination before modifying source
+	if _, errReply = db.getAsList(destKey); errReply != nil {
+		return nil, errReply
+	}
+
+	val := db.popFromList(srcKey, srcList, fromLeft, 1)[0]
+	destList, _, errReply := db.getOrInitList(destKey)
+	if errReply != nil {
+		return nil, errReply
+	}
+	if toLeft {
+		destList.Insert(0, val)
+	} else {
+		destList.Add(val)
+	}
+	db.signalKeyReady(destKey)
+	return val, nil
+}
+
+// execLMove atomically moves an element from source list to destination list
+func execLMove(db *DB, args [][]byte) redis.Reply {
+	fromLeft, errReply := parseListSide(args[2])
+	if errReply != nil {
+		return errReply
+	}
+	toLeft, errReply := parseListSide(args[3])
+	if errReply != nil {
+		return errReply
+	}
+	val, errReply := db.moveElement(string(args[0]), string(args[1]), fromLeft, toLeft)
+	if errReply != nil {
+		return errReply
+	}
+	if val == nil {
+		return protocol.NewNullBulkReply()
+	}
+	db.addAof(utils.ToCmdLine3("lmove", args...))
+	return protocol.NewBulkReply(val)
+}
+
+// execRPopLPush pops last element of list-A then insert it to the head of list-B
+func execRPopLPush(db *DB, args [][]byte) redis.Reply {
+	val, errReply := db.moveElement(string(args[0]), string(args[1]), false, true)
+	if errReply != nil {
+		return errReply
+	}
+	if val == nil {
+		return protocol.NewNullBulkReply()
+	}
+	db.addAof(utils.ToCmdLine3("rpoplpush", args...))
+	return protocol.NewBulkReply(val)
+}
+
+func prepareLMove(args [][]byte) ([]string, []string) {
+	return []string{string(args[0]), string(args[1])}, nil
+}
+
+func prepareBlockingPop(args [][]byte) ([]string, []string) {
+	return writeAllKeys(args[:len(args)-1])
+}
+
+func blockingPopGeneric(db *DB, args [][]byte, left bool) redis.Reply {
+	timeout, errReply := parseBlockingTimeout(args[len(args)-1])
+	if errReply != nil {
+		return errReply
+	}
+	keys := make([]string, len(args)-1)
+	for i, arg := range args[:len(args)-1] {
+		key := string(arg)
+		keys[i] = key
+		list, errReply := db.getAsList(key)
+		if errReply != nil {
+			return errReply
+		}
+		if list == nil {
+			continue
+		}
+		val := db.popFromList(key, list, left, 1)[0]
+		if left {
+			db.addAof(utils.ToCmdLine3("lpop", arg))
+		} else {
+			db.addAof(utils.ToCmdLine3("rpop", arg))
+		}
+		return protocol.NewMultiBulkReply([][]byte{arg, val})
+	}
+	return &blockedReply{
+		keys:    keys,
+		timeout: timeout,
+	}
+}
+
+// execBLPop removes the first element of the first non-empty list, blocks until one of lists is not empty
+func execBLPop(db *DB, args [][]byte) redis.Reply {
+	return blockingPopGeneric(db, args, true)
+}
+
+// execBRPop removes the last element of the first non-empty list, blocks until one of lists is not empty
+func execBRPop(db *DB, args [][]byte) redis.Reply {
+	return blockingPopGeneric(db, args, false)
+}
+
+// execBLMove is the blocking variant of LMOVE
+func execBLMove(db *DB, args [][]byte) redis.Reply {
+	fromLeft, errReply := parseListSide(args[2])
+	if errReply != nil {
+		return errReply
+	}
+	toLeft, errReply := parseListSide(args[3])
+	if errReply != nil {
+		return errReply
+	}
+	timeout, errReply := parseBlockingTimeout(args[4])
+	if errReply != nil {
+		return errReply
+	}
+	val, errReply := db.moveElement(string(args[0]), string(args[1]), fromLeft, toLeft)
+	if errReply != nil {
+		return errReply
+	}
+	if val == nil {
+		return &blockedReply{
+			keys:    []string{string(args[0])},
+			timeout: timeout,
+		}
+	}
+	db.addAof(utils.ToCmdLine3("lmove", args[:4]...))
+	return protocol.NewBulkReply(val)
+}
+
+// execBRPopLPush is the blocking variant of RPOPLPUSH
+func execBRPopLPush(db *DB, args [][]byte) redis.Reply {
+	timeout, errReply := parseBlockingTimeout(args[2])
+	if errReply != nil {
+		return errReply
+	}
+	val, errReply := db.moveElement(string(args[0]), string(args[1]), false, true)
+	if errReply != nil {
+		return errReply
+	}
+	if val == nil {
+		return &blockedReply{
+			keys:    []string{string(args[0])},
+			timeout: timeout,
+		}
+	}
+	db.addAof(utils.ToCmdLine3("rpoplpush", args[:2]...))
+	return protocol.NewBulkReply(val)
+}
+
+// parseNumKeys parses `numkeys key [key ...]` arguments, returns keys and the remaining arguments
+func parseNumKeys(args [][]byte) ([][]byte, [][]byte, protocol.ErrorReply) {
+	numKeys, err := strconv.ParseInt(string(args[0]), 10, 64)
+	if err != nil {
+		return nil, nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	if numKeys <= 0 {
+		return nil, nil, protocol.NewErrReply("ERR numkeys should be greater than 0")
+	}
+	if numKeys > int64(len(args)-1) {
+		return nil, nil, protocol.NewSyntaxErrReply()
+	}
+	return args[1 : 1+numKeys], args[1+numKeys:], nil
+}
+
+// prepareNumKeys returns keys of commands like `LMPOP numkeys key [key ...] ...` as write keys
+func prepareNumKeys(args [][]byte) ([]string, []string) {
+	keys, _, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return nil, nil
+	}
+	return writeAllKeys(keys)
+}
+
+// prepareReadNumKeys returns keys of commands like `SINTERCARD numkeys key [key ...] ...` as read keys
+func prepareReadNumKeys(args [][]byte) ([]string, []string) {
+	keys, _, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return nil, nil
+	}
+	return readAllKeys(keys)
+}
+
+// prepareBlockingNumKeys returns keys of commands like `BLMPOP timeout numkeys key [key ...] ...` as write keys
+func prepareBlockingNumKeys(args [][]byte) ([]string, []string) {
+	return prepareNumKeys(args[1:])
+}
+
+// multiPopGeneric implements LMPOP and BLMPOP, args start from numkeys
+func multiPopGeneric(db *DB, args [][]byte) (redis.Reply, []string) {
+	keys, options, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return errReply, nil
+	}
+	if len(options) == 0 {
+		return protocol.NewSyntaxErrReply(), nil
+	}
+	left, errReply := parseListSide(options[0])
+	if errReply != nil {
+		return errReply, nil
+	}
+	count := 1
+	if len(options) > 1 {
+		if len(options) != 3 || strings.ToUpper(string(options[1])) != "COUNT" {
+			return protocol.NewSyntaxErrReply(), nil
+		}
+		c, err := strconv.ParseInt(string(options[2]), 10, 64)
+		if err != nil || c <= 0 {
+			return protocol.NewErrReply("ERR count should be greater than 0"), nil
+		}
+		if c > math.MaxInt32 {
+			c = math.MaxInt32
+		}
+		count = int(c)
+	}
+
+	keyNames := make([]string, len(keys))
+	for i, arg := range keys {
+		key := string(arg)
+		keyNames[i] = key
+		list, errReply := db.getAsList(key)
+		if errReply != nil {
+			return errReply, nil
+		}
+		if list == nil {
+			continue
+		}
+		values := db.popFromList(key, list, left, count)
+		side := "RIGHT"
+		if left {
+			side = "LEFT"
+		}
+		db.addAof(utils.ToCmdLine2("lmpop", "1", key, side, "COUNT", strconv.Itoa(len(values))))
+		return protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewBulkReply(arg),
+			protocol.NewMultiBulkReply(values),
+		}), nil
+	}
+	return nil, keyNames
+}
+
+// execLMPop pops elements from the first non-empty list
+func execLMPop(db *DB, args [][]byte) redis.Reply {
+	result, _ := multiPopGeneric(db, args)
+	if result == nil {
+		return protocol.NewNullMultiBulkReply()
+	}
+	return result
+}
+
+// execBLMPop is the blocking variant of LMPOP
+func execBLMPop(db *DB, args [][]byte) redis.Reply {
+	timeout, errReply := parseBlockingTimeout(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	result, keys := multiPopGeneric(db, args[1:])
+	if result == nil {
+		return &blockedReply{
+			keys:    keys,
+			timeout: timeout,
+		}
+	}
+	return result
+}
+
+func init() {
+	RegisterCommand("LPush", execLPush, writeFirstKey, -3)
+	RegisterCommand("LPushX", execLPushX, writeFirstKey, -3)
+	RegisterCommand("RPush", execRPush, writeFirstKey, -3)
+	RegisterCommand("RPushX", execRPushX, writeFirstKey, -3)
+	RegisterCommand("LPop", execLPop, writeFirstKey, -2)
+	RegisterCommand("RPop", execRPop, writeFirstKey, -2)
+	RegisterCommand("LLen", execLLen, readFirstKey, 2)
+	RegisterCommand("LIndex", execLIndex, readFirstKey, 3)
+	RegisterCommand("LSet", execLSet, writeFirstKey, 4)
+	RegisterCommand("LRange", execLRange, readFirstKey, 4)
+	RegisterCommand("LRem", execLRem, writeFirstKey, 4)
+	RegisterCommand("LTrim", execLTrim, writeFirstKey, 4)
+	RegisterCommand("LInsert", execLInsert, writeFirstKey, 5)
+	RegisterCommand("LPos", execLPos, readFirstKey, -3)
+	RegisterCommand("LMove", execLMove, prepareLMove, 5)
+	RegisterCommand("RPopLPush", execRPopLPush, prepareLMove, 3)
+	RegisterCommand("LMPop", execLMPop, prepareNumKeys, -4)
+	RegisterCommand("BLPop", execBLPop, prepareBlockingPop, -3)
+	RegisterCommand("BRPop", execBRPop, prepareBlockingPop, -3)
+	RegisterCommand("BLMove", execBLMove, prepareLMove, 6)
+	RegisterCommand("BRPopLPush", execBRPopLPush, prepareLMove, 4)
+	RegisterCommand("BLMPop", execBLMPop, prepareBlockingNumKeys, -5)
+}
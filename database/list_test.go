@@ -0,0 +1,168 @@
+package database
+
+import (
+	"strconv"
+	"testing"
+)
+
+const wrongTypeErr = "WRONGTYPE Operation against a key holding the wrong kind of value"
+
+func TestPush(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "rpush", "list", "b", "c"), 2)
+	assertIntReply(t, execCmd(db, "lpush", "list", "a", "z"), 4)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "z", "a", "b", "c")
+	assertIntReply(t, execCmd(db, "llen", "list"), 4)
+
+	assertIntReply(t, execCmd(db, "lpushx", "missing", "a"), 0)
+	assertIntReply(t, execCmd(db, "rpushx", "missing", "a"), 0)
+	assertNotExists(t, db, "missing")
+	assertIntReply(t, execCmd(db, "rpushx", "list", "d"), 5)
+	assertIntReply(t, execCmd(db, "lpushx", "list", "y"), 6)
+
+	execCmd(db, "set", "str", "a")
+	assertErrReply(t, execCmd(db, "rpush", "str", "a"), wrongTypeErr)
+	assertErrReply(t, execCmd(db, "llen", "str"), wrongTypeErr)
+}
+
+func TestPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "c", "d", "e")
+	assertBulkReply(t, execCmd(db, "lpop", "list"), "a")
+	assertBulkReply(t, execCmd(db, "rpop", "list"), "e")
+	assertMultiBulkReply(t, execCmd(db, "lpop", "list", "2"), "b", "c")
+	assertMultiBulkReply(t, execCmd(db, "rpop", "list", "10"), "d")
+	// popping the last element removes the key
+	assertNotExists(t, db, "list")
+
+	assertReply(t, execCmd(db, "lpop", "list"), "$-1\r\n")
+	assertReply(t, execCmd(db, "lpop", "list", "1"), "*-1\r\n")
+	assertErrReply(t, execCmd(db, "lpop", "list", "-1"), "ERR value is out of range, must be positive")
+	assertErrReply(t, execCmd(db, "lpop", "list", "1", "2"), "ERR wrong number of arguments for 'lpop' command")
+}
+
+func TestLIndexLSet(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "c")
+	assertBulkReply(t, execCmd(db, "lindex", "list", "0"), "a")
+	assertBulkReply(t, execCmd(db, "lindex", "list", "-1"), "c")
+	assertReply(t, execCmd(db, "lindex", "list", "3"), "$-1\r\n")
+	assertReply(t, execCmd(db, "lindex", "list", "-4"), "$-1\r\n")
+	assertReply(t, execCmd(db, "lindex", "missing", "0"), "$-1\r\n")
+
+	assertOkReply(t, execCmd(db, "lset", "list", "-1", "z"))
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "a", "b", "z")
+	assertErrReply(t, execCmd(db, "lset", "list", "3", "z"), "ERR index out of range")
+	assertErrReply(t, execCmd(db, "lset", "missing", "0", "z"), "ERR no such key")
+}
+
+func TestLRange(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "c", "d")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "1", "2"), "b", "c")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "-2", "100"), "c", "d")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "-100", "0"), "a")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "3", "1"))
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "4", "10"))
+	assertMultiBulkReply(t, execCmd(db, "lrange", "missing", "0", "-1"))
+	assertErrReply(t, execCmd(db, "lrange", "list", "a", "1"), "ERR value is not an integer or out of range")
+}
+
+func TestLRem(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "a", "c", "a", "b")
+	assertIntReply(t, execCmd(db, "lrem", "list", "1", "a"), 1)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "b", "a", "c", "a", "b")
+	assertIntReply(t, execCmd(db, "lrem", "list", "-1", "b"), 1)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "b", "a", "c", "a")
+	assertIntReply(t, execCmd(db, "lrem", "list", "0", "a"), 2)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "b", "c")
+	assertIntReply(t, execCmd(db, "lrem", "list", "0", "x"), 0)
+	assertIntReply(t, execCmd(db, "lrem", "list", "-10", "b"), 1)
+	assertIntReply(t, execCmd(db, "lrem", "list", "10", "c"), 1)
+	assertNotExists(t, db, "list")
+	assertIntReply(t, execCmd(db, "lrem", "list", "0", "a"), 0)
+}
+
+func TestLTrim(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "c", "d", "e")
+	assertOkReply(t, execCmd(db, "ltrim", "list", "1", "-2"))
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "b", "c", "d")
+	assertOkReply(t, execCmd(db, "ltrim", "list", "-100", "100"))
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "b", "c", "d")
+	assertOkReply(t, execCmd(db, "ltrim", "list", "2", "1"))
+	assertNotExists(t, db, "list")
+	assertOkReply(t, execCmd(db, "ltrim", "missing", "0", "1"))
+}
+
+func TestLInsert(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "c")
+	assertIntReply(t, execCmd(db, "linsert", "list", "before", "c", "b"), 3)
+	assertIntReply(t, execCmd(db, "linsert", "list", "after", "c", "d"), 4)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "list", "0", "-1"), "a", "b", "c", "d")
+	assertIntReply(t, execCmd(db, "linsert", "list", "after", "x", "y"), -1)
+	assertIntReply(t, execCmd(db, "linsert", "missing", "after", "x", "y"), 0)
+	assertErrReply(t, execCmd(db, "linsert", "list", "middle", "c", "y"), "Err syntax error")
+}
+
+func TestLPos(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "list", "a", "b", "c", "1", "2", "3", "c", "c")
+	assertIntReply(t, execCmd(db, "lpos", "list", "c"), 2)
+	assertIntReply(t, execCmd(db, "lpos", "list", "c", "rank", "2"), 6)
+	assertIntReply(t, execCmd(db, "lpos", "list", "c", "rank", "-1"), 7)
+	assertReply(t, execCmd(db, "lpos", "list", "c", "rank", "4"), "$-1\r\n")
+	assertReply(t, execCmd(db, "lpos", "list", "c", "count", "0"), "*3\r\n:2\r\n:6\r\n:7\r\n")
+	assertReply(t, execCmd(db, "lpos", "list", "c", "count", "2", "rank", "-1"), "*2\r\n:7\r\n:6\r\n")
+	assertReply(t, execCmd(db, "lpos", "list", "c", "count", "0", "maxlen", "3"), "*1\r\n:2\r\n")
+	assertReply(t, execCmd(db, "lpos", "list", "c", "rank", "-1", "count", "0", "maxlen", "2"), "*2\r\n:7\r\n:6\r\n")
+	assertReply(t, execCmd(db, "lpos", "list", "x"), "$-1\r\n")
+	assertReply(t, execCmd(db, "lpos", "list", "x", "count", "1"), "*0\r\n")
+	assertReply(t, execCmd(db, "lpos", "missing", "x"), "$-1\r\n")
+
+	assertErrReply(t, execCmd(db, "lpos", "list", "c", "rank", "0"),
+		"ERR RANK can't be zero: use 1 to start from the first match, "+
+			"2 from the second ... or use negative to start from the end of the list")
+	assertErrReply(t, execCmd(db, "lpos", "list", "c", "count", "-1"), "ERR COUNT can't be negative")
+	assertErrReply(t, execCmd(db, "lpos", "list", "c", "maxlen", "-1"), "ERR MAXLEN can't be negative")
+	assertErrReply(t, execCmd(db, "lpos", "list", "c", "count"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "lpos", "list", "c", "foo", "1"), "Err syntax error")
+}
+
+func TestLMove(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "src", "a", "b", "c")
+	assertBulkReply(t, execCmd(db, "lmove", "src", "dest", "left", "right"), "a")
+	assertBulkReply(t, execCmd(db, "lmove", "src", "dest", "right", "left"), "c")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "c", "a")
+	assertBulkReply(t, execCmd(db, "rpoplpush", "src", "dest"), "b")
+	assertNotExists(t, db, "src")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "b", "c", "a")
+	assertReply(t, execCmd(db, "lmove", "src", "dest", "left", "left"), "$-1\r\n")
+
+	// rotate a list
+	assertBulkReply(t, execCmd(db, "lmove", "dest", "dest", "left", "right"), "b")
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "c", "a", "b")
+
+	execCmd(db, "set", "str", "a")
+	assertErrReply(t, execCmd(db, "lmove", "dest", "str", "left", "right"), wrongTypeErr)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "c", "a", "b")
+	assertErrReply(t, execCmd(db, "lmove", "dest", "dest", "up", "right"), "Err syntax error")
+}
+
+func TestLargeList(t *testing.T) {
+	db := makeTestDB()
+	args := []string{"rpush", "list"}
+	for i := 0; i < 3000; i++ {
+		args = append(args, strconv.Itoa(i))
+	}
+	assertIntReply(t, execCmd(db, args...), 3000)
+	assertBulkReply(t, execCmd(db, "lindex", "list", "2500"), "2500")
+	assertIntReply(t, execCmd(db, "linsert", "list", "before", "1500", "x"), 3001)
+	assertIntReply(t, execCmd(db, "lpos", "list", "x"), 1500)
+	assertOkReply(t, execCmd(db, "ltrim", "list", "1000", "1999"))
+	assertBulkReply(t, execCmd(db, "lindex", "list", "0"), "1000")
+	assertBulkReply(t, execCmd(db, "lindex", "list", "-1"), "1998")
+}
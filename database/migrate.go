@@ -0,0 +1,212 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/serialize"
+	"github.com/Ravior/goredis/redis/client"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMigrateTimeout is used if timeout of MIGRATE is not positive
+const defaultMigrateTimeout = time.Second
+
+// migrateOption is the parsed arguments of MIGRATE
+type migrateOption struct {
+	addr     string
+	keys     []string
+	destDB   int64
+	timeout  time.Duration
+	copy     bool
+	replace  bool
+	username string
+	password string
+	hasAuth  bool
+}
+
+// migrateKeys returns keys of MIGRATE, they follow KEYS option or it is the key argument
+func migrateKeys(args [][]byte) []string {
+	for i := 5; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "AUTH":
+			i++
+		case "AUTH2":
+			i += 2
+		case "KEYS":
+			keys := make([]string, 0, len(args)-i-1)
+			for _, arg := range args[i+1:] {
+				keys = append(keys, string(arg))
+			}
+			return keys
+		}
+	}
+	return []string{string(args[2])}
+}
+
+// prepareMigrate locks keys to migrate since they may be removed
+func prepareMigrate(args [][]byte) ([]string, []string) {
+	return migrateKeys(args), nil
+}
+
+func parseMigrateOption(args [][]byte) (*migrateOption, protocol.ErrorReply) {
+	option := &migrateOption{
+		addr: net.JoinHostPort(string(args[0]), string(args[1])),
+		keys: migrateKeys(args),
+	}
+	var err error
+	option.destDB, err = strconv.ParseInt(string(args[3]), 10, 64)
+	if err != nil {
+		return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	timeout, err := strconv.ParseInt(string(args[4]), 10, 64)
+	if err != nil {
+		return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	option.timeout = time.Duration(timeout) * time.Millisecond
+	if timeout <= 0 {
+		option.timeout = defaultMigrateTimeout
+	}
+	for i := 5; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case arg == "COPY":
+			option.copy = true
+		case arg == "REPLACE":
+			option.replace = true
+		case arg == "AUTH" && i+1 < len(args):
+			option.password = string(args[i+1])
+			option.hasAuth = true
+			i++
+		case arg == "AUTH2" && i+2 < len(args):
+			option.username = string(args[i+1])
+			option.password = string(args[i+2])
+			option.hasAuth = true
+			i += 2
+		case arg == "KEYS":
+			if len(args[2]) > 0 {
+				return nil, protocol.NewErrReply("ERR When using MIGRATE KEYS option, the key argument must be set to the empty string")
+			}
+			// keys are collected by migrateKeys
+			return option, nil
+		default:
+			return nil, protocol.NewSyntaxErrReply()
+		}
+	}
+	return option, nil
+}
+
+// targetErrReply wraps the error replied by target instance
+func targetErrReply(reply redis.Reply) protocol.ErrorReply {
+	msg := strings.TrimSuffix(string(reply.ToBytes()[1:]), protocol.CRLF)
+	return protocol.NewErrReply("ERR Target instance replied with error: " + msg)
+}
+
+// targetIOErrReply is returned if a request to target instance fails without reply, such as time out
+func targetIOErrReply() protocol.ErrorReply {
+	return protocol.NewErrReply("IOERR error or timeout reading to target instance")
+}
+
+// sendToTarget sends a command to target instance, it returns error reply if the request fails or target rejects
+func sendToTarget(cli *client.Client, cmdLine CmdLine) protocol.ErrorReply {
+	reply, err := cli.Do(cmdLine)
+	if err != nil {
+		return targetIOErrReply()
+	}
+	if protocol.IsErrorReply(reply) {
+		return targetErrReply(reply)
+	}
+	return nil
+}
+
+// execMigrate transfers keys to another instance by DUMP and RESTORE,
+// keys are removed after the target acknowledges unless COPY is given
+func execMigrate(db *DB, args [][]byte) redis.Reply {
+	option, errReply := parseMigrateOption(args)
+	if errReply != nil {
+		return errReply
+	}
+
+	// serialize existing keys, ttl in milliseconds is 0 if the key has no expiration
+	keys := make([]string, 0, len(option.keys))
+	restoreCmds := make([]CmdLine, 0, len(option.keys))
+	for _, key := range option.keys {
+		entity, exists := db.GetEntity(key)
+		if !exists {
+			continue
+		}
+		payload, err := serialize.Dump(entity.Data)
+		if err != nil {
+			return protocol.NewErrReply("ERR " + err.Error())
+		}
+		var ttl int64
+		if expireAt, ok := db.TTL(key); ok {
+			ttl = time.Until(expireAt).Milliseconds()
+			if ttl < 1 {
+				ttl = 1
+			}
+		}
+		cmdLine := utils.ToCmdLine3("restore", []byte(key), []byte(strconv.FormatInt(ttl, 10)), payload)
+		if option.replace {
+			cmdLine = append(cmdLine, []byte("REPLACE"))
+		}
+		keys = append(keys, key)
+		restoreCmds = append(restoreCmds, cmdLine)
+	}
+	if len(keys) == 0 {
+		return protocol.NewStatusReply("NOKEY")
+	}
+
+	cli, err := client.MakeClient(option.addr, option.timeout)
+	if err != nil {
+		return protocol.NewErrReply("IOERR error or timeout connecting to the client")
+	}
+	cli.Start()
+	defer cli.Close()
+
+	if option.hasAuth {
+		authCmd := utils.ToCmdLine("auth", option.password)
+		if option.username != "" {
+			authCmd = utils.ToCmdLine("auth", option.username, option.password)
+		}
+		if errReply := sendToTarget(cli, authCmd); errReply != nil {
+			return errReply
+		}
+	}
+	if errReply := sendToTarget(cli, utils.ToCmdLine("select", strconv.FormatInt(option.destDB, 10))); errReply != nil {
+		return errReply
+	}
+
+	// keys restored by target are removed, others are kept and the first error is returned.
+	// Replies after a failed request can't be trusted, so migration stops on io error.
+	migrated := make([]string, 0, len(keys))
+	for i, cmdLine := range restoreCmds {
+		reply, err := cli.Do(cmdLine)
+		if err != nil {
+			errReply = targetIOErrReply()
+			break
+		}
+		if protocol.IsErrorReply(reply) {
+			if errReply == nil {
+				errReply = targetErrReply(reply)
+			}
+			continue
+		}
+		migrated = append(migrated, keys[i])
+	}
+	if !option.copy && len(migrated) > 0 {
+		db.Removes(migrated...)
+		db.addAof(utils.ToCmdLine2("del", migrated...))
+	}
+	if errReply != nil {
+		return errReply
+	}
+	return protocol.NewOkReply()
+}
+
+func init() {
+	RegisterCommand("Migrate", execMigrate, prepareMigrate, -6)
+}
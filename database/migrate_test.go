@@ -0,0 +1,196 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/parser"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"net"
+	"testing"
+)
+
+// serveTestDB serves mdb on a random local port, it returns the host and port
+func serveTestDB(t *testing.T, mdb *MultiDB) (string, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				client := connection.NewConn(conn)
+				for payload := range parser.ParseStream(conn) {
+					if payload.Err != nil {
+						return
+					}
+					cmdLine := payload.Data.(*protocol.MultiBulkReply).Args
+					_ = client.Write(mdb.Exec(client, cmdLine).ToBytes())
+				}
+			}()
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port
+}
+
+// serveFunc serves connections on a random local port by handler, it returns the host and port
+func serveFunc(t *testing.T, handler func(conn net.Conn)) (string, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go handler(conn)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	return host, port
+}
+
+func TestMigrate(t *testing.T) {
+	source := makeTestMultiDB(t, false, "")
+	defer source.Close()
+	target := makeTestMultiDB(t, false, "")
+	defer target.Close()
+	host, port := serveTestDB(t, target)
+	conn := connection.NewFakeConn()
+	source.Exec(conn, utils.ToCmdLine("rpush", "list", "a", "b"))
+	source.Exec(conn, utils.ToCmdLine("expire", "list", "100"))
+	assertOkReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "list", "1", "1000")))
+	assertIntReply(t, source.Exec(conn, utils.ToCmdLine("exists", "list")), 0)
+	conn.SelectDB(1)
+	assertMultiBulkReply(t, target.Exec(conn, utils.ToCmdLine("lrange", "list", "0", "-1")), "a", "b")
+	raw := target.Exec(conn, utils.ToCmdLine("pttl", "list"))
+	if pttl, ok := raw.(*protocol.IntReply); !ok || pttl.Code <= 90000 || pttl.Code > 100000 {
+		t.Errorf("expected ttl migrated, actually %q", raw.ToBytes())
+	}
+	conn.SelectDB(0)
+
+	// existing keys in target are kept without REPLACE
+	source.Exec(conn, utils.ToCmdLine("set", "list", "v"))
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "list", "1", "1000")),
+		"ERR Target instance replied with error: BUSYKEY Target key name already exists.")
+	assertBulkReply(t, source.Exec(conn, utils.ToCmdLine("get", "list")), "v")
+	assertOkReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "list", "1", "1000", "copy", "replace")))
+	assertBulkReply(t, source.Exec(conn, utils.ToCmdLine("get", "list")), "v")
+	conn.SelectDB(1)
+	assertBulkReply(t, target.Exec(conn, utils.ToCmdLine("get", "list")), "v")
+	assertIntReply(t, target.Exec(conn, utils.ToCmdLine("ttl", "list")), -1)
+	conn.SelectDB(0)
+
+	assertReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "missing", "0", "1000")), "+NOKEY\r\n")
+}
+
+func TestMigrateKeys(t *testing.T) {
+	source := makeTestMultiDB(t, false, "")
+	defer source.Close()
+	target := makeTestMultiDB(t, false, "")
+	defer target.Close()
+	host, port := serveTestDB(t, target)
+	conn := connection.NewFakeConn()
+
+	source.Exec(conn, utils.ToCmdLine("set", "a", "1"))
+	source.Exec(conn, utils.ToCmdLine("sadd", "b", "x"))
+	source.Exec(conn, utils.ToCmdLine("set", "c", "3"))
+	target.Exec(conn, utils.ToCmdLine("set", "c", "old"))
+	// keys restored are removed even if others fail
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "", "0", "1000", "keys", "a", "b", "c", "missing")),
+		"ERR Target instance replied with error: BUSYKEY Target key name already exists.")
+	assertIntReply(t, source.Exec(conn, utils.ToCmdLine("exists", "a", "b", "c")), 1)
+	assertBulkReply(t, target.Exec(conn, utils.ToCmdLine("get", "a")), "1")
+	assertMultiBulkReply(t, target.Exec(conn, utils.ToCmdLine("smembers", "b")), "x")
+	assertBulkReply(t, target.Exec(conn, utils.ToCmdLine("get", "c")), "old")
+
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "c", "0", "1000", "keys", "c")),
+		"ERR When using MIGRATE KEYS option, the key argument must be set to the empty string")
+}
+
+func TestMigrateErrors(t *testing.T) {
+	source := makeTestMultiDB(t, false, "")
+	defer source.Close()
+	target := makeTestMultiDB(t, false, "")
+	defer target.Close()
+	host, port := serveTestDB(t, target)
+	conn := connection.NewFakeConn()
+	source.Exec(conn, utils.ToCmdLine("set", "a", "1"))
+
+	// the key is kept if the target rejects
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "0", "1000", "auth", "secret")),
+		"ERR Target instance replied with error: ERR unknown command 'auth'")
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "9", "1000")),
+		"ERR Target instance replied with error: ERR DB index is out of range")
+	assertIntReply(t, source.Exec(conn, utils.ToCmdLine("exists", "a")), 1)
+	assertIntReply(t, target.Exec(conn, utils.ToCmdLine("exists", "a")), 0)
+
+	// nothing listens on the port of a closed listener
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, closedPort, _ := net.SplitHostPort(listener.Addr().String())
+	_ = listener.Close()
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", "127.0.0.1", closedPort, "a", "0", "100")),
+		"IOERR error or timeout connecting to the client")
+
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "x", "1000")),
+		"ERR value is not an integer or out of range")
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "0", "x")),
+		"ERR value is not an integer or out of range")
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "0", "1000", "foo")), "Err syntax error")
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "0", "1000", "auth")), "Err syntax error")
+}
+
+// TestMigrateIOError checks failures without reply of target are reported as io error and keys are kept
+func TestMigrateIOError(t *testing.T) {
+	source := makeTestMultiDB(t, false, "")
+	defer source.Close()
+	conn := connection.NewFakeConn()
+	source.Exec(conn, utils.ToCmdLine("set", "a", "1"))
+
+	// target accepts but never replies
+	host, port := serveFunc(t, func(conn net.Conn) {
+		defer conn.Close()
+		for payload := range parser.ParseStream(conn) {
+			if payload.Err != nil {
+				return
+			}
+		}
+	})
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "a", "0", "50")),
+		"IOERR error or timeout reading to target instance")
+	assertIntReply(t, source.Exec(conn, utils.ToCmdLine("exists", "a")), 1)
+
+	// target closes the connection after SELECT
+	host, port = serveFunc(t, func(conn net.Conn) {
+		defer conn.Close()
+		for payload := range parser.ParseStream(conn) {
+			if payload.Err != nil {
+				return
+			}
+			cmdLine := payload.Data.(*protocol.MultiBulkReply).Args
+			if string(cmdLine[0]) != "select" {
+				return
+			}
+			_, _ = conn.Write(protocol.NewOkReply().ToBytes())
+		}
+	})
+	assertErrReply(t, source.Exec(conn, utils.ToCmdLine("migrate", host, port, "", "0", "1000", "keys", "a", "a")),
+		"IOERR error or timeout reading to target instance")
+	assertIntReply(t, source.Exec(conn, utils.ToCmdLine("exists", "a")), 1)
+}
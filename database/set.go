@@ -1,5 +1,473 @@
 package database
 
-func (db *DB) getAsSet(key string) {
+import (
+	HashSet "github.com/Ravior/goredis/datastruct/set"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/wildcard"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+)
 
+func (db *DB) getAsSet(key string) (*HashSet.Set, protocol.ErrorReply) {
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil, nil
+	}
+	set, ok := entity.Data.(*HashSet.Set)
+	if !ok {
+		return nil, &protocol.WrongTypeErrReply{}
+	}
+	return set, nil
+}
+
+func (db *DB) getOrInitSet(key string) (set *HashSet.Set, inited bool, errReply protocol.ErrorReply) {
+	set, errReply = db.getAsSet(key)
+	if errReply != nil {
+		return nil, false, errReply
+	}
+	inited = false
+	if set == nil {
+		set = HashSet.Make()
+		db.PutEntity(key, &database.DataEntity{
+			Data: set,
+		})
+		inited = true
+	}
+	return set, inited, nil
+}
+
+// execSAdd adds members into set
+func execSAdd(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	set, _, errReply := db.getOrInitSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	counter := 0
+	for _, member := range args[1:] {
+		counter += set.Add(string(member))
+	}
+	db.addAof(utils.ToCmdLine3("sadd", args...))
+	return protocol.NewIntReply(int64(counter))
+}
+
+// execSRem removes members from set, the key will be removed if the set becomes empty
+func execSRem(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		return protocol.NewIntReply(0)
+	}
+	counter := 0
+	for _, member := range args[1:] {
+		counter += set.Remove(string(member))
+	}
+	if set.Len() == 0 {
+		db.Remove(key)
+	}
+	if counter > 0 {
+		db.addAof(utils.ToCmdLine3("srem", args...))
+	}
+	return protocol.NewIntReply(int64(counter))
+}
+
+// execSIsMember checks whether the given value is a member of set
+func execSIsMember(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil || !set.Has(string(args[1])) {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(1)
+}
+
+// execSMIsMember checks whether each of the given values is a member of set
+func execSMIsMember(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := make([]redis.Reply, len(args)-1)
+	for i, member := range args[1:] {
+		if set != nil && set.Has(string(member)) {
+			result[i] = protocol.NewIntReply(1)
+		} else {
+			result[i] = protocol.NewIntReply(0)
+		}
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+// execSCard returns the number of members in set
+func execSCard(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(int64(set.Len()))
+}
+
+// execSMembers returns all members of set
+func execSMembers(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	return protocol.NewMultiBulkReply(toBytesSlice(set.ToSlice()))
+}
+
+func toBytesSlice(members []string) [][]byte {
+	result := make([][]byte, len(members))
+	for i, member := range members {
+		result[i] = []byte(member)
+	}
+	return result
+}
+
+// execSPop removes and returns random members of set
+func execSPop(db *DB, args [][]byte) redis.Reply {
+	if len(args) > 2 {
+		return protocol.NewSyntaxErrReply()
+	}
+	key := string(args[0])
+	withCount := len(args) == 2
+	count := int64(1)
+	if withCount {
+		var err error
+		count, err = strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil || count < 0 {
+			return protocol.NewErrReply("ERR value is out of range, must be positive")
+		}
+	}
+
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		if withCount {
+			return protocol.NewEmptyMultiBulkReply()
+		}
+		return protocol.NewNullBulkReply()
+	}
+	if count > int64(set.Len()) {
+		count = int64(set.Len())
+	}
+	members := set.RandomDistinctMembers(int(count))
+	for _, member := range members {
+		set.Remove(member)
+	}
+	if set.Len() == 0 {
+		db.Remove(key)
+	}
+	if len(members) > 0 {
+		// members are chosen randomly, propagate the removed ones to keep aof deterministic
+		db.addAof(utils.ToCmdLine3("srem", append([][]byte{args[0]}, toBytesSlice(members)...)...))
+	}
+	if !withCount {
+		return protocol.NewBulkReply([]byte(members[0]))
+	}
+	return protocol.NewMultiBulkReply(toBytesSlice(members))
+}
+
+// execSRandMember returns random members of set
+// a positive count returns distinct members, a negative count allows the same member to be returned multiple times
+func execSRandMember(db *DB, args [][]byte) redis.Reply {
+	if len(args) > 2 {
+		return protocol.NewSyntaxErrReply()
+	}
+	key := string(args[0])
+	withCount := len(args) == 2
+	var count int64
+	if withCount {
+		var err error
+		count, err = strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		if count == math.MinInt64 || -count > math.MaxInt32 {
+			return protocol.NewErrReply("ERR value is out of range")
+		}
+	}
+
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if set == nil {
+		if withCount {
+			return protocol.NewEmptyMultiBulkReply()
+		}
+		return protocol.NewNullBulkReply()
+	}
+	if !withCount {
+		return protocol.NewBulkReply([]byte(set.RandomMembers(1)[0]))
+	}
+	if count >= 0 {
+		if count > int64(set.Len()) {
+			count = int64(set.Len())
+		}
+		return protocol.NewMultiBulkReply(toBytesSlice(set.RandomDistinctMembers(int(count))))
+	}
+	return protocol.NewMultiBulkReply(toBytesSlice(set.RandomMembers(int(-count))))
+}
+
+// execSMove moves a member from source set to destination set
+func execSMove(db *DB, args [][]byte) redis.Reply {
+	src := string(args[0])
+	dest := string(args[1])
+	member := string(args[2])
+	srcSet, errReply := db.getAsSet(src)
+	if errReply != nil {
+		return errReply
+	}
+	destSet, errReply := db.getAsSet(dest)
+	if errReply != nil {
+		return errReply
+	}
+	if srcSet == nil || !srcSet.Has(member) {
+		return protocol.NewIntReply(0)
+	}
+	if src == dest {
+		return protocol.NewIntReply(1)
+	}
+	srcSet.Remove(member)
+	if srcSet.Len() == 0 {
+		db.Remove(src)
+	}
+	if destSet == nil {
+		destSet, _, _ = db.getOrInitSet(dest)
+	}
+	destSet.Add(member)
+	db.addAof(utils.ToCmdLine3("smove", args...))
+	return protocol.NewIntReply(1)
+}
+
+const (
+	setIntersect = iota
+	setUnion
+	setDiff
+)
+
+// getSets returns sets bound to the given keys, nil for absent key
+func (db *DB) getSets(keys [][]byte) ([]*HashSet.Set, protocol.ErrorReply) {
+	sets := make([]*HashSet.Set, len(keys))
+	for i, key := range keys {
+		set, errReply := db.getAsSet(string(key))
+		if errReply != nil {
+			return nil, errReply
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+// setCalculate returns the intersection, union or difference of sets bound to the given keys
+func (db *DB) setCalculate(keys [][]byte, op int) (*HashSet.Set, protocol.ErrorReply) {
+	sets, errReply := db.getSets(keys)
+	if errReply != nil {
+		return nil, errReply
+	}
+	var result *HashSet.Set
+	for i, set := range sets {
+		if set == nil {
+			if op == setIntersect || (op == setDiff && i == 0) {
+				// the result must be empty
+				return HashSet.Make(), nil
+			}
+			continue
+		}
+		if result == nil {
+			// never modify a set in keyspace
+			result = HashSet.Make().Union(set)
+			continue
+		}
+		switch op {
+		case setIntersect:
+			result = result.Intersect(set)
+		case setUnion:
+			result = result.Union(set)
+		case setDiff:
+			result = result.Diff(set)
+		}
+	}
+	if result == nil {
+		return HashSet.Make(), nil
+	}
+	return result, nil
+}
+
+func setCalculateGeneric(db *DB, args [][]byte, op int) redis.Reply {
+	result, errReply := db.setCalculate(args, op)
+	if errReply != nil {
+		return errReply
+	}
+	return protocol.NewMultiBulkReply(toBytesSlice(result.ToSlice()))
+}
+
+// setCalculateStoreGeneric stores the result into the destination key, the destination key will be removed if the result is empty
+func setCalculateStoreGeneric(db *DB, args [][]byte, op int, cmdName string) redis.Reply {
+	dest := string(args[0])
+	result, errReply := db.setCalculate(args[1:], op)
+	if errReply != nil {
+		return errReply
+	}
+	db.Remove(dest)
+	if result.Len() > 0 {
+		db.PutEntity(dest, &database.DataEntity{
+			Data: result,
+		})
+	}
+	db.addAof(utils.ToCmdLine3(cmdName, args...))
+	return protocol.NewIntReply(int64(result.Len()))
+}
+
+// execSInter returns the intersection of the given sets
+func execSInter(db *DB, args [][]byte) redis.Reply {
+	return setCalculateGeneric(db, args, setIntersect)
+}
+
+// execSInterStore stores the intersection of the given sets into the destination key
+func execSInterStore(db *DB, args [][]byte) redis.Reply {
+	return setCalculateStoreGeneric(db, args, setIntersect, "sinterstore")
+}
+
+// execSUnion returns the union of the given sets
+func execSUnion(db *DB, args [][]byte) redis.Reply {
+	return setCalculateGeneric(db, args, setUnion)
+}
+
+// execSUnionStore stores the union of the given sets into the destination key
+func execSUnionStore(db *DB, args [][]byte) redis.Reply {
+	return setCalculateStoreGeneric(db, args, setUnion, "sunionstore")
+}
+
+// execSDiff returns members of the first set which don't exist in the other sets
+func execSDiff(db *DB, args [][]byte) redis.Reply {
+	return setCalculateGeneric(db, args, setDiff)
+}
+
+// execSDiffStore stores the difference of the given sets into the destination key
+func execSDiffStore(db *DB, args [][]byte) redis.Reply {
+	return setCalculateStoreGeneric(db, args, setDiff, "sdiffstore")
+}
+
+// execSInterCard returns the cardinality of the intersection, it stops counting once reaching LIMIT
+func execSInterCard(db *DB, args [][]byte) redis.Reply {
+	keys, options, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return errReply
+	}
+	limit := int64(0) // 0 means unlimited
+	if len(options) > 0 {
+		if len(options) != 2 || strings.ToUpper(string(options[0])) != "LIMIT" {
+			return protocol.NewSyntaxErrReply()
+		}
+		var err error
+		limit, err = strconv.ParseInt(string(options[1]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return protocol.NewErrReply("ERR LIMIT can't be negative")
+		}
+	}
+
+	sets, errReply := db.getSets(keys)
+	if errReply != nil {
+		return errReply
+	}
+	smallest := 0
+	for i, set := range sets {
+		if set == nil {
+			return protocol.NewIntReply(0)
+		}
+		if set.Len() < sets[smallest].Len() {
+			smallest = i
+		}
+	}
+	var counter int64
+	sets[smallest].ForEach(func(member string) bool {
+		for _, set := range sets {
+			if !set.Has(member) {
+				return true
+			}
+		}
+		counter++
+		return limit == 0 || counter < limit
+	})
+	return protocol.NewIntReply(counter)
+}
+
+// prepareSetCalculateStore returns the destination key as write key and the source keys as read keys
+func prepareSetCalculateStore(args [][]byte) ([]string, []string) {
+	dest := string(args[0])
+	_, keys := readAllKeys(args[1:])
+	return []string{dest}, keys
+}
+
+// execSScan iterates members of set by cursor, the cursor is the bucket to continue with
+func execSScan(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	option, errReply := parseScanOption(args[2:], false)
+	if errReply != nil {
+		return errReply
+	}
+	set, errReply := db.getAsSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := make([][]byte, 0)
+	if set == nil {
+		return makeScanReply(0, result)
+	}
+	cursor = set.Scan(cursor, option.count, func(member string) {
+		if wildcard.Match(option.pattern, member) {
+			result = append(result, []byte(member))
+		}
+	})
+	return makeScanReply(cursor, result)
+}
+
+func init() {
+	RegisterCommand("SAdd", execSAdd, writeFirstKey, -3)
+	RegisterCommand("SRem", execSRem, writeFirstKey, -3)
+	RegisterCommand("SIsMember", execSIsMember, readFirstKey, 3)
+	RegisterCommand("SMIsMember", execSMIsMember, readFirstKey, -3)
+	RegisterCommand("SCard", execSCard, readFirstKey, 2)
+	RegisterCommand("SMembers", execSMembers, readFirstKey, 2)
+	RegisterCommand("SPop", execSPop, writeFirstKey, -2)
+	RegisterCommand("SRandMember", execSRandMember, readFirstKey, -2)
+	RegisterCommand("SMove", execSMove, prepareLMove, 4)
+	RegisterCommand("SInter", execSInter, readAllKeys, -2)
+	RegisterCommand("SInterStore", execSInterStore, prepareSetCalculateStore, -3)
+	RegisterCommand("SUnion", execSUnion, readAllKeys, -2)
+	RegisterCommand("SUnionStore", execSUnionStore, prepareSetCalculateStore, -3)
+	RegisterCommand("SDiff", execSDiff, readAllKeys, -2)
+	RegisterCommand("SDiffStore", execSDiffStore, prepareSetCalculateStore, -3)
+	RegisterCommand("SInterCard", execSInterCard, prepareReadNumKeys, -3)
+	RegisterCommand("SScan", execSScan, readFirstKey, -3)
 }
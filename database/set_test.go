@@ -0,0 +1,180 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/protocol"
+	"strconv"
+	"testing"
+)
+
+func TestSScan(t *testing.T) {
+	db := makeTestDB()
+	args := []string{"sadd", "s"}
+	for i := 0; i < 1000; i++ {
+		args = append(args, strconv.Itoa(i))
+	}
+	execCmd(db, args...)
+
+	// members existing during the whole iteration are returned even if others are added or removed
+	seen := make(map[string]bool)
+	cursor := "0"
+	for i := 0; ; i++ {
+		execCmd(db, "sadd", "s", "new"+strconv.Itoa(i))
+		execCmd(db, "srem", "s", strconv.Itoa(900+i%100))
+		var members []string
+		cursor, members = scanOnce(t, db, "sscan", "s", cursor, "count", "10")
+		for _, member := range members {
+			seen[member] = true
+		}
+		if cursor == "0" {
+			break
+		}
+		if len(members) < 10 {
+			t.Errorf("expected at least 10 members, actually %d", len(members))
+		}
+	}
+	for i := 0; i < 900; i++ {
+		if !seen[strconv.Itoa(i)] {
+			t.Errorf("member %d is not returned", i)
+		}
+	}
+
+	batches := scanAll(t, db, "sscan", "s", "match", "new*", "count", "100000")
+	if len(batches) != 1 || len(batches[0]) == 0 {
+		t.Errorf("expected matched members in one call, actually %v", batches)
+	}
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "sscan", "str", "0"),
+		"WRONGTYPE Operation against a key holding the wrong kind of value")
+}
+
+func TestSAdd(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "sadd", "s", "a", "b", "a"), 2)
+	assertIntReply(t, execCmd(db, "sadd", "s", "b", "c"), 1)
+	assertIntReply(t, execCmd(db, "scard", "s"), 3)
+	assertUnorderedReply(t, execCmd(db, "smembers", "s"), "a", "b", "c")
+	assertIntReply(t, execCmd(db, "sismember", "s", "a"), 1)
+	assertIntReply(t, execCmd(db, "sismember", "s", "x"), 0)
+	assertIntReply(t, execCmd(db, "sismember", "missing", "a"), 0)
+	assertReply(t, execCmd(db, "smismember", "s", "a", "x"), "*2\r\n:1\r\n:0\r\n")
+	assertReply(t, execCmd(db, "smismember", "missing", "a"), "*1\r\n:0\r\n")
+	assertMultiBulkReply(t, execCmd(db, "smembers", "missing"))
+	assertIntReply(t, execCmd(db, "scard", "missing"), 0)
+
+	assertIntReply(t, execCmd(db, "srem", "s", "a", "x"), 1)
+	assertIntReply(t, execCmd(db, "srem", "s", "b", "c"), 2)
+	assertIntReply(t, execCmd(db, "exists", "s"), 0)
+	assertIntReply(t, execCmd(db, "srem", "s", "a"), 0)
+
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "sadd", "str", "a"), wrongTypeErr)
+}
+
+func TestSPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "sadd", "s", "a", "b", "c", "d")
+	popped := make(map[string]bool)
+	member := string(execCmd(db, "spop", "s").(*protocol.BulkReply).Arg)
+	popped[member] = true
+	members := replyStrings(t, execCmd(db, "spop", "s", "2"))
+	if len(members) != 2 {
+		t.Fatalf("expected 2 members, actually %v", members)
+	}
+	for _, member := range members {
+		popped[member] = true
+	}
+	members = replyStrings(t, execCmd(db, "spop", "s", "10"))
+	if len(members) != 1 {
+		t.Fatalf("expected 1 member, actually %v", members)
+	}
+	popped[members[0]] = true
+	if len(popped) != 4 {
+		t.Errorf("expected all members popped once, actually %v", popped)
+	}
+	assertIntReply(t, execCmd(db, "exists", "s"), 0)
+	assertReply(t, execCmd(db, "spop", "s"), "$-1\r\n")
+	assertMultiBulkReply(t, execCmd(db, "spop", "s", "1"))
+	assertErrReply(t, execCmd(db, "spop", "s", "-1"), "ERR value is out of range, must be positive")
+}
+
+func TestSRandMember(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "sadd", "s", "a", "b", "c")
+	member := string(execCmd(db, "srandmember", "s").(*protocol.BulkReply).Arg)
+	if member != "a" && member != "b" && member != "c" {
+		t.Errorf("unexpected member %s", member)
+	}
+	assertUnorderedReply(t, execCmd(db, "srandmember", "s", "10"), "a", "b", "c")
+	if members := replyStrings(t, execCmd(db, "srandmember", "s", "2")); len(members) != 2 || members[0] == members[1] {
+		t.Errorf("expected 2 distinct members, actually %v", members)
+	}
+	if members := replyStrings(t, execCmd(db, "srandmember", "s", "-10")); len(members) != 10 {
+		t.Errorf("expected 10 members, actually %v", members)
+	}
+	assertIntReply(t, execCmd(db, "scard", "s"), 3)
+	assertReply(t, execCmd(db, "srandmember", "missing"), "$-1\r\n")
+	assertMultiBulkReply(t, execCmd(db, "srandmember", "missing", "1"))
+	assertErrReply(t, execCmd(db, "srandmember", "s", "x"), "ERR value is not an integer or out of range")
+}
+
+func TestSMove(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "sadd", "src", "a", "b")
+	assertIntReply(t, execCmd(db, "smove", "src", "dest", "a"), 1)
+	assertIntReply(t, execCmd(db, "smove", "src", "dest", "x"), 0)
+	assertIntReply(t, execCmd(db, "smove", "src", "src", "b"), 1)
+	assertIntReply(t, execCmd(db, "smove", "src", "dest", "b"), 1)
+	assertIntReply(t, execCmd(db, "exists", "src"), 0)
+	assertUnorderedReply(t, execCmd(db, "smembers", "dest"), "a", "b")
+
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "smove", "dest", "str", "a"), wrongTypeErr)
+	assertIntReply(t, execCmd(db, "sismember", "dest", "a"), 1)
+}
+
+func TestSetCalculate(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "sadd", "s1", "a", "b", "c", "d")
+	execCmd(db, "sadd", "s2", "c", "d", "e")
+	execCmd(db, "sadd", "s3", "d", "f")
+
+	assertUnorderedReply(t, execCmd(db, "sinter", "s1", "s2"), "c", "d")
+	assertUnorderedReply(t, execCmd(db, "sinter", "s1", "s2", "s3"), "d")
+	assertMultiBulkReply(t, execCmd(db, "sinter", "s1", "missing"))
+	assertUnorderedReply(t, execCmd(db, "sunion", "s2", "s3", "missing"), "c", "d", "e", "f")
+	assertUnorderedReply(t, execCmd(db, "sdiff", "s1", "s2", "missing"), "a", "b")
+	assertMultiBulkReply(t, execCmd(db, "sdiff", "missing", "s1"))
+
+	assertIntReply(t, execCmd(db, "sinterstore", "dest", "s1", "s2"), 2)
+	assertUnorderedReply(t, execCmd(db, "smembers", "dest"), "c", "d")
+	assertIntReply(t, execCmd(db, "sunionstore", "dest", "s1", "s3"), 5)
+	assertUnorderedReply(t, execCmd(db, "smembers", "dest"), "a", "b", "c", "d", "f")
+	assertIntReply(t, execCmd(db, "sdiffstore", "dest", "s1", "s2"), 2)
+	assertUnorderedReply(t, execCmd(db, "smembers", "dest"), "a", "b")
+	// sources are not modified
+	assertIntReply(t, execCmd(db, "scard", "s1"), 4)
+	// destination is removed if the result is empty, even if it's not a set
+	execCmd(db, "set", "str", "v")
+	assertIntReply(t, execCmd(db, "sinterstore", "str", "s1", "missing"), 0)
+	assertIntReply(t, execCmd(db, "exists", "str"), 0)
+
+	// destination may be one of sources
+	assertIntReply(t, execCmd(db, "sunionstore", "s1", "s1", "s3"), 5)
+
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "sunion", "s1", "str"), wrongTypeErr)
+}
+
+func TestSInterCard(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "sadd", "s1", "a", "b", "c", "d")
+	execCmd(db, "sadd", "s2", "b", "c", "d", "e")
+	assertIntReply(t, execCmd(db, "sintercard", "2", "s1", "s2"), 3)
+	assertIntReply(t, execCmd(db, "sintercard", "2", "s1", "s2", "limit", "2"), 2)
+	assertIntReply(t, execCmd(db, "sintercard", "2", "s1", "s2", "limit", "0"), 3)
+	assertIntReply(t, execCmd(db, "sintercard", "2", "s1", "missing"), 0)
+	assertErrReply(t, execCmd(db, "sintercard", "0", "s1"), "ERR numkeys should be greater than 0")
+	assertErrReply(t, execCmd(db, "sintercard", "3", "s1", "s2"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "sintercard", "2", "s1", "s2", "limit", "-1"), "ERR LIMIT can't be negative")
+	assertErrReply(t, execCmd(db, "sintercard", "2", "s1", "s2", "count", "1"), "Err syntax error")
+}
@@ -2,17 +2,53 @@ package database
 
 import (
 	"github.com/Ravior/goredis/datastruct/dict"
+	"github.com/Ravior/goredis/interface/database"
 	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/sync/lock"
 	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	dataDictSize = 1 << 16
+	ttlDictSize  = 1 << 10
+	lockerSize   = 1024
+)
+
+// keyspace holds keys of a db, FLUSHDB replaces it as a whole
+type keyspace struct {
+	// key -> DataEntity, SCAN resumes by its shards
+	data *dict.ConcurrentDict
+	// key -> expireTime (time.Time)
+	ttlMap *dict.ConcurrentDict
+}
+
 type DB struct {
+	// index changes after SWAPDB, it is protected by swapMu of MultiDB
 	index int
-	// key -> DataEntity
-	data dict.Dict
+	// lockOrder is the initial index which never changes, databases are locked in this order
+	lockOrder int
+	// space stores *keyspace, commands without locking keys may be reading it during FLUSHDB,
+	// so a flushed keyspace is swapped out instead of being cleared
+	space atomic.Value
+
+	// dict.Dict will ensure concurrent-safety of its method
+	// use this mutex for complicated command only, eg. rpush, incr ...
+	locker *lock.Locks
+
+	// clients blocked by keys of this db, such as BLPOP
+	blocking *blockingKeys
+
+	// addAof is used to add command to aof, it does nothing until persistence is enabled
+	addAof func(CmdLine)
 }
 
+// CmdLine is alias for [][]byte, represents a command line
+type CmdLine = [][]byte
+
 // ExecFunc is interface for command executor
 // args don't include cmd line
 type ExecFunc func(db *DB, args [][]byte) redis.Reply
@@ -21,32 +57,116 @@ type ExecFunc func(db *DB, args [][]byte) redis.Reply
 // returns related write keys and read keys
 type PreFunc func(args [][]byte) ([]string, []string)
 
+func makeKeyspace() *keyspace {
+	return &keyspace{
+		data:   dict.CreateConcurrentDict(dataDictSize),
+		ttlMap: dict.CreateConcurrentDict(ttlDictSize),
+	}
+}
+
+// makeDB create DB instance
+func makeDB() *DB {
+	db := &DB{
+		locker:   lock.Make(lockerSize),
+		blocking: makeBlockingKeys(),
+		addAof:   func(line CmdLine) {},
+	}
+	db.space.Store(makeKeyspace())
+	return db
+}
+
+// keyspace returns current keyspace of db
+func (db *DB) keyspace() *keyspace {
+	return db.space.Load().(*keyspace)
+}
+
 // Exec executes command within one database
 func (db *DB) Exec(c redis.Connection, cmdLine [][]byte) redis.Reply {
 	// transaction control commands and other commands which cannot execute within transaction
 	//cmdName := strings.ToLower(string(cmdLine[0]))
-	return db.execNormalCommand(cmdLine)
+	result := db.execNormalCommand(c, cmdLine)
+	if blocked, ok := result.(*blockedReply); ok {
+		return db.blockUntilReady(c, cmdLine, blocked)
+	}
+	return result
 }
 
-func (db *DB) execNormalCommand(cmdLine [][]byte) redis.Reply {
+func (db *DB) execNormalCommand(c redis.Connection, cmdLine [][]byte) redis.Reply {
 	cmdName := strings.ToLower(string(cmdLine[0]))
 	cmd, ok := cmdTable[cmdName]
 	if !ok {
 		return protocol.NewErrReply("ERR unknown command '" + cmdName + "'")
 	}
+	if !validateArity(cmd.arity, cmdLine) {
+		return protocol.NewArgNumErrReply(cmdName)
+	}
+	prepare := cmd.prepare
+	write, read := prepare(cmdLine[1:])
+	db.RWLocks(write, read)
+	defer db.RWUnLocks(write, read)
 	fun := cmd.executor
-	return fun(db, cmdLine[1:])
+	result := fun(db, cmdLine[1:])
+	if blocked, ok := result.(*blockedReply); ok {
+		// register the client before releasing locks, so that it won't miss any push
+		db.blocking.block(c, blocked.keys)
+	}
+	return result
+}
+
+func validateArity(arity int, cmdArgs [][]byte) bool {
+	argNum := len(cmdArgs)
+	if arity >= 0 {
+		return argNum == arity
+	}
+	return argNum >= -arity
+}
+
+/* ---- Data Access ----- */
+
+// GetEntity returns DataEntity bind to given key
+func (db *DB) GetEntity(key string) (*database.DataEntity, bool) {
+	raw, ok := db.keyspace().data.Get(key)
+	if !ok {
+		return nil, false
+	}
+	if db.IsExpired(key) {
+		return nil, false
+	}
+	entity, _ := raw.(*database.DataEntity)
+	return entity, true
+}
+
+// PutEntity a DataEntity into DB
+func (db *DB) PutEntity(key string, entity *database.DataEntity) int {
+	return db.keyspace().data.Put(key, entity)
+}
+
+// PutIfExists edit an existing DataEntity
+func (db *DB) PutIfExists(key string, entity *database.DataEntity) int {
+	if db.IsExpired(key) {
+		return 0
+	}
+	return db.keyspace().data.PutIfExists(key, entity)
+}
+
+// PutIfAbsent insert an DataEntity only if the key not exists
+func (db *DB) PutIfAbsent(key string, entity *database.DataEntity) int {
+	db.IsExpired(key) // an expired key is absent
+	return db.keyspace().data.PutIfAbsent(key, entity)
 }
 
 // Remove the given key from db
 func (db *DB) Remove(key string) {
+	space := db.keyspace()
+	space.data.Remove(key)
+	space.ttlMap.Remove(key)
 }
 
 // Removes the given keys from db
 func (db *DB) Removes(keys ...string) (deleted int) {
 	deleted = 0
 	for _, key := range keys {
-		_, exists := db.data.Get(key)
+		_, exists := db.GetEntity(key)
 		if exists {
 			db.Remove(key)
 			deleted++
@@ -54,3 +174,78 @@ func (db *DB) Removes(keys ...string) (deleted int) {
 	}
 	return deleted
 }
+
+// Unlinks removes the given keys from db, big values are released in background
+func (db *DB) Unlinks(keys ...string) (deleted int) {
+	deleted = 0
+	for _, key := range keys {
+		entity, exists := db.GetEntity(key)
+		if exists {
+			db.Remove(key)
+			freeValueAsync(entity.Data)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Flush clean database, the old keyspace is released in background if async,
+// otherwise the caller should reclaim memory after flushing
+func (db *DB) Flush(async bool) {
+	// wait for all running commands of this db
+	db.locker.LockAll()
+	old := db.keyspace()
+	db.space.Store(makeKeyspace())
+	db.locker.UnLockAll()
+	if async {
+		freeKeyspaceAsync(old)
+	}
+}
+
+/* ---- Lock Function ----- */
+
+// RWLocks lock keys for writing and reading
+func (db *DB) RWLocks(writeKeys []string, readKeys []string) {
+	db.locker.RWLocks(writeKeys, readKeys)
+}
+
+// RWUnLocks unlock keys for writing and reading
+func (db *DB) RWUnLocks(writeKeys []string, readKeys []string) {
+	db.locker.RWUnLocks(writeKeys, readKeys)
+}
+
+/* ---- TTL Functions ---- */
+
+// Expire sets ttlCmd of key
+func (db *DB) Expire(key string, expireTime time.Time) {
+	db.keyspace().ttlMap.Put(key, expireTime)
+}
+
+// Persist cancel ttlCmd of key
+func (db *DB) Persist(key string) {
+	db.keyspace().ttlMap.Remove(key)
+}
+
+// TTL returns the expire time of the given key, the second return value is false if the key has no ttl
+func (db *DB) TTL(key string) (time.Time, bool) {
+	raw, ok := db.keyspace().ttlMap.Get(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	expireTime, _ := raw.(time.Time)
+	return expireTime, true
+}
+
+// IsExpired check whether a key is expired, an expired key will be removed
+func (db *DB) IsExpired(key string) bool {
+	expireTime, ok := db.TTL(key)
+	if !ok {
+		return false
+	}
+	expired := time.Now().After(expireTime)
+	if expired {
+		db.Remove(key)
+		db.addAof(utils.ToCmdLine("del", key))
+	}
+	return expired
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestFlushWhileReading(t *testing.T) {
+	db := makeTestDB()
+	stop := make(chan struct{})
+	wg := sync.WaitGroup{}
+	// these commands read keyspace without locking keys
+	for _, cmdLine := range [][]string{{"keys", "*"}, {"scan", "0", "count", "1000"}, {"randomkey"}} {
+		wg.Add(1)
+		go func(cmdLine []string) {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					execCmd(db, cmdLine...)
+					db.activeExpireCycle()
+				}
+			}
+		}(cmdLine)
+	}
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 100; j++ {
+			key := strconv.Itoa(j)
+			execCmd(db, "set", key, key, "px", "100000")
+		}
+		db.Flush(true)
+	}
+	close(stop)
+	wg.Wait()
+	assertIntReply(t, execCmd(db, "exists", "1"), 0)
+	execCmd(db, "set", "k", "v", "ex", "100")
+	assertBulkReply(t, execCmd(db, "get", "k"), "v")
+	if _, ok := db.TTL("k"); !ok {
+		t.Error("expected ttl of k")
+	}
+}
@@ -0,0 +1,368 @@
+package database
+
+import (
+	"bytes"
+	Dict "github.com/Ravior/goredis/datastruct/dict"
+	List "github.com/Ravior/goredis/datastruct/list"
+	HashSet "github.com/Ravior/goredis/datastruct/set"
+	SortedSet "github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// sortOption is the parsed options of SORT
+type sortOption struct {
+	key string
+	// byPattern is the pattern to look up weights, elements are sorted by themselves if hasBy is false
+	byPattern string
+	hasBy     bool
+	// dontSort is true if BY pattern has no `*`, elements keep their original order
+	dontSort    bool
+	offset      int64
+	count       int64
+	getPatterns []string
+	desc        bool
+	alpha       bool
+	store       string
+	hasStore    bool
+}
+
+// sortItem is an element to sort with its weight
+type sortItem struct {
+	value string
+	score float64
+	// cmp is the weight looked up by BY pattern in ALPHA mode, nil if not found
+	cmp []byte
+}
+
+func parseSortOption(args [][]byte, readOnly bool) (*sortOption, protocol.ErrorReply) {
+	option := &sortOption{
+		key:   string(args[0]),
+		count: -1,
+	}
+	for i := 1; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case arg == "ASC":
+			option.desc = false
+		case arg == "DESC":
+			option.desc = true
+		case arg == "ALPHA":
+			option.alpha = true
+		case arg == "LIMIT" && i+2 < len(args):
+			offset, err1 := strconv.ParseInt(string(args[i+1]), 10, 64)
+			count, err2 := strconv.ParseInt(string(args[i+2]), 10, 64)
+			if err1 != nil || err2 != nil {
+				return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			option.offset = offset
+			option.count = count
+			i += 2
+		case arg == "BY" && i+1 < len(args):
+			option.byPattern = string(args[i+1])
+			option.hasBy = true
+			// a pattern without `*` refers to no key, such as `BY nosort`, so elements are not sorted
+			option.dontSort = !strings.Contains(option.byPattern, "*")
+			i++
+		case arg == "GET" && i+1 < len(args):
+			option.getPatterns = append(option.getPatterns, string(args[i+1]))
+			i++
+		case arg == "STORE" && i+1 < len(args) && !readOnly:
+			option.store = string(args[i+1])
+			option.hasStore = true
+			i++
+		default:
+			return nil, protocol.NewSyntaxErrReply()
+		}
+	}
+	return option, nil
+}
+
+// patternKey substitutes the first `*` of pattern with element, returns the key and hash field to look up.
+// The part after `->` following `*` is the hash field. ok is false if pattern refers to no key.
+func patternKey(pattern string, element string) (key string, field string, hasField bool, ok bool) {
+	star := strings.IndexByte(pattern, '*')
+	if star < 0 {
+		return "", "", false, false
+	}
+	postfix := pattern[star+1:]
+	if arrow := strings.Index(postfix, "->"); arrow >= 0 && arrow+2 < len(postfix) {
+		field = postfix[arrow+2:]
+		hasField = true
+		postfix = postfix[:arrow]
+	}
+	return pattern[:star] + element + postfix, field, hasField, true
+}
+
+// lookupByPattern returns the value referred by pattern for element, `#` refers to the element itself.
+// It returns nil if the key not exists or is not a string (or hash if a field is given).
+func (db *DB) lookupByPattern(pattern string, element string) []byte {
+	if pattern == "#" {
+		return []byte(element)
+	}
+	key, field, hasField, ok := patternKey(pattern, element)
+	if !ok {
+		return nil
+	}
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil
+	}
+	if hasField {
+		dict, isDict := entity.Data.(Dict.Dict)
+		if !isDict {
+			return nil
+		}
+		val, exists := dict.Get(field)
+		if !exists {
+			return nil
+		}
+		return val.([]byte)
+	}
+	val, isString := entity.Data.([]byte)
+	if !isString {
+		return nil
+	}
+	return cloneBytes(val)
+}
+
+// lockKeys returns keys to lock when sorting the given elements, including keys referred by patterns
+func (option *sortOption) lockKeys(elements []string) (write []string, read []string) {
+	if option.hasStore {
+		write = []string{option.store}
+	}
+	read = []string{option.key}
+	patterns := option.getPatterns
+	if option.hasBy && !option.dontSort {
+		patterns = append([]string{option.byPattern}, patterns...)
+	}
+	for _, pattern := range patterns {
+		for _, element := range elements {
+			if key, _, _, ok := patternKey(pattern, element); ok && pattern != "#" {
+				read = append(read, key)
+			}
+		}
+	}
+	return write, read
+}
+
+// sortElements returns elements of list, set or sorted set in their original order
+func (db *DB) sortElements(key string) (elements []string, isSet bool, errReply protocol.ErrorReply) {
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil, false, nil
+	}
+	switch val := entity.Data.(type) {
+	case List.List:
+		elements = make([]string, 0, val.Len())
+		val.ForEach(func(i int, v interface{}) bool {
+			elements = append(elements, string(v.([]byte)))
+			return true
+		})
+	case *HashSet.Set:
+		elements = val.ToSlice()
+		isSet = true
+	case *SortedSet.SortedSet:
+		elements = make([]string, 0, val.Len())
+		val.ForEach(0, val.Len(), false, func(element *SortedSet.Element) bool {
+			elements = append(elements, element.Member)
+			return true
+		})
+	default:
+		return nil, false, &protocol.WrongTypeErrReply{}
+	}
+	return elements, isSet, nil
+}
+
+// lockSortElements locks the key to sort and all keys referred by patterns, then returns elements of the key.
+// Keys referred by patterns are unknown until elements are read, so it retries if elements changed
+// and refer keys not locked. The caller should unlock the returned keys.
+func (db *DB) lockSortElements(option *sortOption) (elements []string, isSet bool, write []string, read []string, errReply protocol.ErrorReply) {
+	write, read = option.lockKeys(nil)
+	for {
+		db.RWLocks(write, read)
+		elements, isSet, errReply = db.sortElements(option.key)
+		if errReply != nil {
+			db.RWUnLocks(write, read)
+			return nil, false, nil, nil, errReply
+		}
+		_, required := option.lockKeys(elements)
+		locked := make(map[string]struct{}, len(read))
+		for _, key := range read {
+			locked[key] = struct{}{}
+		}
+		allLocked := true
+		for _, key := range required {
+			if _, ok := locked[key]; !ok {
+				allLocked = false
+				break
+			}
+		}
+		if allLocked {
+			return elements, isSet, write, read, nil
+		}
+		db.RWUnLocks(write, read)
+		read = required
+	}
+}
+
+// sortItems sorts items by weights, items with the same score are compared by their values
+func sortItems(items []*sortItem, option *sortOption) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		var cmp int
+		if !option.alpha {
+			switch {
+			case a.score < b.score:
+				cmp = -1
+			case a.score > b.score:
+				cmp = 1
+			default:
+				cmp = strings.Compare(a.value, b.value)
+			}
+		} else if option.hasBy {
+			// missing weights are less than any others
+			switch {
+			case a.cmp == nil && b.cmp == nil:
+				cmp = 0
+			case a.cmp == nil:
+				cmp = -1
+			case b.cmp == nil:
+				cmp = 1
+			default:
+				cmp = bytes.Compare(a.cmp, b.cmp)
+			}
+		} else {
+			cmp = strings.Compare(a.value, b.value)
+		}
+		if option.desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+}
+
+func sortGeneric(db *DB, args [][]byte, readOnly bool) redis.Reply {
+	option, errReply := parseSortOption(args, readOnly)
+	if errReply != nil {
+		return errReply
+	}
+	elements, isSet, write, read, errReply := db.lockSortElements(option)
+	if errReply != nil {
+		return errReply
+	}
+	defer db.RWUnLocks(write, read)
+
+	if option.dontSort && isSet && option.hasStore {
+		// order of set is random, sort it to make the stored result deterministic
+		option.dontSort = false
+		option.hasBy = false
+		option.alpha = true
+	}
+	items := make([]*sortItem, len(elements))
+	for i, element := range elements {
+		items[i] = &sortItem{value: element}
+	}
+	if !option.dontSort {
+		for _, item := range items {
+			var weight []byte
+			if option.hasBy {
+				weight = db.lookupByPattern(option.byPattern, item.value)
+			} else {
+				weight = []byte(item.value)
+			}
+			if option.alpha {
+				item.cmp = weight
+				continue
+			}
+			if weight == nil {
+				continue
+			}
+			score, err := strconv.ParseFloat(string(weight), 64)
+			if err != nil || math.IsNaN(score) {
+				return protocol.NewErrReply("ERR One or more scores can't be converted into double")
+			}
+			item.score = score
+		}
+		sortItems(items, option)
+	} else if option.desc && !isSet {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	// apply LIMIT
+	size := int64(len(items))
+	start := option.offset
+	if start < 0 {
+		start = 0
+	}
+	if start > size {
+		start = size
+	}
+	end := size
+	// compare with the remaining size, since start+count may overflow
+	if option.count >= 0 && option.count < size-start {
+		end = start + option.count
+	}
+	items = items[start:end]
+
+	var result [][]byte
+	if len(option.getPatterns) == 0 {
+		result = make([][]byte, len(items))
+		for i, item := range items {
+			result[i] = []byte(item.value)
+		}
+	} else {
+		result = make([][]byte, 0, len(items)*len(option.getPatterns))
+		for _, item := range items {
+			for _, pattern := range option.getPatterns {
+				result = append(result, db.lookupByPattern(pattern, item.value))
+			}
+		}
+	}
+
+	if !option.hasStore {
+		return protocol.NewMultiBulkReply(result)
+	}
+	db.Remove(option.store)
+	// propagate the sorted result since order of elements with the same weight may be random
+	db.addAof(utils.ToCmdLine("del", option.store))
+	if len(result) > 0 {
+		list := List.NewQuickList()
+		for i, val := range result {
+			// missing values are stored as empty strings
+			if val == nil {
+				result[i] = []byte{}
+			}
+			list.Add(result[i])
+		}
+		db.PutEntity(option.store, &database.DataEntity{
+			Data: list,
+		})
+		db.addAof(utils.ToCmdLine3("rpush", append([][]byte{[]byte(option.store)}, result...)...))
+		db.signalKeyReady(option.store)
+	}
+	return protocol.NewIntReply(int64(len(result)))
+}
+
+// execSort sorts elements of list, set or sorted set, and stores the result if STORE is given
+func execSort(db *DB, args [][]byte) redis.Reply {
+	return sortGeneric(db, args, false)
+}
+
+// execSortRO is the read-only variant of SORT which doesn't accept STORE
+func execSortRO(db *DB, args [][]byte) redis.Reply {
+	return sortGeneric(db, args, true)
+}
+
+func init() {
+	RegisterCommand("Sort", execSort, noPrepare, -2)
+	RegisterCommand("Sort_RO", execSortRO, noPrepare, -2)
+}
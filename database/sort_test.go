@@ -0,0 +1,31 @@
+package database
+
+import "testing"
+
+func TestSortLimit(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "l", "3", "1", "2", "5", "4")
+	assertMultiBulkReply(t, execCmd(db, "sort", "l"), "1", "2", "3", "4", "5")
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "desc", "limit", "1", "9223372036854775807"), "4", "3", "2", "1")
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "limit", "9223372036854775807", "9223372036854775807"))
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "limit", "-1", "2"), "1", "2")
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "limit", "3", "-1"), "4", "5")
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "limit", "1", "0"))
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "limit", "10", "2"))
+}
+
+func TestSortByGet(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "rpush", "l", "a", "b", "c")
+	execCmd(db, "mset", "w_a", "3", "w_b", "1", "w_c", "2", "o_a", "x", "o_b", "y")
+	execCmd(db, "hset", "h_c", "f", "z")
+	assertMultiBulkReply(t, execCmd(db, "sort", "l", "by", "w_*"), "b", "c", "a")
+	assertReply(t, execCmd(db, "sort", "l", "by", "w_*", "get", "#", "get", "o_*"),
+		"*6\r\n$1\r\nb\r\n$1\r\ny\r\n$1\r\nc\r\n$-1\r\n$1\r\na\r\n$1\r\nx\r\n")
+	assertReply(t, execCmd(db, "sort", "l", "by", "nosort", "get", "h_*->f", "alpha"),
+		"*3\r\n$-1\r\n$-1\r\n$1\r\nz\r\n")
+	assertIntReply(t, execCmd(db, "sort", "l", "by", "w_*", "store", "dest"), 3)
+	assertMultiBulkReply(t, execCmd(db, "lrange", "dest", "0", "-1"), "b", "c", "a")
+	assertErrReply(t, execCmd(db, "sort_ro", "l", "store", "dest"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "sort", "l"), "ERR One or more scores can't be converted into double")
+}
@@ -0,0 +1,1153 @@
+package database
+
+import (
+	HashSet "github.com/Ravior/goredis/datastruct/set"
+	SortedSet "github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/wildcard"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func (db *DB) getAsSortedSet(key string) (*SortedSet.SortedSet, protocol.ErrorReply) {
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil, nil
+	}
+	sortedSet, ok := entity.Data.(*SortedSet.SortedSet)
+	if !ok {
+		return nil, &protocol.WrongTypeErrReply{}
+	}
+	return sortedSet, nil
+}
+
+func (db *DB) getOrInitSortedSet(key string) (sortedSet *SortedSet.SortedSet, inited bool, errReply protocol.ErrorReply) {
+	sortedSet, errReply = db.getAsSortedSet(key)
+	if errReply != nil {
+		return nil, false, errReply
+	}
+	inited = false
+	if sortedSet == nil {
+		sortedSet = SortedSet.NwSortedSet()
+		db.PutEntity(key, &database.DataEntity{
+			Data: sortedSet,
+		})
+		inited = true
+	}
+	return sortedSet, inited, nil
+}
+
+// parseScore parses a float score, NaN is not allowed
+func parseScore(arg []byte) (float64, protocol.ErrorReply) {
+	score, err := strconv.ParseFloat(string(arg), 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, protocol.NewErrReply("ERR value is not a valid float")
+	}
+	return score, nil
+}
+
+// formatScore formats score the same as redis does, such as "1.5", "inf" and "-inf"
+func formatScore(score float64) []byte {
+	if math.IsInf(score, 1) {
+		return []byte("inf")
+	}
+	if math.IsInf(score, -1) {
+		return []byte("-inf")
+	}
+	return []byte(strconv.FormatFloat(score, 'f', -1, 64))
+}
+
+func elementsToReply(elements []*SortedSet.Element, withScores bool) redis.Reply {
+	size := len(elements)
+	if withScores {
+		size *= 2
+	}
+	result := make([][]byte, 0, size)
+	for _, element := range elements {
+		result = append(result, []byte(element.Member))
+		if withScores {
+			result = append(result, formatScore(element.Score))
+		}
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+const (
+	zaddNX = 1 << iota
+	zaddXX
+	zaddGT
+	zaddLT
+	zaddCH
+	zaddIncr
+)
+
+// execZAdd adds members into sorted set, or updates scores of existing members
+func execZAdd(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	flags := 0
+	i := 1
+parseFlags:
+	for ; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "NX":
+			flags |= zaddNX
+		case "XX":
+			flags |= zaddXX
+		case "GT":
+			flags |= zaddGT
+		case "LT":
+			flags |= zaddLT
+		case "CH":
+			flags |= zaddCH
+		case "INCR":
+			flags |= zaddIncr
+		default:
+			break parseFlags
+		}
+	}
+	pairs := args[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return protocol.NewSyntaxErrReply()
+	}
+	if flags&zaddNX > 0 && flags&zaddXX > 0 {
+		return protocol.NewErrReply("ERR XX and NX options at the same time are not compatible")
+	}
+	if (flags&zaddGT > 0 && flags&zaddLT > 0) || (flags&zaddNX > 0 && flags&(zaddGT|zaddLT) > 0) {
+		return protocol.NewErrReply("ERR GT, LT, and/or NX options at the same time are not compatible")
+	}
+	if flags&zaddIncr > 0 && len(pairs) > 2 {
+		return protocol.NewErrReply("ERR INCR option supports a single increment-element pair")
+	}
+	elements := make([]*SortedSet.Element, len(pairs)/2)
+	for j := range elements {
+		score, errReply := parseScore(pairs[2*j])
+		if errReply != nil {
+			return errReply
+		}
+		elements[j] = &SortedSet.Element{
+			Member: string(pairs[2*j+1]),
+			Score:  score,
+		}
+	}
+
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		if flags&zaddXX > 0 {
+			if flags&zaddIncr > 0 {
+				return protocol.NewNullBulkReply()
+			}
+			return protocol.NewIntReply(0)
+		}
+		sortedSet, _, _ = db.getOrInitSortedSet(key)
+	}
+
+	added, updated := 0, 0
+	var incrResult *float64
+	for _, element := range elements {
+		score := element.Score
+		current, exists := sortedSet.Get(element.Member)
+		if exists {
+			if flags&zaddNX > 0 {
+				continue
+			}
+			if flags&zaddIncr > 0 {
+				score += current.Score
+				if math.IsNaN(score) {
+					return protocol.NewErrReply("ERR resulting score is not a number (NaN)")
+				}
+			}
+			if (flags&zaddGT > 0 && score <= current.Score) || (flags&zaddLT > 0 && score >= current.Score) {
+				continue
+			}
+			if score != current.Score {
+				sortedSet.Add(element.Member, score)
+				updated++
+			}
+		} else {
+			if flags&zaddXX > 0 {
+				continue
+			}
+			sortedSet.Add(element.Member, score)
+			added++
+		}
+		incrResult = &score
+	}
+	if added > 0 || updated > 0 {
+		db.addAof(utils.ToCmdLine3("zadd", args...))
+	}
+	if added > 0 {
+		db.signalKeyReady(key)
+	}
+
+	if flags&zaddIncr > 0 {
+		if incrResult == nil {
+			return protocol.NewNullBulkReply()
+		}
+		return protocol.NewBulkReply(formatScore(*incrResult))
+	}
+	if flags&zaddCH > 0 {
+		return protocol.NewIntReply(int64(added + updated))
+	}
+	return protocol.NewIntReply(int64(added))
+}
+
+// execZIncrBy increments the score of member
+func execZIncrBy(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	delta, errReply := parseScore(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	member := string(args[2])
+	sortedSet, _, errReply := db.getOrInitSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	score := delta
+	if element, exists := sortedSet.Get(member); exists {
+		score += element.Score
+		if math.IsNaN(score) {
+			return protocol.NewErrReply("ERR resulting score is not a number (NaN)")
+		}
+	}
+	sortedSet.Add(member, score)
+	db.addAof(utils.ToCmdLine3("zincrby", args...))
+	db.signalKeyReady(key)
+	return protocol.NewBulkReply(formatScore(score))
+}
+
+// execZRem removes members from sorted set, the key will be removed if the sorted set becomes empty
+func execZRem(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	var deleted int64 = 0
+	for _, member := range args[1:] {
+		if sortedSet.Remove(string(member)) {
+			deleted++
+		}
+	}
+	if sortedSet.Len() == 0 {
+		db.Remove(key)
+	}
+	if deleted > 0 {
+		db.addAof(utils.ToCmdLine3("zrem", args...))
+	}
+	return protocol.NewIntReply(deleted)
+}
+
+// execZScore returns the score of member
+func execZScore(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewNullBulkReply()
+	}
+	element, exists := sortedSet.Get(string(args[1]))
+	if !exists {
+		return protocol.NewNullBulkReply()
+	}
+	return protocol.NewBulkReply(formatScore(element.Score))
+}
+
+// execZMScore returns scores of members, nil for absent member
+func execZMScore(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := make([][]byte, len(args)-1)
+	if sortedSet == nil {
+		return protocol.NewMultiBulkReply(result)
+	}
+	for i, member := range args[1:] {
+		if element, exists := sortedSet.Get(string(member)); exists {
+			result[i] = formatScore(element.Score)
+		}
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+// execZCard returns the number of members in sorted set
+func execZCard(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(sortedSet.Len())
+}
+
+func parseScoreBorders(minArg, maxArg []byte) (*SortedSet.ScoreBorder, *SortedSet.ScoreBorder, protocol.ErrorReply) {
+	min, err := SortedSet.ParseScoreBorder(string(minArg))
+	if err != nil {
+		return nil, nil, protocol.NewErrReply(err.Error())
+	}
+	max, err := SortedSet.ParseScoreBorder(string(maxArg))
+	if err != nil {
+		return nil, nil, protocol.NewErrReply(err.Error())
+	}
+	return min, max, nil
+}
+
+// execZCount returns the number of members which score within the given border
+func execZCount(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	min, max, errReply := parseScoreBorders(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(sortedSet.Count(min, max))
+}
+
+func rankGeneric(db *DB, args [][]byte, desc bool) redis.Reply {
+	key := string(args[0])
+	member := string(args[1])
+	withScore := false
+	if len(args) == 3 {
+		if strings.ToUpper(string(args[2])) != "WITHSCORE" {
+			return protocol.NewSyntaxErrReply()
+		}
+		withScore = true
+	} else if len(args) > 3 {
+		return protocol.NewSyntaxErrReply()
+	}
+
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	var element *SortedSet.Element
+	exists := false
+	if sortedSet != nil {
+		element, exists = sortedSet.Get(member)
+	}
+	if !exists {
+		if withScore {
+			return protocol.NewNullMultiBulkReply()
+		}
+		return protocol.NewNullBulkReply()
+	}
+	rank := sortedSet.GetRank(member, desc)
+	if withScore {
+		return protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewIntReply(rank),
+			protocol.NewBulkReply(formatScore(element.Score)),
+		})
+	}
+	return protocol.NewIntReply(rank)
+}
+
+// execZRank returns the rank of member, sort by ascending order, rank starts from 0
+func execZRank(db *DB, args [][]byte) redis.Reply {
+	return rankGeneric(db, args, false)
+}
+
+// execZRevRank returns the rank of member, sort by descending order, rank starts from 0
+func execZRevRank(db *DB, args [][]byte) redis.Reply {
+	return rankGeneric(db, args, true)
+}
+
+const (
+	zrangeByRank = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// zrangeOption holds options of the unified ZRANGE command
+type zrangeOption struct {
+	by         int
+	rev        bool
+	withScores bool
+	hasLimit   bool
+	offset     int64
+	count      int64
+}
+
+// parseZRangeOption parses BYSCORE/BYLEX/REV/LIMIT/WITHSCORES options, opt carries defaults of the command
+func parseZRangeOption(args [][]byte, opt *zrangeOption, allowWithScores bool) protocol.ErrorReply {
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "BYSCORE":
+			opt.by = zrangeByScore
+		case "BYLEX":
+			opt.by = zrangeByLex
+		case "REV":
+			opt.rev = true
+		case "WITHSCORES":
+			if !allowWithScores {
+				return protocol.NewSyntaxErrReply()
+			}
+			opt.withScores = true
+		case "LIMIT":
+			if i+2 >= len(args) {
+				return protocol.NewSyntaxErrReply()
+			}
+			offset, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			count, err := strconv.ParseInt(string(args[i+2]), 10, 64)
+			if err != nil {
+				return protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			opt.hasLimit = true
+			opt.offset = offset
+			opt.count = count
+			i += 2
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+	if opt.hasLimit && opt.by == zrangeByRank {
+		return protocol.NewErrReply("ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	}
+	if opt.withScores && opt.by == zrangeByLex {
+		return protocol.NewErrReply("ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+	}
+	return nil
+}
+
+// zrange returns members of sorted set within [start, stop] according to opt
+// start and stop are ranks, scores or lex borders, with REV start is the upper border for BYSCORE and BYLEX
+func (db *DB) zrange(key string, start, stop []byte, opt *zrangeOption) ([]*SortedSet.Element, protocol.ErrorReply) {
+	// parse borders before looking up the key, so that a malformed command always fails
+	var startRank, stopRank int64
+	var minScore, maxScore *SortedSet.ScoreBorder
+	var minLex, maxLex *SortedSet.LexBorder
+	switch opt.by {
+	case zrangeByRank:
+		var err error
+		startRank, err = strconv.ParseInt(string(start), 10, 64)
+		if err != nil {
+			return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		stopRank, err = strconv.ParseInt(string(stop), 10, 64)
+		if err != nil {
+			return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+	case zrangeByScore:
+		var errReply protocol.ErrorReply
+		if opt.rev {
+			minScore, maxScore, errReply = parseScoreBorders(stop, start)
+		} else {
+			minScore, maxScore, errReply = parseScoreBorders(start, stop)
+		}
+		if errReply != nil {
+			return nil, errReply
+		}
+	case zrangeByLex:
+		var errReply protocol.ErrorReply
+		if opt.rev {
+			minLex, maxLex, errReply = parseLexBorders(stop, start)
+		} else {
+			minLex, maxLex, errReply = parseLexBorders(start, stop)
+		}
+		if errReply != nil {
+			return nil, errReply
+		}
+	}
+
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return nil, errReply
+	}
+	if sortedSet == nil {
+		return nil, nil
+	}
+	if opt.by == zrangeByRank {
+		begin, end, ok := normalizeRange(startRank, stopRank, sortedSet.Len())
+		if !ok {
+			return nil, nil
+		}
+		return sortedSet.Range(int64(begin), int64(end), opt.rev), nil
+	}
+
+	offset, count := int64(0), int64(-1)
+	if opt.hasLimit {
+		if opt.offset < 0 {
+			return nil, nil
+		}
+		offset, count = opt.offset, opt.count
+	}
+	if opt.by == zrangeByScore {
+		return sortedSet.RangeByScore(minScore, maxScore, offset, count, opt.rev), nil
+	}
+	return sortedSet.RangeByLex(minLex, maxLex, offset, count, opt.rev), nil
+}
+
+func parseLexBorders(minArg, maxArg []byte) (*SortedSet.LexBorder, *SortedSet.LexBorder, protocol.ErrorReply) {
+	min, err := SortedSet.ParseLexBorder(string(minArg))
+	if err != nil {
+		return nil, nil, protocol.NewErrReply(err.Error())
+	}
+	max, err := SortedSet.ParseLexBorder(string(maxArg))
+	if err != nil {
+		return nil, nil, protocol.NewErrReply(err.Error())
+	}
+	return min, max, nil
+}
+
+func zrangeGeneric(db *DB, args [][]byte, opt *zrangeOption) redis.Reply {
+	if errReply := parseZRangeOption(args[3:], opt, true); errReply != nil {
+		return errReply
+	}
+	elements, errReply := db.zrange(string(args[0]), args[1], args[2], opt)
+	if errReply != nil {
+		return errReply
+	}
+	return elementsToReply(elements, opt.withScores)
+}
+
+// execZRange returns members within the given range
+// ZRANGE key start stop [BYSCORE | BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func execZRange(db *DB, args [][]byte) redis.Reply {
+	return zrangeGeneric(db, args, &zrangeOption{})
+}
+
+// execZRevRange returns members within the given rank range, sort by descending order
+func execZRevRange(db *DB, args [][]byte) redis.Reply {
+	return zrangeGeneric(db, args, &zrangeOption{rev: true})
+}
+
+// execZRangeByScore returns members which score within [min, max], sort by ascending order
+func execZRangeByScore(db *DB, args [][]byte) redis.Reply {
+	return zrangeGeneric(db, args, &zrangeOption{by: zrangeByScore})
+}
+
+// execZRevRangeByScore returns members which score within [min, max], sort by descending order
+// ZREVRANGEBYSCORE key max min [WITHSCORES] [LIMIT offset count]
+func execZRevRangeByScore(db *DB, args [][]byte) redis.Reply {
+	return zrangeGeneric(db, args, &zrangeOption{by: zrangeByScore, rev: true})
+}
+
+// execZRangeByLex returns members within [min, max], all members are supposed to have the same score
+// ZRANGEBYLEX key min max [LIMIT offset count]
+func execZRangeByLex(db *DB, args [][]byte) redis.Reply {
+	return zrangeGeneric(db, args, &zrangeOption{by: zrangeByLex})
+}
+
+// execZRevRangeByLex returns members within [min, max] in reverse lex order
+// ZREVRANGEBYLEX key max min [LIMIT offset count]
+func execZRevRangeByLex(db *DB, args [][]byte) redis.Reply {
+	return zrangeGeneric(db, args, &zrangeOption{by: zrangeByLex, rev: true})
+}
+
+// execZLexCount returns the number of members within the given lex border
+func execZLexCount(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	min, max, errReply := parseLexBorders(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(sortedSet.LexCount(min, max))
+}
+
+// execZRangeStore stores the result of ZRANGE into the destination key
+// ZRANGESTORE dst src min max [BYSCORE | BYLEX] [REV] [LIMIT offset count]
+func execZRangeStore(db *DB, args [][]byte) redis.Reply {
+	dest := string(args[0])
+	opt := &zrangeOption{}
+	if errReply := parseZRangeOption(args[4:], opt, false); errReply != nil {
+		return errReply
+	}
+	elements, errReply := db.zrange(string(args[1]), args[2], args[3], opt)
+	if errReply != nil {
+		return errReply
+	}
+	db.Remove(dest)
+	if len(elements) > 0 {
+		sortedSet := SortedSet.NwSortedSet()
+		for _, element := range elements {
+			sortedSet.Add(element.Member, element.Score)
+		}
+		db.PutEntity(dest, &database.DataEntity{
+			Data: sortedSet,
+		})
+		db.signalKeyReady(dest)
+	}
+	db.addAof(utils.ToCmdLine3("zrangestore", args...))
+	return protocol.NewIntReply(int64(len(elements)))
+}
+
+// execZRemRangeByScore removes members which score within the given border
+func execZRemRangeByScore(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	min, max, errReply := parseScoreBorders(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	removed := sortedSet.RemoveByScore(min, max)
+	if sortedSet.Len() == 0 {
+		db.Remove(key)
+	}
+	if removed > 0 {
+		db.addAof(utils.ToCmdLine3("zremrangebyscore", args...))
+	}
+	return protocol.NewIntReply(removed)
+}
+
+// execZRemRangeByLex removes members within the given lex border
+func execZRemRangeByLex(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	min, max, errReply := parseLexBorders(args[1], args[2])
+	if errReply != nil {
+		return errReply
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	removed := sortedSet.RemoveByLex(min, max)
+	if sortedSet.Len() == 0 {
+		db.Remove(key)
+	}
+	if removed > 0 {
+		db.addAof(utils.ToCmdLine3("zremrangebylex", args...))
+	}
+	return protocol.NewIntReply(removed)
+}
+
+// execZRemRangeByRank removes members which rank within [start, stop]
+func execZRemRangeByRank(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	start, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	stop, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil {
+		return protocol.NewIntReply(0)
+	}
+	begin, end, ok := normalizeRange(start, stop, sortedSet.Len())
+	if !ok {
+		return protocol.NewIntReply(0)
+	}
+	removed := sortedSet.RemoveByRank(int64(begin), int64(end))
+	if sortedSet.Len() == 0 {
+		db.Remove(key)
+	}
+	if removed > 0 {
+		db.addAof(utils.ToCmdLine3("zremrangebyrank", args...))
+	}
+	return protocol.NewIntReply(removed)
+}
+
+// popFromSortedSet removes and returns at most count members with the lowest or highest scores
+// the key will be removed if the sorted set becomes empty
+func (db *DB) popFromSortedSet(key string, sortedSet *SortedSet.SortedSet, count int64, max bool) []*SortedSet.Element {
+	if count > sortedSet.Len() {
+		count = sortedSet.Len()
+	}
+	elements := sortedSet.Range(0, count, max)
+	for _, element := range elements {
+		sortedSet.Remove(element.Member)
+	}
+	if sortedSet.Len() == 0 {
+		db.Remove(key)
+	}
+	return elements
+}
+
+func popGenericZ(db *DB, args [][]byte, max bool, cmdName string) redis.Reply {
+	key := string(args[0])
+	count := int64(1)
+	if len(args) == 2 {
+		var err error
+		count, err = strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil || count < 0 {
+			return protocol.NewErrReply("ERR value is out of range, must be positive")
+		}
+	} else if len(args) > 2 {
+		return protocol.NewSyntaxErrReply()
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	if sortedSet == nil || count == 0 {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	elements := db.popFromSortedSet(key, sortedSet, count, max)
+	db.addAof(utils.ToCmdLine3(cmdName, args...))
+	return elementsToReply(elements, true)
+}
+
+// execZPopMin removes and returns members with the lowest scores
+func execZPopMin(db *DB, args [][]byte) redis.Reply {
+	return popGenericZ(db, args, false, "zpopmin")
+}
+
+// execZPopMax removes and returns members with the highest scores
+func execZPopMax(db *DB, args [][]byte) redis.Reply {
+	return popGenericZ(db, args, true, "zpopmax")
+}
+
+// parseZSetSide parses MIN or MAX, returns true for MAX
+func parseZSetSide(arg []byte) (bool, protocol.ErrorReply) {
+	switch strings.ToUpper(string(arg)) {
+	case "MIN":
+		return false, nil
+	case "MAX":
+		return true, nil
+	}
+	return false, protocol.NewSyntaxErrReply()
+}
+
+func prepareBlockingZPop(args [][]byte) ([]string, []string) {
+	return writeAllKeys(args[:len(args)-1])
+}
+
+func blockingZPopGeneric(db *DB, args [][]byte, max bool, cmdName string) redis.Reply {
+	timeout, errReply := parseBlockingTimeout(args[len(args)-1])
+	if errReply != nil {
+		return errReply
+	}
+	keys := make([]string, len(args)-1)
+	for i, arg := range args[:len(args)-1] {
+		key := string(arg)
+		keys[i] = key
+		sortedSet, errReply := db.getAsSortedSet(key)
+		if errReply != nil {
+			return errReply
+		}
+		if sortedSet == nil {
+			continue
+		}
+		element := db.popFromSortedSet(key, sortedSet, 1, max)[0]
+		db.addAof(utils.ToCmdLine3(cmdName, arg))
+		return protocol.NewMultiBulkReply([][]byte{arg, []byte(element.Member), formatScore(element.Score)})
+	}
+	return &blockedReply{
+		keys:    keys,
+		timeout: timeout,
+	}
+}
+
+// execBZPopMin removes the member with the lowest score from the first non-empty sorted set,
+// blocks until one of sorted sets is not empty
+func execBZPopMin(db *DB, args [][]byte) redis.Reply {
+	return blockingZPopGeneric(db, args, false, "zpopmin")
+}
+
+// execBZPopMax removes the member with the highest score from the first non-empty sorted set,
+// blocks until one of sorted sets is not empty
+func execBZPopMax(db *DB, args [][]byte) redis.Reply {
+	return blockingZPopGeneric(db, args, true, "zpopmax")
+}
+
+// multiZPopGeneric implements ZMPOP and BZMPOP, args start from numkeys
+// it returns nil reply and keys to wait for if all sorted sets are empty
+func multiZPopGeneric(db *DB, args [][]byte) (redis.Reply, []string) {
+	keys, options, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return errReply, nil
+	}
+	if len(options) == 0 {
+		return protocol.NewSyntaxErrReply(), nil
+	}
+	max, errReply := parseZSetSide(options[0])
+	if errReply != nil {
+		return errReply, nil
+	}
+	count := int64(1)
+	if len(options) > 1 {
+		if len(options) != 3 || strings.ToUpper(string(options[1])) != "COUNT" {
+			return protocol.NewSyntaxErrReply(), nil
+		}
+		c, err := strconv.ParseInt(string(options[2]), 10, 64)
+		if err != nil || c <= 0 {
+			return protocol.NewErrReply("ERR count should be greater than 0"), nil
+		}
+		count = c
+	}
+
+	keyNames := make([]string, len(keys))
+	for i, arg := range keys {
+		key := string(arg)
+		keyNames[i] = key
+		sortedSet, errReply := db.getAsSortedSet(key)
+		if errReply != nil {
+			return errReply, nil
+		}
+		if sortedSet == nil {
+			continue
+		}
+		elements := db.popFromSortedSet(key, sortedSet, count, max)
+		db.addAof(utils.ToCmdLine2("zmpop", "1", key, strings.ToUpper(string(options[0])),
+			"COUNT", strconv.Itoa(len(elements))))
+		result := make([]redis.Reply, len(elements))
+		for j, element := range elements {
+			result[j] = protocol.NewMultiBulkReply([][]byte{[]byte(element.Member), formatScore(element.Score)})
+		}
+		return protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewBulkReply(arg),
+			protocol.NewMultiRawReply(result),
+		}), nil
+	}
+	return nil, keyNames
+}
+
+// execZMPop pops members from the first non-empty sorted set
+func execZMPop(db *DB, args [][]byte) redis.Reply {
+	result, _ := multiZPopGeneric(db, args)
+	if result == nil {
+		return protocol.NewNullMultiBulkReply()
+	}
+	return result
+}
+
+// execBZMPop is the blocking variant of ZMPOP
+func execBZMPop(db *DB, args [][]byte) redis.Reply {
+	timeout, errReply := parseBlockingTimeout(args[0])
+	if errReply != nil {
+		return errReply
+	}
+	result, keys := multiZPopGeneric(db, args[1:])
+	if result == nil {
+		return &blockedReply{
+			keys:    keys,
+			timeout: timeout,
+		}
+	}
+	return result
+}
+
+// getZSetOperand returns the sorted set bound to the given key as an operand of ZUNION/ZINTER/ZDIFF
+// a plain set is converted into a sorted set which scores are all 1, the same as redis
+func (db *DB) getZSetOperand(key string) (*SortedSet.SortedSet, protocol.ErrorReply) {
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil, nil
+	}
+	switch val := entity.Data.(type) {
+	case *SortedSet.SortedSet:
+		return val, nil
+	case *HashSet.Set:
+		sortedSet := SortedSet.NwSortedSet()
+		val.ForEach(func(member string) bool {
+			sortedSet.Add(member, 1)
+			return true
+		})
+		return sortedSet, nil
+	}
+	return nil, &protocol.WrongTypeErrReply{}
+}
+
+func (db *DB) getZSetOperands(keys [][]byte) ([]*SortedSet.SortedSet, protocol.ErrorReply) {
+	sets := make([]*SortedSet.SortedSet, len(keys))
+	for i, key := range keys {
+		set, errReply := db.getZSetOperand(string(key))
+		if errReply != nil {
+			return nil, errReply
+		}
+		sets[i] = set
+	}
+	return sets, nil
+}
+
+const (
+	zsetUnion = iota
+	zsetIntersect
+	zsetDiff
+)
+
+// zsetCalculate computes the union, intersection or difference of sorted sets
+// args start from numkeys: numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX] [WITHSCORES]
+func (db *DB) zsetCalculate(args [][]byte, op int, allowWithScores bool) (*SortedSet.SortedSet, bool, protocol.ErrorReply) {
+	keys, options, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return nil, false, errReply
+	}
+	var weights []float64
+	fn := SortedSet.Aggregate(SortedSet.AggregateSum)
+	withScores := false
+	for i := 0; i < len(options); i++ {
+		switch strings.ToUpper(string(options[i])) {
+		case "WEIGHTS":
+			if op == zsetDiff || i+len(keys) >= len(options) {
+				return nil, false, protocol.NewSyntaxErrReply()
+			}
+			weights = make([]float64, len(keys))
+			for j := range weights {
+				weight, err := strconv.ParseFloat(string(options[i+1+j]), 64)
+				if err != nil || math.IsNaN(weight) {
+					return nil, false, protocol.NewErrReply("ERR weight value is not a float")
+				}
+				weights[j] = weight
+			}
+			i += len(keys)
+		case "AGGREGATE":
+			if op == zsetDiff || i+1 >= len(options) {
+				return nil, false, protocol.NewSyntaxErrReply()
+			}
+			switch strings.ToUpper(string(options[i+1])) {
+			case "SUM":
+				fn = SortedSet.AggregateSum
+			case "MIN":
+				fn = SortedSet.AggregateMin
+			case "MAX":
+				fn = SortedSet.AggregateMax
+			default:
+				return nil, false, protocol.NewSyntaxErrReply()
+			}
+			i++
+		case "WITHSCORES":
+			if !allowWithScores {
+				return nil, false, protocol.NewSyntaxErrReply()
+			}
+			withScores = true
+		default:
+			return nil, false, protocol.NewSyntaxErrReply()
+		}
+	}
+
+	sets, errReply := db.getZSetOperands(keys)
+	if errReply != nil {
+		return nil, false, errReply
+	}
+	switch op {
+	case zsetUnion:
+		return SortedSet.Union(sets, weights, fn), withScores, nil
+	case zsetIntersect:
+		return SortedSet.Intersect(sets, weights, fn), withScores, nil
+	default:
+		return SortedSet.Diff(sets), withScores, nil
+	}
+}
+
+func zsetCalculateGeneric(db *DB, args [][]byte, op int) redis.Reply {
+	result, withScores, errReply := db.zsetCalculate(args, op, true)
+	if errReply != nil {
+		return errReply
+	}
+	if result.Len() == 0 {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	return elementsToReply(result.Range(0, result.Len(), false), withScores)
+}
+
+// zsetCalculateStoreGeneric stores the result into the destination key, the destination key will be removed if the result is empty
+func zsetCalculateStoreGeneric(db *DB, args [][]byte, op int, cmdName string) redis.Reply {
+	dest := string(args[0])
+	result, _, errReply := db.zsetCalculate(args[1:], op, false)
+	if errReply != nil {
+		return errReply
+	}
+	db.Remove(dest)
+	if result.Len() > 0 {
+		db.PutEntity(dest, &database.DataEntity{
+			Data: result,
+		})
+		db.signalKeyReady(dest)
+	}
+	db.addAof(utils.ToCmdLine3(cmdName, args...))
+	return protocol.NewIntReply(result.Len())
+}
+
+// execZUnion returns the union of sorted sets
+func execZUnion(db *DB, args [][]byte) redis.Reply {
+	return zsetCalculateGeneric(db, args, zsetUnion)
+}
+
+// execZUnionStore stores the union of sorted sets into the destination key
+func execZUnionStore(db *DB, args [][]byte) redis.Reply {
+	return zsetCalculateStoreGeneric(db, args, zsetUnion, "zunionstore")
+}
+
+// execZInter returns the intersection of sorted sets
+func execZInter(db *DB, args [][]byte) redis.Reply {
+	return zsetCalculateGeneric(db, args, zsetIntersect)
+}
+
+// execZInterStore stores the intersection of sorted sets into the destination key
+func execZInterStore(db *DB, args [][]byte) redis.Reply {
+	return zsetCalculateStoreGeneric(db, args, zsetIntersect, "zinterstore")
+}
+
+// execZDiff returns members of the first sorted set which don't exist in the others
+func execZDiff(db *DB, args [][]byte) redis.Reply {
+	return zsetCalculateGeneric(db, args, zsetDiff)
+}
+
+// execZDiffStore stores the difference of sorted sets into the destination key
+func execZDiffStore(db *DB, args [][]byte) redis.Reply {
+	return zsetCalculateStoreGeneric(db, args, zsetDiff, "zdiffstore")
+}
+
+// execZInterCard returns the cardinality of the intersection, it stops counting once reaching LIMIT
+func execZInterCard(db *DB, args [][]byte) redis.Reply {
+	keys, options, errReply := parseNumKeys(args)
+	if errReply != nil {
+		return errReply
+	}
+	limit := int64(0) // 0 means unlimited
+	if len(options) > 0 {
+		if len(options) != 2 || strings.ToUpper(string(options[0])) != "LIMIT" {
+			return protocol.NewSyntaxErrReply()
+		}
+		var err error
+		limit, err = strconv.ParseInt(string(options[1]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		if limit < 0 {
+			return protocol.NewErrReply("ERR LIMIT can't be negative")
+		}
+	}
+
+	sets, errReply := db.getZSetOperands(keys)
+	if errReply != nil {
+		return errReply
+	}
+	smallest := 0
+	for i, set := range sets {
+		if set == nil {
+			return protocol.NewIntReply(0)
+		}
+		if set.Len() < sets[smallest].Len() {
+			smallest = i
+		}
+	}
+	var counter int64
+	sets[smallest].ForEach(0, sets[smallest].Len(), false, func(element *SortedSet.Element) bool {
+		for _, set := range sets {
+			if _, ok := set.Get(element.Member); !ok {
+				return true
+			}
+		}
+		counter++
+		return limit == 0 || counter < limit
+	})
+	return protocol.NewIntReply(counter)
+}
+
+// prepareZSetCalculateStore returns the destination key as write key and the source keys as read keys
+func prepareZSetCalculateStore(args [][]byte) ([]string, []string) {
+	dest := string(args[0])
+	keys, _, errReply := parseNumKeys(args[1:])
+	if errReply != nil {
+		return nil, nil
+	}
+	_, readKeys := readAllKeys(keys)
+	return []string{dest}, readKeys
+}
+
+// prepareZRangeStore returns the destination key as write key and the source key as read key
+func prepareZRangeStore(args [][]byte) ([]string, []string) {
+	return []string{string(args[0])}, []string{string(args[1])}
+}
+
+// execZScan iterates members and scores of sorted set by cursor, the cursor is the bucket to continue with
+func execZScan(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	cursor, errReply := parseScanCursor(args[1])
+	if errReply != nil {
+		return errReply
+	}
+	option, errReply := parseScanOption(args[2:], false)
+	if errReply != nil {
+		return errReply
+	}
+	sortedSet, errReply := db.getAsSortedSet(key)
+	if errReply != nil {
+		return errReply
+	}
+	result := make([][]byte, 0)
+	if sortedSet == nil {
+		return makeScanReply(0, result)
+	}
+	cursor = sortedSet.Scan(cursor, option.count, func(element *SortedSet.Element) {
+		if wildcard.Match(option.pattern, element.Member) {
+			result = append(result, []byte(element.Member), formatScore(element.Score))
+		}
+	})
+	return makeScanReply(cursor, result)
+}
+
+func init() {
+	RegisterCommand("ZAdd", execZAdd, writeFirstKey, -4)
+	RegisterCommand("ZIncrBy", execZIncrBy, writeFirstKey, 4)
+	RegisterCommand("ZRem", execZRem, writeFirstKey, -3)
+	RegisterCommand("ZScore", execZScore, readFirstKey, 3)
+	RegisterCommand("ZMScore", execZMScore, readFirstKey, -3)
+	RegisterCommand("ZCard", execZCard, readFirstKey, 2)
+	RegisterCommand("ZCount", execZCount, readFirstKey, 4)
+	RegisterCommand("ZRank", execZRank, readFirstKey, -3)
+	RegisterCommand("ZRevRank", execZRevRank, readFirstKey, -3)
+	RegisterCommand("ZRange", execZRange, readFirstKey, -4)
+	RegisterCommand("ZRevRange", execZRevRange, readFirstKey, -4)
+	RegisterCommand("ZRangeByScore", execZRangeByScore, readFirstKey, -4)
+	RegisterCommand("ZRevRangeByScore", execZRevRangeByScore, readFirstKey, -4)
+	RegisterCommand("ZRangeByLex", execZRangeByLex, readFirstKey, -4)
+	RegisterCommand("ZRevRangeByLex", execZRevRangeByLex, readFirstKey, -4)
+	RegisterCommand("ZLexCount", execZLexCount, readFirstKey, 4)
+	RegisterCommand("ZRangeStore", execZRangeStore, prepareZRangeStore, -5)
+	RegisterCommand("ZRemRangeByScore", execZRemRangeByScore, writeFirstKey, 4)
+	RegisterCommand("ZRemRangeByLex", execZRemRangeByLex, writeFirstKey, 4)
+	RegisterCommand("ZRemRangeByRank", execZRemRangeByRank, writeFirstKey, 4)
+	RegisterCommand("ZUnion", execZUnion, prepareReadNumKeys, -3)
+	RegisterCommand("ZUnionStore", execZUnionStore, prepareZSetCalculateStore, -4)
+	RegisterCommand("ZInter", execZInter, prepareReadNumKeys, -3)
+	RegisterCommand("ZInterStore", execZInterStore, prepareZSetCalculateStore, -4)
+	RegisterCommand("ZDiff", execZDiff, prepareReadNumKeys, -3)
+	RegisterCommand("ZDiffStore", execZDiffStore, prepareZSetCalculateStore, -4)
+	RegisterCommand("ZInterCard", execZInterCard, prepareReadNumKeys, -3)
+	RegisterCommand("ZPopMin", execZPopMin, writeFirstKey, -2)
+	RegisterCommand("ZPopMax", execZPopMax, writeFirstKey, -2)
+	RegisterCommand("BZPopMin", execBZPopMin, prepareBlockingZPop, -3)
+	RegisterCommand("BZPopMax", execBZPopMax, prepareBlockingZPop, -3)
+	RegisterCommand("ZMPop", execZMPop, prepareNumKeys, -4)
+	RegisterCommand("BZMPop", execBZMPop, prepareBlockingNumKeys, -5)
+	RegisterCommand("ZScan", execZScan, readFirstKey, -3)
+}
@@ -0,0 +1,409 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/redis/connection"
+	"strconv"
+	"testing"
+)
+
+func TestScoreBorderInvalid(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b")
+	commands := [][]string{
+		{"zcount", "z", "%s", "1"},
+		{"zcount", "z", "0", "%s"},
+		{"zrangebyscore", "z", "%s", "1"},
+		{"zrangebyscore", "z", "0", "%s"},
+		{"zrevrangebyscore", "z", "%s", "0"},
+		{"zremrangebyscore", "z", "%s", "1"},
+		{"zremrangebyscore", "z", "0", "%s"},
+		{"zrange", "z", "%s", "1", "byscore"},
+		{"zrange", "z", "0", "%s", "byscore", "rev"},
+		{"zrangestore", "dest", "z", "%s", "1", "byscore"},
+	}
+	for _, border := range []string{"", "(", "nan", "(nan", "NaN", "abc"} {
+		for _, command := range commands {
+			cmdLine := make([]string, len(command))
+			for i, arg := range command {
+				if arg == "%s" {
+					arg = border
+				}
+				cmdLine[i] = arg
+			}
+			assertErrReply(t, execCmd(db, cmdLine...), "ERR min or max is not a float")
+		}
+	}
+	assertIntReply(t, execCmd(db, "zcard", "z"), 2)
+}
+
+func TestScoreBorderValid(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c")
+	assertIntReply(t, execCmd(db, "zcount", "z", "(1", "3"), 2)
+	assertIntReply(t, execCmd(db, "zcount", "z", "-inf", "+inf"), 3)
+	assertMultiBulkReply(t, execCmd(db, "zrangebyscore", "z", "(1", "(3"), "b")
+	assertMultiBulkReply(t, execCmd(db, "zrevrangebyscore", "z", "inf", "2"), "c", "b")
+	assertIntReply(t, execCmd(db, "zremrangebyscore", "z", "-inf", "(2"), 1)
+}
+
+func TestZScan(t *testing.T) {
+	db := makeTestDB()
+	args := []string{"zadd", "z"}
+	for i := 0; i < 1000; i++ {
+		args = append(args, strconv.Itoa(i), "m"+strconv.Itoa(i))
+	}
+	execCmd(db, args...)
+
+	// members existing during the whole iteration are returned even if lower-scored members are inserted
+	seen := make(map[string]string)
+	cursor := "0"
+	for i := 0; ; i++ {
+		execCmd(db, "zadd", "z", "-"+strconv.Itoa(i+1), "new"+strconv.Itoa(i))
+		execCmd(db, "zrem", "z", "m"+strconv.Itoa(900+i%100))
+		var elements []string
+		cursor, elements = scanOnce(t, db, "zscan", "z", cursor, "count", "30")
+		for j := 0; j+1 < len(elements); j += 2 {
+			seen[elements[j]] = elements[j+1]
+		}
+		if cursor == "0" {
+			break
+		}
+		if len(elements) < 60 {
+			t.Errorf("expected at least 30 members, actually %d", len(elements)/2)
+		}
+	}
+	for i := 0; i < 900; i++ {
+		if score := seen["m"+strconv.Itoa(i)]; score != strconv.Itoa(i) {
+			t.Errorf("expected m%d with score %d, actually %q", i, i, score)
+		}
+	}
+
+	batches := scanAll(t, db, "zscan", "z", "match", "m9?", "count", "100000")
+	if len(batches) != 1 || len(batches[0]) != 20 {
+		t.Errorf("expected 10 matched members in one call, actually %v", batches)
+	}
+	// a small sorted set is returned in one call
+	execCmd(db, "zadd", "small", "1", "a", "2", "b", "3", "c")
+	cursor, elements := scanOnce(t, db, "zscan", "small", "0", "count", "1")
+	if cursor != "0" || len(elements) != 6 {
+		t.Errorf("unexpected cursor %s and elements %v", cursor, elements)
+	}
+	assertReply(t, execCmd(db, "zscan", "missing", "0"), "*2\r\n$1\r\n0\r\n*0\r\n")
+}
+
+func TestZAdd(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "a"), 2)
+	assertBulkReply(t, execCmd(db, "zscore", "z", "a"), "3")
+	assertIntReply(t, execCmd(db, "zadd", "z", "nx", "10", "a", "4", "c"), 1)
+	assertBulkReply(t, execCmd(db, "zscore", "z", "a"), "3")
+	assertIntReply(t, execCmd(db, "zadd", "z", "xx", "10", "a", "5", "d"), 0)
+	assertBulkReply(t, execCmd(db, "zscore", "z", "a"), "10")
+	assertReply(t, execCmd(db, "zscore", "z", "d"), "$-1\r\n")
+	assertIntReply(t, execCmd(db, "zadd", "z", "gt", "ch", "1", "a", "3", "b"), 1)
+	assertBulkReply(t, execCmd(db, "zscore", "z", "a"), "10")
+	assertIntReply(t, execCmd(db, "zadd", "z", "lt", "ch", "1", "a", "3", "b"), 1)
+	assertBulkReply(t, execCmd(db, "zscore", "z", "a"), "1")
+	assertIntReply(t, execCmd(db, "zadd", "z", "ch", "1", "a", "0.5", "e"), 1)
+
+	assertBulkReply(t, execCmd(db, "zadd", "z", "incr", "2.5", "a"), "3.5")
+	assertReply(t, execCmd(db, "zadd", "z", "nx", "incr", "1", "a"), "$-1\r\n")
+	assertReply(t, execCmd(db, "zadd", "missing", "xx", "1", "a"), ":0\r\n")
+	assertReply(t, execCmd(db, "zadd", "missing", "xx", "incr", "1", "a"), "$-1\r\n")
+	assertIntReply(t, execCmd(db, "exists", "missing"), 0)
+	assertIntReply(t, execCmd(db, "zadd", "inf", "-inf", "a", "+inf", "b"), 2)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "inf", "0", "-1", "withscores"), "a", "-inf", "b", "inf")
+	assertErrReply(t, execCmd(db, "zadd", "inf", "incr", "-inf", "b"), "ERR resulting score is not a number (NaN)")
+
+	assertErrReply(t, execCmd(db, "zadd", "z", "nx", "1"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zadd", "z", "1", "a", "2"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zadd", "z", "nx", "xx", "1", "a"), "ERR XX and NX options at the same time are not compatible")
+	assertErrReply(t, execCmd(db, "zadd", "z", "gt", "lt", "1", "a"), "ERR GT, LT, and/or NX options at the same time are not compatible")
+	assertErrReply(t, execCmd(db, "zadd", "z", "nx", "gt", "1", "a"), "ERR GT, LT, and/or NX options at the same time are not compatible")
+	assertErrReply(t, execCmd(db, "zadd", "z", "incr", "1", "a", "2", "b"), "ERR INCR option supports a single increment-element pair")
+	assertErrReply(t, execCmd(db, "zadd", "z", "x", "a"), "ERR value is not a valid float")
+	assertErrReply(t, execCmd(db, "zadd", "z", "nan", "a"), "ERR value is not a valid float")
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "zadd", "str", "1", "a"), wrongTypeErr)
+}
+
+func TestZIncrBy(t *testing.T) {
+	db := makeTestDB()
+	assertBulkReply(t, execCmd(db, "zincrby", "z", "1.5", "a"), "1.5")
+	assertBulkReply(t, execCmd(db, "zincrby", "z", "-3", "a"), "-1.5")
+	assertBulkReply(t, execCmd(db, "zincrby", "z", "inf", "b"), "inf")
+	assertErrReply(t, execCmd(db, "zincrby", "z", "-inf", "b"), "ERR resulting score is not a number (NaN)")
+	assertErrReply(t, execCmd(db, "zincrby", "z", "x", "a"), "ERR value is not a valid float")
+	assertBulkReply(t, execCmd(db, "zscore", "z", "b"), "inf")
+}
+
+func TestZRemAndScore(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c")
+	assertIntReply(t, execCmd(db, "zcard", "z"), 3)
+	assertReply(t, execCmd(db, "zmscore", "z", "a", "x", "c"), "*3\r\n$1\r\n1\r\n$-1\r\n$1\r\n3\r\n")
+	assertReply(t, execCmd(db, "zmscore", "missing", "a"), "*1\r\n$-1\r\n")
+	assertReply(t, execCmd(db, "zscore", "missing", "a"), "$-1\r\n")
+	assertIntReply(t, execCmd(db, "zrem", "z", "a", "x"), 1)
+	assertIntReply(t, execCmd(db, "zrem", "z", "b", "c"), 2)
+	assertIntReply(t, execCmd(db, "exists", "z"), 0)
+	assertIntReply(t, execCmd(db, "zrem", "z", "a"), 0)
+	assertIntReply(t, execCmd(db, "zcard", "z"), 0)
+}
+
+func TestZRank(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c")
+	assertIntReply(t, execCmd(db, "zrank", "z", "a"), 0)
+	assertIntReply(t, execCmd(db, "zrank", "z", "c"), 2)
+	assertIntReply(t, execCmd(db, "zrevrank", "z", "c"), 0)
+	assertReply(t, execCmd(db, "zrank", "z", "b", "withscore"), "*2\r\n:1\r\n$1\r\n2\r\n")
+	assertReply(t, execCmd(db, "zrevrank", "z", "a", "WITHSCORE"), "*2\r\n:2\r\n$1\r\n1\r\n")
+	assertReply(t, execCmd(db, "zrank", "z", "x"), "$-1\r\n")
+	assertReply(t, execCmd(db, "zrank", "z", "x", "withscore"), "*-1\r\n")
+	assertReply(t, execCmd(db, "zrank", "missing", "a"), "$-1\r\n")
+	assertErrReply(t, execCmd(db, "zrank", "z", "a", "withscores"), "Err syntax error")
+}
+
+func TestZRange(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "0", "-1"), "a", "b", "c", "d", "e")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "1", "2", "withscores"), "b", "2", "c", "3")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "-2", "100"), "d", "e")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "3", "1"))
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "0", "1", "rev"), "e", "d")
+	assertMultiBulkReply(t, execCmd(db, "zrevrange", "z", "0", "1", "withscores"), "e", "5", "d", "4")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "missing", "0", "-1"))
+
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "(1", "4", "byscore"), "b", "c", "d")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "4", "(1", "byscore", "rev"), "d", "c", "b")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "-inf", "+inf", "byscore", "limit", "1", "2"), "b", "c")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "+inf", "-inf", "byscore", "rev", "limit", "1", "2", "withscores"),
+		"d", "4", "c", "3")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "-inf", "+inf", "byscore", "limit", "3", "-1"), "d", "e")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "-inf", "+inf", "byscore", "limit", "-1", "2"))
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "3", "1", "byscore"))
+	assertMultiBulkReply(t, execCmd(db, "zrangebyscore", "z", "2", "3", "withscores"), "b", "2", "c", "3")
+	assertMultiBulkReply(t, execCmd(db, "zrangebyscore", "z", "-inf", "inf", "limit", "4", "10"), "e")
+	assertMultiBulkReply(t, execCmd(db, "zrevrangebyscore", "z", "3", "-inf", "limit", "0", "2"), "c", "b")
+
+	assertErrReply(t, execCmd(db, "zrange", "z", "0", "-1", "limit", "0", "1"),
+		"ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+	assertErrReply(t, execCmd(db, "zrange", "z", "a", "-1"), "ERR value is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "zrange", "z", "0", "-1", "byscore", "limit", "0"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zrange", "z", "0", "-1", "foo"), "Err syntax error")
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "zrange", "str", "0", "-1"), wrongTypeErr)
+}
+
+func TestZRangeStore(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c")
+	assertIntReply(t, execCmd(db, "zrangestore", "dest", "z", "0", "1"), 2)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "dest", "0", "-1", "withscores"), "a", "1", "b", "2")
+	assertIntReply(t, execCmd(db, "zrangestore", "dest", "z", "+inf", "(1", "byscore", "rev", "limit", "0", "1"), 1)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "dest", "0", "-1"), "c")
+	// destination is removed if the result is empty
+	assertIntReply(t, execCmd(db, "zrangestore", "dest", "z", "10", "20", "byscore"), 0)
+	assertIntReply(t, execCmd(db, "exists", "dest"), 0)
+	assertErrReply(t, execCmd(db, "zrangestore", "dest", "z", "0", "-1", "withscores"), "Err syntax error")
+}
+
+func TestZRemRange(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c", "4", "d", "5", "e")
+	assertIntReply(t, execCmd(db, "zremrangebyrank", "z", "0", "1"), 2)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "0", "-1"), "c", "d", "e")
+	assertIntReply(t, execCmd(db, "zremrangebyrank", "z", "5", "10"), 0)
+	assertIntReply(t, execCmd(db, "zremrangebyscore", "z", "(3", "4"), 1)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "0", "-1"), "c", "e")
+	assertIntReply(t, execCmd(db, "zremrangebyrank", "z", "-2", "-1"), 2)
+	assertIntReply(t, execCmd(db, "exists", "z"), 0)
+	assertIntReply(t, execCmd(db, "zremrangebyscore", "z", "-inf", "+inf"), 0)
+	assertErrReply(t, execCmd(db, "zremrangebyrank", "z", "x", "1"), "ERR value is not an integer or out of range")
+}
+
+func TestZRangeByLex(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "0", "a", "0", "b", "0", "c", "0", "d", "0", "e")
+	assertMultiBulkReply(t, execCmd(db, "zrangebylex", "z", "-", "+"), "a", "b", "c", "d", "e")
+	assertMultiBulkReply(t, execCmd(db, "zrangebylex", "z", "[b", "(d"), "b", "c")
+	assertMultiBulkReply(t, execCmd(db, "zrangebylex", "z", "(a", "+", "limit", "1", "2"), "c", "d")
+	assertMultiBulkReply(t, execCmd(db, "zrangebylex", "z", "[d", "[b"))
+	assertMultiBulkReply(t, execCmd(db, "zrevrangebylex", "z", "+", "(c"), "e", "d")
+	assertMultiBulkReply(t, execCmd(db, "zrevrangebylex", "z", "[d", "-", "limit", "1", "10"), "c", "b", "a")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "[b", "[c", "bylex"), "b", "c")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "[c", "[b", "bylex", "rev"), "c", "b")
+	assertMultiBulkReply(t, execCmd(db, "zrangebylex", "missing", "-", "+"))
+
+	assertErrReply(t, execCmd(db, "zrangebylex", "z", "a", "+"), "ERR min or max not valid string range item")
+	assertErrReply(t, execCmd(db, "zrange", "z", "-", "+", "bylex", "withscores"),
+		"ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+}
+
+func TestZLexCount(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "0", "a", "0", "b", "0", "c", "0", "d", "0", "e")
+	assertIntReply(t, execCmd(db, "zlexcount", "z", "-", "+"), 5)
+	assertIntReply(t, execCmd(db, "zlexcount", "z", "(a", "[c"), 2)
+	assertIntReply(t, execCmd(db, "zlexcount", "z", "[x", "+"), 0)
+	assertIntReply(t, execCmd(db, "zlexcount", "missing", "-", "+"), 0)
+	assertErrReply(t, execCmd(db, "zlexcount", "z", "-", "c"), "ERR min or max not valid string range item")
+
+	assertIntReply(t, execCmd(db, "zremrangebylex", "z", "(a", "[c"), 2)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z", "0", "-1"), "a", "d", "e")
+	assertIntReply(t, execCmd(db, "zremrangebylex", "z", "[x", "+"), 0)
+	assertIntReply(t, execCmd(db, "zremrangebylex", "z", "-", "+"), 3)
+	assertIntReply(t, execCmd(db, "exists", "z"), 0)
+	assertErrReply(t, execCmd(db, "zremrangebylex", "z", "-", "c"), "ERR min or max not valid string range item")
+}
+
+func TestZUnion(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z1", "1", "a", "2", "b")
+	execCmd(db, "zadd", "z2", "3", "b", "4", "c")
+	execCmd(db, "sadd", "s", "c", "d")
+	assertMultiBulkReply(t, execCmd(db, "zunion", "2", "z1", "z2", "withscores"), "a", "1", "c", "4", "b", "5")
+	assertMultiBulkReply(t, execCmd(db, "zunion", "3", "z1", "z2", "missing"), "a", "c", "b")
+	assertMultiBulkReply(t, execCmd(db, "zunion", "2", "z1", "z2", "weights", "2", "-1", "withscores"),
+		"c", "-4", "b", "1", "a", "2")
+	assertMultiBulkReply(t, execCmd(db, "zunion", "2", "z1", "z2", "aggregate", "min", "withscores"),
+		"a", "1", "b", "2", "c", "4")
+	assertMultiBulkReply(t, execCmd(db, "zunion", "2", "z2", "s", "aggregate", "max", "withscores"),
+		"d", "1", "b", "3", "c", "4")
+	assertMultiBulkReply(t, execCmd(db, "zunion", "1", "missing"))
+
+	assertIntReply(t, execCmd(db, "zunionstore", "dest", "2", "z1", "z2", "weights", "1", "2"), 3)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "dest", "0", "-1", "withscores"), "a", "1", "b", "8", "c", "8")
+	assertIntReply(t, execCmd(db, "zunionstore", "z1", "2", "z1", "s"), 4)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z1", "0", "-1", "withscores"), "a", "1", "c", "1", "d", "1", "b", "2")
+
+	assertErrReply(t, execCmd(db, "zunion", "0", "z1"), "ERR numkeys should be greater than 0")
+	assertErrReply(t, execCmd(db, "zunion", "3", "z1", "z2"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zunion", "2", "z1", "z2", "weights", "1"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zunion", "2", "z1", "z2", "weights", "1", "x"), "ERR weight value is not a float")
+	assertErrReply(t, execCmd(db, "zunion", "2", "z1", "z2", "aggregate", "avg"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zunionstore", "dest", "2", "z1", "z2", "withscores"), "Err syntax error")
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "zunion", "2", "z1", "str"), wrongTypeErr)
+}
+
+func TestZInter(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z1", "1", "a", "2", "b", "3", "c")
+	execCmd(db, "zadd", "z2", "3", "b", "4", "c", "5", "d")
+	execCmd(db, "sadd", "s", "c", "d")
+	assertMultiBulkReply(t, execCmd(db, "zinter", "2", "z1", "z2", "withscores"), "b", "5", "c", "7")
+	assertMultiBulkReply(t, execCmd(db, "zinter", "3", "z1", "z2", "s", "aggregate", "max", "withscores"), "c", "4")
+	assertMultiBulkReply(t, execCmd(db, "zinter", "2", "z1", "z2", "weights", "0", "1", "aggregate", "min"), "b", "c")
+	assertMultiBulkReply(t, execCmd(db, "zinter", "2", "z1", "missing"))
+
+	assertIntReply(t, execCmd(db, "zinterstore", "dest", "2", "z1", "z2"), 2)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "dest", "0", "-1", "withscores"), "b", "5", "c", "7")
+	// destination is removed if the result is empty, even if it's not a sorted set
+	execCmd(db, "set", "str", "v")
+	assertIntReply(t, execCmd(db, "zinterstore", "str", "2", "z1", "missing"), 0)
+	assertIntReply(t, execCmd(db, "exists", "str"), 0)
+}
+
+func TestZDiff(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z1", "1", "a", "2", "b", "3", "c")
+	execCmd(db, "zadd", "z2", "3", "b")
+	execCmd(db, "sadd", "s", "c")
+	assertMultiBulkReply(t, execCmd(db, "zdiff", "2", "z1", "z2", "withscores"), "a", "1", "c", "3")
+	assertMultiBulkReply(t, execCmd(db, "zdiff", "3", "z1", "z2", "s"), "a")
+	assertMultiBulkReply(t, execCmd(db, "zdiff", "2", "missing", "z1"))
+	assertIntReply(t, execCmd(db, "zdiffstore", "dest", "2", "z1", "missing"), 3)
+	assertMultiBulkReply(t, execCmd(db, "zrange", "dest", "0", "-1", "withscores"), "a", "1", "b", "2", "c", "3")
+	assertErrReply(t, execCmd(db, "zdiff", "2", "z1", "z2", "weights", "1", "1"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zdiff", "2", "z1", "z2", "aggregate", "sum"), "Err syntax error")
+}
+
+func TestZInterCard(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z1", "1", "a", "2", "b", "3", "c", "4", "d")
+	execCmd(db, "zadd", "z2", "1", "b", "1", "c", "1", "d", "1", "e")
+	assertIntReply(t, execCmd(db, "zintercard", "2", "z1", "z2"), 3)
+	assertIntReply(t, execCmd(db, "zintercard", "2", "z1", "z2", "limit", "2"), 2)
+	assertIntReply(t, execCmd(db, "zintercard", "2", "z1", "z2", "limit", "0"), 3)
+	assertIntReply(t, execCmd(db, "zintercard", "2", "z1", "missing"), 0)
+	assertErrReply(t, execCmd(db, "zintercard", "0", "z1"), "ERR numkeys should be greater than 0")
+	assertErrReply(t, execCmd(db, "zintercard", "2", "z1", "z2", "limit", "-1"), "ERR LIMIT can't be negative")
+	assertErrReply(t, execCmd(db, "zintercard", "2", "z1", "z2", "limit"), "Err syntax error")
+}
+
+func TestZPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z", "1", "a", "2", "b", "3", "c", "4", "d")
+	assertMultiBulkReply(t, execCmd(db, "zpopmin", "z"), "a", "1")
+	assertMultiBulkReply(t, execCmd(db, "zpopmax", "z", "2"), "d", "4", "c", "3")
+	assertMultiBulkReply(t, execCmd(db, "zpopmin", "z", "0"))
+	assertMultiBulkReply(t, execCmd(db, "zpopmin", "z", "10"), "b", "2")
+	assertIntReply(t, execCmd(db, "exists", "z"), 0)
+	assertMultiBulkReply(t, execCmd(db, "zpopmax", "z"))
+	assertErrReply(t, execCmd(db, "zpopmin", "z", "-1"), "ERR value is out of range, must be positive")
+	assertErrReply(t, execCmd(db, "zpopmin", "z", "1", "2"), "Err syntax error")
+}
+
+func TestZMPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z2", "1", "a", "2", "b", "3", "c")
+	assertReply(t, execCmd(db, "zmpop", "2", "z1", "z2", "min"),
+		"*2\r\n$2\r\nz2\r\n*1\r\n*2\r\n$1\r\na\r\n$1\r\n1\r\n")
+	assertReply(t, execCmd(db, "zmpop", "1", "z2", "max", "count", "10"),
+		"*2\r\n$2\r\nz2\r\n*2\r\n*2\r\n$1\r\nc\r\n$1\r\n3\r\n*2\r\n$1\r\nb\r\n$1\r\n2\r\n")
+	assertIntReply(t, execCmd(db, "exists", "z2"), 0)
+	assertReply(t, execCmd(db, "zmpop", "2", "z1", "z2", "min"), "*-1\r\n")
+
+	assertErrReply(t, execCmd(db, "zmpop", "2", "z1", "z2"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zmpop", "1", "z1", "left"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zmpop", "1", "z1", "min", "count", "0"), "ERR count should be greater than 0")
+	assertErrReply(t, execCmd(db, "zmpop", "1", "z1", "min", "limit", "1"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "zmpop", "0", "z1", "min"), "ERR numkeys should be greater than 0")
+}
+
+func TestBZPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z2", "1", "a", "2", "b")
+	// served immediately by the first non-empty sorted set
+	assertMultiBulkReply(t, execCmd(db, "bzpopmin", "z1", "z2", "0"), "z2", "a", "1")
+	assertMultiBulkReply(t, execCmd(db, "bzpopmax", "z1", "z2", "0"), "z2", "b", "2")
+	assertIntReply(t, execCmd(db, "exists", "z2"), 0)
+
+	ch := execAsync(db, connection.NewFakeConn(), "bzpopmax", "z1", "z2", "0")
+	waitBlocked(t, db, 1)
+	execCmd(db, "zadd", "z2", "1", "x", "5", "y")
+	assertMultiBulkReply(t, receive(t, ch), "z2", "y", "5")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z2", "0", "-1"), "x")
+	waitBlocked(t, db, 0)
+
+	// updating scores of existing members doesn't serve blocked clients
+	ch = execAsync(db, connection.NewFakeConn(), "bzpopmin", "z1", "0")
+	waitBlocked(t, db, 1)
+	execCmd(db, "zincrby", "z1", "2", "m")
+	assertMultiBulkReply(t, receive(t, ch), "z1", "m", "2")
+
+	assertReply(t, execCmd(db, "bzpopmin", "z1", "0.05"), "*-1\r\n")
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "bzpopmin", "str", "0"), wrongTypeErr)
+	assertErrReply(t, execCmd(db, "bzpopmin", "z1", "-1"), "ERR timeout is negative")
+}
+
+func TestBZMPop(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "zadd", "z2", "1", "a", "2", "b")
+	assertReply(t, execCmd(db, "bzmpop", "0", "2", "z1", "z2", "max"),
+		"*2\r\n$2\r\nz2\r\n*1\r\n*2\r\n$1\r\nb\r\n$1\r\n2\r\n")
+
+	ch := execAsync(db, connection.NewFakeConn(), "bzmpop", "0", "1", "z1", "min", "count", "2")
+	waitBlocked(t, db, 1)
+	execCmd(db, "zadd", "z1", "3", "x", "1", "y", "2", "z")
+	assertReply(t, receive(t, ch),
+		"*2\r\n$2\r\nz1\r\n*2\r\n*2\r\n$1\r\ny\r\n$1\r\n1\r\n*2\r\n$1\r\nz\r\n$1\r\n2\r\n")
+	assertMultiBulkReply(t, execCmd(db, "zrange", "z1", "0", "-1"), "x")
+
+	assertReply(t, execCmd(db, "bzmpop", "0.05", "1", "z3", "min"), "*-1\r\n")
+	assertErrReply(t, execCmd(db, "bzmpop", "0", "2", "z1", "z2"), "Err syntax error")
+}
@@ -0,0 +1,1214 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/datastruct/stream"
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// approxTrimLimit is the default LIMIT of trimming with `~`, the same as redis with stream-node-max-entries 100
+const approxTrimLimit = 100 * 100
+
+func (db *DB) getAsStream(key string) (*stream.Stream, protocol.ErrorReply) {
+	entity, exists := db.GetEntity(key)
+	if !exists {
+		return nil, nil
+	}
+	s, ok := entity.Data.(*stream.Stream)
+	if !ok {
+		return nil, &protocol.WrongTypeErrReply{}
+	}
+	return s, nil
+}
+
+func (db *DB) initStream(key string) *stream.Stream {
+	s := stream.New()
+	db.PutEntity(key, &database.DataEntity{
+		Data: s,
+	})
+	return s
+}
+
+func nowMillis() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func invalidStreamIDErr() protocol.ErrorReply {
+	return protocol.NewErrReply("ERR Invalid stream ID specified as stream command argument")
+}
+
+// parseStreamID parses a complete ID, sequence number is 0 if omitted
+func parseStreamID(arg []byte) (stream.ID, protocol.ErrorReply) {
+	id, _, err := stream.ParseID(string(arg))
+	if err != nil {
+		return stream.ID{}, invalidStreamIDErr()
+	}
+	return id, nil
+}
+
+// parseStreamIDs parses all args as IDs
+func parseStreamIDs(args [][]byte) ([]stream.ID, protocol.ErrorReply) {
+	ids := make([]stream.ID, len(args))
+	for i, arg := range args {
+		id, errReply := parseStreamID(arg)
+		if errReply != nil {
+			return nil, errReply
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// parseRangeID parses start or end of range, it supports `-`, `+`, exclusive ID such as `(1-1`
+// and incomplete ID which sequence number is 0 as start or max as end
+func parseRangeID(arg []byte, isEnd bool) (stream.ID, protocol.ErrorReply) {
+	s := string(arg)
+	switch s {
+	case "-":
+		return stream.MinID, nil
+	case "+":
+		return stream.MaxID, nil
+	}
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	id, seqGiven, err := stream.ParseID(s)
+	if err != nil {
+		return stream.ID{}, invalidStreamIDErr()
+	}
+	if !seqGiven && isEnd {
+		id.Seq = math.MaxUint64
+	}
+	if !exclusive {
+		return id, nil
+	}
+	var ok bool
+	if isEnd {
+		id, ok = id.Decr()
+		if !ok {
+			return stream.ID{}, protocol.NewErrReply("ERR invalid end ID for the interval")
+		}
+	} else {
+		id, ok = id.Incr()
+		if !ok {
+			return stream.ID{}, protocol.NewErrReply("ERR invalid start ID for the interval")
+		}
+	}
+	return id, nil
+}
+
+func entryToReply(entry *stream.Entry) redis.Reply {
+	return protocol.NewMultiRawReply([]redis.Reply{
+		protocol.NewBulkReply([]byte(entry.ID.String())),
+		protocol.NewMultiBulkReply(entry.Fields),
+	})
+}
+
+func entriesToReply(entries []*stream.Entry) redis.Reply {
+	result := make([]redis.Reply, len(entries))
+	for i, entry := range entries {
+		result[i] = entryToReply(entry)
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+const (
+	trimNone = iota
+	trimByMaxLen
+	trimByMinID
+)
+
+type streamTrimOption struct {
+	strategy int
+	maxLen   int64
+	minID    stream.ID
+	approx   bool
+	// limit is the max number of entries to remove, 0 means no limit
+	limit int64
+}
+
+// trim removes entries from stream by the option, returns the number of removed entries
+func (opt *streamTrimOption) trim(s *stream.Stream) int64 {
+	switch opt.strategy {
+	case trimByMaxLen:
+		return s.TrimByLen(opt.maxLen, opt.limit)
+	case trimByMinID:
+		return s.TrimByMinID(opt.minID, opt.limit)
+	}
+	return 0
+}
+
+// parseStreamTrimArgs parses trim options of XADD and XTRIM from args[i:]
+// NOMKSTREAM is accepted for XADD, and it returns the index of the first unknown argument which is the ID of XADD
+func parseStreamTrimArgs(args [][]byte, i int, xadd bool) (opt *streamTrimOption, noMkStream bool, next int, errReply protocol.ErrorReply) {
+	opt = &streamTrimOption{}
+	limitGiven := false
+parseArgs:
+	for ; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case xadd && arg == "NOMKSTREAM":
+			noMkStream = true
+		case (arg == "MAXLEN" || arg == "MINID") && i+1 < len(args):
+			if opt.strategy != trimNone {
+				return nil, false, 0, protocol.NewErrReply("ERR syntax error, MAXLEN and MINID options at the same time are not compatible")
+			}
+			i++
+			switch string(args[i]) {
+			case "~":
+				opt.approx = true
+				i++
+			case "=":
+				i++
+			}
+			if i >= len(args) {
+				return nil, false, 0, protocol.NewSyntaxErrReply()
+			}
+			if arg == "MAXLEN" {
+				maxLen, err := strconv.ParseInt(string(args[i]), 10, 64)
+				if err != nil {
+					return nil, false, 0, protocol.NewErrReply("ERR value is not an integer or out of range")
+				}
+				if maxLen < 0 {
+					return nil, false, 0, protocol.NewErrReply("ERR The MAXLEN argument must be >= 0.")
+				}
+				opt.strategy, opt.maxLen = trimByMaxLen, maxLen
+			} else {
+				minID, errReply := parseStreamID(args[i])
+				if errReply != nil {
+					return nil, false, 0, errReply
+				}
+				opt.strategy, opt.minID = trimByMinID, minID
+			}
+		case arg == "LIMIT" && i+1 < len(args):
+			limit, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, false, 0, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			if limit < 0 {
+				return nil, false, 0, protocol.NewErrReply("ERR The LIMIT argument must be >= 0.")
+			}
+			opt.limit = limit
+			limitGiven = true
+			i++
+		default:
+			if xadd {
+				break parseArgs
+			}
+			return nil, false, 0, protocol.NewSyntaxErrReply()
+		}
+	}
+	if limitGiven && !opt.approx {
+		return nil, false, 0, protocol.NewErrReply("ERR syntax error, LIMIT cannot be used without the special ~ option")
+	}
+	if opt.approx && !limitGiven {
+		opt.limit = approxTrimLimit
+	}
+	return opt, noMkStream, i, nil
+}
+
+// execXAdd appends an entry into stream
+// XADD key [NOMKSTREAM] [<MAXLEN | MINID> [= | ~] threshold [LIMIT count]] <* | id> field value [field value ...]
+func execXAdd(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	trimOpt, noMkStream, idIndex, errReply := parseStreamTrimArgs(args, 1, true)
+	if errReply != nil {
+		return errReply
+	}
+	if idIndex >= len(args) {
+		return protocol.NewArgNumErrReply("xadd")
+	}
+	fields := args[idIndex+1:]
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return protocol.NewArgNumErrReply("xadd")
+	}
+
+	// ID is `*`, `ms-*` or explicit
+	idArg := string(args[idIndex])
+	var id stream.ID
+	autoID, autoSeq := idArg == "*", false
+	if !autoID {
+		var err error
+		if strings.HasSuffix(idArg, "-*") {
+			autoSeq = true
+			id.Ms, err = strconv.ParseUint(strings.TrimSuffix(idArg, "-*"), 10, 64)
+		} else {
+			id, _, err = stream.ParseID(idArg)
+		}
+		if err != nil {
+			return invalidStreamIDErr()
+		}
+		if !autoSeq && id == stream.MinID {
+			return protocol.NewErrReply("ERR The ID specified in XADD must be greater than 0-0")
+		}
+	}
+
+	s, errReply := db.getAsStream(key)
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil {
+		if noMkStream {
+			return protocol.NewNullBulkReply()
+		}
+		s = db.initStream(key)
+	}
+	lastID := s.LastID()
+	switch {
+	case autoID:
+		var ok bool
+		id, ok = s.NextID(uint64(nowMillis()))
+		if !ok {
+			return protocol.NewErrReply("ERR The stream has exhausted the last possible ID, unable to add more items")
+		}
+	case autoSeq && id.Ms == lastID.Ms:
+		if lastID.Seq == math.MaxUint64 {
+			return protocol.NewErrReply("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+		}
+		id.Seq = lastID.Seq + 1
+	}
+	if !lastID.Less(id) {
+		return protocol.NewErrReply("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	}
+	s.Add(id, fields)
+	trimOpt.trim(s)
+
+	idBytes := []byte(id.String())
+	aofArgs := make([][]byte, len(args))
+	copy(aofArgs, args)
+	aofArgs[idIndex] = idBytes
+	db.addAof(utils.ToCmdLine3("xadd", aofArgs...))
+	db.signalKeyReady(key)
+	return protocol.NewBulkReply(idBytes)
+}
+
+// execXLen returns the number of entries
+func execXLen(db *DB, args [][]byte) redis.Reply {
+	s, errReply := db.getAsStream(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil {
+		return protocol.NewIntReply(0)
+	}
+	return protocol.NewIntReply(s.Len())
+}
+
+func xrangeGeneric(db *DB, args [][]byte, desc bool) redis.Reply {
+	startArg, endArg := args[1], args[2]
+	if desc {
+		startArg, endArg = endArg, startArg
+	}
+	start, errReply := parseRangeID(startArg, false)
+	if errReply != nil {
+		return errReply
+	}
+	end, errReply := parseRangeID(endArg, true)
+	if errReply != nil {
+		return errReply
+	}
+	count := int64(-1)
+	if len(args) > 3 {
+		if len(args) != 5 || strings.ToUpper(string(args[3])) != "COUNT" {
+			return protocol.NewSyntaxErrReply()
+		}
+		var err error
+		count, err = strconv.ParseInt(string(args[4]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		if count <= 0 {
+			return protocol.NewEmptyMultiBulkReply()
+		}
+	}
+	s, errReply := db.getAsStream(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil {
+		return protocol.NewEmptyMultiBulkReply()
+	}
+	return entriesToReply(s.Range(start, end, count, desc))
+}
+
+// execXRange returns entries within the given range
+// XRANGE key start end [COUNT count]
+func execXRange(db *DB, args [][]byte) redis.Reply {
+	return xrangeGeneric(db, args, false)
+}
+
+// execXRevRange returns entries within the given range in reverse order
+// XREVRANGE key end start [COUNT count]
+func execXRevRange(db *DB, args [][]byte) redis.Reply {
+	return xrangeGeneric(db, args, true)
+}
+
+// execXDel removes entries, returns the number of removed entries
+func execXDel(db *DB, args [][]byte) redis.Reply {
+	ids, errReply := parseStreamIDs(args[1:])
+	if errReply != nil {
+		return errReply
+	}
+	s, errReply := db.getAsStream(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil {
+		return protocol.NewIntReply(0)
+	}
+	deleted := int64(0)
+	for _, id := range ids {
+		if s.Delete(id) {
+			deleted++
+		}
+	}
+	if deleted > 0 {
+		db.addAof(utils.ToCmdLine3("xdel", args...))
+	}
+	return protocol.NewIntReply(deleted)
+}
+
+// execXTrim removes the oldest entries, returns the number of removed entries
+// XTRIM key <MAXLEN | MINID> [= | ~] threshold [LIMIT count]
+func execXTrim(db *DB, args [][]byte) redis.Reply {
+	trimOpt, _, _, errReply := parseStreamTrimArgs(args, 1, false)
+	if errReply != nil {
+		return errReply
+	}
+	if trimOpt.strategy == trimNone {
+		return protocol.NewErrReply("ERR syntax error, XTRIM must be called with a trimming strategy")
+	}
+	s, errReply := db.getAsStream(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil {
+		return protocol.NewIntReply(0)
+	}
+	removed := trimOpt.trim(s)
+	if removed > 0 {
+		db.addAof(utils.ToCmdLine3("xtrim", args...))
+	}
+	return protocol.NewIntReply(removed)
+}
+
+/* ---- consumer group ---- */
+
+func noGroupErr(key string, group string) protocol.ErrorReply {
+	return protocol.NewErrReply("NOGROUP No such key '" + key + "' or consumer group '" + group + "'")
+}
+
+// getStreamGroup returns the stream and the consumer group, NOGROUP error if either not exists
+func (db *DB) getStreamGroup(key string, groupName string) (*stream.Stream, *stream.Group, protocol.ErrorReply) {
+	s, errReply := db.getAsStream(key)
+	if errReply != nil {
+		return nil, nil, errReply
+	}
+	if s == nil {
+		return nil, nil, noGroupErr(key, groupName)
+	}
+	group, ok := s.GetGroup(groupName)
+	if !ok {
+		return nil, nil, noGroupErr(key, groupName)
+	}
+	return s, group, nil
+}
+
+// parseGroupLastID parses the ID to start delivering of consumer group, `$` means the last ID of stream
+func parseGroupLastID(s *stream.Stream, arg []byte) (stream.ID, protocol.ErrorReply) {
+	if string(arg) == "$" {
+		if s == nil {
+			return stream.MinID, nil
+		}
+		return s.LastID(), nil
+	}
+	return parseStreamID(arg)
+}
+
+// execXGroup manages consumer groups
+// XGROUP CREATE key group <id | $> [MKSTREAM]
+// XGROUP SETID key group <id | $>
+// XGROUP DESTROY key group
+// XGROUP CREATECONSUMER key group consumer
+// XGROUP DELCONSUMER key group consumer
+func execXGroup(db *DB, args [][]byte) redis.Reply {
+	subCmd := strings.ToLower(string(args[0]))
+	argNum := map[string][2]int{
+		"create":         {4, 5},
+		"setid":          {4, 4},
+		"destroy":        {3, 3},
+		"createconsumer": {4, 4},
+		"delconsumer":    {4, 4},
+	}
+	limits, ok := argNum[subCmd]
+	if !ok {
+		return protocol.NewErrReply("ERR unknown subcommand '" + string(args[0]) + "'. Try XGROUP HELP.")
+	}
+	if len(args) < limits[0] || len(args) > limits[1] {
+		return protocol.NewArgNumErrReply("xgroup|" + subCmd)
+	}
+	key, groupName := string(args[1]), string(args[2])
+	mkStream := false
+	if subCmd == "create" && len(args) == 5 {
+		if strings.ToUpper(string(args[4])) != "MKSTREAM" {
+			return protocol.NewSyntaxErrReply()
+		}
+		mkStream = true
+	}
+	s, errReply := db.getAsStream(key)
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil && !mkStream {
+		return protocol.NewErrReply("ERR The XGROUP subcommand requires the key to exist. " +
+			"Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	}
+	if subCmd == "create" {
+		lastID, errReply := parseGroupLastID(s, args[3])
+		if errReply != nil {
+			return errReply
+		}
+		if s == nil {
+			s = db.initStream(key)
+		}
+		if _, ok := s.CreateGroup(groupName, lastID); !ok {
+			return protocol.NewErrReply("BUSYGROUP Consumer Group name already exists")
+		}
+		aofArgs := [][]byte{args[0], args[1], args[2], []byte(lastID.String())}
+		db.addAof(utils.ToCmdLine3("xgroup", append(aofArgs, args[4:]...)...))
+		return protocol.NewOkReply()
+	}
+
+	group, ok := s.GetGroup(groupName)
+	if !ok {
+		if subCmd == "destroy" {
+			return protocol.NewIntReply(0)
+		}
+		return protocol.NewErrReply("NOGROUP No such consumer group '" + groupName + "' for key name '" + key + "'")
+	}
+	switch subCmd {
+	case "setid":
+		lastID, errReply := parseGroupLastID(s, args[3])
+		if errReply != nil {
+			return errReply
+		}
+		group.LastID = lastID
+		db.addAof(utils.ToCmdLine3("xgroup", args[0], args[1], args[2], []byte(lastID.String())))
+		return protocol.NewOkReply()
+	case "destroy":
+		s.DestroyGroup(groupName)
+		db.addAof(utils.ToCmdLine3("xgroup", args...))
+		// wake up clients blocked by XREADGROUP of this group
+		db.signalKeyReady(key)
+		return protocol.NewIntReply(1)
+	case "createconsumer":
+		_, created := group.CreateConsumer(string(args[3]), nowMillis())
+		if !created {
+			return protocol.NewIntReply(0)
+		}
+		db.addAof(utils.ToCmdLine3("xgroup", args...))
+		return protocol.NewIntReply(1)
+	default: // delconsumer
+		pending, existed := group.DeleteConsumer(string(args[3]))
+		if existed {
+			db.addAof(utils.ToCmdLine3("xgroup", args...))
+		}
+		return protocol.NewIntReply(pending)
+	}
+}
+
+// makeXClaimCmd generates command line to replicate delivering an entry to consumer
+func makeXClaimCmd(key string, group *stream.Group, consumer string, pending *stream.PendingEntry) CmdLine {
+	return utils.ToCmdLine("xclaim", key, group.Name, consumer, "0", pending.ID.String(),
+		"TIME", strconv.FormatInt(pending.DeliveryTime, 10),
+		"RETRYCOUNT", strconv.FormatUint(pending.DeliveryCount, 10),
+		"FORCE", "JUSTID", "LASTID", group.LastID.String())
+}
+
+// execXAck acknowledges pending entries of consumer group, returns the number of acknowledged entries
+// XACK key group id [id ...]
+func execXAck(db *DB, args [][]byte) redis.Reply {
+	ids, errReply := parseStreamIDs(args[2:])
+	if errReply != nil {
+		return errReply
+	}
+	s, errReply := db.getAsStream(string(args[0]))
+	if errReply != nil {
+		return errReply
+	}
+	if s == nil {
+		return protocol.NewIntReply(0)
+	}
+	group, ok := s.GetGroup(string(args[1]))
+	if !ok {
+		return protocol.NewIntReply(0)
+	}
+	acked := int64(0)
+	for _, id := range ids {
+		if group.Ack(id) {
+			acked++
+		}
+	}
+	if acked > 0 {
+		db.addAof(utils.ToCmdLine3("xack", args...))
+	}
+	return protocol.NewIntReply(acked)
+}
+
+// execXPending returns pending entries of consumer group
+// XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+func execXPending(db *DB, args [][]byte) redis.Reply {
+	key, groupName := string(args[0]), string(args[1])
+	minIdle := int64(0)
+	extended := args[2:]
+	if len(extended) >= 2 && strings.ToUpper(string(extended[0])) == "IDLE" {
+		var err error
+		minIdle, err = strconv.ParseInt(string(extended[1]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+		extended = extended[2:]
+		if len(extended) == 0 {
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+	if len(extended) != 0 && len(extended) != 3 && len(extended) != 4 {
+		return protocol.NewSyntaxErrReply()
+	}
+	var start, end stream.ID
+	var count int64
+	if len(extended) > 0 {
+		var errReply protocol.ErrorReply
+		start, errReply = parseRangeID(extended[0], false)
+		if errReply != nil {
+			return errReply
+		}
+		end, errReply = parseRangeID(extended[1], true)
+		if errReply != nil {
+			return errReply
+		}
+		var err error
+		count, err = strconv.ParseInt(string(extended[2]), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+	}
+
+	_, group, errReply := db.getStreamGroup(key, groupName)
+	if errReply != nil {
+		return errReply
+	}
+	if len(extended) == 0 {
+		// summary form
+		if group.PendingLen() == 0 {
+			return protocol.NewMultiRawReply([]redis.Reply{
+				protocol.NewIntReply(0),
+				protocol.NewNullBulkReply(),
+				protocol.NewNullBulkReply(),
+				protocol.NewNullMultiBulkReply(),
+			})
+		}
+		var first, last stream.ID
+		group.ForEachPending(stream.MinID, stream.MaxID, func(pending *stream.PendingEntry) bool {
+			first = pending.ID
+			return false
+		})
+		group.ForEachPendingDesc(func(pending *stream.PendingEntry) bool {
+			last = pending.ID
+			return false
+		})
+		consumers := make([]redis.Reply, 0)
+		for _, consumer := range group.Consumers() {
+			if consumer.PendingLen() == 0 {
+				continue
+			}
+			consumers = append(consumers, protocol.NewMultiBulkReply([][]byte{
+				[]byte(consumer.Name),
+				[]byte(strconv.FormatInt(consumer.PendingLen(), 10)),
+			}))
+		}
+		return protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewIntReply(group.PendingLen()),
+			protocol.NewBulkReply([]byte(first.String())),
+			protocol.NewBulkReply([]byte(last.String())),
+			protocol.NewMultiRawReply(consumers),
+		})
+	}
+
+	result := make([]redis.Reply, 0)
+	if count <= 0 {
+		return protocol.NewMultiRawReply(result)
+	}
+	now := nowMillis()
+	consumer := func(pending *stream.PendingEntry) bool {
+		idle := now - pending.DeliveryTime
+		if idle < minIdle {
+			return true
+		}
+		result = append(result, protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewBulkReply([]byte(pending.ID.String())),
+			protocol.NewBulkReply([]byte(pending.Consumer.Name)),
+			protocol.NewIntReply(idle),
+			protocol.NewIntReply(int64(pending.DeliveryCount)),
+		}))
+		return int64(len(result)) < count
+	}
+	if len(extended) == 4 {
+		c, ok := group.GetConsumer(string(extended[3]))
+		if ok {
+			c.ForEachPending(start, end, consumer)
+		}
+	} else {
+		group.ForEachPending(start, end, consumer)
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+// execXClaim changes the owner of pending entries which idle at least min-idle-time
+// XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME unix-time-milliseconds]
+// [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID lastid]
+func execXClaim(db *DB, args [][]byte) redis.Reply {
+	key, groupName, consumerName := string(args[0]), string(args[1]), string(args[2])
+	minIdle, err := strconv.ParseInt(string(args[3]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR Invalid min-idle-time argument for XCLAIM")
+	}
+	// IDs are followed by options
+	i := 4
+	ids := make([]stream.ID, 0)
+	for ; i < len(args); i++ {
+		id, _, err := stream.ParseID(string(args[i]))
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+	now := nowMillis()
+	deliveryTime := now
+	retryCount := int64(-1)
+	force, justID := false, false
+	var lastID *stream.ID
+	for ; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case arg == "FORCE":
+			force = true
+		case arg == "JUSTID":
+			justID = true
+		case (arg == "IDLE" || arg == "TIME" || arg == "RETRYCOUNT") && i+1 < len(args):
+			val, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return protocol.NewErrReply("ERR Invalid " + arg + " option argument for XCLAIM")
+			}
+			switch arg {
+			case "IDLE":
+				deliveryTime = now - val
+			case "TIME":
+				deliveryTime = val
+			default:
+				retryCount = val
+			}
+			i++
+		case arg == "LASTID" && i+1 < len(args):
+			id, errReply := parseStreamID(args[i+1])
+			if errReply != nil {
+				return errReply
+			}
+			lastID = &id
+			i++
+		default:
+			return protocol.NewErrReply("ERR Unrecognized XCLAIM option '" + string(args[i]) + "'")
+		}
+	}
+	if deliveryTime < 0 || deliveryTime > now {
+		deliveryTime = now
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+
+	s, group, errReply := db.getStreamGroup(key, groupName)
+	if errReply != nil {
+		return errReply
+	}
+	lastIDChanged := false
+	if lastID != nil && group.LastID.Less(*lastID) {
+		group.LastID = *lastID
+		lastIDChanged = true
+	}
+	consumer, _ := group.CreateConsumer(consumerName, now)
+	consumer.SeenTime = now
+
+	result := make([]redis.Reply, 0, len(ids))
+	propagated := false
+	for _, id := range ids {
+		entry, entryExists := s.Get(id)
+		pending, pendingExists := group.GetPending(id)
+		if !pendingExists {
+			if !force || !entryExists {
+				continue
+			}
+		} else {
+			if !entryExists {
+				// entry has been deleted, remove it from pending entries list
+				group.Ack(id)
+				db.addAof(utils.ToCmdLine("xack", key, groupName, id.String()))
+				propagated = true
+				continue
+			}
+			if minIdle > 0 && now-pending.DeliveryTime < minIdle {
+				continue
+			}
+		}
+		pending = group.Deliver(id, consumer)
+		pending.DeliveryTime = deliveryTime
+		if retryCount >= 0 {
+			pending.DeliveryCount = uint64(retryCount)
+		} else if !justID {
+			pending.DeliveryCount++
+		}
+		if justID {
+			result = append(result, protocol.NewBulkReply([]byte(id.String())))
+		} else {
+			result = append(result, entryToReply(entry))
+		}
+		db.addAof(makeXClaimCmd(key, group, consumerName, pending))
+		propagated = true
+	}
+	if lastIDChanged && !propagated {
+		db.addAof(utils.ToCmdLine("xgroup", "SETID", key, groupName, group.LastID.String()))
+	}
+	return protocol.NewMultiRawReply(result)
+}
+
+// execXAutoClaim scans pending entries from start, and claims those idle at least min-idle-time
+// XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+func execXAutoClaim(db *DB, args [][]byte) redis.Reply {
+	key, groupName, consumerName := string(args[0]), string(args[1]), string(args[2])
+	minIdle, err := strconv.ParseInt(string(args[3]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	if minIdle < 0 {
+		minIdle = 0
+	}
+	start, errReply := parseRangeID(args[4], false)
+	if errReply != nil {
+		return errReply
+	}
+	count := int64(100)
+	justID := false
+	for i := 5; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case arg == "JUSTID":
+			justID = true
+		case arg == "COUNT" && i+1 < len(args):
+			count, err = strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			// at most count*10 pending entries are scanned
+			if count <= 0 || count > math.MaxInt64/10 {
+				return protocol.NewErrReply("ERR COUNT must be > 0")
+			}
+			i++
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+
+	s, group, errReply := db.getStreamGroup(key, groupName)
+	if errReply != nil {
+		return errReply
+	}
+	now := nowMillis()
+	consumer, _ := group.CreateConsumer(consumerName, now)
+	consumer.SeenTime = now
+
+	// collect first, the pending entries list can't be modified while scanning
+	attempts := count * 10
+	next := stream.MinID
+	claimed := make([]*stream.Entry, 0)
+	deleted := make([]stream.ID, 0)
+	group.ForEachPending(start, stream.MaxID, func(pending *stream.PendingEntry) bool {
+		if int64(len(claimed)) >= count || attempts == 0 {
+			next = pending.ID
+			return false
+		}
+		attempts--
+		entry, ok := s.Get(pending.ID)
+		if !ok {
+			deleted = append(deleted, pending.ID)
+			return true
+		}
+		if minIdle > 0 && now-pending.DeliveryTime < minIdle {
+			return true
+		}
+		claimed = append(claimed, entry)
+		return true
+	})
+
+	claimedReplies := make([]redis.Reply, len(claimed))
+	for i, entry := range claimed {
+		pending := group.Deliver(entry.ID, consumer)
+		pending.DeliveryTime = now
+		if !justID {
+			pending.DeliveryCount++
+		}
+		if justID {
+			claimedReplies[i] = protocol.NewBulkReply([]byte(entry.ID.String()))
+		} else {
+			claimedReplies[i] = entryToReply(entry)
+		}
+		db.addAof(makeXClaimCmd(key, group, consumerName, pending))
+	}
+	deletedIDs := make([][]byte, len(deleted))
+	for i, id := range deleted {
+		group.Ack(id)
+		deletedIDs[i] = []byte(id.String())
+	}
+	if len(deleted) > 0 {
+		db.addAof(utils.ToCmdLine3("xack", append([][]byte{args[0], args[1]}, deletedIDs...)...))
+	}
+	return protocol.NewMultiRawReply([]redis.Reply{
+		protocol.NewBulkReply([]byte(next.String())),
+		protocol.NewMultiRawReply(claimedReplies),
+		protocol.NewMultiBulkReply(deletedIDs),
+	})
+}
+
+/* ---- read ---- */
+
+type xreadOption struct {
+	count    int64
+	block    bool
+	timeout  time.Duration
+	noAck    bool
+	group    string
+	consumer string
+	// streamsIndex is the index of the first key in args
+	streamsIndex int
+	keys         [][]byte
+	ids          [][]byte
+}
+
+// findStreamsIndex returns the index of the first key after STREAMS, -1 if not found
+func findStreamsIndex(args [][]byte) int {
+	for i := 0; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "STREAMS":
+			return i + 1
+		case "COUNT", "BLOCK":
+			i++
+		case "GROUP":
+			i += 2
+		}
+	}
+	return -1
+}
+
+// parseXReadOption parses options of XREAD and XREADGROUP
+func parseXReadOption(args [][]byte, xreadGroup bool) (*xreadOption, protocol.ErrorReply) {
+	opt := &xreadOption{}
+	cmdName := "xread"
+	if xreadGroup {
+		cmdName = "xreadgroup"
+	}
+	groupGiven := false
+	i := 0
+parseArgs:
+	for ; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch {
+		case arg == "STREAMS":
+			break parseArgs
+		case arg == "COUNT" && i+1 < len(args):
+			count, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, protocol.NewErrReply("ERR value is not an integer or out of range")
+			}
+			if count < 0 {
+				count = 0
+			}
+			opt.count = count
+			i++
+		case arg == "BLOCK" && i+1 < len(args):
+			ms, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				return nil, protocol.NewErrReply("ERR timeout is not an integer or out of range")
+			}
+			if ms < 0 {
+				return nil, protocol.NewErrReply("ERR timeout is negative")
+			}
+			opt.block = true
+			opt.timeout = time.Duration(ms) * time.Millisecond
+			i++
+		case arg == "GROUP" && i+2 < len(args):
+			if !xreadGroup {
+				return nil, protocol.NewErrReply("ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+			}
+			opt.group = string(args[i+1])
+			opt.consumer = string(args[i+2])
+			groupGiven = true
+			i += 2
+		case arg == "NOACK" && xreadGroup:
+			opt.noAck = true
+		default:
+			return nil, protocol.NewSyntaxErrReply()
+		}
+	}
+	if i >= len(args) {
+		return nil, protocol.NewSyntaxErrReply()
+	}
+	if xreadGroup && !groupGiven {
+		return nil, protocol.NewErrReply("ERR Missing GROUP option for XREADGROUP")
+	}
+	opt.streamsIndex = i + 1
+	rest := args[opt.streamsIndex:]
+	if len(rest) == 0 || len(rest)%2 != 0 {
+		specialID := "$"
+		if xreadGroup {
+			specialID = ">"
+		}
+		return nil, protocol.NewErrReply("ERR Unbalanced '" + cmdName + "' list of streams: for each stream key an ID or '" +
+			specialID + "' must be specified.")
+	}
+	opt.keys = rest[:len(rest)/2]
+	opt.ids = rest[len(rest)/2:]
+	return opt, nil
+}
+
+// execXRead reads entries after the given IDs from streams, it blocks until any entry available with BLOCK
+// XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
+func execXRead(db *DB, args [][]byte) redis.Reply {
+	opt, errReply := parseXReadOption(args, false)
+	if errReply != nil {
+		return errReply
+	}
+	// `$` means entries added after blocking, it is resolved into the last ID so that the command could be rerun
+	resolvedArgs := make([][]byte, len(args))
+	copy(resolvedArgs, args)
+	result := make([]redis.Reply, 0)
+	for i, keyArg := range opt.keys {
+		s, errReply := db.getAsStream(string(keyArg))
+		if errReply != nil {
+			return errReply
+		}
+		var after stream.ID
+		if string(opt.ids[i]) == "$" {
+			if s != nil {
+				after = s.LastID()
+			}
+			resolvedArgs[opt.streamsIndex+len(opt.keys)+i] = []byte(after.String())
+		} else {
+			after, errReply = parseStreamID(opt.ids[i])
+			if errReply != nil {
+				return errReply
+			}
+		}
+		if s == nil {
+			continue
+		}
+		start, ok := after.Incr()
+		if !ok {
+			continue
+		}
+		entries := s.Range(start, stream.MaxID, opt.count, false)
+		if len(entries) == 0 {
+			continue
+		}
+		result = append(result, protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewBulkReply(keyArg),
+			entriesToReply(entries),
+		}))
+	}
+	if len(result) > 0 {
+		return protocol.NewMultiRawReply(result)
+	}
+	if opt.block {
+		keys := make([]string, len(opt.keys))
+		for i, keyArg := range opt.keys {
+			keys[i] = string(keyArg)
+		}
+		return &blockedReply{
+			keys:    keys,
+			timeout: opt.timeout,
+			cmdLine: utils.ToCmdLine3("xread", resolvedArgs...),
+		}
+	}
+	return protocol.NewNullMultiBulkReply()
+}
+
+// execXReadGroup reads entries as a consumer of group, `>` means entries never delivered to other consumers,
+// other IDs means pending entries of the consumer after the given ID
+// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]
+func execXReadGroup(db *DB, args [][]byte) redis.Reply {
+	opt, errReply := parseXReadOption(args, true)
+	if errReply != nil {
+		return errReply
+	}
+	streams := make([]*stream.Stream, len(opt.keys))
+	groups := make([]*stream.Group, len(opt.keys))
+	for i, keyArg := range opt.keys {
+		key := string(keyArg)
+		s, errReply := db.getAsStream(key)
+		if errReply != nil {
+			return errReply
+		}
+		var group *stream.Group
+		ok := false
+		if s != nil {
+			group, ok = s.GetGroup(opt.group)
+		}
+		if !ok {
+			return protocol.NewErrReply("NOGROUP No such key '" + key + "' or consumer group '" + opt.group +
+				"' in XREADGROUP with GROUP option")
+		}
+		if string(opt.ids[i]) == "$" {
+			return protocol.NewErrReply("ERR The $ ID is meaningless in the context of XREADGROUP: " +
+				"you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. " +
+				"The $ ID would just return an empty result set.")
+		}
+		if string(opt.ids[i]) != ">" {
+			if _, errReply := parseStreamID(opt.ids[i]); errReply != nil {
+				return errReply
+			}
+		}
+		streams[i], groups[i] = s, group
+	}
+
+	now := nowMillis()
+	result := make([]redis.Reply, 0)
+	history := false
+	for i, keyArg := range opt.keys {
+		key := string(keyArg)
+		s, group := streams[i], groups[i]
+		consumer, created := group.CreateConsumer(opt.consumer, now)
+		consumer.SeenTime = now
+		if created {
+			db.addAof(utils.ToCmdLine("xgroup", "CREATECONSUMER", key, group.Name, opt.consumer))
+		}
+
+		if string(opt.ids[i]) != ">" {
+			// history of the consumer, deleted entries are replied with nil
+			history = true
+			after, _ := parseStreamID(opt.ids[i])
+			entries := make([]redis.Reply, 0)
+			if start, ok := after.Incr(); ok {
+				consumer.ForEachPending(start, stream.MaxID, func(pending *stream.PendingEntry) bool {
+					if entry, ok := s.Get(pending.ID); ok {
+						entries = append(entries, entryToReply(entry))
+					} else {
+						entries = append(entries, protocol.NewMultiRawReply([]redis.Reply{
+							protocol.NewBulkReply([]byte(pending.ID.String())),
+							protocol.NewNullMultiBulkReply(),
+						}))
+					}
+					pending.DeliveryTime = now
+					pending.DeliveryCount++
+					return opt.count <= 0 || int64(len(entries)) < opt.count
+				})
+			}
+			result = append(result, protocol.NewMultiRawReply([]redis.Reply{
+				protocol.NewBulkReply(keyArg),
+				protocol.NewMultiRawReply(entries),
+			}))
+			continue
+		}
+
+		start, ok := group.LastID.Incr()
+		if !ok {
+			continue
+		}
+		entries := s.Range(start, stream.MaxID, opt.count, false)
+		if len(entries) == 0 {
+			continue
+		}
+		for _, entry := range entries {
+			group.LastID = entry.ID
+			if opt.noAck {
+				continue
+			}
+			pending := group.Deliver(entry.ID, consumer)
+			pending.DeliveryTime = now
+			pending.DeliveryCount = 1
+			db.addAof(makeXClaimCmd(key, group, opt.consumer, pending))
+		}
+		if opt.noAck {
+			db.addAof(utils.ToCmdLine("xgroup", "SETID", key, group.Name, group.LastID.String()))
+		}
+		result = append(result, protocol.NewMultiRawReply([]redis.Reply{
+			protocol.NewBulkReply(keyArg),
+			entriesToReply(entries),
+		}))
+	}
+	if len(result) > 0 {
+		return protocol.NewMultiRawReply(result)
+	}
+	if opt.block && !history {
+		keys := make([]string, len(opt.keys))
+		for i, keyArg := range opt.keys {
+			keys[i] = string(keyArg)
+		}
+		return &blockedReply{
+			keys:    keys,
+			timeout: opt.timeout,
+		}
+	}
+	return protocol.NewNullMultiBulkReply()
+}
+
+// prepareXRead returns keys after STREAMS as read keys
+func prepareXRead(args [][]byte) ([]string, []string) {
+	return nil, streamKeys(args)
+}
+
+// prepareXReadGroup returns keys after STREAMS as write keys, since reading updates consumer group
+func prepareXReadGroup(args [][]byte) ([]string, []string) {
+	return streamKeys(args), nil
+}
+
+func streamKeys(args [][]byte) []string {
+	index := findStreamsIndex(args)
+	if index < 0 || index >= len(args) {
+		return nil
+	}
+	rest := args[index:]
+	keys := make([]string, len(rest)/2)
+	for i := range keys {
+		keys[i] = string(rest[i])
+	}
+	return keys
+}
+
+// prepareXGroup returns the key after sub command as write key
+func prepareXGroup(args [][]byte) ([]string, []string) {
+	if len(args) < 2 {
+		return nil, nil
+	}
+	return []string{string(args[1])}, nil
+}
+
+func init() {
+	RegisterCommand("XAdd", execXAdd, writeFirstKey, -5)
+	RegisterCommand("XLen", execXLen, readFirstKey, 2)
+	RegisterCommand("XRange", execXRange, readFirstKey, -4)
+	RegisterCommand("XRevRange", execXRevRange, readFirstKey, -4)
+	RegisterCommand("XDel", execXDel, writeFirstKey, -3)
+	RegisterCommand("XTrim", execXTrim, writeFirstKey, -4)
+	RegisterCommand("XGroup", execXGroup, prepareXGroup, -2)
+	RegisterCommand("XAck", execXAck, writeFirstKey, -4)
+	RegisterCommand("XPending", execXPending, readFirstKey, -3)
+	RegisterCommand("XClaim", execXClaim, writeFirstKey, -6)
+	RegisterCommand("XAutoClaim", execXAutoClaim, writeFirstKey, -6)
+	RegisterCommand("XRead", execXRead, prepareXRead, -4)
+	RegisterCommand("XReadGroup", execXReadGroup, prepareXReadGroup, -7)
+}
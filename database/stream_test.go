@@ -0,0 +1,357 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/protocol"
+	"testing"
+)
+
+// entryIDs returns IDs of an array of stream entries
+func entryIDs(t *testing.T, reply redis.Reply) []string {
+	t.Helper()
+	entries, ok := reply.(*protocol.MultiRawReply)
+	if !ok {
+		if _, ok := reply.(*protocol.EmptyMultiBulkReply); ok {
+			return []string{}
+		}
+		t.Fatalf("expected entries, actually %q", reply.ToBytes())
+	}
+	ids := make([]string, len(entries.Replies))
+	for i, entry := range entries.Replies {
+		ids[i] = string(entry.(*protocol.MultiRawReply).Replies[0].(*protocol.BulkReply).Arg)
+	}
+	return ids
+}
+
+func assertEntryIDs(t *testing.T, reply redis.Reply, expected ...string) {
+	t.Helper()
+	ids := entryIDs(t, reply)
+	if len(ids) != len(expected) {
+		t.Fatalf("expected %v, actually %v", expected, ids)
+	}
+	for i := range expected {
+		if ids[i] != expected[i] {
+			t.Fatalf("expected %v, actually %v", expected, ids)
+		}
+	}
+}
+
+// streamsReply returns entries reply of each stream in reply of XREAD or XREADGROUP
+func streamsReply(t *testing.T, reply redis.Reply) map[string]redis.Reply {
+	t.Helper()
+	streams, ok := reply.(*protocol.MultiRawReply)
+	if !ok {
+		t.Fatalf("expected streams, actually %q", reply.ToBytes())
+	}
+	result := make(map[string]redis.Reply)
+	for _, item := range streams.Replies {
+		pair := item.(*protocol.MultiRawReply).Replies
+		result[string(pair[0].(*protocol.BulkReply).Arg)] = pair[1]
+	}
+	return result
+}
+
+func TestXAdd(t *testing.T) {
+	db := makeTestDB()
+	assertBulkReply(t, execCmd(db, "xadd", "s", "1-1", "f", "v"), "1-1")
+	assertBulkReply(t, execCmd(db, "xadd", "s", "1-*", "f", "v"), "1-2")
+	assertBulkReply(t, execCmd(db, "xadd", "s", "5-*", "f", "v"), "5-0")
+	assertBulkReply(t, execCmd(db, "xadd", "s", "6", "f", "v", "f2", "v2"), "6-0")
+	id := execCmd(db, "xadd", "s", "*", "f", "v").(*protocol.BulkReply)
+	assertIntReply(t, execCmd(db, "xlen", "s"), 5)
+	assertReply(t, execCmd(db, "xrange", "s", "6", "6"),
+		"*1\r\n*2\r\n$3\r\n6-0\r\n*4\r\n$1\r\nf\r\n$1\r\nv\r\n$2\r\nf2\r\n$2\r\nv2\r\n")
+	assertEntryIDs(t, execCmd(db, "xrevrange", "s", "+", "-", "count", "1"), string(id.Arg))
+
+	topErr := "ERR The ID specified in XADD is equal or smaller than the target stream top item"
+	assertErrReply(t, execCmd(db, "xadd", "s", "6-0", "f", "v"), topErr)
+	assertErrReply(t, execCmd(db, "xadd", "s", "1-*", "f", "v"), topErr)
+	assertErrReply(t, execCmd(db, "xadd", "new", "0-0", "f", "v"), "ERR The ID specified in XADD must be greater than 0-0")
+	assertErrReply(t, execCmd(db, "xadd", "new", "a-1", "f", "v"), "ERR Invalid stream ID specified as stream command argument")
+	assertErrReply(t, execCmd(db, "xadd", "new", "*", "f", "v", "f2"), "ERR wrong number of arguments for 'xadd' command")
+	assertNotExists(t, db, "new")
+	assertBulkReply(t, execCmd(db, "xadd", "new", "0-*", "f", "v"), "0-1")
+	assertBulkReply(t, execCmd(db, "xadd", "max", "18446744073709551615-18446744073709551615", "f", "v"),
+		"18446744073709551615-18446744073709551615")
+	assertErrReply(t, execCmd(db, "xadd", "max", "*", "f", "v"),
+		"ERR The stream has exhausted the last possible ID, unable to add more items")
+
+	assertReply(t, execCmd(db, "xadd", "missing", "nomkstream", "*", "f", "v"), "$-1\r\n")
+	assertNotExists(t, db, "missing")
+	assertReply(t, execCmd(db, "type", "s"), "+stream\r\n")
+}
+
+func TestXAddTrim(t *testing.T) {
+	db := makeTestDB()
+	for i := 1; i <= 5; i++ {
+		execCmd(db, "xadd", "s", "maxlen", "3", "*", "f", "v")
+	}
+	assertIntReply(t, execCmd(db, "xlen", "s"), 3)
+	execCmd(db, "xadd", "t", "1", "f", "v")
+	execCmd(db, "xadd", "t", "2", "f", "v")
+	execCmd(db, "xadd", "t", "minid", "=", "2", "3", "f", "v")
+	assertEntryIDs(t, execCmd(db, "xrange", "t", "-", "+"), "2-0", "3-0")
+
+	assertErrReply(t, execCmd(db, "xadd", "s", "maxlen", "-1", "*", "f", "v"), "ERR The MAXLEN argument must be >= 0.")
+	assertErrReply(t, execCmd(db, "xadd", "s", "maxlen", "1", "limit", "10", "*", "f", "v"),
+		"ERR syntax error, LIMIT cannot be used without the special ~ option")
+	assertErrReply(t, execCmd(db, "xadd", "s", "maxlen", "1", "minid", "1", "*", "f", "v"),
+		"ERR syntax error, MAXLEN and MINID options at the same time are not compatible")
+}
+
+func TestXRange(t *testing.T) {
+	db := makeTestDB()
+	for _, id := range []string{"1-0", "1-1", "2-0", "2-5", "3-0"} {
+		execCmd(db, "xadd", "s", id, "f", "v")
+	}
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "-", "+"), "1-0", "1-1", "2-0", "2-5", "3-0")
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "1", "2"), "1-0", "1-1", "2-0", "2-5")
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "(1-0", "(2-5"), "1-1", "2-0")
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "2", "+", "count", "2"), "2-0", "2-5")
+	assertEntryIDs(t, execCmd(db, "xrevrange", "s", "2", "-"), "2-5", "2-0", "1-1", "1-0")
+	assertEntryIDs(t, execCmd(db, "xrevrange", "s", "(3-0", "(1-1", "count", "1"), "2-5")
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "3", "1"))
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "-", "+", "count", "0"))
+	assertEntryIDs(t, execCmd(db, "xrange", "missing", "-", "+"))
+
+	assertErrReply(t, execCmd(db, "xrange", "s", "(18446744073709551615-18446744073709551615", "+"),
+		"ERR invalid start ID for the interval")
+	assertErrReply(t, execCmd(db, "xrange", "s", "-", "(0-0"), "ERR invalid end ID for the interval")
+	assertErrReply(t, execCmd(db, "xrange", "s", "x", "+"), "ERR Invalid stream ID specified as stream command argument")
+	assertErrReply(t, execCmd(db, "xrange", "s", "-", "+", "limit", "1"), "Err syntax error")
+	execCmd(db, "set", "str", "v")
+	assertErrReply(t, execCmd(db, "xrange", "str", "-", "+"), wrongTypeErr)
+}
+
+func TestXDelTrim(t *testing.T) {
+	db := makeTestDB()
+	for _, id := range []string{"1", "2", "3", "4", "5"} {
+		execCmd(db, "xadd", "s", id, "f", "v")
+	}
+	assertIntReply(t, execCmd(db, "xdel", "s", "2", "3-0", "10"), 2)
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "-", "+"), "1-0", "4-0", "5-0")
+	// IDs are never reused
+	assertErrReply(t, execCmd(db, "xadd", "s", "5", "f", "v"),
+		"ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	assertIntReply(t, execCmd(db, "xtrim", "s", "minid", "4"), 1)
+	assertIntReply(t, execCmd(db, "xtrim", "s", "maxlen", "=", "1"), 1)
+	assertEntryIDs(t, execCmd(db, "xrange", "s", "-", "+"), "5-0")
+	assertIntReply(t, execCmd(db, "xtrim", "s", "maxlen", "~", "0", "limit", "0"), 1)
+	// empty stream is kept
+	assertIntReply(t, execCmd(db, "xlen", "s"), 0)
+	assertExists(t, db, "s")
+	assertIntReply(t, execCmd(db, "xdel", "missing", "1"), 0)
+	assertIntReply(t, execCmd(db, "xtrim", "missing", "maxlen", "0"), 0)
+
+	assertErrReply(t, execCmd(db, "xtrim", "s", "limit", "1", "x"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "xtrim", "s", "maxlen", "x"), "ERR value is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "xtrim", "s", "maxlen", "~", "1", "limit", "-1"), "ERR The LIMIT argument must be >= 0.")
+	assertErrReply(t, execCmd(db, "xdel", "s", "x"), "ERR Invalid stream ID specified as stream command argument")
+}
+
+func TestXRead(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "xadd", "s1", "1", "f", "v")
+	execCmd(db, "xadd", "s1", "2", "f", "v")
+	execCmd(db, "xadd", "s2", "3", "f", "v")
+	streams := streamsReply(t, execCmd(db, "xread", "streams", "s1", "s2", "missing", "1", "0", "0"))
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 streams, actually %v", streams)
+	}
+	assertEntryIDs(t, streams["s1"], "2-0")
+	assertEntryIDs(t, streams["s2"], "3-0")
+	streams = streamsReply(t, execCmd(db, "xread", "count", "1", "streams", "s1", "0"))
+	assertEntryIDs(t, streams["s1"], "1-0")
+	assertReply(t, execCmd(db, "xread", "streams", "s1", "$"), "*-1\r\n")
+	assertReply(t, execCmd(db, "xread", "streams", "s1", "2"), "*-1\r\n")
+
+	assertErrReply(t, execCmd(db, "xread", "streams", "s1", "s2", "0"),
+		"ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")
+	assertErrReply(t, execCmd(db, "xread", "count", "1", "s1", "0"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "xread", "group", "g", "c", "streams", "s1", "0"),
+		"ERR The GROUP option is only supported by XREADGROUP. You called XREAD instead.")
+	assertErrReply(t, execCmd(db, "xread", "block", "-1", "streams", "s1", "0"), "ERR timeout is negative")
+	assertErrReply(t, execCmd(db, "xread", "streams", "s1", "x"), "ERR Invalid stream ID specified as stream command argument")
+}
+
+func TestXReadBlocking(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "xadd", "s1", "1", "f", "v")
+	// `$` means entries added after blocking
+	ch := execAsync(db, connection.NewFakeConn(), "xread", "block", "0", "streams", "s1", "s2", "$", "$")
+	waitBlocked(t, db, 1)
+	execCmd(db, "xadd", "s2", "5", "f", "v")
+	streams := streamsReply(t, receive(t, ch))
+	if len(streams) != 1 {
+		t.Fatalf("expected 1 stream, actually %v", streams)
+	}
+	assertEntryIDs(t, streams["s2"], "5-0")
+	waitBlocked(t, db, 0)
+
+	ch = execAsync(db, connection.NewFakeConn(), "xread", "block", "0", "streams", "s1", "$")
+	waitBlocked(t, db, 1)
+	execCmd(db, "xadd", "s1", "2", "f", "v")
+	assertEntryIDs(t, streamsReply(t, receive(t, ch))["s1"], "2-0")
+
+	assertReply(t, execCmd(db, "xread", "block", "50", "streams", "s1", "$"), "*-1\r\n")
+	waitBlocked(t, db, 0)
+}
+
+func TestXGroup(t *testing.T) {
+	db := makeTestDB()
+	assertErrReply(t, execCmd(db, "xgroup", "create", "s", "g", "$"),
+		"ERR The XGROUP subcommand requires the key to exist. "+
+			"Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	assertOkReply(t, execCmd(db, "xgroup", "create", "s", "g", "$", "mkstream"))
+	assertIntReply(t, execCmd(db, "xlen", "s"), 0)
+	assertErrReply(t, execCmd(db, "xgroup", "create", "s", "g", "0"), "BUSYGROUP Consumer Group name already exists")
+	assertIntReply(t, execCmd(db, "xgroup", "createconsumer", "s", "g", "c"), 1)
+	assertIntReply(t, execCmd(db, "xgroup", "createconsumer", "s", "g", "c"), 0)
+	assertIntReply(t, execCmd(db, "xgroup", "delconsumer", "s", "g", "c"), 0)
+	assertOkReply(t, execCmd(db, "xgroup", "setid", "s", "g", "0"))
+	assertErrReply(t, execCmd(db, "xgroup", "setid", "s", "missing", "0"),
+		"NOGROUP No such consumer group 'missing' for key name 's'")
+	assertIntReply(t, execCmd(db, "xgroup", "destroy", "s", "g"), 1)
+	assertIntReply(t, execCmd(db, "xgroup", "destroy", "s", "g"), 0)
+
+	assertErrReply(t, execCmd(db, "xgroup", "foo", "s", "g"), "ERR unknown subcommand 'foo'. Try XGROUP HELP.")
+	assertErrReply(t, execCmd(db, "xgroup", "destroy", "s"), "ERR wrong number of arguments for 'xgroup|destroy' command")
+	assertErrReply(t, execCmd(db, "xgroup", "create", "s", "g", "$", "foo"), "Err syntax error")
+}
+
+func TestXReadGroup(t *testing.T) {
+	db := makeTestDB()
+	for _, id := range []string{"1", "2", "3"} {
+		execCmd(db, "xadd", "s", id, "f", "v")
+	}
+	execCmd(db, "xgroup", "create", "s", "g", "0")
+	assertEntryIDs(t, streamsReply(t, execCmd(db, "xreadgroup", "group", "g", "alice", "count", "2", "streams", "s", ">"))["s"],
+		"1-0", "2-0")
+	assertEntryIDs(t, streamsReply(t, execCmd(db, "xreadgroup", "group", "g", "bob", "streams", "s", ">"))["s"], "3-0")
+	assertReply(t, execCmd(db, "xreadgroup", "group", "g", "bob", "streams", "s", ">"), "*-1\r\n")
+	// history of the consumer
+	assertEntryIDs(t, streamsReply(t, execCmd(db, "xreadgroup", "group", "g", "alice", "streams", "s", "0"))["s"],
+		"1-0", "2-0")
+	assertEntryIDs(t, streamsReply(t, execCmd(db, "xreadgroup", "group", "g", "alice", "streams", "s", "1"))["s"], "2-0")
+
+	assertIntReply(t, execCmd(db, "xack", "s", "g", "1", "1", "5"), 1)
+	assertIntReply(t, execCmd(db, "xack", "s", "missing", "2"), 0)
+	assertReply(t, execCmd(db, "xpending", "s", "g"),
+		"*4\r\n:2\r\n$3\r\n2-0\r\n$3\r\n3-0\r\n*2\r\n*2\r\n$5\r\nalice\r\n$1\r\n1\r\n*2\r\n$3\r\nbob\r\n$1\r\n1\r\n")
+	// deleted entries are replied with nil
+	execCmd(db, "xdel", "s", "2")
+	assertReply(t, execCmd(db, "xreadgroup", "group", "g", "alice", "streams", "s", "0"),
+		"*1\r\n*2\r\n$1\r\ns\r\n*1\r\n*2\r\n$3\r\n2-0\r\n*-1\r\n")
+
+	execCmd(db, "xadd", "s", "4", "f", "v")
+	assertEntryIDs(t, streamsReply(t, execCmd(db, "xreadgroup", "group", "g", "carol", "noack", "streams", "s", ">"))["s"],
+		"4-0")
+	assertIntReply(t, execCmd(db, "xgroup", "delconsumer", "s", "g", "carol"), 0)
+	assertIntReply(t, execCmd(db, "xgroup", "delconsumer", "s", "g", "bob"), 1)
+
+	assertErrReply(t, execCmd(db, "xreadgroup", "group", "missing", "c", "streams", "s", ">"),
+		"NOGROUP No such key 's' or consumer group 'missing' in XREADGROUP with GROUP option")
+	assertErrReply(t, execCmd(db, "xreadgroup", "count", "1", "noack", "streams", "s", ">"), "ERR Missing GROUP option for XREADGROUP")
+	assertErrReply(t, execCmd(db, "xreadgroup", "group", "g", "c", "streams", "s", "$"),
+		"ERR The $ ID is meaningless in the context of XREADGROUP: "+
+			"you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. "+
+			"The $ ID would just return an empty result set.")
+}
+
+func TestXReadGroupBlocking(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "xgroup", "create", "s", "g", "$", "mkstream")
+	ch := execAsync(db, connection.NewFakeConn(), "xreadgroup", "group", "g", "c", "block", "0", "streams", "s", ">")
+	waitBlocked(t, db, 1)
+	execCmd(db, "xadd", "s", "1", "f", "v")
+	assertEntryIDs(t, streamsReply(t, receive(t, ch))["s"], "1-0")
+	waitBlocked(t, db, 0)
+
+	// destroying the group wakes up blocked clients
+	ch = execAsync(db, connection.NewFakeConn(), "xreadgroup", "group", "g", "c", "block", "0", "streams", "s", ">")
+	waitBlocked(t, db, 1)
+	execCmd(db, "xgroup", "destroy", "s", "g")
+	assertErrReply(t, receive(t, ch), "NOGROUP No such key 's' or consumer group 'g' in XREADGROUP with GROUP option")
+}
+
+func TestXPending(t *testing.T) {
+	db := makeTestDB()
+	for _, id := range []string{"1", "2", "3"} {
+		execCmd(db, "xadd", "s", id, "f", "v")
+	}
+	execCmd(db, "xgroup", "create", "s", "g", "0")
+	assertReply(t, execCmd(db, "xpending", "s", "g"), "*4\r\n:0\r\n$-1\r\n$-1\r\n*-1\r\n")
+	execCmd(db, "xreadgroup", "group", "g", "alice", "count", "2", "streams", "s", ">")
+	execCmd(db, "xreadgroup", "group", "g", "bob", "streams", "s", ">")
+
+	reply, ok := execCmd(db, "xpending", "s", "g", "-", "+", "10").(*protocol.MultiRawReply)
+	if !ok || len(reply.Replies) != 3 {
+		t.Fatalf("expected 3 pending entries")
+	}
+	detail := reply.Replies[0].(*protocol.MultiRawReply).Replies
+	assertBulkReply(t, detail[0], "1-0")
+	assertBulkReply(t, detail[1], "alice")
+	assertIntReply(t, detail[3], 1)
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "(1", "+", "1"), "2-0")
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "-", "+", "10", "bob"), "3-0")
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "-", "+", "10", "missing"))
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "idle", "100000", "-", "+", "10"))
+
+	assertErrReply(t, execCmd(db, "xpending", "s", "missing"), "NOGROUP No such key 's' or consumer group 'missing'")
+	assertErrReply(t, execCmd(db, "xpending", "s", "g", "-", "+"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "xpending", "s", "g", "-", "+", "x"), "ERR value is not an integer or out of range")
+}
+
+func TestXClaim(t *testing.T) {
+	db := makeTestDB()
+	for _, id := range []string{"1", "2", "3", "4"} {
+		execCmd(db, "xadd", "s", id, "f", "v")
+	}
+	execCmd(db, "xgroup", "create", "s", "g", "0")
+	execCmd(db, "xreadgroup", "group", "g", "alice", "count", "3", "streams", "s", ">")
+
+	// entries are not idle long enough
+	assertEntryIDs(t, execCmd(db, "xclaim", "s", "g", "bob", "100000", "1"))
+	assertEntryIDs(t, execCmd(db, "xclaim", "s", "g", "bob", "0", "1", "2"), "1-0", "2-0")
+	assertReply(t, execCmd(db, "xclaim", "s", "g", "bob", "0", "3", "justid"), "*1\r\n$3\r\n3-0\r\n")
+	// entry not pending is claimed only with FORCE
+	assertReply(t, execCmd(db, "xclaim", "s", "g", "bob", "0", "4", "justid"), "*0\r\n")
+	assertReply(t, execCmd(db, "xclaim", "s", "g", "bob", "0", "4", "force", "justid", "retrycount", "5"), "*1\r\n$3\r\n4-0\r\n")
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "-", "+", "10", "bob"), "1-0", "2-0", "3-0", "4-0")
+	detail := execCmd(db, "xpending", "s", "g", "4", "4", "1").(*protocol.MultiRawReply).Replies[0].(*protocol.MultiRawReply)
+	assertIntReply(t, detail.Replies[3], 5)
+	// deleted entries are removed from pending entries list
+	execCmd(db, "xdel", "s", "1")
+	assertEntryIDs(t, execCmd(db, "xclaim", "s", "g", "alice", "0", "1"))
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "-", "+", "10"), "2-0", "3-0", "4-0")
+
+	assertErrReply(t, execCmd(db, "xclaim", "s", "g", "bob", "x", "1"), "ERR Invalid min-idle-time argument for XCLAIM")
+	assertErrReply(t, execCmd(db, "xclaim", "s", "g", "bob", "0", "1", "idle", "x"), "ERR Invalid IDLE option argument for XCLAIM")
+	assertErrReply(t, execCmd(db, "xclaim", "s", "g", "bob", "0", "1", "foo"), "ERR Unrecognized XCLAIM option 'foo'")
+	assertErrReply(t, execCmd(db, "xclaim", "s", "missing", "bob", "0", "1"), "NOGROUP No such key 's' or consumer group 'missing'")
+}
+
+func TestXAutoClaim(t *testing.T) {
+	db := makeTestDB()
+	for _, id := range []string{"1", "2", "3", "4"} {
+		execCmd(db, "xadd", "s", id, "f", "v")
+	}
+	execCmd(db, "xgroup", "create", "s", "g", "0")
+	execCmd(db, "xreadgroup", "group", "g", "alice", "streams", "s", ">")
+	execCmd(db, "xdel", "s", "2")
+
+	reply := execCmd(db, "xautoclaim", "s", "g", "bob", "0", "0", "count", "2").(*protocol.MultiRawReply)
+	assertBulkReply(t, reply.Replies[0], "4-0")
+	assertEntryIDs(t, reply.Replies[1], "1-0", "3-0")
+	assertMultiBulkReply(t, reply.Replies[2], "2-0")
+	assertReply(t, execCmd(db, "xautoclaim", "s", "g", "bob", "0", "4", "justid"),
+		"*3\r\n$3\r\n0-0\r\n*1\r\n$3\r\n4-0\r\n*0\r\n")
+	assertEntryIDs(t, execCmd(db, "xpending", "s", "g", "-", "+", "10", "bob"), "1-0", "3-0", "4-0")
+	reply = execCmd(db, "xautoclaim", "s", "g", "alice", "100000", "0").(*protocol.MultiRawReply)
+	assertEntryIDs(t, reply.Replies[1])
+
+	assertErrReply(t, execCmd(db, "xautoclaim", "s", "g", "bob", "x", "0"), "ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	assertErrReply(t, execCmd(db, "xautoclaim", "s", "g", "bob", "0", "0", "count", "0"), "ERR COUNT must be > 0")
+	assertErrReply(t, execCmd(db, "xautoclaim", "s", "g", "bob", "0", "0", "foo"), "Err syntax error")
+}
@@ -0,0 +1,600 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxStringSize is the max length of a string value, the same as proto-max-bulk-len of redis
+const maxStringSize = 512 * 1024 * 1024
+
+// Strings are modified in place by SETRANGE, SETBIT and BITFIELD, while replies are encoded after keys are unlocked.
+// So a stored string is never shared: replies and other values get a copy made by cloneBytes.
+func (db *DB) getAsString(key string) ([]byte, protocol.ErrorReply) {
+	entity, ok := db.GetEntity(key)
+	if !ok {
+		return nil, nil
+	}
+	bytes, ok := entity.Data.([]byte)
+	if !ok {
+		return nil, &protocol.WrongTypeErrReply{}
+	}
+	return bytes, nil
+}
+
+// cloneBytes copies a stored string before it leaves its key
+func cloneBytes(bytes []byte) []byte {
+	if bytes == nil {
+		return nil
+	}
+	result := make([]byte, len(bytes))
+	copy(result, bytes)
+	return result
+}
+
+// execGet returns string value bound to the given key
+func execGet(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	bytes, err := db.getAsString(key)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return protocol.NewNullBulkReply()
+	}
+	return protocol.NewBulkReply(cloneBytes(bytes))
+}
+
+const (
+	upsertPolicy = iota // default
+	insertPolicy        // set nx
+	updatePolicy        // set xx
+)
+
+// expireOption holds EX/PX/EXAT/PXAT/KEEPTTL/PERSIST options of SET and GETEX
+type expireOption struct {
+	expireAt time.Time
+	hasTTL   bool
+	keepTTL  bool
+	persist  bool
+}
+
+// parseExpireArg parses the expire time following EX/PX/EXAT/PXAT
+func parseExpireArg(unit string, arg []byte, cmdName string) (time.Time, protocol.ErrorReply) {
+	raw, err := strconv.ParseInt(string(arg), 10, 64)
+	if err != nil {
+		return time.Time{}, protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	invalid := protocol.NewErrReply("ERR invalid expire time in '" + cmdName + "' command")
+	if raw <= 0 {
+		return time.Time{}, invalid
+	}
+	switch unit {
+	case "EX":
+		if raw > math.MaxInt64/int64(time.Second) {
+			return time.Time{}, invalid
+		}
+		return time.Now().Add(time.Duration(raw) * time.Second), nil
+	case "PX":
+		if raw > math.MaxInt64/int64(time.Millisecond) {
+			return time.Time{}, invalid
+		}
+		return time.Now().Add(time.Duration(raw) * time.Millisecond), nil
+	case "EXAT":
+		return time.Unix(raw, 0), nil
+	default: // PXAT
+		return time.UnixMilli(raw), nil
+	}
+}
+
+// execSet sets string value and time to live to the given key
+func execSet(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	value := args[1]
+	policy := upsertPolicy
+	returnOld := false
+	opt := &expireOption{}
+
+	// parse options
+	for i := 2; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch arg {
+		case "NX":
+			if policy == updatePolicy {
+				return protocol.NewSyntaxErrReply()
+			}
+			policy = insertPolicy
+		case "XX":
+			if policy == insertPolicy {
+				return protocol.NewSyntaxErrReply()
+			}
+			policy = updatePolicy
+		case "GET":
+			returnOld = true
+		case "KEEPTTL":
+			if opt.hasTTL {
+				return protocol.NewSyntaxErrReply()
+			}
+			opt.keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if opt.hasTTL || opt.keepTTL || i+1 >= len(args) {
+				return protocol.NewSyntaxErrReply()
+			}
+			expireAt, errReply := parseExpireArg(arg, args[i+1], "set")
+			if errReply != nil {
+				return errReply
+			}
+			opt.expireAt = expireAt
+			opt.hasTTL = true
+			i++ // skip next arg
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+
+	var old []byte
+	if returnOld {
+		var errReply protocol.ErrorReply
+		old, errReply = db.getAsString(key)
+		if errReply != nil {
+			return errReply
+		}
+	}
+
+	entity := &database.DataEntity{
+		Data: value,
+	}
+	var result int
+	switch policy {
+	case upsertPolicy:
+		db.PutEntity(key, entity)
+		result = 1
+	case insertPolicy:
+		result = db.PutIfAbsent(key, entity)
+	case updatePolicy:
+		result = db.PutIfExists(key, entity)
+	}
+	if result > 0 {
+		if opt.hasTTL {
+			db.Expire(key, opt.expireAt)
+			db.addAof(utils.ToCmdLine3("set", args[0], value))
+			db.addAof(makeExpireCmd(key, opt.expireAt))
+		} else if opt.keepTTL {
+			db.addAof(utils.ToCmdLine3("set", args[0], value, []byte("KEEPTTL")))
+		} else {
+			db.Persist(key) // override ttl
+			db.addAof(utils.ToCmdLine3("set", args[0], value))
+		}
+	}
+
+	if returnOld {
+		if old == nil {
+			return protocol.NewNullBulkReply()
+		}
+		// old value is still stored if NX or XX prevented setting
+		return protocol.NewBulkReply(cloneBytes(old))
+	}
+	if result > 0 {
+		return protocol.NewOkReply()
+	}
+	return protocol.NewNullBulkReply()
+}
+
+// execSetNX sets string if not exists
+func execSetNX(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	value := args[1]
+	entity := &database.DataEntity{
+		Data: value,
+	}
+	result := db.PutIfAbsent(key, entity)
+	if result > 0 {
+		db.addAof(utils.ToCmdLine3("set", args...))
+	}
+	return protocol.NewIntReply(int64(result))
+}
+
+// execSetEX sets string and its ttl
+func execSetEX(db *DB, args [][]byte) redis.Reply {
+	return setWithTTL(db, "EX", "setex", args)
+}
+
+// execPSetEX set a key's time to live in milliseconds
+func execPSetEX(db *DB, args [][]byte) redis.Reply {
+	return setWithTTL(db, "PX", "psetex", args)
+}
+
+func setWithTTL(db *DB, unit string, cmdName string, args [][]byte) redis.Reply {
+	key := string(args[0])
+	value := args[2]
+	expireAt, errReply := parseExpireArg(unit, args[1], cmdName)
+	if errReply != nil {
+		return errReply
+	}
+	entity := &database.DataEntity{
+		Data: value,
+	}
+	db.PutEntity(key, entity)
+	db.Expire(key, expireAt)
+	db.addAof(utils.ToCmdLine3("set", args[0], value))
+	db.addAof(makeExpireCmd(key, expireAt))
+	return protocol.NewOkReply()
+}
+
+func prepareMSet(args [][]byte) ([]string, []string) {
+	size := len(args) / 2
+	keys := make([]string, size)
+	for i := 0; i < size; i++ {
+		keys[i] = string(args[2*i])
+	}
+	return keys, nil
+}
+
+// execMSet sets multi key-value in database
+func execMSet(db *DB, args [][]byte) redis.Reply {
+	if len(args)%2 != 0 {
+		return protocol.NewArgNumErrReply("mset")
+	}
+
+	size := len(args) / 2
+	for i := 0; i < size; i++ {
+		key := string(args[2*i])
+		value := args[2*i+1]
+		db.PutEntity(key, &database.DataEntity{Data: value})
+		db.Persist(key)
+	}
+	db.addAof(utils.ToCmdLine3("mset", args...))
+	return protocol.NewOkReply()
+}
+
+// execMSetNX sets multi key-value in database, only if none of the given keys exist
+func execMSetNX(db *DB, args [][]byte) redis.Reply {
+	if len(args)%2 != 0 {
+		return protocol.NewArgNumErrReply("msetnx")
+	}
+
+	size := len(args) / 2
+	for i := 0; i < size; i++ {
+		key := string(args[2*i])
+		if _, exists := db.GetEntity(key); exists {
+			return protocol.NewIntReply(0)
+		}
+	}
+
+	for i := 0; i < size; i++ {
+		key := string(args[2*i])
+		value := args[2*i+1]
+		db.PutEntity(key, &database.DataEntity{Data: value})
+	}
+	db.addAof(utils.ToCmdLine3("msetnx", args...))
+	return protocol.NewIntReply(1)
+}
+
+// execMGet get multi key-value from database
+func execMGet(db *DB, args [][]byte) redis.Reply {
+	result := make([][]byte, len(args))
+	for i, arg := range args {
+		bytes, err := db.getAsString(string(arg))
+		if err != nil {
+			// keys holding other types are regarded as nil
+			result[i] = nil
+			continue
+		}
+		result[i] = cloneBytes(bytes)
+	}
+	return protocol.NewMultiBulkReply(result)
+}
+
+// execGetSet sets value of a string-type key and returns its old value
+func execGetSet(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	value := args[1]
+
+	old, err := db.getAsString(key)
+	if err != nil {
+		return err
+	}
+	db.PutEntity(key, &database.DataEntity{Data: value})
+	db.Persist(key) // override ttl
+	db.addAof(utils.ToCmdLine3("set", args...))
+	if old == nil {
+		return protocol.NewNullBulkReply()
+	}
+	return protocol.NewBulkReply(old)
+}
+
+// execGetDel returns the value of a string-type key and deletes the key
+func execGetDel(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	old, err := db.getAsString(key)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return protocol.NewNullBulkReply()
+	}
+	db.Remove(key)
+	db.addAof(utils.ToCmdLine3("del", args...))
+	return protocol.NewBulkReply(old)
+}
+
+// execGetEx returns the value of a string-type key and optionally sets or clears its ttl
+func execGetEx(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	opt := &expireOption{}
+	for i := 1; i < len(args); i++ {
+		arg := strings.ToUpper(string(args[i]))
+		switch arg {
+		case "PERSIST":
+			if opt.hasTTL {
+				return protocol.NewSyntaxErrReply()
+			}
+			opt.persist = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if opt.hasTTL || opt.persist || i+1 >= len(args) {
+				return protocol.NewSyntaxErrReply()
+			}
+			expireAt, errReply := parseExpireArg(arg, args[i+1], "getex")
+			if errReply != nil {
+				return errReply
+			}
+			opt.expireAt = expireAt
+			opt.hasTTL = true
+			i++ // skip next arg
+		default:
+			return protocol.NewSyntaxErrReply()
+		}
+	}
+
+	bytes, err := db.getAsString(key)
+	if err != nil {
+		return err
+	}
+	if bytes == nil {
+		return protocol.NewNullBulkReply()
+	}
+	if opt.hasTTL {
+		db.Expire(key, opt.expireAt)
+		db.addAof(makeExpireCmd(key, opt.expireAt))
+	} else if opt.persist {
+		db.Persist(key)
+		db.addAof(utils.ToCmdLine3("persist", args[0]))
+	}
+	return protocol.NewBulkReply(cloneBytes(bytes))
+}
+
+// execStrLen returns len of string value bound to the given key
+func execStrLen(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	bytes, err := db.getAsString(key)
+	if err != nil {
+		return err
+	}
+	return protocol.NewIntReply(int64(len(bytes)))
+}
+
+// execAppend sets string value to the given key
+func execAppend(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	bytes, err := db.getAsString(key)
+	if err != nil {
+		return err
+	}
+	if len(bytes)+len(args[1]) > maxStringSize {
+		return protocol.NewErrReply("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+	bytes = append(bytes, args[1]...)
+	db.PutEntity(key, &database.DataEntity{
+		Data: bytes,
+	})
+	db.addAof(utils.ToCmdLine3("append", args...))
+	return protocol.NewIntReply(int64(len(bytes)))
+}
+
+// execGetRange returns a substring of the string value bound to the given key
+func execGetRange(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	start, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	end, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	size := int64(len(bytes))
+	if start < 0 && end < 0 && start > end {
+		return protocol.NewBulkReply([]byte{})
+	}
+	if start < 0 {
+		start = size + start
+	}
+	if end < 0 {
+		end = size + end
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end < 0 {
+		end = 0
+	}
+	if end >= size {
+		end = size - 1
+	}
+	if start > end || size == 0 {
+		return protocol.NewBulkReply([]byte{})
+	}
+	return protocol.NewBulkReply(cloneBytes(bytes[start : end+1]))
+}
+
+// execSetRange overwrites part of the string value bound to the given key, starting at the given offset
+func execSetRange(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	offset, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	if offset < 0 {
+		return protocol.NewErrReply("ERR offset is out of range")
+	}
+	value := args[2]
+
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	if len(value) == 0 {
+		// nothing to write, do not create an empty key
+		return protocol.NewIntReply(int64(len(bytes)))
+	}
+	if offset+int64(len(value)) > maxStringSize {
+		return protocol.NewErrReply("ERR string exceeds maximum allowed size (proto-max-bulk-len)")
+	}
+
+	if gap := offset + int64(len(value)) - int64(len(bytes)); gap > 0 {
+		bytes = append(bytes, make([]byte, gap)...)
+	}
+	copy(bytes[offset:], value)
+	db.PutEntity(key, &database.DataEntity{
+		Data: bytes,
+	})
+	db.addAof(utils.ToCmdLine3("setrange", args...))
+	return protocol.NewIntReply(int64(len(bytes)))
+}
+
+// incrBy adds delta to the integer value bound to the given key, the key will be created if not exists
+func (db *DB) incrBy(key string, delta int64) redis.Reply {
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	var val int64
+	if bytes != nil {
+		var err error
+		val, err = strconv.ParseInt(string(bytes), 10, 64)
+		if err != nil {
+			return protocol.NewErrReply("ERR value is not an integer or out of range")
+		}
+	}
+	if (delta > 0 && val > math.MaxInt64-delta) || (delta < 0 && val < math.MinInt64-delta) {
+		return protocol.NewErrReply("ERR increment or decrement would overflow")
+	}
+	val += delta
+	db.PutEntity(key, &database.DataEntity{
+		Data: []byte(strconv.FormatInt(val, 10)),
+	})
+	return protocol.NewIntReply(val)
+}
+
+// execIncr increments the integer value of a key by one
+func execIncr(db *DB, args [][]byte) redis.Reply {
+	result := db.incrBy(string(args[0]), 1)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("incr", args...))
+	}
+	return result
+}
+
+// execIncrBy increments the integer value of a key by the given amount
+func execIncrBy(db *DB, args [][]byte) redis.Reply {
+	delta, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	result := db.incrBy(string(args[0]), delta)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("incrby", args...))
+	}
+	return result
+}
+
+// execDecr decrements the integer value of a key by one
+func execDecr(db *DB, args [][]byte) redis.Reply {
+	result := db.incrBy(string(args[0]), -1)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("decr", args...))
+	}
+	return result
+}
+
+// execDecrBy decrements the integer value of a key by the given amount
+func execDecrBy(db *DB, args [][]byte) redis.Reply {
+	delta, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not an integer or out of range")
+	}
+	if delta == math.MinInt64 {
+		return protocol.NewErrReply("ERR decrement would overflow")
+	}
+	result := db.incrBy(string(args[0]), -delta)
+	if !protocol.IsErrorReply(result) {
+		db.addAof(utils.ToCmdLine3("decrby", args...))
+	}
+	return result
+}
+
+// execIncrByFloat increments the float value of a key by the given amount
+func execIncrByFloat(db *DB, args [][]byte) redis.Reply {
+	key := string(args[0])
+	delta, err := strconv.ParseFloat(string(args[1]), 64)
+	if err != nil {
+		return protocol.NewErrReply("ERR value is not a valid float")
+	}
+
+	bytes, errReply := db.getAsString(key)
+	if errReply != nil {
+		return errReply
+	}
+	var val float64
+	if bytes != nil {
+		val, err = strconv.ParseFloat(string(bytes), 64)
+		if err != nil || math.IsNaN(val) {
+			return protocol.NewErrReply("ERR value is not a valid float")
+		}
+	}
+	val += delta
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		return protocol.NewErrReply("ERR increment would produce NaN or Infinity")
+	}
+	result := []byte(strconv.FormatFloat(val, 'f', -1, 64))
+	db.PutEntity(key, &database.DataEntity{
+		Data: result,
+	})
+	// float arithmetic may differ between platforms, so propagate the result instead of the increment
+	db.addAof(utils.ToCmdLine3("set", args[0], result, []byte("KEEPTTL")))
+	return protocol.NewBulkReply(result)
+}
+
+func init() {
+	RegisterCommand("Set", execSet, writeFirstKey, -3)
+	RegisterCommand("SetNx", execSetNX, writeFirstKey, 3)
+	RegisterCommand("SetEX", execSetEX, writeFirstKey, 4)
+	RegisterCommand("PSetEX", execPSetEX, writeFirstKey, 4)
+	RegisterCommand("MSet", execMSet, prepareMSet, -3)
+	RegisterCommand("MSetNx", execMSetNX, prepareMSet, -3)
+	RegisterCommand("MGet", execMGet, readAllKeys, -2)
+	RegisterCommand("Get", execGet, readFirstKey, 2)
+	RegisterCommand("GetSet", execGetSet, writeFirstKey, 3)
+	RegisterCommand("GetDel", execGetDel, writeFirstKey, 2)
+	RegisterCommand("GetEx", execGetEx, writeFirstKey, -2)
+	RegisterCommand("StrLen", execStrLen, readFirstKey, 2)
+	RegisterCommand("Append", execAppend, writeFirstKey, 3)
+	RegisterCommand("GetRange", execGetRange, readFirstKey, 4)
+	RegisterCommand("SetRange", execSetRange, writeFirstKey, 4)
+	RegisterCommand("Incr", execIncr, writeFirstKey, 2)
+	RegisterCommand("IncrBy", execIncrBy, writeFirstKey, 3)
+	RegisterCommand("IncrByFloat", execIncrByFloat, writeFirstKey, 3)
+	RegisterCommand("Decr", execDecr, writeFirstKey, 2)
+	RegisterCommand("DecrBy", execDecrBy, writeFirstKey, 3)
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/datastruct/set"
+	"github.com/Ravior/goredis/interface/database"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ttlOf returns the remaining seconds of key rounded like TTL, -1 if key has no ttl and -2 if key doesn't exist
+func ttlOf(db *DB, key string) int64 {
+	if _, exists := db.GetEntity(key); !exists {
+		return -2
+	}
+	expireTime, hasTTL := db.TTL(key)
+	if !hasTTL {
+		return -1
+	}
+	return int64((time.Until(expireTime) + 500*time.Millisecond) / time.Second)
+}
+
+func assertTTL(t *testing.T, db *DB, key string, expected int64) {
+	t.Helper()
+	if ttl := ttlOf(db, key); ttl != expected {
+		t.Errorf("expected ttl %d, actually %d", expected, ttl)
+	}
+}
+
+func assertNotExists(t *testing.T, db *DB, key string) {
+	t.Helper()
+	if _, exists := db.GetEntity(key); exists {
+		t.Errorf("expected %s not exists", key)
+	}
+}
+
+func assertExists(t *testing.T, db *DB, key string) {
+	t.Helper()
+	if _, exists := db.GetEntity(key); !exists {
+		t.Errorf("expected %s exists", key)
+	}
+}
+
+// putSet puts a set into db, it is a value of other type than string
+func putSet(db *DB, key string, members ...string) {
+	db.PutEntity(key, &database.DataEntity{
+		Data: set.Make(members...),
+	})
+}
+
+func TestSet(t *testing.T) {
+	db := makeTestDB()
+	assertOkReply(t, execCmd(db, "set", "a", "1"))
+	assertBulkReply(t, execCmd(db, "get", "a"), "1")
+	assertReply(t, execCmd(db, "get", "missing"), "$-1\r\n")
+
+	// NX and XX
+	assertReply(t, execCmd(db, "set", "a", "2", "nx"), "$-1\r\n")
+	assertBulkReply(t, execCmd(db, "get", "a"), "1")
+	assertOkReply(t, execCmd(db, "set", "a", "2", "xx"))
+	assertBulkReply(t, execCmd(db, "get", "a"), "2")
+	assertReply(t, execCmd(db, "set", "b", "1", "xx"), "$-1\r\n")
+	assertNotExists(t, db, "b")
+	assertOkReply(t, execCmd(db, "set", "b", "1", "nx"))
+
+	// GET
+	assertBulkReply(t, execCmd(db, "set", "a", "3", "get"), "2")
+	assertReply(t, execCmd(db, "set", "c", "1", "get"), "$-1\r\n")
+	assertBulkReply(t, execCmd(db, "set", "a", "4", "nx", "get"), "3")
+	assertBulkReply(t, execCmd(db, "get", "a"), "3")
+
+	putSet(db, "set", "a")
+	assertErrReply(t, execCmd(db, "get", "set"),
+		"WRONGTYPE Operation against a key holding the wrong kind of value")
+	assertErrReply(t, execCmd(db, "set", "set", "a", "get"),
+		"WRONGTYPE Operation against a key holding the wrong kind of value")
+	assertOkReply(t, execCmd(db, "set", "set", "a"))
+	assertBulkReply(t, execCmd(db, "get", "set"), "a")
+}
+
+func TestSetTTL(t *testing.T) {
+	db := makeTestDB()
+	assertOkReply(t, execCmd(db, "set", "a", "1", "ex", "100"))
+	assertTTL(t, db, "a", 100)
+	assertOkReply(t, execCmd(db, "set", "a", "1", "px", "100000"))
+	assertTTL(t, db, "a", 100)
+	assertOkReply(t, execCmd(db, "set", "a", "2", "keepttl"))
+	assertTTL(t, db, "a", 100)
+	assertOkReply(t, execCmd(db, "set", "a", "3"))
+	assertTTL(t, db, "a", -1)
+
+	at := time.Now().Add(100 * time.Second)
+	assertOkReply(t, execCmd(db, "set", "a", "1", "exat", strconv.FormatInt(at.Unix(), 10)))
+	// EXAT truncates the fraction of second
+	if ttl := ttlOf(db, "a"); ttl != 99 && ttl != 100 {
+		t.Errorf("expected ttl about 100, actually %d", ttl)
+	}
+	assertOkReply(t, execCmd(db, "set", "a", "1", "pxat", strconv.FormatInt(at.UnixMilli(), 10)))
+	assertTTL(t, db, "a", 100)
+
+	// expire time in the past removes the key
+	assertOkReply(t, execCmd(db, "set", "a", "1", "pxat", "1"))
+	assertNotExists(t, db, "a")
+
+	assertOkReply(t, execCmd(db, "setex", "b", "100", "1"))
+	assertTTL(t, db, "b", 100)
+	assertOkReply(t, execCmd(db, "psetex", "b", "100000", "1"))
+	assertTTL(t, db, "b", 100)
+
+	syntaxErr := "Err syntax error"
+	assertErrReply(t, execCmd(db, "set", "a", "1", "nx", "xx"), syntaxErr)
+	assertErrReply(t, execCmd(db, "set", "a", "1", "ex", "10", "px", "100"), syntaxErr)
+	assertErrReply(t, execCmd(db, "set", "a", "1", "ex", "10", "keepttl"), syntaxErr)
+	assertErrReply(t, execCmd(db, "set", "a", "1", "ex"), syntaxErr)
+	assertErrReply(t, execCmd(db, "set", "a", "1", "foo"), syntaxErr)
+	assertErrReply(t, execCmd(db, "set", "a", "1", "ex", "abc"), "ERR value is not an integer or out of range")
+	assertErrReply(t, execCmd(db, "set", "a", "1", "ex", "0"), "ERR invalid expire time in 'set' command")
+	assertErrReply(t, execCmd(db, "set", "a", "1", "ex", "9223372036854775807"), "ERR invalid expire time in 'set' command")
+	assertErrReply(t, execCmd(db, "setex", "a", "-1", "1"), "ERR invalid expire time in 'setex' command")
+	assertErrReply(t, execCmd(db, "psetex", "a", "0", "1"), "ERR invalid expire time in 'psetex' command")
+}
+
+func TestSetNX(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "setnx", "a", "1"), 1)
+	assertIntReply(t, execCmd(db, "setnx", "a", "2"), 0)
+	assertBulkReply(t, execCmd(db, "get", "a"), "1")
+}
+
+func TestMSet(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "0", "ex", "100")
+	assertOkReply(t, execCmd(db, "mset", "a", "1", "b", "2"))
+	assertTTL(t, db, "a", -1)
+	putSet(db, "set", "a")
+	assertMultiBulkReply(t, execCmd(db, "mget", "a", "b"), "1", "2")
+	assertReply(t, execCmd(db, "mget", "a", "missing", "set"), "*3\r\n$1\r\n1\r\n$-1\r\n$-1\r\n")
+	assertErrReply(t, execCmd(db, "mset", "a", "1", "b"), "ERR wrong number of arguments for 'mset' command")
+
+	assertIntReply(t, execCmd(db, "msetnx", "b", "3", "c", "3"), 0)
+	assertNotExists(t, db, "c")
+	assertIntReply(t, execCmd(db, "msetnx", "c", "3", "d", "4"), 1)
+	assertMultiBulkReply(t, execCmd(db, "mget", "b", "c", "d"), "2", "3", "4")
+	assertErrReply(t, execCmd(db, "msetnx", "e"), "ERR wrong number of arguments for 'msetnx' command")
+}
+
+func TestGetSetDel(t *testing.T) {
+	db := makeTestDB()
+	assertReply(t, execCmd(db, "getset", "a", "1"), "$-1\r\n")
+	db.Expire("a", time.Now().Add(100*time.Second))
+	assertBulkReply(t, execCmd(db, "getset", "a", "2"), "1")
+	assertTTL(t, db, "a", -1)
+	assertBulkReply(t, execCmd(db, "get", "a"), "2")
+
+	assertBulkReply(t, execCmd(db, "getdel", "a"), "2")
+	assertNotExists(t, db, "a")
+	assertReply(t, execCmd(db, "getdel", "a"), "$-1\r\n")
+}
+
+func TestGetEx(t *testing.T) {
+	db := makeTestDB()
+	assertReply(t, execCmd(db, "getex", "a", "ex", "100"), "$-1\r\n")
+	assertNotExists(t, db, "a")
+
+	execCmd(db, "set", "a", "1")
+	assertBulkReply(t, execCmd(db, "getex", "a"), "1")
+	assertTTL(t, db, "a", -1)
+	assertBulkReply(t, execCmd(db, "getex", "a", "ex", "100"), "1")
+	assertTTL(t, db, "a", 100)
+	assertBulkReply(t, execCmd(db, "getex", "a", "persist"), "1")
+	assertTTL(t, db, "a", -1)
+
+	assertErrReply(t, execCmd(db, "getex", "a", "persist", "ex", "10"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "getex", "a", "keepttl"), "Err syntax error")
+	assertErrReply(t, execCmd(db, "getex", "a", "px", "0"), "ERR invalid expire time in 'getex' command")
+}
+
+func TestAppend(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "strlen", "a"), 0)
+	assertIntReply(t, execCmd(db, "append", "a", "hello"), 5)
+	assertIntReply(t, execCmd(db, "append", "a", " world"), 11)
+	assertBulkReply(t, execCmd(db, "get", "a"), "hello world")
+	assertIntReply(t, execCmd(db, "strlen", "a"), 11)
+}
+
+func TestGetRange(t *testing.T) {
+	db := makeTestDB()
+	execCmd(db, "set", "a", "hello world")
+	cases := []struct {
+		start, end string
+		expected   string
+	}{
+		{"0", "4", "hello"},
+		{"-5", "-1", "world"},
+		{"0", "-1", "hello world"},
+		{"6", "100", "world"},
+		{"-100", "4", "hello"},
+		{"5", "3", ""},
+		{"-1", "-5", ""},
+		{"100", "200", ""},
+	}
+	for _, c := range cases {
+		assertBulkReply(t, execCmd(db, "getrange", "a", c.start, c.end), c.expected)
+	}
+	assertBulkReply(t, execCmd(db, "getrange", "missing", "0", "-1"), "")
+	assertErrReply(t, execCmd(db, "getrange", "a", "x", "1"), "ERR value is not an integer or out of range")
+}
+
+func TestIncr(t *testing.T) {
+	db := makeTestDB()
+	assertIntReply(t, execCmd(db, "incr", "a"), 1)
+	assertIntReply(t, execCmd(db, "incrby", "a", "10"), 11)
+	assertIntReply(t, execCmd(db, "decr", "a"), 10)
+	assertIntReply(t, execCmd(db, "decrby", "a", "20"), -10)
+	assertBulkReply(t, execCmd(db, "get", "a"), "-10")
+
+	// ttl is kept
+	db.Expire("a", time.Now().Add(100*time.Second))
+	assertIntReply(t, execCmd(db, "incr", "a"), -9)
+	assertTTL(t, db, "a", 100)
+
+	overflow := "ERR increment or decrement would overflow"
+	execCmd(db, "set", "max", strconv.FormatInt(math.MaxInt64, 10))
+	assertErrReply(t, execCmd(db, "incr", "max"), overflow)
+	execCmd(db, "set", "min", strconv.FormatInt(math.MinInt64, 10))
+	assertErrReply(t, execCmd(db, "decr", "min"), overflow)
+	assertErrReply(t, execCmd(db, "decrby", "a", "-9223372036854775808"), "ERR decrement would overflow")
+
+	notInt := "ERR value is not an integer or out of range"
+	execCmd(db, "set", "str", "abc")
+	assertErrReply(t, execCmd(db, "incr", "str"), notInt)
+	assertErrReply(t, execCmd(db, "incrby", "a", "1.5"), notInt)
+	execCmd(db, "set", "float", "1.5")
+	assertErrReply(t, execCmd(db, "incr", "float"), notInt)
+}
+
+func TestIncrByFloat(t *testing.T) {
+	db := makeTestDB()
+	assertBulkReply(t, execCmd(db, "incrbyfloat", "a", "1.5"), "1.5")
+	assertBulkReply(t, execCmd(db, "incrbyfloat", "a", "-0.5"), "1")
+	assertBulkReply(t, execCmd(db, "incrbyfloat", "a", "1e3"), "1001")
+	execCmd(db, "set", "str", "abc")
+	assertErrReply(t, execCmd(db, "incrbyfloat", "str", "1"), "ERR value is not a valid float")
+	assertErrReply(t, execCmd(db, "incrbyfloat", "a", "abc"), "ERR value is not a valid float")
+	assertErrReply(t, execCmd(db, "incrbyfloat", "a", "inf"), "ERR increment would produce NaN or Infinity")
+	assertBulkReply(t, execCmd(db, "get", "a"), "1001")
+}
+
+func TestIncrConcurrent(t *testing.T) {
+	db := makeTestDB()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			execCmd(db, "incr", "a")
+		}()
+	}
+	wg.Wait()
+	assertBulkReply(t, execCmd(db, "get", "a"), "100")
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func makeTestDB() *DB {
+	return makeDB()
+}
+
+// execCmd executes command line on db with a connection discarding writes
+func execCmd(db *DB, cmdLine ...string) redis.Reply {
+	return db.Exec(connection.NewFakeConn(), utils.ToCmdLine(cmdLine...))
+}
+
+func assertErrReply(t *testing.T, reply redis.Reply, expected string) {
+	t.Helper()
+	errReply, ok := reply.(protocol.ErrorReply)
+	if !ok {
+		t.Errorf("expected error reply %q, actually %q", expected, reply.ToBytes())
+		return
+	}
+	if errReply.Error() != expected {
+		t.Errorf("expected error %q, actually %q", expected, errReply.Error())
+	}
+}
+
+func assertIntReply(t *testing.T, reply redis.Reply, expected int64) {
+	t.Helper()
+	intReply, ok := reply.(*protocol.IntReply)
+	if !ok {
+		t.Errorf("expected int reply %d, actually %q", expected, reply.ToBytes())
+		return
+	}
+	if intReply.Code != expected {
+		t.Errorf("expected %d, actually %d", expected, intReply.Code)
+	}
+}
+
+func assertBulkReply(t *testing.T, reply redis.Reply, expected string) {
+	t.Helper()
+	bulkReply, ok := reply.(*protocol.BulkReply)
+	if !ok {
+		t.Errorf("expected bulk reply %q, actually %q", expected, reply.ToBytes())
+		return
+	}
+	if string(bulkReply.Arg) != expected {
+		t.Errorf("expected %q, actually %q", expected, bulkReply.Arg)
+	}
+}
+
+func assertOkReply(t *testing.T, reply redis.Reply) {
+	t.Helper()
+	if !protocol.IsOKReply(reply) {
+		t.Errorf("expected OK, actually %q", reply.ToBytes())
+	}
+}
+
+// assertMultiBulkReply checks the reply is an array of the expected strings in order
+func assertMultiBulkReply(t *testing.T, reply redis.Reply, expected ...string) {
+	t.Helper()
+	expectedReply := protocol.NewMultiBulkReply(utils.ToCmdLine(expected...))
+	if len(expected) == 0 {
+		if string(reply.ToBytes()) != string(protocol.NewEmptyMultiBulkReply().ToBytes()) &&
+			string(reply.ToBytes()) != string(expectedReply.ToBytes()) {
+			t.Errorf("expected empty array, actually %q", reply.ToBytes())
+		}
+		return
+	}
+	if string(reply.ToBytes()) != string(expectedReply.ToBytes()) {
+		t.Errorf("expected %q, actually %q", expectedReply.ToBytes(), reply.ToBytes())
+	}
+}
+
+// replyStrings returns elements of an array reply, it fails if reply is not an array of bulk strings
+func replyStrings(t *testing.T, reply redis.Reply) []string {
+	t.Helper()
+	multiBulk, ok := reply.(*protocol.MultiBulkReply)
+	if !ok {
+		if _, ok := reply.(*protocol.EmptyMultiBulkReply); ok {
+			return []string{}
+		}
+		t.Fatalf("expected array reply, actually %q", reply.ToBytes())
+	}
+	result := make([]string, len(multiBulk.Args))
+	for i, arg := range multiBulk.Args {
+		result[i] = string(arg)
+	}
+	return result
+}
+
+// assertUnorderedReply checks the reply is an array of the expected strings in any order
+func assertUnorderedReply(t *testing.T, reply redis.Reply, expected ...string) {
+	t.Helper()
+	actual := replyStrings(t, reply)
+	sort.Strings(actual)
+	sorted := append([]string(nil), expected...)
+	sort.Strings(sorted)
+	if strings.Join(actual, ",") != strings.Join(sorted, ",") || len(actual) != len(sorted) {
+		t.Errorf("expected %v in any order, actually %v", expected, actual)
+	}
+}
+
+// assertReply compares the RESP encoding of reply
+func assertReply(t *testing.T, reply redis.Reply, expected string) {
+	t.Helper()
+	if string(reply.ToBytes()) != expected {
+		t.Errorf("expected %q, actually %q", expected, reply.ToBytes())
+	}
+}
+
+// scanOnce executes a SCAN family command, returns the next cursor and elements
+func scanOnce(t *testing.T, db *DB, cmdLine ...string) (string, []string) {
+	t.Helper()
+	reply, ok := execCmd(db, cmdLine...).(*protocol.MultiRawReply)
+	if !ok {
+		t.Fatalf("unexpected reply of %v", cmdLine)
+	}
+	cursor := string(reply.Replies[0].(*protocol.BulkReply).Arg)
+	elements := make([]string, 0)
+	for _, arg := range reply.Replies[1].(*protocol.MultiBulkReply).Args {
+		elements = append(elements, string(arg))
+	}
+	return cursor, elements
+}
+
+// scanAll iterates with a SCAN family command until cursor is 0, it returns elements of each call
+func scanAll(t *testing.T, db *DB, cmdName string, key string, options ...string) [][]string {
+	t.Helper()
+	cursor := "0"
+	var batches [][]string
+	for i := 0; ; i++ {
+		if i > 100000 {
+			t.Fatal("scan doesn't finish")
+		}
+		cmdLine := []string{cmdName}
+		if key != "" {
+			cmdLine = append(cmdLine, key)
+		}
+		cmdLine = append(cmdLine, cursor)
+		cmdLine = append(cmdLine, options...)
+		var batch []string
+		cursor, batch = scanOnce(t, db, cmdLine...)
+		batches = append(batches, batch)
+		if cursor == "0" {
+			return batches
+		}
+	}
+}
@@ -1,5 +1,10 @@
 package database
 
+import (
+	"strconv"
+	"time"
+)
+
 func readFirstKey(args [][]byte) ([]string, []string) {
 	key := string(args[0])
 	return nil, []string{key}
@@ -18,6 +23,10 @@ func writeAllKeys(args [][]byte) ([]string, []string) {
 	return keys, nil
 }
 
+func noPrepare(args [][]byte) ([]string, []string) {
+	return nil, nil
+}
+
 func readAllKeys(args [][]byte) ([]string, []string) {
 	keys := make([]string, len(args))
 	for i, v := range args {
@@ -25,3 +34,12 @@ func readAllKeys(args [][]byte) ([]string, []string) {
 	}
 	return nil, keys
 }
+
+// makeExpireCmd generates command line to set expiration for the given key
+func makeExpireCmd(key string, expireAt time.Time) CmdLine {
+	args := make([][]byte, 3)
+	args[0] = []byte("pexpireat")
+	args[1] = []byte(key)
+	args[2] = []byte(strconv.FormatInt(expireAt.UnixNano()/1e6, 10))
+	return args
+}
@@ -1,5 +1,7 @@
 package bitmap
 
+// BitMap is a bit array, bit 0 is the most significant bit of byte 0, the same as redis
+// so that a BitMap and a redis string share the same bytes
 type BitMap []byte
 
 func New() *BitMap {
@@ -7,6 +9,17 @@ func New() *BitMap {
 	return &b
 }
 
+// FromBytes wraps the given bytes as BitMap without copy
+func FromBytes(bytes []byte) *BitMap {
+	bm := BitMap(bytes)
+	return &bm
+}
+
+// ToBytes returns the underlying bytes of BitMap
+func (b *BitMap) ToBytes() []byte {
+	return *b
+}
+
 func toByteSize(bitSize int64) int64 {
 	if bitSize%8 == 0 {
 		return bitSize / 8
@@ -30,7 +43,7 @@ func (b *BitMap) grow(bitSize int64) {
 func (b *BitMap) SetBit(offset int64, val byte) {
 	byteIndex := offset / 8
 	bitOffset := offset % 8
-	mask := byte(1 << bitOffset)
+	mask := byte(0x80 >> bitOffset)
 	b.grow(offset + 1)
 	if val > 0 {
 		// set bit
@@ -47,33 +60,26 @@ func (b *BitMap) GetBit(offset int64) byte {
 	if byteIndex >= int64(len(*b)) {
 		return 0
 	}
-	return ((*b)[byteIndex] >> bitOffset) & 0x01
+	return ((*b)[byteIndex] >> (7 - bitOffset)) & 0x01
 }
 
 type Callback func(offset int64, val byte) bool
 
+// ForEachBit visits bits within [begin, end), end <= 0 means to the last bit
 func (b *BitMap) ForEachBit(begin int64, end int64, cb Callback) {
-	offset := begin
-	byteIndex := offset / 8
-	bitOffset := offset % 8
-	for byteIndex < int64(len(*b)) {
-		b := (*b)[byteIndex]
-		for bitOffset < 8 {
-			bit := byte(b >> bitOffset & 0x01)
-			if !cb(offset, bit) {
-				return
-			}
-			bitOffset++
-			offset++
-		}
-		byteIndex++
-		bitOffset = 0
-		if end > 0 && offset == end {
-			break
+	bitSize := int64(b.BitSize())
+	if end <= 0 || end > bitSize {
+		end = bitSize
+	}
+	for offset := begin; offset < end; offset++ {
+		bit := ((*b)[offset/8] >> (7 - offset%8)) & 0x01
+		if !cb(offset, bit) {
+			return
 		}
 	}
 }
 
+// ForEachByte visits bytes within [begin, end), end == 0 means to the last byte
 func (b *BitMap) ForEachByte(begin int, end int, cb Callback) {
 	if end == 0 {
 		end = len(*b)
@@ -86,3 +92,22 @@ func (b *BitMap) ForEachByte(begin int, end int, cb Callback) {
 		}
 	}
 }
+
+// GetBits returns the unsigned integer stored in bits [offset, offset+width), the most significant bit first
+// width should be within [1, 64], bits beyond the BitMap are 0
+func (b *BitMap) GetBits(offset int64, width int) uint64 {
+	var val uint64
+	for i := 0; i < width; i++ {
+		val = val<<1 | uint64(b.GetBit(offset+int64(i)))
+	}
+	return val
+}
+
+// SetBits stores the lowest `width` bits of val into bits [offset, offset+width), the most significant bit first
+// width should be within [1, 64], the BitMap grows as needed
+func (b *BitMap) SetBits(offset int64, width int, val uint64) {
+	b.grow(offset + int64(width))
+	for i := 0; i < width; i++ {
+		b.SetBit(offset+int64(i), byte(val>>(width-1-i))&0x01)
+	}
+}
@@ -0,0 +1,111 @@
+package bitmap
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSetBit(t *testing.T) {
+	bm := New()
+	bm.SetBit(1, 1)
+	bm.SetBit(15, 1)
+	if !bytes.Equal(bm.ToBytes(), []byte{0x40, 0x01}) {
+		t.Errorf("unexpected bytes %x", bm.ToBytes())
+	}
+	if bm.BitSize() != 16 {
+		t.Errorf("expected 16 bits, actually %d", bm.BitSize())
+	}
+	for offset := int64(0); offset < 32; offset++ {
+		expected := byte(0)
+		if offset == 1 || offset == 15 {
+			expected = 1
+		}
+		if bit := bm.GetBit(offset); bit != expected {
+			t.Errorf("expected %d at %d, actually %d", expected, offset, bit)
+		}
+	}
+	bm.SetBit(1, 0)
+	if !bytes.Equal(bm.ToBytes(), []byte{0x00, 0x01}) {
+		t.Errorf("unexpected bytes %x", bm.ToBytes())
+	}
+}
+
+func TestFromBytes(t *testing.T) {
+	raw := []byte{0x80, 0x00}
+	bm := FromBytes(raw)
+	if bm.GetBit(0) != 1 {
+		t.Error("expected bit 0 set")
+	}
+	// bytes are shared if the BitMap doesn't grow
+	bm.SetBit(15, 1)
+	if raw[1] != 0x01 {
+		t.Errorf("expected shared bytes, actually %x", raw)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	bm := FromBytes([]byte{0xa0, 0xff, 0x01})
+	var ones []int64
+	bm.ForEachBit(0, 0, func(offset int64, val byte) bool {
+		if val == 1 {
+			ones = append(ones, offset)
+		}
+		return true
+	})
+	if len(ones) != 11 || ones[0] != 0 || ones[1] != 2 || ones[2] != 8 || ones[10] != 23 {
+		t.Errorf("unexpected set bits %v", ones)
+	}
+
+	var visited []int64
+	bm.ForEachBit(6, 100, func(offset int64, val byte) bool {
+		visited = append(visited, offset)
+		return offset < 9
+	})
+	if len(visited) != 4 || visited[0] != 6 || visited[3] != 9 {
+		t.Errorf("unexpected visited bits %v", visited)
+	}
+
+	var values []byte
+	bm.ForEachByte(1, 0, func(offset int64, val byte) bool {
+		values = append(values, val)
+		return true
+	})
+	if !bytes.Equal(values, []byte{0xff, 0x01}) {
+		t.Errorf("unexpected bytes %x", values)
+	}
+	values = values[:0]
+	bm.ForEachByte(0, 10, func(offset int64, val byte) bool {
+		values = append(values, val)
+		return offset < 1
+	})
+	if !bytes.Equal(values, []byte{0xa0, 0xff}) {
+		t.Errorf("unexpected bytes %x", values)
+	}
+}
+
+func TestBits(t *testing.T) {
+	bm := New()
+	bm.SetBits(4, 8, 0xab)
+	if !bytes.Equal(bm.ToBytes(), []byte{0x0a, 0xb0}) {
+		t.Errorf("unexpected bytes %x", bm.ToBytes())
+	}
+	if val := bm.GetBits(4, 8); val != 0xab {
+		t.Errorf("expected 0xab, actually %x", val)
+	}
+	if val := bm.GetBits(0, 4); val != 0 {
+		t.Errorf("expected 0, actually %x", val)
+	}
+	// bits beyond the BitMap are 0
+	if val := bm.GetBits(12, 16); val != 0 {
+		t.Errorf("expected 0, actually %x", val)
+	}
+	bm.SetBits(0, 64, ^uint64(0))
+	if bm.BitSize() != 64 || bm.GetBits(0, 64) != ^uint64(0) {
+		t.Errorf("unexpected bytes %x", bm.ToBytes())
+	}
+	// only the lowest width bits are stored
+	bm.SetBits(8, 4, 0x12)
+	if val := bm.GetBits(8, 8); val != 0x2f {
+		t.Errorf("expected 0x2f, actually %x", val)
+	}
+}
@@ -220,8 +220,8 @@ func (dict *ConcurrentDict) Keys() []string {
 	return keys
 }
 
-// RandomKey returns a key randomly
-func (shard *shard) RandomKey() string {
+// RandomKey returns a key randomly, returns false if the shard is empty
+func (shard *shard) RandomKey() (string, bool) {
 	if shard == nil {
 		panic("shard is nil")
 	}
@@ -229,12 +229,67 @@ func (shard *shard) RandomKey() string {
 	defer shard.mutex.RUnlock()
 
 	for key := range shard.m {
-		return key
+		return key, true
 	}
-	return ""
+	return "", false
 }
 
-// RandomKeys randomly returns keys of the given number, may contain duplicated key
+// RandomKey returns a key randomly, returns false if dict is empty.
+// It tries random shards first, a sparse dict gets more tries but no more than the shards.
+// If all tries miss, it walks shards and picks a key by its position, so that keys following
+// many empty shards are not favored.
+func (dict *ConcurrentDict) RandomKey() (string, bool) {
+	if dict == nil {
+		panic("dict is nil")
+	}
+	shardCount := len(dict.table)
+	tries := shardCount
+	if size := dict.Len(); size > 0 && randomTriesPerKey*shardCount/size < tries {
+		tries = randomTriesPerKey * shardCount / size
+	}
+	for i := 0; i < tries && dict.Len() > 0; i++ {
+		if key, ok := dict.getShard(uint32(rand.Intn(shardCount))).RandomKey(); ok {
+			return key, true
+		}
+	}
+	size := dict.Len()
+	if size <= 0 {
+		return "", false
+	}
+	return dict.nthKey(rand.Intn(size))
+}
+
+// nthKey returns the n-th key in order of shards, so each shard is picked in proportion to its keys.
+// It returns the last key visited if keys are removed concurrently and there are no more than n keys.
+func (dict *ConcurrentDict) nthKey(n int) (string, bool) {
+	last, found := "", false
+	for _, shard := range dict.table {
+		shard.mutex.RLock()
+		if n < len(shard.m) {
+			for key := range shard.m {
+				if n == 0 {
+					shard.mutex.RUnlock()
+					return key, true
+				}
+				n--
+			}
+		}
+		n -= len(shard.m)
+		for key := range shard.m {
+			last, found = key, true
+			break
+		}
+		shard.mutex.RUnlock()
+	}
+	return last, found
+}
+
+// randomTriesPerKey limits random shards tried for each key wanted by RandomKey, RandomKeys and RandomDistinctKeys.
+// Keys may be removed concurrently, so they return fewer keys rather than spinning on empty shards.
+const randomTriesPerKey = 32
+
+// RandomKeys randomly returns keys of the given number, may contain duplicated key.
+// It may return fewer keys if the dict is sparse or shrinks during sampling.
 func (dict *ConcurrentDict) RandomKeys(limit int) []string {
 	size := dict.Len()
 	if limit >= size {
@@ -242,22 +297,18 @@ func (dict *ConcurrentDict) RandomKeys(limit int) []string {
 	}
 	shardCount := len(dict.table)
 
-	result := make([]string, limit)
-	for i := 0; i < limit; {
+	result := make([]string, 0, limit)
+	for tries := 0; len(result) < limit && tries < limit*randomTriesPerKey; tries++ {
 		shard := dict.getShard(uint32(rand.Intn(shardCount)))
-		if shard == nil {
-			continue
-		}
-		key := shard.RandomKey()
-		if key != "" {
-			result[i] = key
-			i++
+		if key, ok := shard.RandomKey(); ok {
+			result = append(result, key)
 		}
 	}
 	return result
 }
 
-// RandomDistinctKeys randomly returns keys of the given number, won't contain duplicated key
+// RandomDistinctKeys randomly returns keys of the given number, won't contain duplicated key.
+// It may return fewer keys if the dict is sparse or shrinks during sampling.
 func (dict *ConcurrentDict) RandomDistinctKeys(limit int) []string {
 	size := dict.Len()
 	if limit >= size {
@@ -266,27 +317,49 @@ func (dict *ConcurrentDict) RandomDistinctKeys(limit int) []string {
 
 	shardCount := len(dict.table)
 	result := make(map[string]bool)
-	for len(result) < limit {
-		shardIndex := uint32(rand.Intn(shardCount))
-		shard := dict.getShard(shardIndex)
-		if shard == nil {
-			continue
-		}
-		key := shard.RandomKey()
-		if key != "" {
+	for tries := 0; len(result) < limit && tries < limit*randomTriesPerKey; tries++ {
+		shard := dict.getShard(uint32(rand.Intn(shardCount)))
+		if key, ok := shard.RandomKey(); ok {
 			result[key] = true
 		}
 	}
-	arr := make([]string, limit)
-	i := 0
+	arr := make([]string, 0, len(result))
 	for k := range result {
-		arr[i] = k
-		i++
+		arr = append(arr, k)
 	}
 	return arr
 }
 
-// Clear removes all keys in dict
+// Scan visits keys shard by shard from the shard of cursor, until at least count keys are visited.
+// It returns the cursor to continue scanning, 0 means all shards are visited.
+// Shards are fixed since the dict is created, so keys existing during the whole scan are visited at least once.
+// Each shard is visited entirely so the return value of iterator is ignored,
+// and iterator shouldn't modify the dict, since it is invoked with the shard locked.
+func (dict *ConcurrentDict) Scan(cursor int, count int, iterator Iterator) int {
+	visited := 0
+	for cursor < len(dict.table) && visited < count {
+		shard := dict.table[cursor]
+		shard.mutex.RLock()
+		for key, value := range shard.m {
+			iterator(key, value)
+			visited++
+		}
+		shard.mutex.RUnlock()
+		cursor++
+	}
+	if cursor >= len(dict.table) {
+		return 0
+	}
+	return cursor
+}
+
+// Clear removes all keys in dict, shards are cleared one by one so that it's safe to use dict meanwhile
 func (dict *ConcurrentDict) Clear() {
-	*dict = *CreateConcurrentDict(dict.shardCount)
+	for _, s := range dict.table {
+		s.mutex.Lock()
+		removed := len(s.m)
+		s.m = make(map[string]interface{})
+		s.mutex.Unlock()
+		atomic.AddInt32(&dict.count, -int32(removed))
+	}
 }
@@ -0,0 +1,121 @@
+package dict
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRandomKey(t *testing.T) {
+	d := CreateConcurrentDict(1 << 16)
+	if _, ok := d.RandomKey(); ok {
+		t.Error("expected no key in empty dict")
+	}
+	d.Put("", 1)
+	if key, ok := d.RandomKey(); !ok || key != "" {
+		t.Errorf("expected empty key, actually %q %t", key, ok)
+	}
+	d.Remove("")
+	for i := 0; i < 100; i++ {
+		d.Put("key"+strconv.Itoa(i), i)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		key, ok := d.RandomKey()
+		if !ok {
+			t.Fatal("expected a key")
+		}
+		if _, exists := d.Get(key); !exists {
+			t.Errorf("expected existing key, actually %q", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("expected random keys, actually %d distinct keys", len(seen))
+	}
+}
+
+// TestRandomKeyFair checks keys of a sparse dict have the same chance whatever shards they are in
+func TestRandomKeyFair(t *testing.T) {
+	d := CreateConcurrentDict(1 << 16)
+	// find two keys in adjacent shards, the second one follows no empty shard
+	shards := make(map[uint32]string)
+	var first, second string
+	for i := 0; second == ""; i++ {
+		key := "key" + strconv.Itoa(i)
+		index := d.spread(fnv32(key))
+		if prev, ok := shards[index-1]; ok {
+			first, second = prev, key
+		} else if next, ok := shards[index+1]; ok {
+			first, second = key, next
+		}
+		shards[index] = key
+	}
+	d.Put(first, 1)
+	d.Put(second, 2)
+	counts := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		key, _ := d.RandomKey()
+		counts[key]++
+	}
+	if counts[first] < 60 || counts[second] < 60 {
+		t.Errorf("expected fair random keys, actually %d %s and %d %s", counts[first], first, counts[second], second)
+	}
+}
+
+func TestConcurrentRandomKeys(t *testing.T) {
+	d := CreateConcurrentDict(1 << 10)
+	for i := 0; i < 100; i++ {
+		d.Put("key"+strconv.Itoa(i), i)
+	}
+	keys := d.RandomDistinctKeys(20)
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if seen[key] {
+			t.Errorf("duplicated key %s", key)
+		}
+		seen[key] = true
+		if _, exists := d.Get(key); !exists {
+			t.Errorf("expected existing key, actually %s", key)
+		}
+	}
+	if len(d.RandomKeys(20)) == 0 || len(keys) == 0 {
+		t.Error("expected sampled keys")
+	}
+	if len(d.RandomDistinctKeys(1000)) != 100 {
+		t.Error("expected all keys if limit exceeds size")
+	}
+}
+
+// TestRandomKeysShrinking checks samplers return while keys are removed concurrently
+func TestRandomKeysShrinking(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		// sampling a sparse dict takes a while, so keys are removed during sampling
+		d := CreateConcurrentDict(1 << 16)
+		for i := 0; i < 25; i++ {
+			d.Put("key"+strconv.Itoa(i), i)
+		}
+		removed := make(chan struct{})
+		go func() {
+			defer close(removed)
+			for i := 0; i < 25; i++ {
+				d.Remove("key" + strconv.Itoa(i))
+			}
+		}()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			d.RandomDistinctKeys(20)
+			d.RandomKeys(20)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("sampling doesn't return after keys removed")
+		}
+		<-removed
+		if keys := d.RandomDistinctKeys(20); len(keys) != 0 {
+			t.Errorf("expected no keys, actually %v", keys)
+		}
+	}
+}
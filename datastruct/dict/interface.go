@@ -14,5 +14,6 @@ type Dict interface {
 	Keys() []string
 	RandomKeys(limit int) []string
 	RandomDistinctKeys(limit int) []string
+	Scan(cursor int, count int, iterator Iterator) int
 	Clear()
 }
@@ -0,0 +1,101 @@
+package dict
+
+import "math/bits"
+
+const (
+	// scanBuckets is the number of buckets keys are grouped into by hash, a scan cursor is the bucket to continue with
+	scanBuckets = 1 << 16
+	// ScanIndexThreshold is the size above which a collection keeps a ScanIndex,
+	// smaller ones are scanned entirely in one call, just like compact encodings of redis
+	ScanIndexThreshold = 128
+)
+
+// ScanIndex groups keys by the bucket of their hash, so that a scan only visits the buckets it returns.
+// Buckets never change, so a key existing during the whole scan is visited at least once however keys change.
+type ScanIndex struct {
+	buckets map[uint16][]string
+	// occupied marks non-empty buckets, summary marks non-zero words of occupied
+	occupied [scanBuckets / 64]uint64
+	summary  [scanBuckets / 64 / 64]uint64
+}
+
+// MakeScanIndex creates an empty ScanIndex
+func MakeScanIndex() *ScanIndex {
+	return &ScanIndex{
+		buckets: make(map[uint16][]string),
+	}
+}
+
+func scanBucket(key string) int {
+	return int(fnv32(key) % scanBuckets)
+}
+
+// Add puts a key which is not in the index yet
+func (index *ScanIndex) Add(key string) {
+	bucket := scanBucket(key)
+	index.buckets[uint16(bucket)] = append(index.buckets[uint16(bucket)], key)
+	index.occupied[bucket/64] |= 1 << (bucket % 64)
+	index.summary[bucket/64/64] |= 1 << (bucket / 64 % 64)
+}
+
+// Remove removes a key from the index
+func (index *ScanIndex) Remove(key string) {
+	bucket := scanBucket(key)
+	keys := index.buckets[uint16(bucket)]
+	for i, k := range keys {
+		if k == key {
+			keys[i] = keys[len(keys)-1]
+			keys = keys[:len(keys)-1]
+			break
+		}
+	}
+	if len(keys) > 0 {
+		index.buckets[uint16(bucket)] = keys
+		return
+	}
+	delete(index.buckets, uint16(bucket))
+	index.occupied[bucket/64] &^= 1 << (bucket % 64)
+	if index.occupied[bucket/64] == 0 {
+		index.summary[bucket/64/64] &^= 1 << (bucket / 64 % 64)
+	}
+}
+
+// next returns the first non-empty bucket not less than the given one, -1 if there is none
+func (index *ScanIndex) next(bucket int) int {
+	word := bucket / 64
+	if rest := index.occupied[word] >> (bucket % 64); rest != 0 {
+		return bucket + bits.TrailingZeros64(rest)
+	}
+	// look for the next non-zero word by summary
+	for word++; word < len(index.occupied); {
+		if rest := index.summary[word/64] >> (word % 64); rest != 0 {
+			word += bits.TrailingZeros64(rest)
+			return word*64 + bits.TrailingZeros64(index.occupied[word])
+		}
+		word = (word/64 + 1) * 64
+	}
+	return -1
+}
+
+// Scan visits keys bucket by bucket from the bucket of cursor, until at least count keys are visited.
+// It returns the cursor to continue scanning, 0 means all buckets are visited.
+// Each bucket is visited entirely, and iterator shouldn't modify the index.
+func (index *ScanIndex) Scan(cursor int, count int, iterator func(key string)) int {
+	visited := 0
+	for cursor < scanBuckets {
+		bucket := index.next(cursor)
+		if bucket < 0 {
+			return 0
+		}
+		if visited >= count {
+			return bucket
+		}
+		keys := index.buckets[uint16(bucket)]
+		for _, key := range keys {
+			iterator(key)
+		}
+		visited += len(keys)
+		cursor = bucket + 1
+	}
+	return 0
+}
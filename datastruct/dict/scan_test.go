@@ -0,0 +1,93 @@
+package dict
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestScanIndexNext(t *testing.T) {
+	index := MakeScanIndex()
+	if index.next(0) != -1 {
+		t.Error("expected no bucket in empty index")
+	}
+	for _, bucket := range []int{0, 63, 64, 4095, 4096, scanBuckets - 1} {
+		index.buckets[uint16(bucket)] = []string{strconv.Itoa(bucket)}
+		index.occupied[bucket/64] |= 1 << (bucket % 64)
+		index.summary[bucket/64/64] |= 1 << (bucket / 64 % 64)
+	}
+	cases := map[int]int{0: 0, 1: 63, 63: 63, 64: 64, 65: 4095, 4096: 4096, 4097: scanBuckets - 1}
+	for from, expected := range cases {
+		if actual := index.next(from); actual != expected {
+			t.Errorf("next(%d): expected %d, actually %d", from, expected, actual)
+		}
+	}
+}
+
+func TestSimpleDictScan(t *testing.T) {
+	d := CreateSimpleDict()
+	for i := 0; i < 10; i++ {
+		d.Put("key"+strconv.Itoa(i), i)
+	}
+	// a small dict is visited in one call
+	visited := 0
+	if cursor := d.Scan(0, 1, func(key string, val interface{}) bool {
+		visited++
+		return true
+	}); cursor != 0 || visited != 10 {
+		t.Errorf("expected 10 keys in one call, actually %d keys and cursor %d", visited, cursor)
+	}
+
+	for i := 10; i < 10000; i++ {
+		d.Put("key"+strconv.Itoa(i), i)
+	}
+	// keys existing during the whole scan are visited at least once
+	seen := make(map[string]bool)
+	cursor, calls := 0, 0
+	for {
+		for i := 0; i < 10; i++ {
+			d.Put("new"+strconv.Itoa(rand.Int()), 0)
+			d.Remove("key" + strconv.Itoa(9000+rand.Intn(1000)))
+		}
+		visited := 0
+		cursor = d.Scan(cursor, 100, func(key string, val interface{}) bool {
+			if key != "" && key[0] == 'k' && val.(int) != mustAtoi(key[3:]) {
+				t.Fatalf("unexpected value %v of %s", val, key)
+			}
+			seen[key] = true
+			visited++
+			return true
+		})
+		calls++
+		if cursor == 0 {
+			break
+		}
+		if visited < 100 {
+			t.Errorf("expected at least 100 keys, actually %d", visited)
+		}
+	}
+	for i := 0; i < 9000; i++ {
+		if !seen["key"+strconv.Itoa(i)] {
+			t.Fatalf("key%d is not visited", i)
+		}
+	}
+	if calls > 200 {
+		t.Errorf("expected about 100 calls, actually %d", calls)
+	}
+
+	// removing all keys empties index
+	for _, key := range d.Keys() {
+		d.Remove(key)
+	}
+	if d.Scan(0, 10, func(key string, val interface{}) bool {
+		t.Errorf("unexpected key %s", key)
+		return true
+	}) != 0 {
+		t.Error("expected cursor 0")
+	}
+}
+
+func mustAtoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -3,6 +3,8 @@ package dict
 // SimpleDict wraps a map, it is not thread safe
 type SimpleDict struct {
 	m map[string]interface{}
+	// index is built once the dict grows above ScanIndexThreshold
+	index *ScanIndex
 }
 
 // CreateSimpleDict create a new map
@@ -33,6 +35,7 @@ func (dict *SimpleDict) Put(key string, val interface{}) (result int) {
 	if existed {
 		return 0
 	}
+	dict.indexKey(key)
 	return 1
 }
 
@@ -43,6 +46,7 @@ func (dict *SimpleDict) PutIfAbsent(key string, val interface{}) (result int) {
 		return 0
 	}
 	dict.m[key] = val
+	dict.indexKey(key)
 	return 1
 }
 
@@ -61,11 +65,28 @@ func (dict *SimpleDict) Remove(key string) (result int) {
 	_, existed := dict.m[key]
 	delete(dict.m, key)
 	if existed {
+		if dict.index != nil {
+			dict.index.Remove(key)
+		}
 		return 1
 	}
 	return 0
 }
 
+// indexKey adds a new key into scan index, the index is built when the dict becomes big
+func (dict *SimpleDict) indexKey(key string) {
+	if dict.index != nil {
+		dict.index.Add(key)
+		return
+	}
+	if len(dict.m) > ScanIndexThreshold {
+		dict.index = MakeScanIndex()
+		for k := range dict.m {
+			dict.index.Add(k)
+		}
+	}
+}
+
 // ForEach traversal the dict
 func (dict *SimpleDict) ForEach(iterator Iterator) {
 	for k, v := range dict.m {
@@ -75,6 +96,21 @@ func (dict *SimpleDict) ForEach(iterator Iterator) {
 	}
 }
 
+// Scan visits keys bucket by bucket from the bucket of cursor, until at least count keys are visited.
+// It returns the cursor to continue scanning, 0 means all keys are visited. A small dict is visited in one call.
+// Iterator shouldn't modify the dict and its return value is ignored.
+func (dict *SimpleDict) Scan(cursor int, count int, iterator Iterator) int {
+	if dict.index == nil {
+		for k, v := range dict.m {
+			iterator(k, v)
+		}
+		return 0
+	}
+	return dict.index.Scan(cursor, count, func(key string) {
+		iterator(key, dict.m[key])
+	})
+}
+
 // Keys returns all keys in dict
 func (dict *SimpleDict) Keys() []string {
 	result := make([]string, len(dict.m))
@@ -0,0 +1,164 @@
+package hyperloglog
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func newFilled(start, count int) *HyperLogLog {
+	hll := New()
+	for i := start; i < start+count; i++ {
+		hll.Add([]byte("element" + strconv.Itoa(i)))
+	}
+	return hll
+}
+
+func assertEstimate(t *testing.T, hll *HyperLogLog, expected int) {
+	t.Helper()
+	actual := hll.Count()
+	if math.Abs(float64(actual)-float64(expected)) > float64(expected)*0.03 {
+		t.Errorf("expected about %d, actually %d", expected, actual)
+	}
+}
+
+func TestCount(t *testing.T) {
+	hll := New()
+	if hll.Count() != 0 {
+		t.Errorf("expected 0, actually %d", hll.Count())
+	}
+	for _, size := range []int{10, 100, 1000, 10000, 100000} {
+		hll := newFilled(0, size)
+		assertEstimate(t, hll, size)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	hll := New()
+	if !hll.Add([]byte("a")) {
+		t.Error("expected updated")
+	}
+	if hll.Add([]byte("a")) {
+		t.Error("expected not updated by duplicated element")
+	}
+	if hll.Count() != 1 {
+		t.Errorf("expected 1, actually %d", hll.Count())
+	}
+}
+
+func TestPromote(t *testing.T) {
+	hll := newFilled(0, 100)
+	if !hll.IsSparse() {
+		t.Error("expected sparse")
+	}
+	hll = newFilled(0, 10000)
+	if hll.IsSparse() {
+		t.Error("expected dense")
+	}
+}
+
+func TestMerge(t *testing.T) {
+	// sparse into sparse, and sparse into dense
+	for _, size := range []int{100, 10000} {
+		hll := newFilled(0, size)
+		hll.Merge(newFilled(size/2, size))
+		assertEstimate(t, hll, size*3/2)
+	}
+	// dense into sparse
+	hll := newFilled(0, 100)
+	hll.Merge(newFilled(0, 10000))
+	assertEstimate(t, hll, 10000)
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, size := range []int{0, 100, 10000} {
+		hll := newFilled(0, size)
+		data, err := hll.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data[:len(magic)]) != magic {
+			t.Errorf("expected magic %q, actually %q", magic, data[:len(magic)])
+		}
+		restored := &HyperLogLog{}
+		if err := restored.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if restored.IsSparse() != hll.IsSparse() {
+			t.Errorf("expected sparse %t, actually %t", hll.IsSparse(), restored.IsSparse())
+		}
+		if restored.Count() != hll.Count() {
+			t.Errorf("expected %d, actually %d", hll.Count(), restored.Count())
+		}
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	dense, _ := newFilled(0, 10000).MarshalBinary()
+	sparse, _ := newFilled(0, 100).MarshalBinary()
+	cases := map[string][]byte{
+		"empty":            {},
+		"plain string":     []byte("hello world"),
+		"bad magic":        append([]byte("HYLX"), dense[len(magic):]...),
+		"unknown encoding": append([]byte(magic), 2),
+		"truncated dense":  dense[:len(dense)-1],
+		"truncated sparse": sparse[:len(sparse)-1],
+		"zero register":    append([]byte(magic), encodingSparse, 0, 1, 0),
+		"register too big": append([]byte(magic), encodingSparse, 0, 1, registerMax+1),
+		"index too big":    append([]byte(magic), encodingSparse, 0xff, 0xff, 1),
+	}
+	for name, data := range cases {
+		hll := &HyperLogLog{}
+		if err := hll.UnmarshalBinary(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestAddEncoded(t *testing.T) {
+	hll := New()
+	data, _ := hll.MarshalBinary()
+	// sparse, promoted to dense, and dense
+	for i := 0; i < 10000; i += 100 {
+		var elements [][]byte
+		for j := i; j < i+100; j++ {
+			elements = append(elements, []byte("element"+strconv.Itoa(j)))
+		}
+		var updated bool
+		var err error
+		data, updated, err = AddEncoded(data, elements...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectUpdated := false
+		for _, element := range elements {
+			if hll.Add(element) {
+				expectUpdated = true
+			}
+		}
+		if updated != expectUpdated {
+			t.Errorf("expected updated %t, actually %t", expectUpdated, updated)
+		}
+		expected, _ := hll.MarshalBinary()
+		if string(data) != string(expected) {
+			t.Fatalf("encoding mismatched after %d elements", i+100)
+		}
+	}
+	if hll.IsSparse() {
+		t.Error("expected dense")
+	}
+
+	// dense registers are updated in place
+	updated := false
+	for i := 10000; !updated; i++ {
+		var result []byte
+		result, updated, _ = AddEncoded(data, []byte("element"+strconv.Itoa(i)))
+		if &result[0] != &data[0] {
+			t.Fatal("expected updated in place")
+		}
+	}
+
+	if _, _, err := AddEncoded([]byte("hello world"), []byte("a")); err == nil {
+		t.Error("expected error")
+	}
+}
@@ -14,11 +14,18 @@ type QuickList struct {
 	size int
 }
 
+// NewQuickList creates an empty QuickList
+func NewQuickList() *QuickList {
+	return &QuickList{
+		data: list.New(),
+	}
+}
+
 // Add adds value to the tail
 func (ql *QuickList) Add(val interface{}) {
 	ql.size++
 	if ql.data.Len() == 0 { // empty list
-		page := make([]interface{}, pageSize)
+		page := make([]interface{}, 0, pageSize)
 		page = append(page, val)
 		ql.data.PushBack(page)
 		return
@@ -158,6 +165,9 @@ func (ql *QuickList) RemoveLast() interface{} {
 
 // RemoveAllByVal removes all elements with the given val
 func (ql *QuickList) RemoveAllByVal(expected Expected) int {
+	if ql.size == 0 {
+		return 0
+	}
 	iter := ql.find(0)
 	removed := 0
 	for !iter.atEnd() {
@@ -203,7 +213,7 @@ func (ql *QuickList) ReverseRemoveByVal(expected Expected, count int) int {
 		if expected(iter.get()) {
 			iter.remove()
 			removed++
-			if removed == count {
+			if removed == count || ql.size == 0 {
 				break
 			}
 		}
@@ -91,10 +91,17 @@ func (qln *quickListNode) remove() interface{} {
 	} else {
 		// page is empty, update iter.doubleLinkedListNode and iter.offset
 		if qln.node == qln.ql.data.Back() {
-			// removed last element, ql is empty now
+			// removed the last page, move to the end of previous page so that iter.atEnd() == true
+			prevNode := qln.node.Prev()
 			qln.ql.data.Remove(qln.node)
-			qln.node = nil
-			qln.offset = 0
+			if prevNode != nil {
+				qln.node = prevNode
+				qln.offset = len(prevNode.Value.([]interface{}))
+			} else {
+				// ql is empty now
+				qln.node = nil
+				qln.offset = 0
+			}
 		} else {
 			nextNode := qln.node.Next()
 			qln.ql.data.Remove(qln.node)
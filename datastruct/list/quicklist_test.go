@@ -0,0 +1,198 @@
+package list
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// assertList compares the QuickList with the expected slice through Len, Get, ForEach and Range
+func assertList(t *testing.T, ql *QuickList, expected []int) {
+	t.Helper()
+	if ql.Len() != len(expected) {
+		t.Fatalf("expected len %d, actually %d", len(expected), ql.Len())
+	}
+	ql.ForEach(func(i int, v interface{}) bool {
+		if v.(int) != expected[i] {
+			t.Fatalf("expected %d at %d, actually %d", expected[i], i, v)
+		}
+		return true
+	})
+	if len(expected) == 0 {
+		return
+	}
+	for _, i := range []int{0, len(expected) / 2, len(expected) - 1} {
+		if ql.Get(i).(int) != expected[i] {
+			t.Fatalf("expected %d at %d, actually %d", expected[i], i, ql.Get(i))
+		}
+	}
+	for i, v := range ql.Range(0, len(expected)) {
+		if v.(int) != expected[i] {
+			t.Fatalf("expected %d at %d of range, actually %d", expected[i], i, v)
+		}
+	}
+}
+
+func makeQuickList(size int) (*QuickList, []int) {
+	ql := NewQuickList()
+	expected := make([]int, size)
+	for i := 0; i < size; i++ {
+		ql.Add(i)
+		expected[i] = i
+	}
+	return ql, expected
+}
+
+func equals(val int) Expected {
+	return func(a interface{}) bool {
+		return a.(int) == val
+	}
+}
+
+func TestQuickListAdd(t *testing.T) {
+	ql, expected := makeQuickList(pageSize*3 + 1)
+	assertList(t, ql, expected)
+	for i := range expected {
+		ql.Set(i, i*2)
+		expected[i] = i * 2
+	}
+	assertList(t, ql, expected)
+}
+
+func TestQuickListInsert(t *testing.T) {
+	ql, expected := makeQuickList(pageSize * 2)
+	// insert into full pages, at begin, middle and end of them
+	for _, index := range []int{0, pageSize / 2, pageSize - 1, pageSize, pageSize + 10, len(expected)} {
+		ql.Insert(index, -index)
+		expected = append(expected[:index], append([]int{-index}, expected[index:]...)...)
+		assertList(t, ql, expected)
+	}
+	for i := 0; i < 1000; i++ {
+		index := rand.Intn(len(expected) + 1)
+		ql.Insert(index, i)
+		expected = append(expected[:index], append([]int{i}, expected[index:]...)...)
+	}
+	assertList(t, ql, expected)
+}
+
+func TestQuickListRemove(t *testing.T) {
+	ql, expected := makeQuickList(pageSize*2 + 10)
+	for len(expected) > 0 {
+		index := rand.Intn(len(expected))
+		val := ql.Remove(index)
+		if val.(int) != expected[index] {
+			t.Fatalf("expected %d, actually %d", expected[index], val)
+		}
+		expected = append(expected[:index], expected[index+1:]...)
+		if len(expected)%100 == 0 {
+			assertList(t, ql, expected)
+		}
+	}
+	assertList(t, ql, expected)
+
+	ql, expected = makeQuickList(pageSize + 1)
+	for len(expected) > 0 {
+		if ql.RemoveLast().(int) != expected[len(expected)-1] {
+			t.Fatal("unexpected last element")
+		}
+		expected = expected[:len(expected)-1]
+	}
+	assertList(t, ql, expected)
+	if ql.RemoveLast() != nil {
+		t.Error("expected nil from empty list")
+	}
+}
+
+// makeRepeatedList builds a list alternating 0 and 1, so values to remove spread over pages
+func makeRepeatedList(size int) (*QuickList, []int) {
+	ql := NewQuickList()
+	expected := make([]int, size)
+	for i := 0; i < size; i++ {
+		ql.Add(i % 2)
+		expected[i] = i % 2
+	}
+	return ql, expected
+}
+
+func removeFromSlice(slice []int, val int, count int, reverse bool) []int {
+	removed := 0
+	result := make([]int, 0, len(slice))
+	if !reverse {
+		for _, v := range slice {
+			if v == val && removed < count {
+				removed++
+				continue
+			}
+			result = append(result, v)
+		}
+		return result
+	}
+	for i := len(slice) - 1; i >= 0; i-- {
+		if slice[i] == val && removed < count {
+			removed++
+			continue
+		}
+		result = append([]int{slice[i]}, result...)
+	}
+	return result
+}
+
+func TestQuickListRemoveByVal(t *testing.T) {
+	size := pageSize*2 + 3
+	for _, count := range []int{1, 10, pageSize, size} {
+		ql, expected := makeRepeatedList(size)
+		removed := ql.RemoveByVal(equals(1), count)
+		expected2 := removeFromSlice(expected, 1, count, false)
+		if removed != len(expected)-len(expected2) {
+			t.Errorf("expected %d removed, actually %d", len(expected)-len(expected2), removed)
+		}
+		assertList(t, ql, expected2)
+
+		ql, expected = makeRepeatedList(size)
+		removed = ql.ReverseRemoveByVal(equals(0), count)
+		expected2 = removeFromSlice(expected, 0, count, true)
+		if removed != len(expected)-len(expected2) {
+			t.Errorf("expected %d removed, actually %d", len(expected)-len(expected2), removed)
+		}
+		assertList(t, ql, expected2)
+	}
+
+	// remove every element, pages get empty and removed
+	ql, expected := makeQuickList(pageSize + 1)
+	if removed := ql.RemoveAllByVal(func(a interface{}) bool { return true }); removed != len(expected) {
+		t.Errorf("expected %d removed, actually %d", len(expected), removed)
+	}
+	assertList(t, ql, nil)
+	ql, expected = makeQuickList(pageSize + 1)
+	if removed := ql.ReverseRemoveByVal(func(a interface{}) bool { return true }, len(expected)); removed != len(expected) {
+		t.Errorf("expected %d removed, actually %d", len(expected), removed)
+	}
+	assertList(t, ql, nil)
+	ql, expected = makeQuickList(pageSize + 1)
+	if removed := ql.RemoveByVal(func(a interface{}) bool { return true }, len(expected)); removed != len(expected) {
+		t.Errorf("expected %d removed, actually %d", len(expected), removed)
+	}
+	assertList(t, ql, nil)
+
+	// removing from an empty list
+	if ql.RemoveAllByVal(equals(0)) != 0 || ql.RemoveByVal(equals(0), 1) != 0 || ql.ReverseRemoveByVal(equals(0), 1) != 0 {
+		t.Error("expected nothing removed")
+	}
+
+	// remove the tail of the last page, iterator must stop at the end
+	ql, expected = makeQuickList(pageSize + 2)
+	last := len(expected) - 1
+	if removed := ql.RemoveAllByVal(equals(last)); removed != 1 {
+		t.Errorf("expected 1 removed, actually %d", removed)
+	}
+	assertList(t, ql, expected[:last])
+}
+
+func TestQuickListContains(t *testing.T) {
+	ql, _ := makeQuickList(pageSize + 1)
+	if !ql.Contains(equals(pageSize)) {
+		t.Error("expected contains")
+	}
+	if ql.Contains(equals(-1)) {
+		t.Error("expected not contains")
+	}
+}
@@ -62,6 +62,15 @@ func (set *Set) ForEach(iterator func(member string) bool) {
 	})
 }
 
+// Scan visits members from cursor until at least count members are visited, returns the cursor to continue.
+// 0 means all members are visited.
+func (set *Set) Scan(cursor int, count int, iterator func(member string)) int {
+	return set.dict.Scan(cursor, count, func(key string, val interface{}) bool {
+		iterator(key)
+		return true
+	})
+}
+
 // Intersect intersects two sets
 func (set *Set) Intersect(another *Set) *Set {
 	if set == nil {
@@ -0,0 +1,129 @@
+package sortedset
+
+import "math"
+
+// Aggregate combines scores of the same member from different sorted sets
+type Aggregate func(a, b float64) float64
+
+// AggregateSum adds scores up, it is the default aggregate of ZUNIONSTORE and ZINTERSTORE
+func AggregateSum(a, b float64) float64 {
+	return a + b
+}
+
+// AggregateMin keeps the lower score
+func AggregateMin(a, b float64) float64 {
+	return math.Min(a, b)
+}
+
+// AggregateMax keeps the higher score
+func AggregateMax(a, b float64) float64 {
+	return math.Max(a, b)
+}
+
+// weighted multiplies score by weight, NaN (e.g. inf * 0) is treated as 0 the same as redis
+func weighted(score float64, weight float64) float64 {
+	result := score * weight
+	if math.IsNaN(result) {
+		return 0
+	}
+	return result
+}
+
+func aggregate(fn Aggregate, a, b float64) float64 {
+	result := fn(a, b)
+	if math.IsNaN(result) {
+		// -inf + inf
+		return 0
+	}
+	return result
+}
+
+// Union returns a sorted set contains members of all given sets, nil set is treated as empty set
+// score of each set is multiplied by the corresponding weight before aggregation, nil weights means all 1
+func Union(sets []*SortedSet, weights []float64, fn Aggregate) *SortedSet {
+	result := NwSortedSet()
+	for i, set := range sets {
+		if set == nil {
+			continue
+		}
+		weight := 1.0
+		if weights != nil {
+			weight = weights[i]
+		}
+		for member, element := range set.dict {
+			score := weighted(element.Score, weight)
+			if current, ok := result.dict[member]; ok {
+				score = aggregate(fn, current.Score, score)
+			}
+			result.Add(member, score)
+		}
+	}
+	return result
+}
+
+// Intersect returns a sorted set contains members existing in all given sets, nil set is treated as empty set
+// score of each set is multiplied by the corresponding weight before aggregation, nil weights means all 1
+func Intersect(sets []*SortedSet, weights []float64, fn Aggregate) *SortedSet {
+	result := NwSortedSet()
+	if len(sets) == 0 {
+		return result
+	}
+	// iterate the smallest set
+	smallest := 0
+	for i, set := range sets {
+		if set == nil {
+			return result
+		}
+		if set.Len() < sets[smallest].Len() {
+			smallest = i
+		}
+	}
+	for member := range sets[smallest].dict {
+		var score float64
+		matched := true
+		for i, set := range sets {
+			element, ok := set.dict[member]
+			if !ok {
+				matched = false
+				break
+			}
+			weight := 1.0
+			if weights != nil {
+				weight = weights[i]
+			}
+			if i == 0 {
+				score = weighted(element.Score, weight)
+			} else {
+				score = aggregate(fn, score, weighted(element.Score, weight))
+			}
+		}
+		if matched {
+			result.Add(member, score)
+		}
+	}
+	return result
+}
+
+// Diff returns a sorted set contains members of the first set which don't exist in the other sets
+func Diff(sets []*SortedSet) *SortedSet {
+	result := NwSortedSet()
+	if len(sets) == 0 || sets[0] == nil {
+		return result
+	}
+	for member, element := range sets[0].dict {
+		found := false
+		for _, set := range sets[1:] {
+			if set == nil {
+				continue
+			}
+			if _, ok := set.dict[member]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result.Add(member, element.Score)
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package sortedset
+
+import (
+	"math"
+	"testing"
+)
+
+func makeSet(pairs ...interface{}) *SortedSet {
+	set := NwSortedSet()
+	for i := 0; i < len(pairs); i += 2 {
+		set.Add(pairs[i].(string), pairs[i+1].(float64))
+	}
+	return set
+}
+
+func assertSet(t *testing.T, actual *SortedSet, expected *SortedSet) {
+	t.Helper()
+	if actual.Len() != expected.Len() {
+		t.Fatalf("expected %d members, actually %d", expected.Len(), actual.Len())
+	}
+	if expected.Len() == 0 {
+		return
+	}
+	assertElements(t, actual.Range(0, actual.Len(), false), expected.Range(0, expected.Len(), false))
+}
+
+func TestUnion(t *testing.T) {
+	a := makeSet("a", 1.0, "b", 2.0)
+	b := makeSet("b", 3.0, "c", 4.0)
+	assertSet(t, Union([]*SortedSet{a, b, nil}, nil, AggregateSum), makeSet("a", 1.0, "b", 5.0, "c", 4.0))
+	assertSet(t, Union([]*SortedSet{a, b}, nil, AggregateMin), makeSet("a", 1.0, "b", 2.0, "c", 4.0))
+	assertSet(t, Union([]*SortedSet{a, b}, nil, AggregateMax), makeSet("a", 1.0, "b", 3.0, "c", 4.0))
+	assertSet(t, Union([]*SortedSet{a, b}, []float64{2, -1}, AggregateSum), makeSet("c", -4.0, "b", 1.0, "a", 2.0))
+	assertSet(t, Union(nil, nil, AggregateSum), NwSortedSet())
+	// operands are not modified
+	assertSet(t, a, makeSet("a", 1.0, "b", 2.0))
+}
+
+func TestIntersect(t *testing.T) {
+	a := makeSet("a", 1.0, "b", 2.0, "c", 3.0)
+	b := makeSet("b", 3.0, "c", 4.0, "d", 5.0)
+	c := makeSet("c", 1.0)
+	assertSet(t, Intersect([]*SortedSet{a, b}, nil, AggregateSum), makeSet("b", 5.0, "c", 7.0))
+	assertSet(t, Intersect([]*SortedSet{a, b, c}, nil, AggregateMax), makeSet("c", 4.0))
+	assertSet(t, Intersect([]*SortedSet{a, b}, []float64{0, 1}, AggregateMin), makeSet("b", 0.0, "c", 0.0))
+	assertSet(t, Intersect([]*SortedSet{a, nil}, nil, AggregateSum), NwSortedSet())
+	assertSet(t, Intersect(nil, nil, AggregateSum), NwSortedSet())
+}
+
+func TestDiff(t *testing.T) {
+	a := makeSet("a", 1.0, "b", 2.0, "c", 3.0)
+	b := makeSet("b", 3.0)
+	c := makeSet("c", 4.0, "d", 5.0)
+	assertSet(t, Diff([]*SortedSet{a, b, nil, c}), makeSet("a", 1.0))
+	assertSet(t, Diff([]*SortedSet{a}), a)
+	assertSet(t, Diff([]*SortedSet{nil, a}), NwSortedSet())
+}
+
+func TestAlgebraInf(t *testing.T) {
+	a := makeSet("a", math.Inf(1))
+	b := makeSet("a", math.Inf(-1))
+	// inf * 0 and inf + -inf are treated as 0
+	assertSet(t, Union([]*SortedSet{a}, []float64{0}, AggregateSum), makeSet("a", 0.0))
+	assertSet(t, Union([]*SortedSet{a, b}, nil, AggregateSum), makeSet("a", 0.0))
+	assertSet(t, Intersect([]*SortedSet{a, b}, nil, AggregateMin), b)
+}
@@ -2,6 +2,7 @@ package sortedset
 
 import (
 	"errors"
+	"math"
 	"strconv"
 )
 
@@ -59,7 +60,9 @@ var negativeInfBorder = &ScoreBorder{
 	Inf: negativeInf,
 }
 
-// ParseScoreBorder creates ScoreBorder from redis arguments
+var errScoreBorder = errors.New("ERR min or max is not a float")
+
+// ParseScoreBorder creates ScoreBorder from redis arguments, NaN is not a valid border
 func ParseScoreBorder(s string) (*ScoreBorder, error) {
 	if s == "inf" || s == "+inf" {
 		return positiveInfBorder, nil
@@ -67,24 +70,85 @@ func ParseScoreBorder(s string) (*ScoreBorder, error) {
 	if s == "-inf" {
 		return negativeInfBorder, nil
 	}
-	if s[0] == '(' {
-		value, err := strconv.ParseFloat(s[1:], 64)
-		if err != nil {
-			return nil, errors.New("ERR min or max is not a float")
-		}
-		return &ScoreBorder{
-			Inf:     0,
-			Value:   value,
-			Exclude: true,
-		}, nil
+	exclude := false
+	if len(s) > 0 && s[0] == '(' {
+		exclude = true
+		s = s[1:]
 	}
 	value, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return nil, errors.New("ERR min or max is not a float")
+	if err != nil || math.IsNaN(value) {
+		return nil, errScoreBorder
 	}
 	return &ScoreBorder{
 		Inf:     0,
 		Value:   value,
-		Exclude: false,
+		Exclude: exclude,
+	}, nil
+}
+
+/*
+ * LexBorder is a struct represents `min` `max` parameter of redis command `ZRANGEBYLEX`
+ * can accept:
+ *   inclusive value, such as [a
+ *   exclusive value, such as (a
+ *   infinity: - and +
+ */
+
+// LexBorder represents range of a member, including: <, <=, >, >=, +, -
+type LexBorder struct {
+	Inf     int8
+	Value   string
+	Exclude bool
+}
+
+// if max.greater(member) then the member is within the upper border
+// do not use min.greater()
+func (border *LexBorder) greater(value string) bool {
+	if border.Inf == negativeInf {
+		return false
+	} else if border.Inf == positiveInf {
+		return true
+	}
+	if border.Exclude {
+		return border.Value > value
+	}
+	return border.Value >= value
+}
+
+func (border *LexBorder) less(value string) bool {
+	if border.Inf == negativeInf {
+		return true
+	} else if border.Inf == positiveInf {
+		return false
+	}
+	if border.Exclude {
+		return border.Value < value
+	}
+	return border.Value <= value
+}
+
+var positiveInfLexBorder = &LexBorder{
+	Inf: positiveInf,
+}
+
+var negativeInfLexBorder = &LexBorder{
+	Inf: negativeInf,
+}
+
+// ParseLexBorder creates LexBorder from redis arguments
+func ParseLexBorder(s string) (*LexBorder, error) {
+	if s == "+" {
+		return positiveInfLexBorder, nil
+	}
+	if s == "-" {
+		return negativeInfLexBorder, nil
+	}
+	if s == "" || (s[0] != '(' && s[0] != '[') {
+		return nil, errors.New("ERR min or max not valid string range item")
+	}
+	return &LexBorder{
+		Inf:     0,
+		Value:   s[1:],
+		Exclude: s[0] == '(',
 	}, nil
 }
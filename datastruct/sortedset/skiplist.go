@@ -71,7 +71,7 @@ func (skiplist *skipList) insert(score float64, member string) *node {
 
 	x := skiplist.header
 	// 找到插入的位置
-	for i := skiplist.level; i >= 0; i-- {
+	for i := skiplist.level - 1; i >= 0; i-- {
 		// store rank that is crossed to reach the insert position
 		if i == skiplist.level-1 {
 			rank[i] = 0
@@ -212,7 +212,12 @@ func (skiplist *skipList) getByRank(rank int64) *node {
 }
 
 func (skiplist *skipList) hasInRange(min *ScoreBorder, max *ScoreBorder) bool {
-	if min.Value > max.Value || (min.Value == max.Value && (min.Exclude || max.Exclude)) {
+	// Value of an infinite border is meaningless
+	if min.Inf == positiveInf || max.Inf == negativeInf {
+		return false
+	}
+	if min.Inf == 0 && max.Inf == 0 &&
+		(min.Value > max.Value || (min.Value == max.Value && (min.Exclude || max.Exclude))) {
 		return false
 	}
 	x := skiplist.tail
@@ -263,6 +268,23 @@ func (skiplist *skipList) getLastInScoreRange(min *ScoreBorder, max *ScoreBorder
 	return n
 }
 
+// seek returns the node `offset` steps after n (before n if desc), it jumps by rank instead of walking one by one
+func (skiplist *skipList) seek(n *node, offset int64, desc bool) *node {
+	if n == nil || offset == 0 {
+		return n
+	}
+	rank := skiplist.getRank(n.Member, n.Score)
+	if desc {
+		rank -= offset
+	} else {
+		rank += offset
+	}
+	if rank < 1 || rank > skiplist.length {
+		return nil
+	}
+	return skiplist.getByRank(rank)
+}
+
 /*
  * return removed elements
  */
@@ -331,3 +353,97 @@ func (skiplist *skipList) RemoveRangeByRank(start int64, stop int64) (removed []
 	}
 	return removed
 }
+
+func (skiplist *skipList) hasInLexRange(min *LexBorder, max *LexBorder) bool {
+	if min.Inf == positiveInf || max.Inf == negativeInf {
+		return false
+	}
+	if min.Inf == 0 && max.Inf == 0 &&
+		(min.Value > max.Value || (min.Value == max.Value && (min.Exclude || max.Exclude))) {
+		return false
+	}
+	x := skiplist.tail
+	if x == nil || !min.less(x.Member) {
+		return false
+	}
+	x = skiplist.header.level[0].forward
+	if x == nil || !max.greater(x.Member) {
+		return false
+	}
+	return true
+}
+
+// getFirstInLexRange returns the first node within the lex border, all nodes are supposed to have the same score
+func (skiplist *skipList) getFirstInLexRange(min *LexBorder, max *LexBorder) *node {
+	if !skiplist.hasInLexRange(min, max) {
+		return nil
+	}
+	n := skiplist.header
+	// scan from top level
+	for level := skiplist.level - 1; level >= 0; level-- {
+		for n.level[level].forward != nil && !min.less(n.level[level].forward.Member) {
+			n = n.level[level].forward
+		}
+	}
+	n = n.level[0].forward
+	if !max.greater(n.Member) {
+		return nil
+	}
+	return n
+}
+
+// getLastInLexRange returns the last node within the lex border, all nodes are supposed to have the same score
+func (skiplist *skipList) getLastInLexRange(min *LexBorder, max *LexBorder) *node {
+	if !skiplist.hasInLexRange(min, max) {
+		return nil
+	}
+	n := skiplist.header
+	// scan from top level
+	for level := skiplist.level - 1; level >= 0; level-- {
+		for n.level[level].forward != nil && max.greater(n.level[level].forward.Member) {
+			n = n.level[level].forward
+		}
+	}
+	if !min.less(n.Member) {
+		return nil
+	}
+	return n
+}
+
+/*
+ * return removed elements
+ */
+func (skiplist *skipList) RemoveRangeByLex(min *LexBorder, max *LexBorder, limit int) (removed []*Element) {
+	update := make([]*node, maxLevel)
+	removed = make([]*Element, 0)
+	// find backward nodes (of target range) or last x of each level
+	x := skiplist.header
+	for i := skiplist.level - 1; i >= 0; i-- {
+		for x.level[i].forward != nil {
+			if min.less(x.level[i].forward.Member) { // already in range
+				break
+			}
+			x = x.level[i].forward
+		}
+		update[i] = x
+	}
+
+	// x is the first one within range
+	x = x.level[0].forward
+
+	// remove nodes in range
+	for x != nil {
+		if !max.greater(x.Member) { // already out of range
+			break
+		}
+		next := x.level[0].forward
+		removedElement := x.Element
+		removed = append(removed, &removedElement)
+		skiplist.removeNode(x, update)
+		if limit > 0 && len(removed) == limit {
+			break
+		}
+		x = next
+	}
+	return removed
+}
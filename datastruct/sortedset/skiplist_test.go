@@ -0,0 +1,86 @@
+package sortedset
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestSkipListRank(t *testing.T) {
+	skiplist := CreateSkipList()
+	set, elements := makeRandomSet(1000)
+	for _, element := range elements {
+		skiplist.insert(element.Score, element.Member)
+	}
+	// remove some nodes so that spans are updated
+	for i := 0; i < 100; i++ {
+		j := rand.Intn(len(elements))
+		if !skiplist.remove(elements[j].Member, elements[j].Score) {
+			t.Fatalf("failed to remove %v", *elements[j])
+		}
+		set.Remove(elements[j].Member)
+		elements = append(elements[:j], elements[j+1:]...)
+	}
+	if skiplist.length != int64(len(elements)) {
+		t.Fatalf("expected length %d, actually %d", len(elements), skiplist.length)
+	}
+	for i, element := range elements {
+		rank := int64(i + 1)
+		if actual := skiplist.getRank(element.Member, element.Score); actual != rank {
+			t.Fatalf("expected rank %d of %s, actually %d", rank, element.Member, actual)
+		}
+		if n := skiplist.getByRank(rank); n == nil || n.Member != element.Member {
+			t.Fatalf("expected %s at rank %d", element.Member, rank)
+		}
+	}
+	if skiplist.getByRank(int64(len(elements)+1)) != nil {
+		t.Error("expected nil out of range")
+	}
+
+	first := skiplist.getByRank(1)
+	if n := skiplist.seek(first, 10, false); n == nil || n.Member != elements[10].Member {
+		t.Errorf("expected %s", elements[10].Member)
+	}
+	last := skiplist.getByRank(skiplist.length)
+	if n := skiplist.seek(last, 10, true); n == nil || n.Member != elements[len(elements)-11].Member {
+		t.Errorf("expected %s", elements[len(elements)-11].Member)
+	}
+	if skiplist.seek(first, 1, true) != nil || skiplist.seek(last, 1, false) != nil {
+		t.Error("expected nil out of range")
+	}
+
+	// Count works after removal
+	min, _ := ParseScoreBorder("-inf")
+	max, _ := ParseScoreBorder("+inf")
+	if count := set.Count(min, max); count != int64(len(elements)) {
+		t.Errorf("expected count %d, actually %d", len(elements), count)
+	}
+}
+
+func TestCountDuplicatedScores(t *testing.T) {
+	set := NwSortedSet()
+	for i := 0; i < 100; i++ {
+		set.Add("m"+strconv.Itoa(i), float64(i/10))
+	}
+	cases := map[[2]string]int64{
+		{"0", "0"}:     10,
+		{"(0", "1"}:    10,
+		{"(0", "(1"}:   0,
+		{"1", "(3"}:    20,
+		{"9", "+inf"}:  10,
+		{"(9", "+inf"}: 0,
+		{"-inf", "-1"}: 0,
+		{"3", "2"}:     0,
+		{"3", "(3"}:    0,
+	}
+	for borders, expected := range cases {
+		min, _ := ParseScoreBorder(borders[0])
+		max, _ := ParseScoreBorder(borders[1])
+		if count := set.Count(min, max); count != expected {
+			t.Errorf("%v: expected %d, actually %d", borders, expected, count)
+		}
+		if elements := set.RangeByScore(min, max, 0, -1, false); int64(len(elements)) != expected {
+			t.Errorf("%v: expected %d elements, actually %d", borders, expected, len(elements))
+		}
+	}
+}
@@ -1,11 +1,16 @@
 package sortedset
 
-import "strconv"
+import (
+	"github.com/Ravior/goredis/datastruct/dict"
+	"strconv"
+)
 
 // SortedSet is a set which keys sorted by bound score
 type SortedSet struct {
 	dict     map[string]*Element
 	skiplist *skipList
+	// index is built once the set grows above dict.ScanIndexThreshold
+	index *dict.ScanIndex
 }
 
 // NwSortedSet make a new SortedSet
@@ -26,13 +31,51 @@ func (sortedSet *SortedSet) Add(member string, score float64) bool {
 	if ok {
 		if score != element.Score {
 			sortedSet.skiplist.remove(member, element.Score)
-			sortedSet.skiplist.insert(member, score)
+			sortedSet.skiplist.insert(score, member)
 		}
 		return false
 	}
+	sortedSet.skiplist.insert(score, member)
+	sortedSet.indexMember(member)
 	return true
 }
 
+// indexMember adds a new member into scan index, the index is built when the set becomes big
+func (sortedSet *SortedSet) indexMember(member string) {
+	if sortedSet.index != nil {
+		sortedSet.index.Add(member)
+		return
+	}
+	if len(sortedSet.dict) > dict.ScanIndexThreshold {
+		sortedSet.index = dict.MakeScanIndex()
+		for m := range sortedSet.dict {
+			sortedSet.index.Add(m)
+		}
+	}
+}
+
+// removeMember removes member from dict and scan index, the skiplist is not modified
+func (sortedSet *SortedSet) removeMember(member string) {
+	delete(sortedSet.dict, member)
+	if sortedSet.index != nil {
+		sortedSet.index.Remove(member)
+	}
+}
+
+// Scan visits members from cursor until at least count members are visited, returns the cursor to continue.
+// 0 means all members are visited. A small set is visited in one call.
+func (sortedSet *SortedSet) Scan(cursor int, count int, consumer func(element *Element)) int {
+	if sortedSet.index == nil {
+		for _, element := range sortedSet.dict {
+			consumer(element)
+		}
+		return 0
+	}
+	return sortedSet.index.Scan(cursor, count, func(member string) {
+		consumer(sortedSet.dict[member])
+	})
+}
+
 // Get returns the given member
 func (sortedSet *SortedSet) Get(member string) (element *Element, ok bool) {
 	element, ok = sortedSet.dict[member]
@@ -47,7 +90,7 @@ func (sortedSet *SortedSet) Remove(member string) bool {
 	v, ok := sortedSet.dict[member]
 	if ok {
 		sortedSet.skiplist.remove(member, v.Score)
-		delete(sortedSet.dict, member)
+		sortedSet.removeMember(member)
 		return true
 	}
 	return false
@@ -118,33 +161,125 @@ func (sortedSet *SortedSet) Range(start int64, stop int64, desc bool) []*Element
 	return slice
 }
 
-// Count returns the number of  members which score within the given border
+// Count returns the number of members which score within the given border
+// it finds both ends of the range and gets the answer by rank arithmetic, O(log N)
 func (sortedSet *SortedSet) Count(min *ScoreBorder, max *ScoreBorder) int64 {
-	var i int64 = 0
-	// ascending order
-	sortedSet.ForEach(0, sortedSet.Len(), false, func(element *Element) bool {
-		gtMin := min.less(element.Score) // greater than min
-		if !gtMin {
-			// has not into range, continue foreach
-			return true
+	first := sortedSet.skiplist.getFirstInScoreRange(min, max)
+	if first == nil {
+		return 0
+	}
+	last := sortedSet.skiplist.getLastInScoreRange(min, max)
+	if last == nil {
+		return 0
+	}
+	return sortedSet.skiplist.getRank(last.Member, last.Score) - sortedSet.skiplist.getRank(first.Member, first.Score) + 1
+}
+
+// ForEachByScore visits members which score within the given border
+// it skips the first `offset` members, and visits at most `limit` members, limit < 0 means no limit
+func (sortedSet *SortedSet) ForEachByScore(min *ScoreBorder, max *ScoreBorder, offset int64, limit int64, desc bool,
+	consumer func(element *Element) bool) {
+	// find start node
+	var node *node
+	if desc {
+		node = sortedSet.skiplist.getLastInScoreRange(min, max)
+	} else {
+		node = sortedSet.skiplist.getFirstInScoreRange(min, max)
+	}
+	node = sortedSet.skiplist.seek(node, offset, desc)
+
+	for i := int64(0); node != nil && (limit < 0 || i < limit); i++ {
+		if desc && !min.less(node.Score) || !desc && !max.greater(node.Score) {
+			break
 		}
-		ltMax := max.greater(element.Score) // less than max
-		if !ltMax {
-			// break through score border, break foreach
-			return false
+		if !consumer(&node.Element) {
+			break
 		}
-		// gtMin && ltMax
-		i++
+		if desc {
+			node = node.backward
+		} else {
+			node = node.level[0].forward
+		}
+	}
+}
+
+// RangeByScore returns members which score within the given border
+// it skips the first `offset` members, and returns at most `limit` members, limit < 0 means no limit
+func (sortedSet *SortedSet) RangeByScore(min *ScoreBorder, max *ScoreBorder, offset int64, limit int64, desc bool) []*Element {
+	slice := make([]*Element, 0)
+	sortedSet.ForEachByScore(min, max, offset, limit, desc, func(element *Element) bool {
+		slice = append(slice, element)
 		return true
 	})
-	return i
+	return slice
+}
+
+// ForEachByLex visits members within the given lex border, all members are supposed to have the same score
+// it skips the first `offset` members, and visits at most `limit` members, limit < 0 means no limit
+func (sortedSet *SortedSet) ForEachByLex(min *LexBorder, max *LexBorder, offset int64, limit int64, desc bool,
+	consumer func(element *Element) bool) {
+	// find start node
+	var node *node
+	if desc {
+		node = sortedSet.skiplist.getLastInLexRange(min, max)
+	} else {
+		node = sortedSet.skiplist.getFirstInLexRange(min, max)
+	}
+
+	node = sortedSet.skiplist.seek(node, offset, desc)
+
+	for i := int64(0); node != nil && (limit < 0 || i < limit); i++ {
+		if desc && !min.less(node.Member) || !desc && !max.greater(node.Member) {
+			break
+		}
+		if !consumer(&node.Element) {
+			break
+		}
+		if desc {
+			node = node.backward
+		} else {
+			node = node.level[0].forward
+		}
+	}
+}
+
+// RangeByLex returns members within the given lex border
+func (sortedSet *SortedSet) RangeByLex(min *LexBorder, max *LexBorder, offset int64, limit int64, desc bool) []*Element {
+	slice := make([]*Element, 0)
+	sortedSet.ForEachByLex(min, max, offset, limit, desc, func(element *Element) bool {
+		slice = append(slice, element)
+		return true
+	})
+	return slice
+}
+
+// LexCount returns the number of members within the given lex border
+func (sortedSet *SortedSet) LexCount(min *LexBorder, max *LexBorder) int64 {
+	first := sortedSet.skiplist.getFirstInLexRange(min, max)
+	if first == nil {
+		return 0
+	}
+	last := sortedSet.skiplist.getLastInLexRange(min, max)
+	if last == nil {
+		return 0
+	}
+	return sortedSet.skiplist.getRank(last.Member, last.Score) - sortedSet.skiplist.getRank(first.Member, first.Score) + 1
+}
+
+// RemoveByLex removes members within the given lex border
+func (sortedSet *SortedSet) RemoveByLex(min *LexBorder, max *LexBorder) int64 {
+	removed := sortedSet.skiplist.RemoveRangeByLex(min, max, 0)
+	for _, element := range removed {
+		sortedSet.removeMember(element.Member)
+	}
+	return int64(len(removed))
 }
 
 // RemoveByScore removes members which score within the given border
 func (sortedSet *SortedSet) RemoveByScore(min *ScoreBorder, max *ScoreBorder) int64 {
 	removed := sortedSet.skiplist.RemoveRangeByScore(min, max, 0)
 	for _, element := range removed {
-		delete(sortedSet.dict, element.Member)
+		sortedSet.removeMember(element.Member)
 	}
 	return int64(len(removed))
 }
@@ -154,7 +289,7 @@ func (sortedSet *SortedSet) RemoveByScore(min *ScoreBorder, max *ScoreBorder) in
 func (sortedSet *SortedSet) RemoveByRank(start int64, stop int64) int64 {
 	removed := sortedSet.skiplist.RemoveRangeByRank(start+1, stop+1)
 	for _, element := range removed {
-		delete(sortedSet.dict, element.Member)
+		sortedSet.removeMember(element.Member)
 	}
 	return int64(len(removed))
 }
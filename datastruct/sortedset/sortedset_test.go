@@ -0,0 +1,313 @@
+package sortedset
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// makeRandomSet creates a SortedSet and the expected elements sorted by score then member
+func makeRandomSet(size int) (*SortedSet, []*Element) {
+	set := NwSortedSet()
+	elements := make([]*Element, 0, size)
+	for i := 0; i < size; i++ {
+		member := "m" + strconv.Itoa(i)
+		// duplicated scores are sorted by member
+		score := float64(rand.Intn(size / 2))
+		set.Add(member, score)
+		elements = append(elements, &Element{Member: member, Score: score})
+	}
+	sortElements(elements)
+	return set, elements
+}
+
+func sortElements(elements []*Element) {
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].Score != elements[j].Score {
+			return elements[i].Score < elements[j].Score
+		}
+		return elements[i].Member < elements[j].Member
+	})
+}
+
+func assertElements(t *testing.T, actual []*Element, expected []*Element) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %d elements, actually %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if actual[i].Member != expected[i].Member || actual[i].Score != expected[i].Score {
+			t.Fatalf("expected %v at %d, actually %v", *expected[i], i, *actual[i])
+		}
+	}
+}
+
+func reversed(elements []*Element) []*Element {
+	result := make([]*Element, len(elements))
+	for i, element := range elements {
+		result[len(elements)-1-i] = element
+	}
+	return result
+}
+
+func TestAddRemove(t *testing.T) {
+	set := NwSortedSet()
+	if !set.Add("a", 1) {
+		t.Error("expected new member")
+	}
+	if set.Add("a", 2) {
+		t.Error("expected existing member")
+	}
+	if element, ok := set.Get("a"); !ok || element.Score != 2 {
+		t.Errorf("expected score 2, actually %v", element)
+	}
+	set.Add("b", 1)
+	assertElements(t, set.Range(0, 2, false), []*Element{{"b", 1}, {"a", 2}})
+	if !set.Remove("a") || set.Remove("a") {
+		t.Error("expected removed only once")
+	}
+	if _, ok := set.Get("a"); ok {
+		t.Error("expected a removed")
+	}
+	if set.Len() != 1 {
+		t.Errorf("expected len 1, actually %d", set.Len())
+	}
+}
+
+func TestRank(t *testing.T) {
+	set, elements := makeRandomSet(1000)
+	for i, element := range elements {
+		if rank := set.GetRank(element.Member, false); rank != int64(i) {
+			t.Fatalf("expected rank %d of %s, actually %d", i, element.Member, rank)
+		}
+		if rank := set.GetRank(element.Member, true); rank != int64(len(elements)-1-i) {
+			t.Fatalf("expected reversed rank %d of %s, actually %d", len(elements)-1-i, element.Member, rank)
+		}
+	}
+	if set.GetRank("missing", false) != -1 {
+		t.Error("expected -1 for missing member")
+	}
+}
+
+func TestRange(t *testing.T) {
+	set, elements := makeRandomSet(1000)
+	assertElements(t, set.Range(0, 1000, false), elements)
+	assertElements(t, set.Range(0, 1000, true), reversed(elements))
+	assertElements(t, set.Range(100, 200, false), elements[100:200])
+	assertElements(t, set.Range(100, 200, true), reversed(elements)[100:200])
+
+	// scores updated are reordered
+	for i := 0; i < 100; i++ {
+		element := elements[rand.Intn(len(elements))]
+		element.Score = float64(rand.Intn(1000))
+		set.Add(element.Member, element.Score)
+	}
+	sortElements(elements)
+	assertElements(t, set.Range(0, 1000, false), elements)
+}
+
+func TestRangeByScore(t *testing.T) {
+	set, elements := makeRandomSet(1000)
+	cases := []struct {
+		min, max string
+	}{
+		{"-inf", "+inf"},
+		{"10", "20"},
+		{"(10", "20"},
+		{"10", "(20"},
+		{"(10", "(11"},
+		{"100.5", "300"},
+		{"20", "10"},
+		{"1000", "+inf"},
+		{"-inf", "-1"},
+	}
+	for _, c := range cases {
+		min, _ := ParseScoreBorder(c.min)
+		max, _ := ParseScoreBorder(c.max)
+		var expected []*Element
+		for _, element := range elements {
+			if min.less(element.Score) && max.greater(element.Score) {
+				expected = append(expected, element)
+			}
+		}
+		assertElements(t, set.RangeByScore(min, max, 0, -1, false), expected)
+		assertElements(t, set.RangeByScore(min, max, 0, -1, true), reversed(expected))
+		if count := set.Count(min, max); count != int64(len(expected)) {
+			t.Errorf("[%s, %s] expected count %d, actually %d", c.min, c.max, len(expected), count)
+		}
+		if len(expected) > 3 {
+			assertElements(t, set.RangeByScore(min, max, 1, 2, false), expected[1:3])
+			assertElements(t, set.RangeByScore(min, max, 1, 2, true), reversed(expected)[1:3])
+		}
+		assertElements(t, set.RangeByScore(min, max, int64(len(expected)), -1, false), nil)
+		assertElements(t, set.RangeByScore(min, max, 0, 0, false), nil)
+	}
+}
+
+func TestRemoveByScoreAndRank(t *testing.T) {
+	set, elements := makeRandomSet(1000)
+	min, _ := ParseScoreBorder("100")
+	max, _ := ParseScoreBorder("(200")
+	var expected []*Element
+	for _, element := range elements {
+		if !min.less(element.Score) || !max.greater(element.Score) {
+			expected = append(expected, element)
+		}
+	}
+	if removed := set.RemoveByScore(min, max); removed != int64(len(elements)-len(expected)) {
+		t.Errorf("expected %d removed, actually %d", len(elements)-len(expected), removed)
+	}
+	assertElements(t, set.Range(0, set.Len(), false), expected)
+
+	removed := set.RemoveByRank(10, 20)
+	if removed != 10 {
+		t.Errorf("expected 10 removed, actually %d", removed)
+	}
+	expected = append(expected[:10], expected[20:]...)
+	assertElements(t, set.Range(0, set.Len(), false), expected)
+	for _, element := range expected {
+		if _, ok := set.Get(element.Member); !ok {
+			t.Fatalf("expected %s exists", element.Member)
+		}
+	}
+}
+
+func TestParseScoreBorder(t *testing.T) {
+	cases := map[string]ScoreBorder{
+		"inf":    {Inf: positiveInf},
+		"+inf":   {Inf: positiveInf},
+		"-inf":   {Inf: negativeInf},
+		"1.5":    {Value: 1.5},
+		"(1.5":   {Value: 1.5, Exclude: true},
+		"-2":     {Value: -2},
+		"(-1e10": {Value: -1e10, Exclude: true},
+	}
+	for s, expected := range cases {
+		border, err := ParseScoreBorder(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if *border != expected {
+			t.Errorf("%q: expected %v, actually %v", s, expected, *border)
+		}
+	}
+	for _, s := range []string{"", "(", "abc", "nan", "(nan", "[1"} {
+		if _, err := ParseScoreBorder(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestParseLexBorder(t *testing.T) {
+	cases := map[string]LexBorder{
+		"+":  {Inf: positiveInf},
+		"-":  {Inf: negativeInf},
+		"[a": {Value: "a"},
+		"(a": {Value: "a", Exclude: true},
+		"[":  {Value: ""},
+	}
+	for s, expected := range cases {
+		border, err := ParseLexBorder(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+			continue
+		}
+		if *border != expected {
+			t.Errorf("%q: expected %v, actually %v", s, expected, *border)
+		}
+	}
+	for _, s := range []string{"", "a", "+a", "-a"} {
+		if _, err := ParseLexBorder(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestRangeByLex(t *testing.T) {
+	set := NwSortedSet()
+	var elements []*Element
+	for i := 0; i < 26; i++ {
+		member := string(rune('a' + i))
+		set.Add(member, 0)
+		elements = append(elements, &Element{Member: member, Score: 0})
+	}
+	cases := []struct {
+		min, max   string
+		begin, end int
+	}{
+		{"-", "+", 0, 26},
+		{"[c", "[f", 2, 6},
+		{"(c", "(f", 3, 5},
+		{"[c", "(c", 0, 0},
+		{"[f", "[c", 0, 0},
+		{"-", "(b", 0, 1},
+		{"(x", "+", 24, 26},
+		{"[bb", "[d", 2, 4},
+	}
+	for _, c := range cases {
+		min, _ := ParseLexBorder(c.min)
+		max, _ := ParseLexBorder(c.max)
+		expected := elements[c.begin:c.end]
+		assertElements(t, set.RangeByLex(min, max, 0, -1, false), expected)
+		assertElements(t, set.RangeByLex(min, max, 0, -1, true), reversed(expected))
+		if count := set.LexCount(min, max); count != int64(len(expected)) {
+			t.Errorf("%s %s: expected count %d, actually %d", c.min, c.max, len(expected), count)
+		}
+		if len(expected) > 2 {
+			assertElements(t, set.RangeByLex(min, max, 1, 1, false), expected[1:2])
+			assertElements(t, set.RangeByLex(min, max, 1, 1, true), reversed(expected)[1:2])
+		}
+	}
+
+	min, _ := ParseLexBorder("(c")
+	max, _ := ParseLexBorder("[x")
+	if removed := set.RemoveByLex(min, max); removed != 21 {
+		t.Errorf("expected 21 removed, actually %d", removed)
+	}
+	expected := append(append([]*Element{}, elements[:3]...), elements[24:]...)
+	assertElements(t, set.Range(0, set.Len(), false), expected)
+	if _, ok := set.Get("d"); ok {
+		t.Error("expected d removed")
+	}
+}
+
+// scanAll returns members visited by a full scan
+func scanAll(set *SortedSet, count int) map[string]float64 {
+	result := make(map[string]float64)
+	cursor := 0
+	for {
+		cursor = set.Scan(cursor, count, func(element *Element) {
+			result[element.Member] = element.Score
+		})
+		if cursor == 0 {
+			return result
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	set, elements := makeRandomSet(1000)
+	if visited := scanAll(set, 10); len(visited) != 1000 {
+		t.Fatalf("expected 1000 members, actually %d", len(visited))
+	}
+	min, _ := ParseScoreBorder("100")
+	max, _ := ParseScoreBorder("200")
+	set.RemoveByScore(min, max)
+	set.RemoveByRank(0, 100)
+	set.Remove(elements[len(elements)-1].Member)
+	visited := scanAll(set, 10)
+	if int64(len(visited)) != set.Len() {
+		t.Fatalf("expected %d members, actually %d", set.Len(), len(visited))
+	}
+	for member, score := range visited {
+		if element, ok := set.Get(member); !ok || element.Score != score {
+			t.Fatalf("unexpected member %s with score %f", member, score)
+		}
+	}
+	set.RemoveByRank(0, set.Len())
+	if len(scanAll(set, 10)) != 0 {
+		t.Error("expected no members")
+	}
+}
@@ -0,0 +1,154 @@
+package stream
+
+import "sort"
+
+// Group is a consumer group of stream, it delivers each entry to one of its consumers
+// and tracks delivered entries until they are acknowledged
+type Group struct {
+	Name string
+	// LastID is the ID of the last entry delivered to consumers
+	LastID    ID
+	pending   *radixTree // ID -> *PendingEntry
+	consumers map[string]*Consumer
+}
+
+// Consumer is a member of consumer group
+type Consumer struct {
+	Name string
+	// SeenTime is the unix milliseconds time when the consumer interacted with the group
+	SeenTime int64
+	pending  *radixTree // ID -> *PendingEntry
+}
+
+// PendingEntry is an entry delivered but not acknowledged yet
+type PendingEntry struct {
+	ID       ID
+	Consumer *Consumer
+	// DeliveryTime is the unix milliseconds time of the last delivery
+	DeliveryTime  int64
+	DeliveryCount uint64
+}
+
+// GetConsumer returns the consumer of the given name
+func (g *Group) GetConsumer(name string) (*Consumer, bool) {
+	consumer, ok := g.consumers[name]
+	return consumer, ok
+}
+
+// CreateConsumer returns the consumer of the given name, creates it if not exists
+func (g *Group) CreateConsumer(name string, now int64) (consumer *Consumer, created bool) {
+	if consumer, ok := g.consumers[name]; ok {
+		return consumer, false
+	}
+	consumer = &Consumer{
+		Name:     name,
+		SeenTime: now,
+		pending:  newRadixTree(),
+	}
+	g.consumers[name] = consumer
+	return consumer, true
+}
+
+// DeleteConsumer removes the consumer and its pending entries, returns the number of pending entries
+// ok is false if the consumer not exists
+func (g *Group) DeleteConsumer(name string) (pending int64, ok bool) {
+	consumer, ok := g.consumers[name]
+	if !ok {
+		return 0, false
+	}
+	consumer.pending.Ascend(nil, func(key []byte, value interface{}) bool {
+		g.pending.Remove(key)
+		return true
+	})
+	delete(g.consumers, name)
+	return int64(consumer.pending.Len()), true
+}
+
+// Consumers returns all consumers ordered by name
+func (g *Group) Consumers() []*Consumer {
+	consumers := make([]*Consumer, 0, len(g.consumers))
+	for _, consumer := range g.consumers {
+		consumers = append(consumers, consumer)
+	}
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].Name < consumers[j].Name
+	})
+	return consumers
+}
+
+// PendingLen returns the number of pending entries of the group
+func (g *Group) PendingLen() int64 {
+	return int64(g.pending.Len())
+}
+
+// GetPending returns the pending entry of the given ID
+func (g *Group) GetPending(id ID) (*PendingEntry, bool) {
+	val, ok := g.pending.Get(id.bytes())
+	if !ok {
+		return nil, false
+	}
+	return val.(*PendingEntry), true
+}
+
+// Deliver assigns the entry of the given ID to consumer, the entry is added into pending entries list if not yet.
+// The caller should update DeliveryTime and DeliveryCount of the returned pending entry.
+func (g *Group) Deliver(id ID, consumer *Consumer) *PendingEntry {
+	pending, ok := g.GetPending(id)
+	if !ok {
+		pending = &PendingEntry{
+			ID: id,
+		}
+		g.pending.Put(id.bytes(), pending)
+	} else if pending.Consumer != consumer {
+		pending.Consumer.pending.Remove(id.bytes())
+	}
+	pending.Consumer = consumer
+	consumer.pending.Put(id.bytes(), pending)
+	return pending
+}
+
+// Ack removes the entry of the given ID from pending entries list, returns whether it was pending
+func (g *Group) Ack(id ID) bool {
+	pending, ok := g.GetPending(id)
+	if !ok {
+		return false
+	}
+	g.pending.Remove(id.bytes())
+	pending.Consumer.pending.Remove(id.bytes())
+	return true
+}
+
+func forEachPending(tree *radixTree, start ID, end ID, consumer func(pending *PendingEntry) bool) {
+	if end.Less(start) {
+		return
+	}
+	tree.Ascend(start.bytes(), func(key []byte, value interface{}) bool {
+		pending := value.(*PendingEntry)
+		if end.Less(pending.ID) {
+			return false
+		}
+		return consumer(pending)
+	})
+}
+
+// ForEachPending visits pending entries which ID within [start, end] in ascending order
+func (g *Group) ForEachPending(start ID, end ID, consumer func(pending *PendingEntry) bool) {
+	forEachPending(g.pending, start, end, consumer)
+}
+
+// ForEachPendingDesc visits all pending entries in descending order
+func (g *Group) ForEachPendingDesc(consumer func(pending *PendingEntry) bool) {
+	g.pending.Descend(nil, func(key []byte, value interface{}) bool {
+		return consumer(value.(*PendingEntry))
+	})
+}
+
+// PendingLen returns the number of pending entries of the consumer
+func (c *Consumer) PendingLen() int64 {
+	return int64(c.pending.Len())
+}
+
+// ForEachPending visits pending entries of the consumer which ID within [start, end] in ascending order
+func (c *Consumer) ForEachPending(start ID, end ID, consumer func(pending *PendingEntry) bool) {
+	forEachPending(c.pending, start, end, consumer)
+}
@@ -0,0 +1,97 @@
+package stream
+
+import "testing"
+
+func collectPending(forEach func(start ID, end ID, consumer func(pending *PendingEntry) bool), start ID, end ID) []ID {
+	ids := make([]ID, 0)
+	forEach(start, end, func(pending *PendingEntry) bool {
+		ids = append(ids, pending.ID)
+		return true
+	})
+	return ids
+}
+
+func assertPendingIDs(t *testing.T, actual []ID, expected ...ID) {
+	t.Helper()
+	if len(actual) != len(expected) {
+		t.Fatalf("expected %v, actually %v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("expected %v, actually %v", expected, actual)
+		}
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := New()
+	if _, ok := s.CreateGroup("b", MinID); !ok {
+		t.Error("expected group created")
+	}
+	if _, ok := s.CreateGroup("a", MinID); !ok {
+		t.Error("expected group created")
+	}
+	if _, ok := s.CreateGroup("a", MinID); ok {
+		t.Error("expected duplicated group")
+	}
+	if groups := s.Groups(); len(groups) != 2 || groups[0].Name != "a" || groups[1].Name != "b" {
+		t.Errorf("unexpected groups %v", groups)
+	}
+	if !s.DestroyGroup("a") || s.DestroyGroup("a") {
+		t.Error("unexpected result of destroy")
+	}
+	if _, ok := s.GetGroup("a"); ok {
+		t.Error("expected group destroyed")
+	}
+}
+
+func TestPending(t *testing.T) {
+	s := New()
+	group, _ := s.CreateGroup("g", MinID)
+	alice, created := group.CreateConsumer("alice", 1)
+	if !created {
+		t.Error("expected consumer created")
+	}
+	if same, created := group.CreateConsumer("alice", 2); created || same != alice || same.SeenTime != 1 {
+		t.Error("expected existing consumer")
+	}
+	bob, _ := group.CreateConsumer("bob", 1)
+	if consumers := group.Consumers(); len(consumers) != 2 || consumers[0] != alice || consumers[1] != bob {
+		t.Errorf("unexpected consumers %v", consumers)
+	}
+
+	for i := uint64(1); i <= 4; i++ {
+		group.Deliver(ID{Ms: i}, alice)
+	}
+	group.Deliver(ID{Ms: 5}, bob)
+	// claimed by another consumer
+	pending := group.Deliver(ID{Ms: 2}, bob)
+	if pending.Consumer != bob || group.PendingLen() != 5 || alice.PendingLen() != 3 || bob.PendingLen() != 2 {
+		t.Errorf("unexpected pending length %d %d %d", group.PendingLen(), alice.PendingLen(), bob.PendingLen())
+	}
+	assertPendingIDs(t, collectPending(group.ForEachPending, ID{Ms: 2}, ID{Ms: 4}), ID{Ms: 2}, ID{Ms: 3}, ID{Ms: 4})
+	assertPendingIDs(t, collectPending(alice.ForEachPending, MinID, MaxID), ID{Ms: 1}, ID{Ms: 3}, ID{Ms: 4})
+	assertPendingIDs(t, collectPending(bob.ForEachPending, ID{Ms: 3}, MaxID), ID{Ms: 5})
+	assertPendingIDs(t, collectPending(group.ForEachPending, ID{Ms: 4}, ID{Ms: 2}))
+	var desc []ID
+	group.ForEachPendingDesc(func(pending *PendingEntry) bool {
+		desc = append(desc, pending.ID)
+		return len(desc) < 2
+	})
+	assertPendingIDs(t, desc, ID{Ms: 5}, ID{Ms: 4})
+
+	if !group.Ack(ID{Ms: 3}) || group.Ack(ID{Ms: 3}) || group.Ack(ID{Ms: 10}) {
+		t.Error("unexpected result of ack")
+	}
+	if _, ok := group.GetPending(ID{Ms: 3}); ok || alice.PendingLen() != 2 {
+		t.Error("expected acknowledged")
+	}
+
+	if count, ok := group.DeleteConsumer("alice"); !ok || count != 2 {
+		t.Errorf("expected 2 pending entries, actually %d", count)
+	}
+	if _, ok := group.DeleteConsumer("alice"); ok {
+		t.Error("expected consumer deleted")
+	}
+	assertPendingIDs(t, collectPending(group.ForEachPending, MinID, MaxID), ID{Ms: 2}, ID{Ms: 5})
+}
@@ -0,0 +1,260 @@
+package stream
+
+import "bytes"
+
+// radixTree is a compressed prefix tree, keys are visited in lexicographical order of bytes.
+// Stream IDs are encoded in big-endian so that they are ordered by ID in radixTree,
+// and entries added in a row share most of the key bytes.
+type radixTree struct {
+	root *radixNode
+	size int
+}
+
+type radixNode struct {
+	// prefix is the compressed edge from parent to this node
+	prefix []byte
+	// children are sorted by the first byte of their prefix
+	children []*radixNode
+	value    interface{}
+	hasValue bool
+}
+
+func newRadixTree() *radixTree {
+	return &radixTree{
+		root: &radixNode{},
+	}
+}
+
+// Len returns the number of keys
+func (tree *radixTree) Len() int {
+	return tree.size
+}
+
+// findChild returns the index of child starting with the given byte,
+// or the index to insert such child if not found
+func (node *radixNode) findChild(b byte) (int, bool) {
+	lo, hi := 0, len(node.children)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		c := node.children[mid].prefix[0]
+		if c == b {
+			return mid, true
+		} else if c < b {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, false
+}
+
+func commonPrefixLen(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Put sets value of key, returns whether a new key is inserted
+func (tree *radixTree) Put(key []byte, value interface{}) bool {
+	node := tree.root
+	for {
+		if len(key) == 0 {
+			inserted := !node.hasValue
+			node.value = value
+			node.hasValue = true
+			if inserted {
+				tree.size++
+			}
+			return inserted
+		}
+		i, found := node.findChild(key[0])
+		if !found {
+			leaf := &radixNode{
+				prefix:   append([]byte(nil), key...),
+				value:    value,
+				hasValue: true,
+			}
+			node.children = append(node.children, nil)
+			copy(node.children[i+1:], node.children[i:])
+			node.children[i] = leaf
+			tree.size++
+			return true
+		}
+		child := node.children[i]
+		common := commonPrefixLen(child.prefix, key)
+		if common < len(child.prefix) {
+			// split the edge
+			mid := &radixNode{
+				prefix:   child.prefix[:common],
+				children: []*radixNode{child},
+			}
+			child.prefix = child.prefix[common:]
+			node.children[i] = mid
+			child = mid
+		}
+		node = child
+		key = key[common:]
+	}
+}
+
+// Get returns value of key
+func (tree *radixTree) Get(key []byte) (interface{}, bool) {
+	node := tree.root
+	for len(key) > 0 {
+		i, found := node.findChild(key[0])
+		if !found {
+			return nil, false
+		}
+		child := node.children[i]
+		if !bytes.HasPrefix(key, child.prefix) {
+			return nil, false
+		}
+		node = child
+		key = key[len(child.prefix):]
+	}
+	return node.value, node.hasValue
+}
+
+// Remove deletes key, returns whether the key existed
+func (tree *radixTree) Remove(key []byte) bool {
+	path := []*radixNode{tree.root}
+	node := tree.root
+	for len(key) > 0 {
+		i, found := node.findChild(key[0])
+		if !found {
+			return false
+		}
+		child := node.children[i]
+		if !bytes.HasPrefix(key, child.prefix) {
+			return false
+		}
+		node = child
+		path = append(path, node)
+		key = key[len(child.prefix):]
+	}
+	if !node.hasValue {
+		return false
+	}
+	node.value = nil
+	node.hasValue = false
+	tree.size--
+
+	// remove empty nodes and merge nodes with single child, root is never removed or merged
+	for depth := len(path) - 1; depth > 0; depth-- {
+		node = path[depth]
+		parent := path[depth-1]
+		if !node.hasValue && len(node.children) == 0 {
+			i, _ := parent.findChild(node.prefix[0])
+			parent.children = append(parent.children[:i], parent.children[i+1:]...)
+			continue
+		}
+		if !node.hasValue && len(node.children) == 1 {
+			child := node.children[0]
+			prefix := make([]byte, 0, len(node.prefix)+len(child.prefix))
+			prefix = append(prefix, node.prefix...)
+			prefix = append(prefix, child.prefix...)
+			child.prefix = prefix
+			i, _ := parent.findChild(node.prefix[0])
+			parent.children[i] = child
+		}
+		break
+	}
+	return true
+}
+
+// Ascend visits keys greater than or equal to from in ascending order, nil from means from the first key.
+// It stops when consumer returns false.
+func (tree *radixTree) Ascend(from []byte, consumer func(key []byte, value interface{}) bool) {
+	if from == nil {
+		tree.root.ascend(nil, nil, false, consumer)
+		return
+	}
+	tree.root.ascend(nil, from, true, consumer)
+}
+
+// Descend visits keys less than or equal to from in descending order, nil from means from the last key.
+// It stops when consumer returns false.
+func (tree *radixTree) Descend(from []byte, consumer func(key []byte, value interface{}) bool) {
+	if from == nil {
+		tree.root.descend(nil, nil, false, consumer)
+		return
+	}
+	tree.root.descend(nil, from, true, consumer)
+}
+
+// ascend visits keys of sub-tree, path is the key of node,
+// bound is the rest part of lower bound after path if bounded, returns false if visiting should stop
+func (node *radixNode) ascend(path []byte, bound []byte, bounded bool, consumer func([]byte, interface{}) bool) bool {
+	// path is less than bound unless the rest part of bound is empty
+	if node.hasValue && (!bounded || len(bound) == 0) {
+		if !consumer(path, node.value) {
+			return false
+		}
+	}
+	for _, child := range node.children {
+		childBounded := bounded && len(bound) > 0
+		var childBound []byte
+		if childBounded {
+			n := len(child.prefix)
+			if n > len(bound) {
+				n = len(bound)
+			}
+			cmp := bytes.Compare(child.prefix[:n], bound[:n])
+			if cmp < 0 {
+				continue
+			}
+			if cmp > 0 || len(child.prefix) > len(bound) {
+				// all keys of child are greater than bound
+				childBounded = false
+			} else {
+				childBound = bound[len(child.prefix):]
+			}
+		}
+		childPath := make([]byte, 0, len(path)+len(child.prefix))
+		childPath = append(childPath, path...)
+		childPath = append(childPath, child.prefix...)
+		if !child.ascend(childPath, childBound, childBounded, consumer) {
+			return false
+		}
+	}
+	return true
+}
+
+// descend visits keys of sub-tree in reverse order, path is the key of node,
+// bound is the rest part of upper bound after path if bounded, returns false if visiting should stop
+func (node *radixNode) descend(path []byte, bound []byte, bounded bool, consumer func([]byte, interface{}) bool) bool {
+	for i := len(node.children) - 1; i >= 0; i-- {
+		child := node.children[i]
+		childBounded := bounded
+		var childBound []byte
+		if childBounded {
+			n := len(child.prefix)
+			if n > len(bound) {
+				n = len(bound)
+			}
+			cmp := bytes.Compare(child.prefix[:n], bound[:n])
+			if cmp > 0 || (cmp == 0 && len(child.prefix) > len(bound)) {
+				// all keys of child are greater than bound
+				continue
+			}
+			if cmp < 0 {
+				childBounded = false
+			} else {
+				childBound = bound[len(child.prefix):]
+			}
+		}
+		childPath := make([]byte, 0, len(path)+len(child.prefix))
+		childPath = append(childPath, path...)
+		childPath = append(childPath, child.prefix...)
+		if !child.descend(childPath, childBound, childBounded, consumer) {
+			return false
+		}
+	}
+	// path is a prefix of bound, so it is not greater than bound
+	if node.hasValue {
+		return consumer(path, node.value)
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package stream
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomKey() []byte {
+	// short keys over a small alphabet share many prefixes
+	key := make([]byte, rand.Intn(6))
+	for i := range key {
+		key[i] = byte('a' + rand.Intn(3))
+	}
+	return key
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func assertAscend(t *testing.T, tree *radixTree, m map[string]int, from []byte) {
+	t.Helper()
+	var expected []string
+	for _, key := range sortedKeys(m) {
+		if from == nil || key >= string(from) {
+			expected = append(expected, key)
+		}
+	}
+	var actual []string
+	tree.Ascend(from, func(key []byte, value interface{}) bool {
+		if value.(int) != m[string(key)] {
+			t.Fatalf("unexpected value %v of %q", value, key)
+		}
+		actual = append(actual, string(key))
+		return true
+	})
+	if len(actual) != len(expected) {
+		t.Fatalf("ascend from %q: expected %q, actually %q", from, expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("ascend from %q: expected %q, actually %q", from, expected, actual)
+		}
+	}
+}
+
+func assertDescend(t *testing.T, tree *radixTree, m map[string]int, from []byte) {
+	t.Helper()
+	keys := sortedKeys(m)
+	var expected []string
+	for i := len(keys) - 1; i >= 0; i-- {
+		if from == nil || keys[i] <= string(from) {
+			expected = append(expected, keys[i])
+		}
+	}
+	var actual []string
+	tree.Descend(from, func(key []byte, value interface{}) bool {
+		actual = append(actual, string(key))
+		return true
+	})
+	if len(actual) != len(expected) {
+		t.Fatalf("descend from %q: expected %q, actually %q", from, expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Fatalf("descend from %q: expected %q, actually %q", from, expected, actual)
+		}
+	}
+}
+
+func TestRadixTree(t *testing.T) {
+	tree := newRadixTree()
+	m := make(map[string]int)
+	for i := 0; i < 2000; i++ {
+		key := randomKey()
+		if rand.Intn(3) == 0 {
+			_, existed := m[string(key)]
+			if tree.Remove(key) != existed {
+				t.Fatalf("remove %q: expected %v", key, existed)
+			}
+			delete(m, string(key))
+		} else {
+			_, existed := m[string(key)]
+			if tree.Put(key, i) == existed {
+				t.Fatalf("put %q: expected inserted %v", key, !existed)
+			}
+			m[string(key)] = i
+		}
+		if tree.Len() != len(m) {
+			t.Fatalf("expected size %d, actually %d", len(m), tree.Len())
+		}
+		if i%100 == 0 {
+			for j := 0; j < 10; j++ {
+				from := randomKey()
+				assertAscend(t, tree, m, from)
+				assertDescend(t, tree, m, from)
+			}
+			assertAscend(t, tree, m, nil)
+			assertDescend(t, tree, m, nil)
+		}
+	}
+	for key, value := range m {
+		actual, ok := tree.Get([]byte(key))
+		if !ok || actual.(int) != value {
+			t.Fatalf("get %q: expected %d, actually %v", key, value, actual)
+		}
+	}
+	if _, ok := tree.Get([]byte("abcabc")); ok {
+		t.Error("expected missing key")
+	}
+}
+
+func TestRadixTreeSplitAndMerge(t *testing.T) {
+	tree := newRadixTree()
+	tree.Put([]byte("abcd"), 1)
+	tree.Put([]byte("abef"), 2)
+	tree.Put([]byte("ab"), 3)
+	if _, ok := tree.Get([]byte("a")); ok {
+		t.Error("expected inner node has no value")
+	}
+	if !tree.Remove([]byte("ab")) || tree.Remove([]byte("ab")) {
+		t.Error("unexpected result of remove")
+	}
+	if !tree.Remove([]byte("abcd")) {
+		t.Error("expected abcd removed")
+	}
+	// abef is merged into a single edge of root
+	if len(tree.root.children) != 1 || !bytes.Equal(tree.root.children[0].prefix, []byte("abef")) {
+		t.Errorf("expected merged node, actually %q", tree.root.children[0].prefix)
+	}
+	if value, ok := tree.Get([]byte("abef")); !ok || value.(int) != 2 {
+		t.Errorf("expected 2, actually %v", value)
+	}
+	// empty key is stored in root
+	tree.Put([]byte{}, 0)
+	if value, ok := tree.Get(nil); !ok || value.(int) != 0 || tree.Len() != 2 {
+		t.Errorf("expected 0, actually %v", value)
+	}
+
+	var visited int
+	tree.Ascend(nil, func(key []byte, value interface{}) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Errorf("expected to stop after 1 key, actually %d", visited)
+	}
+}
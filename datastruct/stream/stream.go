@@ -0,0 +1,341 @@
+package stream
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ID identifies an entry of stream, it consists of milliseconds time and sequence number
+type ID struct {
+	Ms  uint64
+	Seq uint64
+}
+
+var (
+	// MinID is the smallest ID 0-0
+	MinID = ID{}
+	// MaxID is the largest ID
+	MaxID = ID{Ms: math.MaxUint64, Seq: math.MaxUint64}
+)
+
+var errInvalidID = errors.New("invalid stream ID")
+
+// ParseID parses ID in form of `ms-seq` or `ms`, seqGiven is false if sequence number is omitted
+func ParseID(s string) (id ID, seqGiven bool, err error) {
+	msPart, seqPart, seqGiven := strings.Cut(s, "-")
+	id.Ms, err = strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return ID{}, false, errInvalidID
+	}
+	if seqGiven {
+		id.Seq, err = strconv.ParseUint(seqPart, 10, 64)
+		if err != nil {
+			return ID{}, false, errInvalidID
+		}
+	}
+	return id, seqGiven, nil
+}
+
+func (id ID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Compare returns -1, 0 or 1 if id is less than, equal to or greater than another
+func (id ID) Compare(another ID) int {
+	switch {
+	case id.Ms < another.Ms:
+		return -1
+	case id.Ms > another.Ms:
+		return 1
+	case id.Seq < another.Seq:
+		return -1
+	case id.Seq > another.Seq:
+		return 1
+	}
+	return 0
+}
+
+// Less returns whether id is less than another
+func (id ID) Less(another ID) bool {
+	return id.Compare(another) < 0
+}
+
+// Incr returns the next ID, ok is false if id is MaxID
+func (id ID) Incr() (next ID, ok bool) {
+	if id.Seq < math.MaxUint64 {
+		return ID{Ms: id.Ms, Seq: id.Seq + 1}, true
+	}
+	if id.Ms < math.MaxUint64 {
+		return ID{Ms: id.Ms + 1}, true
+	}
+	return id, false
+}
+
+// Decr returns the previous ID, ok is false if id is MinID
+func (id ID) Decr() (prev ID, ok bool) {
+	if id.Seq > 0 {
+		return ID{Ms: id.Ms, Seq: id.Seq - 1}, true
+	}
+	if id.Ms > 0 {
+		return ID{Ms: id.Ms - 1, Seq: math.MaxUint64}, true
+	}
+	return id, false
+}
+
+// bytes encodes id in big-endian as key of radixTree
+func (id ID) bytes() []byte {
+	buf := make([]byte, 16)
+	binary.BigEndian.PutUint64(buf, id.Ms)
+	binary.BigEndian.PutUint64(buf[8:], id.Seq)
+	return buf
+}
+
+// Entry is an entry of stream, Fields holds field and value pairs in order
+type Entry struct {
+	ID     ID
+	Fields [][]byte
+}
+
+// Stream is an append-only log of entries ordered by ID, it tracks consumer groups reading it
+type Stream struct {
+	entries *radixTree // ID -> *Entry
+	// lastID is the ID of the latest added entry, it may have been deleted
+	lastID       ID
+	maxDeletedID ID
+	entriesAdded uint64
+	groups       map[string]*Group
+}
+
+// New creates an empty stream
+func New() *Stream {
+	return &Stream{
+		entries: newRadixTree(),
+		groups:  make(map[string]*Group),
+	}
+}
+
+// Len returns the number of entries
+func (s *Stream) Len() int64 {
+	return int64(s.entries.Len())
+}
+
+// LastID returns the ID of the latest added entry
+func (s *Stream) LastID() ID {
+	return s.lastID
+}
+
+// MaxDeletedID returns the largest ID of deleted entries
+func (s *Stream) MaxDeletedID() ID {
+	return s.maxDeletedID
+}
+
+// EntriesAdded returns the number of entries added during the whole life of stream
+func (s *Stream) EntriesAdded() uint64 {
+	return s.entriesAdded
+}
+
+// SetMeta restores the metadata of stream, it is used to load stream from serialized data
+func (s *Stream) SetMeta(lastID ID, maxDeletedID ID, entriesAdded uint64) {
+	s.lastID = lastID
+	s.maxDeletedID = maxDeletedID
+	s.entriesAdded = entriesAdded
+}
+
+// NextID generates ID for new entry at the given milliseconds time, ok is false if no more ID available
+func (s *Stream) NextID(ms uint64) (id ID, ok bool) {
+	if ms > s.lastID.Ms {
+		return ID{Ms: ms}, true
+	}
+	return s.lastID.Incr()
+}
+
+// Add appends entry to stream, id must be greater than LastID
+func (s *Stream) Add(id ID, fields [][]byte) *Entry {
+	entry := &Entry{
+		ID:     id,
+		Fields: fields,
+	}
+	s.entries.Put(id.bytes(), entry)
+	s.lastID = id
+	s.entriesAdded++
+	return entry
+}
+
+// Get returns entry of the given ID
+func (s *Stream) Get(id ID) (*Entry, bool) {
+	val, ok := s.entries.Get(id.bytes())
+	if !ok {
+		return nil, false
+	}
+	return val.(*Entry), true
+}
+
+// Delete removes entry of the given ID, returns whether it existed
+func (s *Stream) Delete(id ID) bool {
+	if !s.entries.Remove(id.bytes()) {
+		return false
+	}
+	if s.maxDeletedID.Less(id) {
+		s.maxDeletedID = id
+	}
+	return true
+}
+
+// First returns the entry with the smallest ID, nil if stream is empty
+func (s *Stream) First() *Entry {
+	var first *Entry
+	s.entries.Ascend(nil, func(key []byte, value interface{}) bool {
+		first = value.(*Entry)
+		return false
+	})
+	return first
+}
+
+// Last returns the entry with the largest ID, nil if stream is empty
+func (s *Stream) Last() *Entry {
+	var last *Entry
+	s.entries.Descend(nil, func(key []byte, value interface{}) bool {
+		last = value.(*Entry)
+		return false
+	})
+	return last
+}
+
+// ForEach visits entries which ID within [start, end], in descending order if desc
+func (s *Stream) ForEach(start ID, end ID, desc bool, consumer func(entry *Entry) bool) {
+	if end.Less(start) {
+		return
+	}
+	if desc {
+		s.entries.Descend(end.bytes(), func(key []byte, value interface{}) bool {
+			entry := value.(*Entry)
+			if entry.ID.Less(start) {
+				return false
+			}
+			return consumer(entry)
+		})
+		return
+	}
+	s.entries.Ascend(start.bytes(), func(key []byte, value interface{}) bool {
+		entry := value.(*Entry)
+		if end.Less(entry.ID) {
+			return false
+		}
+		return consumer(entry)
+	})
+}
+
+// Range returns at most count entries which ID within [start, end], count <= 0 means no limit
+func (s *Stream) Range(start ID, end ID, count int64, desc bool) []*Entry {
+	result := make([]*Entry, 0)
+	s.ForEach(start, end, desc, func(entry *Entry) bool {
+		result = append(result, entry)
+		return count <= 0 || int64(len(result)) < count
+	})
+	return result
+}
+
+// trim removes the oldest entries while shouldRemove returns true, at most limit entries are removed if limit > 0
+func (s *Stream) trim(limit int64, shouldRemove func(entry *Entry, removed int64) bool) int64 {
+	var ids []ID
+	s.entries.Ascend(nil, func(key []byte, value interface{}) bool {
+		entry := value.(*Entry)
+		if limit > 0 && int64(len(ids)) >= limit || !shouldRemove(entry, int64(len(ids))) {
+			return false
+		}
+		ids = append(ids, entry.ID)
+		return true
+	})
+	for _, id := range ids {
+		s.Delete(id)
+	}
+	return int64(len(ids))
+}
+
+// TrimByLen removes the oldest entries until the stream has no more than maxLen entries,
+// at most limit entries are removed if limit > 0, returns the number of removed entries
+func (s *Stream) TrimByLen(maxLen int64, limit int64) int64 {
+	size := s.Len()
+	return s.trim(limit, func(entry *Entry, removed int64) bool {
+		return size-removed > maxLen
+	})
+}
+
+// TrimByMinID removes entries which ID is less than minID,
+// at most limit entries are removed if limit > 0, returns the number of removed entries
+func (s *Stream) TrimByMinID(minID ID, limit int64) int64 {
+	return s.trim(limit, func(entry *Entry, removed int64) bool {
+		return entry.ID.Less(minID)
+	})
+}
+
+// GetGroup returns the consumer group of the given name
+func (s *Stream) GetGroup(name string) (*Group, bool) {
+	group, ok := s.groups[name]
+	return group, ok
+}
+
+// CreateGroup creates a consumer group which delivers entries after lastID, ok is false if the group exists
+func (s *Stream) CreateGroup(name string, lastID ID) (group *Group, ok bool) {
+	if _, exists := s.groups[name]; exists {
+		return nil, false
+	}
+	group = &Group{
+		Name:      name,
+		LastID:    lastID,
+		pending:   newRadixTree(),
+		consumers: make(map[string]*Consumer),
+	}
+	s.groups[name] = group
+	return group, true
+}
+
+// DestroyGroup removes the consumer group, returns whether it existed
+func (s *Stream) DestroyGroup(name string) bool {
+	if _, exists := s.groups[name]; !exists {
+		return false
+	}
+	delete(s.groups, name)
+	return true
+}
+
+// Groups returns all consumer groups ordered by name
+func (s *Stream) Groups() []*Group {
+	groups := make([]*Group, 0, len(s.groups))
+	for _, group := range s.groups {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// Clone returns a deep copy of the stream including consumer groups, entries are immutable and shared
+func (s *Stream) Clone() *Stream {
+	clone := New()
+	s.entries.Ascend(nil, func(key []byte, value interface{}) bool {
+		clone.entries.Put(key, value)
+		return true
+	})
+	clone.SetMeta(s.lastID, s.maxDeletedID, s.entriesAdded)
+	for name, group := range s.groups {
+		groupClone, _ := clone.CreateGroup(name, group.LastID)
+		for _, consumer := range group.consumers {
+			groupClone.CreateConsumer(consumer.Name, consumer.SeenTime)
+		}
+		group.pending.Ascend(nil, func(key []byte, value interface{}) bool {
+			pending := value.(*PendingEntry)
+			consumer, _ := groupClone.GetConsumer(pending.Consumer.Name)
+			pendingClone := groupClone.Deliver(pending.ID, consumer)
+			pendingClone.DeliveryTime = pending.DeliveryTime
+			pendingClone.DeliveryCount = pending.DeliveryCount
+			return true
+		})
+	}
+	return clone
+}
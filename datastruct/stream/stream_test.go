@@ -0,0 +1,181 @@
+package stream
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseID(t *testing.T) {
+	id, seqGiven, err := ParseID("1526919030474-55")
+	if err != nil || !seqGiven || id != (ID{Ms: 1526919030474, Seq: 55}) {
+		t.Errorf("unexpected id %v %v %v", id, seqGiven, err)
+	}
+	id, seqGiven, err = ParseID("100")
+	if err != nil || seqGiven || id != (ID{Ms: 100}) {
+		t.Errorf("unexpected id %v %v %v", id, seqGiven, err)
+	}
+	if id.String() != "100-0" {
+		t.Errorf("unexpected string %s", id.String())
+	}
+	for _, s := range []string{"", "-", "a-1", "1-a", "1-", "-1", "1-2-3", "18446744073709551616"} {
+		if _, _, err := ParseID(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestIDOrder(t *testing.T) {
+	ids := []ID{MinID, {Seq: 1}, {Ms: 1}, {Ms: 1, Seq: math.MaxUint64}, {Ms: 2}, MaxID}
+	for i := range ids {
+		for j := range ids {
+			expected := 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			if cmp := ids[i].Compare(ids[j]); cmp != expected {
+				t.Errorf("compare %v %v: expected %d, actually %d", ids[i], ids[j], expected, cmp)
+			}
+		}
+	}
+	if next, ok := (ID{Ms: 1, Seq: 1}).Incr(); !ok || next != (ID{Ms: 1, Seq: 2}) {
+		t.Errorf("unexpected next %v", next)
+	}
+	if next, ok := (ID{Ms: 1, Seq: math.MaxUint64}).Incr(); !ok || next != (ID{Ms: 2}) {
+		t.Errorf("unexpected next %v", next)
+	}
+	if prev, ok := (ID{Ms: 2}).Decr(); !ok || prev != (ID{Ms: 1, Seq: math.MaxUint64}) {
+		t.Errorf("unexpected prev %v", prev)
+	}
+	if _, ok := MaxID.Incr(); ok {
+		t.Error("expected no next of MaxID")
+	}
+	if _, ok := MinID.Decr(); ok {
+		t.Error("expected no prev of MinID")
+	}
+}
+
+func makeStream(size int) *Stream {
+	s := New()
+	for i := 1; i <= size; i++ {
+		// several entries share the same milliseconds
+		s.Add(ID{Ms: uint64(i / 3), Seq: uint64(i % 3)}, [][]byte{[]byte("f"), []byte("v")})
+	}
+	return s
+}
+
+func assertIDs(t *testing.T, entries []*Entry, expected ...ID) {
+	t.Helper()
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, actually %d", len(expected), len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ID != expected[i] {
+			t.Fatalf("expected %v at %d, actually %v", expected[i], i, entry.ID)
+		}
+	}
+}
+
+func TestStreamAdd(t *testing.T) {
+	s := New()
+	if id, ok := s.NextID(5); !ok || id != (ID{Ms: 5}) {
+		t.Errorf("unexpected next id %v", id)
+	}
+	s.Add(ID{Ms: 5}, nil)
+	// time going backwards doesn't break the order
+	if id, ok := s.NextID(3); !ok || id != (ID{Ms: 5, Seq: 1}) {
+		t.Errorf("unexpected next id %v", id)
+	}
+	s.Add(ID{Ms: 5, Seq: 1}, nil)
+	if id, ok := s.NextID(6); !ok || id != (ID{Ms: 6}) {
+		t.Errorf("unexpected next id %v", id)
+	}
+	s.Add(MaxID, nil)
+	if _, ok := s.NextID(0); ok {
+		t.Error("expected no more id")
+	}
+	if s.Len() != 3 || s.EntriesAdded() != 3 || s.LastID() != MaxID {
+		t.Errorf("unexpected meta %d %d %v", s.Len(), s.EntriesAdded(), s.LastID())
+	}
+	if s.First().ID != (ID{Ms: 5}) || s.Last().ID != MaxID {
+		t.Errorf("unexpected first %v and last %v", s.First().ID, s.Last().ID)
+	}
+
+	if !s.Delete(MaxID) || s.Delete(MaxID) {
+		t.Error("unexpected result of delete")
+	}
+	if s.Len() != 2 || s.LastID() != MaxID || s.MaxDeletedID() != MaxID || s.EntriesAdded() != 3 {
+		t.Errorf("unexpected meta %d %v %v", s.Len(), s.LastID(), s.MaxDeletedID())
+	}
+	if _, ok := s.Get(MaxID); ok {
+		t.Error("expected deleted")
+	}
+	if entry, ok := s.Get(ID{Ms: 5, Seq: 1}); !ok || entry.ID != (ID{Ms: 5, Seq: 1}) {
+		t.Error("expected entry 5-1")
+	}
+	empty := New()
+	if empty.First() != nil || empty.Last() != nil {
+		t.Error("expected no entry")
+	}
+}
+
+func TestStreamRange(t *testing.T) {
+	s := makeStream(10)
+	assertIDs(t, s.Range(MinID, MaxID, 3, false), ID{Ms: 0, Seq: 1}, ID{Ms: 0, Seq: 2}, ID{Ms: 1, Seq: 0})
+	assertIDs(t, s.Range(MinID, MaxID, 2, true), ID{Ms: 3, Seq: 1}, ID{Ms: 3, Seq: 0})
+	assertIDs(t, s.Range(ID{Ms: 1, Seq: 1}, ID{Ms: 2}, 0, false), ID{Ms: 1, Seq: 1}, ID{Ms: 1, Seq: 2}, ID{Ms: 2})
+	assertIDs(t, s.Range(ID{Ms: 1, Seq: 1}, ID{Ms: 2}, 0, true), ID{Ms: 2}, ID{Ms: 1, Seq: 2}, ID{Ms: 1, Seq: 1})
+	// bounds not existing in stream
+	assertIDs(t, s.Range(ID{Ms: 2, Seq: 5}, ID{Ms: 3, Seq: 0}, 0, false), ID{Ms: 3, Seq: 0})
+	assertIDs(t, s.Range(ID{Ms: 2, Seq: 5}, ID{Ms: 3, Seq: 0}, 0, true), ID{Ms: 3, Seq: 0})
+	assertIDs(t, s.Range(ID{Ms: 2}, ID{Ms: 1}, 0, false))
+	assertIDs(t, s.Range(ID{Ms: 100}, MaxID, 0, false))
+}
+
+func TestStreamTrim(t *testing.T) {
+	s := makeStream(10)
+	if removed := s.TrimByLen(8, 0); removed != 2 || s.Len() != 8 {
+		t.Errorf("unexpected removed %d", removed)
+	}
+	if s.First().ID != (ID{Ms: 1}) || s.MaxDeletedID() != (ID{Ms: 0, Seq: 2}) {
+		t.Errorf("unexpected first %v", s.First().ID)
+	}
+	if removed := s.TrimByLen(2, 3); removed != 3 || s.Len() != 5 {
+		t.Errorf("unexpected removed %d", removed)
+	}
+	if removed := s.TrimByMinID(ID{Ms: 3}, 0); removed != 3 || s.Len() != 2 {
+		t.Errorf("unexpected removed %d", removed)
+	}
+	if removed := s.TrimByMinID(ID{Ms: 3}, 0); removed != 0 {
+		t.Errorf("unexpected removed %d", removed)
+	}
+	if removed := s.TrimByLen(0, 0); removed != 2 || s.Len() != 0 || s.LastID() != (ID{Ms: 3, Seq: 1}) {
+		t.Errorf("unexpected removed %d", removed)
+	}
+}
+
+func TestStreamClone(t *testing.T) {
+	s := makeStream(5)
+	group, _ := s.CreateGroup("g", ID{Ms: 1})
+	consumer, _ := group.CreateConsumer("c", 100)
+	pending := group.Deliver(ID{Ms: 1, Seq: 1}, consumer)
+	pending.DeliveryCount = 2
+
+	clone := s.Clone()
+	s.Add(ID{Ms: 10}, nil)
+	group.Ack(ID{Ms: 1, Seq: 1})
+	group.LastID = ID{Ms: 10}
+
+	if clone.Len() != 5 || clone.LastID() != (ID{Ms: 1, Seq: 2}) || clone.EntriesAdded() != 5 {
+		t.Errorf("unexpected meta of clone %d %v", clone.Len(), clone.LastID())
+	}
+	groupClone, ok := clone.GetGroup("g")
+	if !ok || groupClone.LastID != (ID{Ms: 1}) || groupClone.PendingLen() != 1 {
+		t.Fatal("unexpected group of clone")
+	}
+	pendingClone, ok := groupClone.GetPending(ID{Ms: 1, Seq: 1})
+	if !ok || pendingClone.DeliveryCount != 2 || pendingClone.Consumer.Name != "c" || pendingClone.Consumer == consumer {
+		t.Errorf("unexpected pending entry of clone %v", pendingClone)
+	}
+}
@@ -7,3 +7,8 @@ type DB interface {
 	AfterClientClose(conn redis.Connection)
 	Close()
 }
+
+// DataEntity stores data bound to a key, including a string, list, hash, set and so on
+type DataEntity struct {
+	Data interface{}
+}
@@ -8,4 +8,7 @@ type Connection interface {
 
 	GetDBIndex() int
 	SelectDB(int)
+
+	// IsClosed returns true once the client has gone, commands blocking the client should give up
+	IsClosed() bool
 }
@@ -0,0 +1,100 @@
+package geohash
+
+import "math"
+
+// EarthRadius is the earth radius in meters used by redis
+const EarthRadius = 6372797.560856
+
+func degRad(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func radDeg(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// latDistance returns the distance along the meridian
+func latDistance(lat1, lat2 float64) float64 {
+	return EarthRadius * math.Abs(degRad(lat2)-degRad(lat1))
+}
+
+// Distance returns the distance in meters between two positions by haversine formula
+func Distance(lng1, lat1, lng2, lat2 float64) float64 {
+	v := math.Sin((degRad(lng2) - degRad(lng1)) / 2)
+	if v == 0 {
+		return latDistance(lat1, lat2)
+	}
+	lat1r := degRad(lat1)
+	lat2r := degRad(lat2)
+	u := math.Sin((lat2r - lat1r) / 2)
+	a := u*u + math.Cos(lat1r)*math.Cos(lat2r)*v*v
+	return 2 * EarthRadius * math.Asin(math.Sqrt(a))
+}
+
+// DistanceInBox returns the distance from center to position and whether the position is within the box
+// of width and height in meters centered at (lng1, lat1)
+func DistanceInBox(width, height, lng1, lat1, lng2, lat2 float64) (float64, bool) {
+	if latDistance(lat1, lat2) > height/2 {
+		return 0, false
+	}
+	// measure longitude distance at the latitude of position
+	if Distance(lng1, lat2, lng2, lat2) > width/2 {
+		return 0, false
+	}
+	return Distance(lng1, lat1, lng2, lat2), true
+}
+
+// BoundingBox returns the area which covers the box of width and height in meters centered at position
+func BoundingBox(lng, lat, width, height float64) *Area {
+	latDelta := radDeg(height / 2 / EarthRadius)
+	// a box is wider in longitude on the side nearer to the pole
+	lngDeltaTop := radDeg(width / 2 / EarthRadius / math.Cos(degRad(lat+latDelta)))
+	lngDeltaBottom := radDeg(width / 2 / EarthRadius / math.Cos(degRad(lat-latDelta)))
+	lngDelta := math.Max(lngDeltaTop, lngDeltaBottom)
+	return &Area{
+		Lng: Range{Min: lng - lngDelta, Max: lng + lngDelta},
+		Lat: Range{Min: lat - latDelta, Max: lat + latDelta},
+	}
+}
+
+// SearchAreasByRadius returns geohash ranges [min, max) of 52 bits scores which cover the circle of radius
+// in meters centered at position, positions in these ranges should be filtered by the exact distance
+func SearchAreasByRadius(lng, lat, radius float64) [][2]uint64 {
+	return searchAreas(lng, lat, radius*2, radius*2, radius)
+}
+
+// SearchAreasByBox returns geohash ranges [min, max) of 52 bits scores which cover the box of width and height
+// in meters centered at position, positions in these ranges should be filtered by DistanceInBox
+func SearchAreasByBox(lng, lat, width, height float64) [][2]uint64 {
+	return searchAreas(lng, lat, width, height, math.Sqrt(width*width+height*height)/2)
+}
+
+func searchAreas(lng, lat, width, height, radius float64) [][2]uint64 {
+	bounds := BoundingBox(lng, lat, width, height)
+	step := EstimateStepByRadius(radius, lat)
+	neighbours := Neighbours(Encode(lng, lat, step), step)
+	// the estimated step is not small enough if the search area is near to the edge of the cell
+	if step > 1 {
+		north := Decode(neighbours[1], step)
+		south := Decode(neighbours[2], step)
+		east := Decode(neighbours[3], step)
+		west := Decode(neighbours[4], step)
+		if north.Lat.Max < bounds.Lat.Max || south.Lat.Min > bounds.Lat.Min ||
+			east.Lng.Max < bounds.Lng.Max || west.Lng.Min > bounds.Lng.Min {
+			step--
+			neighbours = Neighbours(Encode(lng, lat, step), step)
+		}
+	}
+	shift := Bits - 2*step
+	ranges := make([][2]uint64, 0, len(neighbours))
+	seen := make(map[uint64]struct{}, len(neighbours))
+	for _, hash := range neighbours {
+		// cells may be duplicated if step is small
+		if _, ok := seen[hash]; ok {
+			continue
+		}
+		seen[hash] = struct{}{}
+		ranges = append(ranges, [2]uint64{hash << shift, (hash + 1) << shift})
+	}
+	return ranges
+}
@@ -0,0 +1,191 @@
+package geohash
+
+import "math"
+
+/*
+ * geohash interleaves bits of longitude and latitude into an integer,
+ * positions near to each other share the same prefix in most cases.
+ * The same as redis, a position is encoded into 52 bits (26 bits for each dimension) so that
+ * it can be stored as the score of sorted set without loss of precision.
+ */
+
+const (
+	// MaxStep is the number of bits for each dimension
+	MaxStep = 26
+	// Bits is the number of bits of a full precision geohash
+	Bits = MaxStep * 2
+
+	// LngMin and LngMax limit longitude
+	LngMin = -180.0
+	LngMax = 180.0
+	// LatMin and LatMax limit latitude, the same as EPSG:900913 / EPSG:3785 / OSGEO:41001 used by redis
+	LatMin = -85.05112878
+	LatMax = 85.05112878
+
+	// mercatorMax is the half circumference of earth in web mercator projection
+	mercatorMax = 20037726.37
+)
+
+// Range is a closed range of a dimension
+type Range struct {
+	Min float64
+	Max float64
+}
+
+// Area is the rectangle represented by a geohash
+type Area struct {
+	Lng Range
+	Lat Range
+}
+
+// Center returns the center of area
+func (area *Area) Center() (lng float64, lat float64) {
+	lng = (area.Lng.Min + area.Lng.Max) / 2
+	lat = (area.Lat.Min + area.Lat.Max) / 2
+	// the center may be slightly out of range due to precision loss
+	lng = math.Max(LngMin, math.Min(LngMax, lng))
+	lat = math.Max(LatMin, math.Min(LatMax, lat))
+	return
+}
+
+// interleave spreads bits of x into even positions and bits of y into odd positions
+func interleave(x, y uint32) uint64 {
+	return spread(x) | spread(y)<<1
+}
+
+// deinterleave is the reverse of interleave
+func deinterleave(bits uint64) (x, y uint32) {
+	return squash(bits), squash(bits >> 1)
+}
+
+func spread(v uint32) uint64 {
+	x := uint64(v)
+	x = (x | x<<16) & 0x0000FFFF0000FFFF
+	x = (x | x<<8) & 0x00FF00FF00FF00FF
+	x = (x | x<<4) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x<<2) & 0x3333333333333333
+	x = (x | x<<1) & 0x5555555555555555
+	return x
+}
+
+func squash(x uint64) uint32 {
+	x &= 0x5555555555555555
+	x = (x | x>>1) & 0x3333333333333333
+	x = (x | x>>2) & 0x0F0F0F0F0F0F0F0F
+	x = (x | x>>4) & 0x00FF00FF00FF00FF
+	x = (x | x>>8) & 0x0000FFFF0000FFFF
+	x = (x | x>>16) & 0x00000000FFFFFFFF
+	return uint32(x)
+}
+
+// encodeRange returns the index of the cell containing val when r is divided into 2^step cells
+func encodeRange(val float64, r Range, step uint) uint32 {
+	offset := (val - r.Min) / (r.Max - r.Min)
+	cell := uint64(offset * float64(uint64(1)<<step))
+	if cell >= 1<<step {
+		// val == r.Max belongs to the last cell
+		cell = 1<<step - 1
+	}
+	return uint32(cell)
+}
+
+// EncodeWithRange encodes position into a geohash of 2*step bits within the given ranges
+func EncodeWithRange(lng, lat float64, lngRange, latRange Range, step uint) uint64 {
+	return interleave(encodeRange(lat, latRange, step), encodeRange(lng, lngRange, step))
+}
+
+// Encode encodes position into a geohash of 2*step bits, the position should be validated by Valid
+func Encode(lng, lat float64, step uint) uint64 {
+	return EncodeWithRange(lng, lat, Range{LngMin, LngMax}, Range{LatMin, LatMax}, step)
+}
+
+// Decode returns the area represented by a geohash of 2*step bits
+func Decode(hash uint64, step uint) *Area {
+	latCell, lngCell := deinterleave(hash)
+	cells := float64(uint64(1) << step)
+	lngScale := (LngMax - LngMin) / cells
+	latScale := (LatMax - LatMin) / cells
+	return &Area{
+		Lng: Range{
+			Min: LngMin + float64(lngCell)*lngScale,
+			Max: LngMin + float64(lngCell+1)*lngScale,
+		},
+		Lat: Range{
+			Min: LatMin + float64(latCell)*latScale,
+			Max: LatMin + float64(latCell+1)*latScale,
+		},
+	}
+}
+
+// Valid returns whether the position could be encoded
+func Valid(lng, lat float64) bool {
+	return lng >= LngMin && lng <= LngMax && lat >= LatMin && lat <= LatMax
+}
+
+// Neighbours returns the geohash of the given one and its 8 neighbours, in order of
+// center, north, south, east, west, north east, north west, south east, south west.
+// Cells beyond the edge of longitude wrap around.
+func Neighbours(hash uint64, step uint) []uint64 {
+	latCell, lngCell := deinterleave(hash)
+	mask := uint32(1)<<step - 1
+	move := func(dLng, dLat int32) uint64 {
+		return interleave((latCell+uint32(dLat))&mask, (lngCell+uint32(dLng))&mask)
+	}
+	return []uint64{
+		hash,
+		move(0, 1),
+		move(0, -1),
+		move(1, 0),
+		move(-1, 0),
+		move(1, 1),
+		move(-1, 1),
+		move(1, -1),
+		move(-1, -1),
+	}
+}
+
+// EstimateStepByRadius returns the max step which 3x3 cells could cover the circle of radius (in meters)
+func EstimateStepByRadius(radius float64, lat float64) uint {
+	if radius == 0 {
+		return MaxStep
+	}
+	step := 1
+	for radius < mercatorMax {
+		radius *= 2
+		step++
+	}
+	// make sure range is included in most of the base cases
+	step -= 2
+	// cells near the poles are narrower
+	if lat > 66 || lat < -66 {
+		step--
+		if lat > 80 || lat < -80 {
+			step--
+		}
+	}
+	if step < 1 {
+		step = 1
+	}
+	if step > MaxStep {
+		step = MaxStep
+	}
+	return uint(step)
+}
+
+const base32 = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// ToString returns the standard 11 characters geohash string of position, the same as GEOHASH of redis
+func ToString(lng, lat float64) string {
+	// the standard geohash uses latitude range [-90, 90]
+	hash := EncodeWithRange(lng, lat, Range{LngMin, LngMax}, Range{-90, 90}, MaxStep)
+	buf := make([]byte, 11)
+	for i := range buf {
+		var idx uint64
+		if i < 10 {
+			idx = (hash >> (Bits - (i+1)*5)) & 0x1f
+		}
+		// the last character has only 2 bits of data, fill it with 0 the same as redis
+		buf[i] = base32[idx]
+	}
+	return string(buf)
+}
@@ -0,0 +1,79 @@
+package geohash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	positions := [][2]float64{
+		{13.361389, 38.115556},
+		{-180, LatMin},
+		{180, LatMax},
+		{0, 0},
+	}
+	for _, pos := range positions {
+		hash := Encode(pos[0], pos[1], MaxStep)
+		area := Decode(hash, MaxStep)
+		if pos[0] < area.Lng.Min-1e-9 || pos[0] > area.Lng.Max+1e-9 ||
+			pos[1] < area.Lat.Min-1e-9 || pos[1] > area.Lat.Max+1e-9 {
+			t.Errorf("%v is not in decoded area %+v", pos, area)
+		}
+		lng, lat := area.Center()
+		if math.Abs(lng-pos[0]) > 1e-5 || math.Abs(lat-pos[1]) > 1e-5 {
+			t.Errorf("expected center near %v, actually %f,%f", pos, lng, lat)
+		}
+	}
+}
+
+func TestToString(t *testing.T) {
+	// the same as GEOHASH of redis
+	if s := ToString(13.361389, 38.115556); s != "sqc8b49rny0" {
+		t.Errorf("expected sqc8b49rny0, actually %s", s)
+	}
+	if s := ToString(15.087269, 37.502669); s != "sqdtr74hyu0" {
+		t.Errorf("expected sqdtr74hyu0, actually %s", s)
+	}
+}
+
+func TestValid(t *testing.T) {
+	if !Valid(180, LatMax) || !Valid(-180, LatMin) {
+		t.Error("expected valid bounds")
+	}
+	for _, pos := range [][2]float64{{181, 0}, {0, 86}, {math.NaN(), 0}, {0, math.NaN()}, {math.Inf(1), 0}} {
+		if Valid(pos[0], pos[1]) {
+			t.Errorf("expected %v invalid", pos)
+		}
+	}
+}
+
+func TestNeighbours(t *testing.T) {
+	hash := Encode(13.361389, 38.115556, 10)
+	neighbours := Neighbours(hash, 10)
+	if len(neighbours) != 9 || neighbours[0] != hash {
+		t.Fatalf("unexpected neighbours %v", neighbours)
+	}
+	seen := make(map[uint64]bool)
+	for _, n := range neighbours {
+		seen[n] = true
+	}
+	if len(seen) != 9 {
+		t.Errorf("expected 9 distinct cells, actually %d", len(seen))
+	}
+}
+
+func TestDistance(t *testing.T) {
+	// distance between Palermo and Catania is 166274.1516 meters in redis, which is computed from stored geohash
+	lng1, lat1 := Decode(Encode(13.361389, 38.115556, MaxStep), MaxStep).Center()
+	lng2, lat2 := Decode(Encode(15.087269, 37.502669, MaxStep), MaxStep).Center()
+	dist := Distance(lng1, lat1, lng2, lat2)
+	if math.Abs(dist-166274.1516) > 0.01 {
+		t.Errorf("expected 166274.1516, actually %f", dist)
+	}
+	if step := EstimateStepByRadius(0, 0); step != MaxStep {
+		t.Errorf("expected max step for radius 0, actually %d", step)
+	}
+	if EstimateStepByRadius(1000, 0) <= EstimateStepByRadius(100000, 0) {
+		t.Error("expected more steps for smaller radius")
+	}
+}
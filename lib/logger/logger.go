@@ -23,7 +23,7 @@ var (
 	logFile            *os.File
 	defaultPrefix      = ""
 	defaultCallerDepth = 2
-	logger             *log.Logger
+	logger             = log.New(os.Stdout, defaultPrefix, flags) // replaced by Setup
 	mu                 sync.RWMutex
 	logPrefix          = ""
 	levelFlags         = []string{"DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
@@ -0,0 +1,399 @@
+package serialize
+
+import (
+	"encoding/binary"
+	"errors"
+	Dict "github.com/Ravior/goredis/datastruct/dict"
+	List "github.com/Ravior/goredis/datastruct/list"
+	HashSet "github.com/Ravior/goredis/datastruct/set"
+	SortedSet "github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/datastruct/stream"
+	"hash/crc64"
+	"math"
+)
+
+/*
+ * Payload of DUMP is laid out as:
+ *   value type (1 byte) | encoded value | format version (2 bytes) | CRC64 of all preceding bytes (8 bytes)
+ * Lengths and integers in encoded value are varints, floats are 8 bytes IEEE 754 in little-endian,
+ * a string is its length followed by its bytes.
+ */
+
+// Version is the version of value encoding, payloads of newer version are rejected
+const Version = 1
+
+const footerSize = 2 + 8
+
+// value types
+const (
+	typeString = iota
+	typeList
+	typeSet
+	typeZSet
+	typeHash
+	typeStream
+)
+
+var (
+	// ErrBadPayload means the payload is truncated, corrupted or of unsupported version
+	ErrBadPayload = errors.New("DUMP payload version or checksum are wrong")
+	// ErrUnknownType means the value can't be serialized
+	ErrUnknownType = errors.New("unknown value type")
+
+	errInvalidValue = errors.New("invalid encoded value")
+	crcTable        = crc64.MakeTable(crc64.ECMA)
+)
+
+// Dump encodes val into a versioned and checksummed payload
+func Dump(val interface{}) ([]byte, error) {
+	buf, err := Encode(nil, val)
+	if err != nil {
+		return nil, err
+	}
+	enc := &encoder{buf: buf}
+	enc.writeUint16(Version)
+	enc.writeUint64(crc64.Checksum(enc.buf, crcTable))
+	return enc.buf, nil
+}
+
+// Restore verifies version and checksum of payload generated by Dump, and decodes the value
+func Restore(payload []byte) (interface{}, error) {
+	if len(payload) < footerSize+1 {
+		return nil, ErrBadPayload
+	}
+	body := payload[:len(payload)-footerSize]
+	footer := payload[len(payload)-footerSize:]
+	if binary.LittleEndian.Uint16(footer) > Version {
+		return nil, ErrBadPayload
+	}
+	if crc64.Checksum(payload[:len(payload)-8], crcTable) != binary.LittleEndian.Uint64(footer[2:]) {
+		return nil, ErrBadPayload
+	}
+	val, rest, err := Decode(body)
+	if err != nil || len(rest) > 0 {
+		return nil, ErrBadPayload
+	}
+	return val, nil
+}
+
+// Encode appends type and encoded val to buf, it is shared by DUMP and snapshots
+func Encode(buf []byte, val interface{}) ([]byte, error) {
+	enc := &encoder{buf: buf}
+	switch v := val.(type) {
+	case []byte:
+		enc.writeByte(typeString)
+		enc.writeBytes(v)
+	case List.List:
+		enc.writeByte(typeList)
+		enc.writeUvarint(uint64(v.Len()))
+		v.ForEach(func(i int, element interface{}) bool {
+			enc.writeBytes(element.([]byte))
+			return true
+		})
+	case *HashSet.Set:
+		enc.writeByte(typeSet)
+		enc.writeUvarint(uint64(v.Len()))
+		v.ForEach(func(member string) bool {
+			enc.writeString(member)
+			return true
+		})
+	case *SortedSet.SortedSet:
+		enc.writeByte(typeZSet)
+		enc.writeUvarint(uint64(v.Len()))
+		v.ForEach(0, v.Len(), false, func(element *SortedSet.Element) bool {
+			enc.writeString(element.Member)
+			enc.writeFloat(element.Score)
+			return true
+		})
+	case Dict.Dict:
+		enc.writeByte(typeHash)
+		enc.writeUvarint(uint64(v.Len()))
+		v.ForEach(func(field string, value interface{}) bool {
+			enc.writeString(field)
+			enc.writeBytes(value.([]byte))
+			return true
+		})
+	case *stream.Stream:
+		enc.writeByte(typeStream)
+		enc.writeStream(v)
+	default:
+		return nil, ErrUnknownType
+	}
+	return enc.buf, nil
+}
+
+// Decode decodes a value encoded by Encode from the beginning of data, returns the value and remaining bytes
+func Decode(data []byte) (val interface{}, rest []byte, err error) {
+	dec := &decoder{data: data}
+	switch dec.readByte() {
+	case typeString:
+		val = dec.readBytes()
+	case typeList:
+		list := List.NewQuickList()
+		for n := dec.readLen(); n > 0 && dec.err == nil; n-- {
+			list.Add(dec.readBytes())
+		}
+		val = list
+	case typeSet:
+		set := HashSet.Make()
+		for n := dec.readLen(); n > 0 && dec.err == nil; n-- {
+			set.Add(dec.readString())
+		}
+		val = set
+	case typeZSet:
+		sortedSet := SortedSet.NwSortedSet()
+		for n := dec.readLen(); n > 0 && dec.err == nil; n-- {
+			member := dec.readString()
+			score := dec.readFloat()
+			if math.IsNaN(score) {
+				dec.fail()
+			}
+			sortedSet.Add(member, score)
+		}
+		val = sortedSet
+	case typeHash:
+		dict := Dict.CreateSimpleDict()
+		for n := dec.readLen(); n > 0 && dec.err == nil; n-- {
+			field := dec.readString()
+			dict.Put(field, dec.readBytes())
+		}
+		val = dict
+	case typeStream:
+		val = dec.readStream()
+	default:
+		dec.fail()
+	}
+	if dec.err != nil {
+		return nil, nil, dec.err
+	}
+	return val, dec.data, nil
+}
+
+func (enc *encoder) writeStream(s *stream.Stream) {
+	enc.writeUvarint(uint64(s.Len()))
+	s.ForEach(stream.MinID, stream.MaxID, false, func(entry *stream.Entry) bool {
+		enc.writeID(entry.ID)
+		enc.writeUvarint(uint64(len(entry.Fields)))
+		for _, field := range entry.Fields {
+			enc.writeBytes(field)
+		}
+		return true
+	})
+	enc.writeID(s.LastID())
+	enc.writeID(s.MaxDeletedID())
+	enc.writeUvarint(s.EntriesAdded())
+
+	groups := s.Groups()
+	enc.writeUvarint(uint64(len(groups)))
+	for _, group := range groups {
+		enc.writeString(group.Name)
+		enc.writeID(group.LastID)
+		consumers := group.Consumers()
+		enc.writeUvarint(uint64(len(consumers)))
+		for _, consumer := range consumers {
+			enc.writeString(consumer.Name)
+			enc.writeVarint(consumer.SeenTime)
+		}
+		enc.writeUvarint(uint64(group.PendingLen()))
+		group.ForEachPending(stream.MinID, stream.MaxID, func(pending *stream.PendingEntry) bool {
+			enc.writeID(pending.ID)
+			enc.writeString(pending.Consumer.Name)
+			enc.writeVarint(pending.DeliveryTime)
+			enc.writeUvarint(pending.DeliveryCount)
+			return true
+		})
+	}
+}
+
+func (dec *decoder) readStream() *stream.Stream {
+	s := stream.New()
+	for n := dec.readLen(); n > 0 && dec.err == nil; n-- {
+		id := dec.readID()
+		fields := make([][]byte, 0, dec.readLen())
+		for m := cap(fields); m > 0 && dec.err == nil; m-- {
+			fields = append(fields, dec.readBytes())
+		}
+		if dec.err == nil && !s.LastID().Less(id) && s.Len() > 0 {
+			// entries must be in ascending order
+			dec.fail()
+		}
+		if dec.err != nil {
+			return nil
+		}
+		s.Add(id, fields)
+	}
+	lastID := dec.readID()
+	maxDeletedID := dec.readID()
+	entriesAdded := dec.readUvarint()
+	s.SetMeta(lastID, maxDeletedID, entriesAdded)
+
+	for n := dec.readLen(); n > 0 && dec.err == nil; n-- {
+		group, ok := s.CreateGroup(dec.readString(), dec.readID())
+		if !ok {
+			dec.fail()
+			return nil
+		}
+		for m := dec.readLen(); m > 0 && dec.err == nil; m-- {
+			name := dec.readString()
+			group.CreateConsumer(name, dec.readVarint())
+		}
+		for m := dec.readLen(); m > 0 && dec.err == nil; m-- {
+			id := dec.readID()
+			consumer, ok := group.GetConsumer(dec.readString())
+			deliveryTime := dec.readVarint()
+			deliveryCount := dec.readUvarint()
+			if !ok {
+				dec.fail()
+				return nil
+			}
+			pending := group.Deliver(id, consumer)
+			pending.DeliveryTime = deliveryTime
+			pending.DeliveryCount = deliveryCount
+		}
+	}
+	if dec.err != nil {
+		return nil
+	}
+	return s
+}
+
+type encoder struct {
+	buf []byte
+}
+
+func (enc *encoder) writeByte(b byte) {
+	enc.buf = append(enc.buf, b)
+}
+
+func (enc *encoder) writeUint16(x uint16) {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], x)
+	enc.buf = append(enc.buf, b[:]...)
+}
+
+func (enc *encoder) writeUint64(x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	enc.buf = append(enc.buf, b[:]...)
+}
+
+func (enc *encoder) writeUvarint(x uint64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(b[:], x)
+	enc.buf = append(enc.buf, b[:n]...)
+}
+
+func (enc *encoder) writeVarint(x int64) {
+	var b [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(b[:], x)
+	enc.buf = append(enc.buf, b[:n]...)
+}
+
+func (enc *encoder) writeBytes(b []byte) {
+	enc.writeUvarint(uint64(len(b)))
+	enc.buf = append(enc.buf, b...)
+}
+
+func (enc *encoder) writeString(s string) {
+	enc.writeUvarint(uint64(len(s)))
+	enc.buf = append(enc.buf, s...)
+}
+
+func (enc *encoder) writeFloat(f float64) {
+	enc.writeUint64(math.Float64bits(f))
+}
+
+func (enc *encoder) writeID(id stream.ID) {
+	enc.writeUvarint(id.Ms)
+	enc.writeUvarint(id.Seq)
+}
+
+// decoder reads encoded data, once an error occurs the following reads return zero values
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (dec *decoder) fail() {
+	if dec.err == nil {
+		dec.err = errInvalidValue
+	}
+}
+
+func (dec *decoder) readByte() byte {
+	if dec.err != nil || len(dec.data) == 0 {
+		dec.fail()
+		return 0
+	}
+	b := dec.data[0]
+	dec.data = dec.data[1:]
+	return b
+}
+
+func (dec *decoder) readUvarint() uint64 {
+	if dec.err != nil {
+		return 0
+	}
+	x, n := binary.Uvarint(dec.data)
+	if n <= 0 {
+		dec.fail()
+		return 0
+	}
+	dec.data = dec.data[n:]
+	return x
+}
+
+func (dec *decoder) readVarint() int64 {
+	if dec.err != nil {
+		return 0
+	}
+	x, n := binary.Varint(dec.data)
+	if n <= 0 {
+		dec.fail()
+		return 0
+	}
+	dec.data = dec.data[n:]
+	return x
+}
+
+// readLen reads the number of following items, it can't be greater than the remaining bytes
+// since each item takes at least 1 byte, so corrupted data won't cause huge allocation
+func (dec *decoder) readLen() int {
+	n := dec.readUvarint()
+	if n > uint64(len(dec.data)) {
+		dec.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (dec *decoder) readBytes() []byte {
+	n := dec.readLen()
+	if dec.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	copy(b, dec.data)
+	dec.data = dec.data[n:]
+	return b
+}
+
+func (dec *decoder) readString() string {
+	return string(dec.readBytes())
+}
+
+func (dec *decoder) readFloat() float64 {
+	if dec.err != nil || len(dec.data) < 8 {
+		dec.fail()
+		return 0
+	}
+	f := math.Float64frombits(binary.LittleEndian.Uint64(dec.data))
+	dec.data = dec.data[8:]
+	return f
+}
+
+func (dec *decoder) readID() stream.ID {
+	ms := dec.readUvarint()
+	seq := dec.readUvarint()
+	return stream.ID{Ms: ms, Seq: seq}
+}
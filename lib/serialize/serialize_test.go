@@ -0,0 +1,95 @@
+package serialize
+
+import (
+	"bytes"
+	Dict "github.com/Ravior/goredis/datastruct/dict"
+	List "github.com/Ravior/goredis/datastruct/list"
+	HashSet "github.com/Ravior/goredis/datastruct/set"
+	SortedSet "github.com/Ravior/goredis/datastruct/sortedset"
+	"github.com/Ravior/goredis/datastruct/stream"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestDumpRestore(t *testing.T) {
+	list := List.NewQuickList()
+	for i := 0; i < 2000; i++ {
+		list.Add([]byte(strconv.Itoa(i)))
+	}
+	zset := SortedSet.NwSortedSet()
+	zset.Add("a", 1.5)
+	zset.Add("b", math.Inf(-1))
+	zset.Add("c", math.Inf(1))
+	s := stream.New()
+	s.Add(stream.ID{Ms: 1, Seq: 1}, [][]byte{[]byte("f"), []byte("v")})
+	s.Add(stream.ID{Ms: 2, Seq: 0}, [][]byte{[]byte("f"), []byte("")})
+	s.CreateGroup("g", stream.ID{Ms: 1, Seq: 1})
+
+	// values with determinate order are dumped into the same payload after restored
+	for _, val := range []interface{}{[]byte(""), []byte("value\r\n"), list, zset, s} {
+		payload, err := Dump(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := Restore(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := Dump(restored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(payload, again) {
+			t.Errorf("restored %T differs from the origin", val)
+		}
+	}
+}
+
+func TestDumpRestoreUnordered(t *testing.T) {
+	set := HashSet.Make("a", "b", "c")
+	payload, _ := Dump(set)
+	restored, err := Restore(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restoredSet := restored.(*HashSet.Set); restoredSet.Len() != 3 || !restoredSet.Has("b") {
+		t.Errorf("unexpected set %v", restoredSet.ToSlice())
+	}
+
+	dict := Dict.CreateSimpleDict()
+	dict.Put("f1", []byte("v1"))
+	dict.Put("f2", []byte(""))
+	payload, _ = Dump(dict)
+	restored, err = Restore(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restoredDict := restored.(Dict.Dict)
+	if val, _ := restoredDict.Get("f1"); restoredDict.Len() != 2 || string(val.([]byte)) != "v1" {
+		t.Errorf("unexpected dict %v", restoredDict.Keys())
+	}
+}
+
+func TestRestoreBadPayload(t *testing.T) {
+	payload, _ := Dump([]byte("value"))
+	cases := [][]byte{
+		nil,
+		payload[:len(payload)-1],
+		payload[1:],
+	}
+	flipped := append([]byte{}, payload...)
+	flipped[2] ^= 0xff
+	cases = append(cases, flipped)
+	newer := append([]byte{}, payload...)
+	newer[len(newer)-footerSize] = Version + 1
+	cases = append(cases, newer)
+	for _, c := range cases {
+		if _, err := Restore(c); err != ErrBadPayload {
+			t.Errorf("expected ErrBadPayload for %q, actually %v", c, err)
+		}
+	}
+	if _, err := Dump(42); err != ErrUnknownType {
+		t.Errorf("expected ErrUnknownType, actually %v", err)
+	}
+}
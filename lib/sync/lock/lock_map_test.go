@@ -0,0 +1,75 @@
+package lock
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRWLocksDuplicateKeys(t *testing.T) {
+	locks := Make(16)
+	done := make(chan struct{})
+	go func() {
+		// a key both written and read must be locked once, otherwise it deadlocks
+		locks.RWLocks([]string{"a", "b"}, []string{"a", "c"})
+		locks.RWUnLocks([]string{"a", "b"}, []string{"a", "c"})
+		locks.Locks("a", "a")
+		locks.UnLocks("a", "a")
+		locks.RLocks("a", "a")
+		locks.RUnLocks("a", "a")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("dead lock")
+	}
+}
+
+func TestLocksExclusive(t *testing.T) {
+	locks := Make(16)
+	keys := make([]string, 8)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	counter := 0
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		// lock overlapping keys in different orders
+		group := []string{keys[i%8], keys[(i*3+1)%8], keys[(i*5+2)%8]}
+		go func() {
+			defer wg.Done()
+			locks.RWLocks(group[:1], group[1:])
+			locks.RWUnLocks(group[:1], group[1:])
+			locks.Locks(append(group, keys[0])...)
+			counter++
+			locks.UnLocks(append(group, keys[0])...)
+		}()
+	}
+	wg.Wait()
+	if counter != 100 {
+		t.Errorf("expected 100, actually %d", counter)
+	}
+}
+
+func TestLockAll(t *testing.T) {
+	locks := Make(16)
+	locks.RLock("a")
+	acquired := make(chan struct{})
+	go func() {
+		locks.LockAll()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("LockAll should wait for key locks")
+	case <-time.After(50 * time.Millisecond):
+	}
+	locks.RUnLock("a")
+	<-acquired
+	locks.UnLockAll()
+	locks.Lock("a")
+	locks.UnLock("a")
+}
@@ -0,0 +1,103 @@
+package wildcard
+
+/*
+ * Match is a glob-style matcher compatible with redis:
+ *   `*` matches any sequence of bytes, including an empty one
+ *   `?` matches a single byte
+ *   `[abc]` matches one of the bytes within brackets, `[a-z]` matches a range, `[^x]` matches bytes other than x
+ *   `\` escapes the next byte, such as `\*` matching `*`
+ * It works on bytes rather than runes, the same as redis.
+ */
+
+// Match returns whether str matches pattern
+func Match(pattern string, str string) bool {
+	p, s := 0, 0
+	// position to retry once a mismatch happens after `*`
+	starP, starS := -1, 0
+	for s < len(str) {
+		if p < len(pattern) && pattern[p] == '*' {
+			// collapse successive stars
+			for p < len(pattern) && pattern[p] == '*' {
+				p++
+			}
+			if p == len(pattern) {
+				return true
+			}
+			starP, starS = p, s
+			continue
+		}
+		if p < len(pattern) {
+			if matched, next := matchOne(pattern, p, str[s]); matched {
+				p = next
+				s++
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		// let the last star consume one more byte
+		starS++
+		p, s = starP, starS
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchOne matches the token at pattern[p] against c, returns the position of the next token
+func matchOne(pattern string, p int, c byte) (matched bool, next int) {
+	switch pattern[p] {
+	case '?':
+		return true, p + 1
+	case '\\':
+		if p+1 < len(pattern) {
+			return pattern[p+1] == c, p + 2
+		}
+		return c == '\\', p + 1
+	case '[':
+		return matchClass(pattern, p+1, c)
+	}
+	return pattern[p] == c, p + 1
+}
+
+// matchClass matches c against the class starting at pattern[p] which is after `[`,
+// an unclosed class ends at the end of pattern, the same as redis
+func matchClass(pattern string, p int, c byte) (matched bool, next int) {
+	not := p < len(pattern) && pattern[p] == '^'
+	if not {
+		p++
+	}
+	for p < len(pattern) && pattern[p] != ']' {
+		switch {
+		case pattern[p] == '\\' && p+1 < len(pattern):
+			p++
+			if pattern[p] == c {
+				matched = true
+			}
+		case p+2 < len(pattern) && pattern[p+1] == '-':
+			start, end := pattern[p], pattern[p+2]
+			if start > end {
+				start, end = end, start
+			}
+			if c >= start && c <= end {
+				matched = true
+			}
+			p += 2
+		default:
+			if pattern[p] == c {
+				matched = true
+			}
+		}
+		p++
+	}
+	if p < len(pattern) {
+		// skip `]`
+		p++
+	}
+	if not {
+		matched = !matched
+	}
+	return matched, p
+}
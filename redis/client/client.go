@@ -1,6 +1,17 @@
 package client
 
-import "net"
+import (
+	"errors"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/lib/logger"
+	"github.com/Ravior/goredis/lib/sync/wait"
+	"github.com/Ravior/goredis/redis/parser"
+	"github.com/Ravior/goredis/redis/protocol"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
 const (
 	created = iota
@@ -8,7 +19,166 @@ const (
 	closed
 )
 
+const (
+	chanSize = 256
+	// defaultTimeout is used if no timeout is given when making client
+	defaultTimeout = 3 * time.Second
+)
+
+// Client is a pipeline mode redis client, requests are sent in order by one goroutine
+// and replies are matched with requests in order by another goroutine
 type Client struct {
-	conn   net.Conn
-	status int32
+	conn        net.Conn
+	pendingReqs chan *request // wait to send
+	waitingReqs chan *request // waiting response
+	addr        string
+	timeout     time.Duration
+
+	status  int32
+	working *sync.WaitGroup // its counter presents unfinished requests(pending and waiting)
+}
+
+// request is a message sends to redis server
+type request struct {
+	args    [][]byte
+	reply   redis.Reply
+	waiting *wait.Wait
+	err     error
+}
+
+// MakeClient connects to redis server, timeout limits both dialing and waiting for each reply
+func MakeClient(addr string, timeout time.Duration) (*Client, error) {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		addr:        addr,
+		conn:        conn,
+		timeout:     timeout,
+		pendingReqs: make(chan *request, chanSize),
+		waitingReqs: make(chan *request, chanSize),
+		status:      created,
+		working:     &sync.WaitGroup{},
+	}, nil
+}
+
+// Start starts asynchronous goroutines
+func (client *Client) Start() {
+	go client.handleWrite()
+	go client.handleRead()
+	atomic.StoreInt32(&client.status, running)
+}
+
+// Close stops asynchronous goroutines and closes connection
+func (client *Client) Close() {
+	if !atomic.CompareAndSwapInt32(&client.status, running, closed) {
+		return
+	}
+	// stop new request
+	close(client.pendingReqs)
+
+	// wait unfinished requests
+	client.working.Wait()
+
+	// clean, waitingReqs is closed by handleWrite which is the only sender
+	_ = client.conn.Close()
+}
+
+var (
+	// ErrClosed is returned for requests sent after the client is closed
+	ErrClosed = errors.New("client closed")
+	// ErrTimeout is returned if the reply is not received in time
+	ErrTimeout = errors.New("server time out")
+)
+
+// Send sends a request to redis server and waits for its reply,
+// failures of the client such as time out are replied as error too
+func (client *Client) Send(args [][]byte) redis.Reply {
+	reply, err := client.Do(args)
+	if err == ErrClosed || err == ErrTimeout {
+		return protocol.NewErrReply(err.Error())
+	}
+	if err != nil {
+		return protocol.NewErrReply("request failed " + err.Error())
+	}
+	return reply
+}
+
+// Do sends a request to redis server and waits for its reply.
+// It returns error if the request fails on the client side, an error replied by server is returned as reply.
+func (client *Client) Do(args [][]byte) (redis.Reply, error) {
+	if atomic.LoadInt32(&client.status) != running {
+		return nil, ErrClosed
+	}
+	req := &request{
+		args:    args,
+		waiting: &wait.Wait{},
+	}
+	req.waiting.Add(1)
+	client.working.Add(1)
+	defer client.working.Done()
+	client.pendingReqs <- req
+	timeout := req.waiting.WaitWithTimeout(client.timeout)
+	if timeout {
+		return nil, ErrTimeout
+	}
+	if req.err != nil {
+		return nil, req.err
+	}
+	return req.reply, nil
+}
+
+func (client *Client) handleWrite() {
+	for req := range client.pendingReqs {
+		client.doRequest(req)
+	}
+	close(client.waitingReqs)
+}
+
+func (client *Client) doRequest(req *request) {
+	if req == nil || len(req.args) == 0 {
+		return
+	}
+	data := protocol.NewMultiBulkReply(req.args).ToBytes()
+	_, err := client.conn.Write(data)
+	if err != nil {
+		req.err = err
+		req.waiting.Done()
+		return
+	}
+	client.waitingReqs <- req
+}
+
+func (client *Client) finishRequest(reply redis.Reply, err error) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error(err)
+		}
+	}()
+	req := <-client.waitingReqs
+	if req == nil {
+		return
+	}
+	req.reply = reply
+	req.err = err
+	req.waiting.Done()
+}
+
+func (client *Client) handleRead() {
+	ch := parser.ParseStream(client.conn)
+	for payload := range ch {
+		if payload.Err != nil {
+			if atomic.LoadInt32(&client.status) == closed {
+				return
+			}
+			// the request waiting for reply fails, parser stops after reporting io error
+			client.finishRequest(nil, payload.Err)
+			continue
+		}
+		client.finishRequest(payload.Data, nil)
+	}
 }
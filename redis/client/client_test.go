@@ -0,0 +1,126 @@
+package client
+
+import (
+	"github.com/Ravior/goredis/redis/parser"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// serveEcho replies each command with its last argument, command "sleep" delays the reply
+// and command "quit" closes the connection without reply
+func serveEcho(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for payload := range parser.ParseStream(conn) {
+					if payload.Err != nil {
+						return
+					}
+					args := payload.Data.(*protocol.MultiBulkReply).Args
+					switch string(args[0]) {
+					case "quit":
+						return
+					case "sleep":
+						time.Sleep(200 * time.Millisecond)
+					}
+					_, _ = conn.Write(protocol.NewBulkReply(args[len(args)-1]).ToBytes())
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestSend(t *testing.T) {
+	client, err := MakeClient(serveEcho(t), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Start()
+	defer client.Close()
+
+	// replies are matched with concurrent requests
+	wg := sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value := strconv.Itoa(i)
+			reply := client.Send(utils.ToCmdLine("echo", value))
+			if string(reply.ToBytes()) != string(protocol.NewBulkReply([]byte(value)).ToBytes()) {
+				t.Errorf("expected %s, actually %q", value, reply.ToBytes())
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTimeout(t *testing.T) {
+	client, err := MakeClient(serveEcho(t), 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Start()
+	defer client.Close()
+	reply := client.Send(utils.ToCmdLine("sleep"))
+	if !protocol.IsErrorReply(reply) || string(reply.ToBytes()) != "-server time out\r\n" {
+		t.Errorf("expected time out, actually %q", reply.ToBytes())
+	}
+}
+
+func TestClose(t *testing.T) {
+	client, err := MakeClient(serveEcho(t), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Start()
+	client.Close()
+	reply := client.Send(utils.ToCmdLine("echo", "a"))
+	if string(reply.ToBytes()) != "-client closed\r\n" {
+		t.Errorf("expected client closed, actually %q", reply.ToBytes())
+	}
+	// closing again is a no-op
+	client.Close()
+}
+
+func TestServerClosed(t *testing.T) {
+	client, err := MakeClient(serveEcho(t), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Start()
+	defer client.Close()
+	reply := client.Send(utils.ToCmdLine("quit"))
+	if !protocol.IsErrorReply(reply) {
+		t.Errorf("expected error, actually %q", reply.ToBytes())
+	}
+}
+
+func TestDialFailed(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+	if _, err := MakeClient(addr, time.Second); err == nil {
+		t.Error("expected dial error")
+	}
+}
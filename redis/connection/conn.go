@@ -1,6 +1,7 @@
 package connection
 
 import (
+	"github.com/Ravior/goredis/lib/sync/atomic"
 	"github.com/Ravior/goredis/lib/sync/wait"
 	"net"
 	"sync"
@@ -21,6 +22,9 @@ type Connection struct {
 
 	// selected db
 	selectedDB int
+
+	// closed is set once the client disconnected
+	closed atomic.Boolean
 }
 
 // NewConn creates Connection instance
@@ -30,6 +34,12 @@ func NewConn(conn net.Conn) *Connection {
 	}
 }
 
+// NewFakeConn creates Connection without network connection, replies to it are discarded.
+// It is used to execute commands from other source than clients, such as loading aof file.
+func NewFakeConn() *Connection {
+	return &Connection{}
+}
+
 // RemoteAddr returns the remote network address
 func (c *Connection) RemoteAddr() net.Addr {
 	return c.conn.RemoteAddr()
@@ -55,7 +65,7 @@ func (c *Connection) SelectDB(dbIndex int) {
 
 // Write sends response to client over tcp connection
 func (c *Connection) Write(b []byte) error {
-	if len(b) == 0 {
+	if len(b) == 0 || c.conn == nil {
 		return nil
 	}
 	c.waitingReply.Add(1)
@@ -67,8 +77,19 @@ func (c *Connection) Write(b []byte) error {
 	return err
 }
 
+// MarkClosed records that the client has disconnected, the socket may still be in use by pending replies
+func (c *Connection) MarkClosed() {
+	c.closed.Set(true)
+}
+
+// IsClosed returns true once the client has disconnected
+func (c *Connection) IsClosed() bool {
+	return c.closed.Get()
+}
+
 // Close disconnect with the client
 func (c *Connection) Close() error {
+	c.closed.Set(true)
 	c.waitingReply.WaitWithTimeout(10 * time.Second)
 	_ = c.conn.Close()
 	return nil
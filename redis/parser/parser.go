@@ -66,6 +66,7 @@ type readState struct {
 	args              [][]byte
 	bulkLen           int64
 	readingRepl       bool
+	readingBody       bool // the next line is the body of a bulk string, even if it starts with '$'
 }
 
 func (s *readState) finished() bool {
@@ -76,6 +77,8 @@ func parse0(reader io.Reader, ch chan<- *Payload) {
 	defer func() {
 		if err := recover(); err != nil {
 			logger.Error(err, string(debug.Stack()))
+			// readers range over ch, stop them as if the stream ended
+			close(ch)
 		}
 	}()
 
@@ -185,7 +188,7 @@ func readLine(bufReader *bufio.Reader, state *readState) ([]byte, bool, error) {
 		if err != nil {
 			return nil, true, err
 		}
-		if len(msg) == 0 || msg[len(msg)-2] != '\r' {
+		if len(msg) < 2 || msg[len(msg)-2] != '\r' {
 			return nil, false, errors.New("protocol error: " + string(msg))
 		}
 	} else { // read bulk line (binary safe)
@@ -235,7 +238,8 @@ func parseBulkHeader(msg []byte, state *readState) error {
 	}
 	if state.bulkLen == -1 { // null bulk
 		return nil
-	} else if state.bulkLen > 0 {
+	} else if state.bulkLen >= 0 {
+		state.readingBody = true
 		state.msgType = msg[0]
 		state.readingMultiLine = true
 		state.expectedArgsCount = 1
@@ -276,18 +280,22 @@ func parseSingleLineReply(msg []byte) (redis.Reply, error) {
 func readBody(msg []byte, state *readState) error {
 	line := msg[0 : len(msg)-2]
 	var err error
-	if line[0] == '$' {
+	if !state.readingBody && len(line) > 0 && line[0] == '$' {
 		// bulk protocol
 		state.bulkLen, err = strconv.ParseInt(string(line[1:]), 10, 64)
 		if err != nil {
 			return errors.New("protocol error: " + string(msg))
 		}
-		if state.bulkLen <= 0 { // null bulk in multi bulks
+		if state.bulkLen < 0 { // null bulk in multi bulks
 			state.args = append(state.args, []byte{})
 			state.bulkLen = 0
+		} else {
+			// an empty bulk is followed by a blank line which will be read as a normal line
+			state.readingBody = true
 		}
 	} else {
 		state.args = append(state.args, line)
+		state.readingBody = false
 	}
 	return nil
 }
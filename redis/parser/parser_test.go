@@ -0,0 +1,129 @@
+package parser
+
+import (
+	"bytes"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/protocol"
+	"io"
+	"testing"
+)
+
+func TestParseStream(t *testing.T) {
+	replies := []redis.Reply{
+		protocol.NewIntReply(1),
+		protocol.NewStatusReply("OK"),
+		protocol.NewErrReply("ERR unknown"),
+		protocol.NewBulkReply([]byte("a\r\nb")), // test binary safe
+		protocol.NewNullBulkReply(),
+		protocol.NewMultiBulkReply([][]byte{
+			[]byte("a"),
+			[]byte("\r\n"),
+		}),
+		protocol.NewEmptyMultiBulkReply(),
+		protocol.NewMultiBulkReply([][]byte{
+			[]byte("$3"), // looks like a bulk header but it's a bulk body
+			[]byte(""),
+			[]byte("b"),
+		}),
+	}
+	reqs := bytes.Buffer{}
+	for _, re := range replies {
+		reqs.Write(re.ToBytes())
+	}
+	reqs.Write([]byte("set a a" + protocol.CRLF)) // test text protocol
+	expected := make([]redis.Reply, len(replies))
+	copy(expected, replies)
+	expected = append(expected, protocol.NewMultiBulkReply([][]byte{
+		[]byte("set"), []byte("a"), []byte("a"),
+	}))
+
+	ch := ParseStream(bytes.NewReader(reqs.Bytes()))
+	i := 0
+	for payload := range ch {
+		if payload.Err != nil {
+			if payload.Err == io.EOF {
+				break
+			}
+			t.Error(payload.Err)
+			return
+		}
+		if payload.Data == nil {
+			t.Error("empty data")
+			return
+		}
+		exp := expected[i]
+		i++
+		if !bytes.Equal(exp.ToBytes(), payload.Data.ToBytes()) {
+			t.Errorf("parse failed, expected %q, actually %q", exp.ToBytes(), payload.Data.ToBytes())
+		}
+	}
+	if i != len(expected) {
+		t.Errorf("expected %d replies, actually %d", len(expected), i)
+	}
+}
+
+func TestParseOne(t *testing.T) {
+	replies := []redis.Reply{
+		protocol.NewIntReply(1),
+		protocol.NewStatusReply("OK"),
+		protocol.NewErrReply("ERR unknown"),
+		protocol.NewBulkReply([]byte("a\r\nb")), // test binary safe
+		protocol.NewNullBulkReply(),
+		protocol.NewMultiBulkReply([][]byte{
+			[]byte("a"),
+			[]byte("\r\n"),
+		}),
+		protocol.NewEmptyMultiBulkReply(),
+	}
+	for _, re := range replies {
+		result, err := ParseOne(re.ToBytes())
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !bytes.Equal(result.ToBytes(), re.ToBytes()) {
+			t.Errorf("parse failed, expected %q, actually %q", re.ToBytes(), result.ToBytes())
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	inputs := []string{
+		"\n",
+		"x\n",
+		"*1\r\n\n",
+		"*1\r\n$1\n",
+		"*x\r\n",
+		"$-2\r\n",
+		":abc\r\n",
+	}
+	for _, input := range inputs {
+		ch := ParseStream(bytes.NewReader([]byte(input)))
+		var errs int
+		for payload := range ch {
+			if payload.Err == nil {
+				continue
+			}
+			if payload.Err == io.EOF || payload.Err == io.ErrUnexpectedEOF {
+				break
+			}
+			errs++
+		}
+		if errs == 0 {
+			t.Errorf("expected protocol error for %q", input)
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	full := protocol.NewMultiBulkReply([][]byte{[]byte("set"), []byte("key"), []byte("value")}).ToBytes()
+	for size := 0; size < len(full); size++ {
+		replies, err := ParseBytes(full[:size])
+		if len(replies) != 0 {
+			t.Errorf("expected no reply from %q, actually %d", full[:size], len(replies))
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			t.Errorf("unexpected error %v for %q", err, full[:size])
+		}
+	}
+}
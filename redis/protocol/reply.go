@@ -7,7 +7,6 @@ import (
 )
 
 var (
-	nullBulkReplyBytes = []byte("$-1")
 	// CRLF is the line separator of redis serialization protocol
 	CRLF = "\r\n"
 )
@@ -28,8 +27,8 @@ func NewBulkReply(arg []byte) *BulkReply {
 
 // ToBytes marshal redis.Reply
 func (r *BulkReply) ToBytes() []byte {
-	if len(r.Arg) == 0 {
-		return nullBulkReplyBytes
+	if r.Arg == nil {
+		return nullBulkBytes
 	}
 	return []byte("$" + strconv.Itoa(len(r.Arg)) + CRLF + string(r.Arg) + CRLF)
 }
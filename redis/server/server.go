@@ -2,9 +2,11 @@ package server
 
 import (
 	"context"
+	"fmt"
 	"github.com/Ravior/goredis/config"
 	database2 "github.com/Ravior/goredis/database"
 	"github.com/Ravior/goredis/interface/database"
+	"github.com/Ravior/goredis/interface/redis"
 	"github.com/Ravior/goredis/lib/logger"
 	"github.com/Ravior/goredis/lib/sync/atomic"
 	"github.com/Ravior/goredis/redis/connection"
@@ -12,6 +14,7 @@ import (
 	"github.com/Ravior/goredis/redis/protocol"
 	"io"
 	"net"
+	"runtime/debug"
 	"strings"
 	"sync"
 )
@@ -20,6 +23,10 @@ var (
 	unknownErrReplyBytes = []byte("-ERR unknown\r\n")
 )
 
+// maxPendingPayloads limits payloads read ahead by relay while a command is running,
+// relay stops reading once it is reached, until the command loop catches up
+const maxPendingPayloads = 1024
+
 type Handler struct {
 	activeConn sync.Map // *client -> placeholder
 	db         database.DB
@@ -41,19 +48,62 @@ func NewHandler() *Handler {
 
 func (h *Handler) closeClient(client *connection.Connection) {
 	_ = client.Close()
+	h.db.AfterClientClose(client)
 	h.activeConn.Delete(client)
 }
 
+func isClosedErr(err error) bool {
+	return err == io.EOF ||
+		err == io.ErrUnexpectedEOF ||
+		strings.Contains(err.Error(), "use of closed network connection")
+}
+
+// relay forwards payloads to the command loop of client.
+// While a command is blocking the client (e.g. BLPOP), the command loop can't see the client has gone,
+// so relay keeps reading and notifies db as soon as the client disconnected.
+func (h *Handler) relay(client *connection.Connection, in <-chan *parser.Payload) <-chan *parser.Payload {
+	out := make(chan *parser.Payload)
+	go func() {
+		defer close(out)
+		var pending []*parser.Payload
+		for in != nil || len(pending) > 0 {
+			var next *parser.Payload
+			var sendCh chan<- *parser.Payload
+			if len(pending) > 0 {
+				next = pending[0]
+				sendCh = out
+			}
+			recvCh := in
+			if len(pending) >= maxPendingPayloads {
+				recvCh = nil
+			}
+			select {
+			case payload, ok := <-recvCh:
+				if !ok {
+					in = nil
+					continue
+				}
+				if payload.Err != nil && isClosedErr(payload.Err) {
+					client.MarkClosed()
+					h.db.AfterClientClose(client)
+				}
+				pending = append(pending, payload)
+			case sendCh <- next:
+				pending = pending[1:]
+			}
+		}
+	}()
+	return out
+}
+
 func (h *Handler) Handle(ctx context.Context, conn net.Conn) {
 	client := connection.NewConn(conn)
 	h.activeConn.Store(client, 1)
 
-	ch := parser.ParseStream(conn)
+	ch := h.relay(client, parser.ParseStream(conn))
 	for payload := range ch {
 		if payload.Err != nil {
-			if payload.Err == io.EOF ||
-				payload.Err == io.ErrUnexpectedEOF ||
-				strings.Contains(payload.Err.Error(), "use of closed network connection") {
+			if isClosedErr(payload.Err) {
 				h.closeClient(client)
 				logger.Info("connection closed: " + client.RemoteAddr().String())
 				return
@@ -77,7 +127,7 @@ func (h *Handler) Handle(ctx context.Context, conn net.Conn) {
 			logger.Error("require multi bulk protocol")
 			continue
 		}
-		result := h.db.Exec(client, r.Args)
+		result := h.exec(client, r.Args)
 		if result != nil {
 			_ = client.Write(result.ToBytes())
 		} else {
@@ -87,6 +137,29 @@ func (h *Handler) Handle(ctx context.Context, conn net.Conn) {
 
 }
 
+// exec executes command with db, a panic of the command is recovered so that it won't crash the server
+func (h *Handler) exec(client *connection.Connection, cmdLine [][]byte) (result redis.Reply) {
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error(fmt.Sprintf("panic when executing %q: %v\n%s", cmdLine[0], err, debug.Stack()))
+			result = nil
+		}
+	}()
+	return h.db.Exec(client, cmdLine)
+}
+
+// Close stops handler
 func (h *Handler) Close() error {
+	if h.closing.Get() {
+		return nil
+	}
+	logger.Info("handler shutting down...")
+	h.closing.Set(true)
+	h.activeConn.Range(func(key interface{}, val interface{}) bool {
+		client := key.(*connection.Connection)
+		_ = client.Close()
+		return true
+	})
+	h.db.Close()
 	return nil
 }
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"github.com/Ravior/goredis/interface/redis"
+	"github.com/Ravior/goredis/redis/connection"
+	"github.com/Ravior/goredis/redis/parser"
+	"github.com/Ravior/goredis/redis/protocol"
+	"github.com/Ravior/goredis/utils"
+	"net"
+	"testing"
+	"time"
+)
+
+// testDB panics on command "panic" and echoes other commands
+type testDB struct{}
+
+func (db *testDB) Exec(client redis.Connection, cmdLine [][]byte) redis.Reply {
+	if string(cmdLine[0]) == "panic" {
+		panic("boom")
+	}
+	return protocol.NewBulkReply(cmdLine[0])
+}
+
+func (db *testDB) AfterClientClose(conn redis.Connection) {}
+
+func (db *testDB) Close() {}
+
+func TestHandleRecoverPanic(t *testing.T) {
+	h := &Handler{db: &testDB{}}
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	go h.Handle(context.Background(), serverConn)
+
+	reader := bufio.NewReader(clientConn)
+	for _, cmd := range []string{"panic", "echo"} {
+		_, err := clientConn.Write(protocol.NewMultiBulkReply(utils.ToCmdLine(cmd)).ToBytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = clientConn.SetReadDeadline(time.Now().Add(time.Second))
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cmd == "panic" && line != "-ERR unknown\r\n" {
+			t.Errorf("expected error reply, actually %q", line)
+		}
+		if cmd == "echo" && line != "$4\r\n" {
+			t.Errorf("expected bulk reply, actually %q", line)
+		}
+	}
+}
+
+func TestRelayBacklog(t *testing.T) {
+	h := &Handler{db: &testDB{}}
+	in := make(chan *parser.Payload)
+	out := h.relay(connection.NewFakeConn(), in)
+	sent := make(chan int)
+	go func() {
+		count := 0
+		defer func() {
+			sent <- count
+		}()
+		for i := 0; i < maxPendingPayloads*2; i++ {
+			select {
+			case in <- &parser.Payload{Data: protocol.NewOkReply()}:
+				count++
+			case <-time.After(100 * time.Millisecond):
+				return
+			}
+		}
+	}()
+	// the command loop doesn't read, relay stops reading once backlog is full
+	if count := <-sent; count > maxPendingPayloads+1 {
+		t.Errorf("expected at most %d payloads read, actually %d", maxPendingPayloads+1, count)
+	}
+	close(in)
+	received := 0
+	for range out {
+		received++
+	}
+	if received == 0 || received > maxPendingPayloads+1 {
+		t.Errorf("unexpected number of payloads relayed: %d", received)
+	}
+}